@@ -0,0 +1,274 @@
+//Package audiobridge pion clients of janus audiobridge plugin, see jwsapi/jplugin/jaudiobridge
+package audiobridge
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jaudiobridge"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("audiobridge")
+
+//span attributes
+const attrKeyRoom = attribute.Key("janus.room")
+
+//Track track
+type Track = rtcsession.Track
+
+//Stats statistics snapshot of a session
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//AudioBridge opus member of janus-gateway audiobridge room, send audio to mix, recv mixed audio
+type AudioBridge struct {
+	rtc     *rtcsession.Session
+	jMember *jaudiobridge.Member
+	track   *Track
+
+	onAudioTrack func(context.Context, *webrtc.Track)
+}
+
+//AudioBridgeOption option for AudioBridge
+type AudioBridgeOption func(*AudioBridge)
+
+//WithAudioBridgeAudioTrack using to setting mixed audio track callback
+func WithAudioBridgeAudioTrack(callback func(context.Context, *webrtc.Track)) AudioBridgeOption {
+	return func(a *AudioBridge) {
+		a.onAudioTrack = callback
+	}
+}
+
+//WithAudioBridgeConfigure set webrtc configure
+func WithAudioBridgeConfigure(configure webrtc.Configuration) AudioBridgeOption {
+	return func(a *AudioBridge) {
+		a.rtc.Configure = configure
+	}
+}
+
+//WithAudioBridgeStats report stats every interval
+func WithAudioBridgeStats(interval time.Duration, callback func(Stats)) AudioBridgeOption {
+	return func(a *AudioBridge) {
+		a.rtc.StatsInterval = interval
+		a.rtc.OnStats = callback
+	}
+}
+
+//NewAudioBridge new member of room, h is handle of janus.plugin.audiobridge, api must support opus
+func NewAudioBridge(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, room uint64, opts ...jaudiobridge.MemberOption) *AudioBridge {
+	a := &AudioBridge{
+		jMember: jaudiobridge.NewMember(ctx, h, room, opts...),
+	}
+	a.rtc = rtcsession.New(ctx, api, h, a.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(a.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(a.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(a.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(a.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(a.rtc.OnSlowLink))
+
+	return a
+}
+
+//Object return jaudiobridge.Member
+func (a *AudioBridge) Object() *jaudiobridge.Member {
+	return a.jMember
+}
+
+//ID return id info
+func (a *AudioBridge) ID() string {
+	return fmt.Sprintf("[AudioBridge.%d.%d]", a.jMember.Room(), a.jMember.ID())
+}
+
+//Stats return statistics snapshot
+func (a *AudioBridge) Stats() Stats {
+	return a.rtc.Stats()
+}
+
+//SetOption set option
+func (a *AudioBridge) SetOption(opts ...AudioBridgeOption) *AudioBridge {
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+//Join join room with opus PeerConnection
+//jaudiobridge.WithMessageOptionMuted, jaudiobridge.WithMessageOptionPin... for other params
+func (a *AudioBridge) Join(opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := a.rtc.StartSpan(a.rtc.Ctx, "audiobridge.AudioBridge.Join", attrKeyRoom.Int64(int64(a.jMember.Room())))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	a.rtc.SetTraceLink(ctx)
+
+	var pc *webrtc.PeerConnection
+	err = a.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = a.rtc.API.NewPeerConnection(a.rtc.Configure)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "NewPeerConnection")
+	}
+	a.rtc.PC = pc
+
+	pc.OnTrack(a.onTrack)
+	pc.OnICECandidate(a.rtc.OnICECandidate)
+	pc.OnConnectionStateChange(a.onPeerConnectionState)
+
+	track, err := pc.NewTrack(webrtc.DefaultPayloadTypeOpus, rand.Uint32(), "audio", "bridgeA0")
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "NewTrack(Audio)")
+	}
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "AddTrack(Audio)")
+	}
+	a.track = rtcsession.NewTrack(track, a.rtc.Collector)
+
+	var offer webrtc.SessionDescription
+	err = a.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateOffer")
+	}
+	err = a.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	answer, err := a.jMember.JoinContext(ctx, offer.SDP, opts...)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "join")
+	}
+	err = a.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+
+	go a.startSender(sender)
+	go a.rtc.DoRemoteCandidate(a.rtc.RemoteCandidates)
+	go a.rtc.DoStats(pc)
+	return nil
+}
+
+//GetTrack return audio track, write opus rtp to mix, nil before Join
+func (a *AudioBridge) GetTrack() *Track {
+	return a.track
+}
+
+//SetMuted mute or unmute itself
+func (a *AudioBridge) SetMuted(muted bool) error {
+	return a.jMember.SetMuted(muted)
+}
+
+//ChangeRoom change to other room, PeerConnection is kept
+func (a *AudioBridge) ChangeRoom(room uint64, opts ...jwsapi.MessageOption) error {
+	return a.jMember.ChangeRoom(room, opts...)
+}
+
+//Leave leave room, close PeerConnection
+func (a *AudioBridge) Leave() error {
+	if a.rtc.PC != nil {
+		a.rtc.PC.Close()
+	}
+	return a.jMember.Leave()
+}
+
+//ReadRTP read rtp from mixed track and update stats
+//audio track callback should using this instead of track.ReadRTP
+func (a *AudioBridge) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	a.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+func (a *AudioBridge) onHangup(msg jwsapi.Message) {
+	if a.rtc.PC != nil {
+		a.rtc.PC.Close()
+	}
+}
+
+func (a *AudioBridge) onWebrtcup(msg jwsapi.Message) {
+	log.Info("webrtcup", logging.F("audiobridge", a.ID()))
+}
+
+func (a *AudioBridge) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("audiobridge", a.ID()), logging.F("state", state.String()))
+}
+
+func (a *AudioBridge) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+
+	log.Info("onTrack", logging.F("audiobridge", a.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+
+	go a.startReceiver(receiver)
+
+	if codec := track.Codec(); codec != nil {
+		a.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
+	if a.onAudioTrack != nil {
+		a.onAudioTrack(a.rtc.Ctx, track)
+		return
+	}
+
+	//no callback for user
+	for a.rtc.Ctx.Err() == nil {
+		if _, err := a.ReadRTP(track); err != nil {
+			return
+		}
+	}
+}
+
+func (a *AudioBridge) startSender(sender *webrtc.RTPSender) {
+	for a.rtc.Ctx.Err() == nil {
+		packets, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		a.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
+
+func (a *AudioBridge) startReceiver(receiver *webrtc.RTPReceiver) {
+	for a.rtc.Ctx.Err() == nil {
+		packets, err := receiver.ReadRTCP()
+		if err != nil {
+			return
+		}
+		a.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
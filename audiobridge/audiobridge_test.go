@@ -0,0 +1,97 @@
+package audiobridge_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/audiobridge"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jaudiobridge"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestAudioBridge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.AudioBridgePlugin, janustest.NewAudioBridge())
+
+	admin := janustest.Attach(t, ctx, s, jaudiobridge.Plugin)
+	room, err := jaudiobridge.CreateRoom(admin, 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	//join of unknown room is *jplugin.Error
+	_, err = jaudiobridge.NewMember(ctx, janustest.Attach(t, ctx, s, jaudiobridge.Plugin), room+1).JoinContext(ctx, "")
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.AudioBridgeErrorNoSuchRoom {
+		t.Errorf("join unknown room: err = %v, want %d", err, janustest.AudioBridgeErrorNoSuchRoom)
+	}
+
+	parts := make(chan jaudiobridge.Participant, 8)
+	packets := make(chan *rtp.Packet, 16)
+	var listener *audiobridge.AudioBridge
+	listener = audiobridge.NewAudioBridge(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jaudiobridge.Plugin), room,
+		jaudiobridge.WithMemberDisplay("listener"),
+		jaudiobridge.WithMemberParticipant(func(part jaudiobridge.Participant) {
+			parts <- part
+		}))
+	listener.SetOption(audiobridge.WithAudioBridgeAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := listener.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := listener.Join(); err != nil {
+		t.Fatalf("join listener: %v", err)
+	}
+	defer listener.Leave()
+
+	speaker := audiobridge.NewAudioBridge(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jaudiobridge.Plugin), room, jaudiobridge.WithMemberDisplay("speaker"))
+	if err := speaker.Join(); err != nil {
+		t.Fatalf("join speaker: %v", err)
+	}
+	defer speaker.Leave()
+
+	select {
+	case part := <-parts:
+		if part.Display() != "speaker" {
+			t.Errorf("participant = %s, want speaker", part.Display())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("joined event of speaker is not received")
+	}
+	list, err := jaudiobridge.ListParticipants(admin, room)
+	if err != nil || len(list) != 2 {
+		t.Errorf("listparticipants = %v, %v, want 2", list, err)
+	}
+
+	deadline := time.After(10 * time.Second)
+	track := speaker.GetTrack()
+	for seq := uint32(1); ; seq++ {
+		track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), Timestamp: seq * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		select {
+		case <-packets:
+			if stats := listener.Stats(); stats.Audio.Packets == 0 {
+				t.Errorf("stats of audio = %+v", stats.Audio)
+			}
+			return
+		case <-deadline:
+			t.Fatal("audio of speaker is not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package audiobridge
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jaudiobridge"
+	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/streaming"
+	"github.com/pion/rtp"
+	"github.com/pkg/errors"
+)
+
+//AudioBridgeRTP plain rtp member of audiobridge room, without webrtc
+//opus rtp written to Source is sent to mix, mix is read by ReadRTP
+//
+//	a := audiobridge.NewAudioBridgeRTP(ctx, h, room, jaudiobridge.WithMemberDisplay("announcer"))
+//	a.Join()
+//	a.Source().PlayFile("announcement.rtpdump", 111, 0, false)
+type AudioBridgeRTP struct {
+	ctx     context.Context
+	jMember *jaudiobridge.Member
+	local   string
+	host    string
+	pt      uint8
+
+	mu     sync.Mutex
+	sink   *RTPSink
+	source *streaming.RTPSource
+}
+
+//AudioBridgeRTPOption option for AudioBridgeRTP
+type AudioBridgeRTPOption func(*AudioBridgeRTP)
+
+//WithAudioBridgeRTPLocal local ip to receive mix, janus-gateway must reach it, default 127.0.0.1
+func WithAudioBridgeRTPLocal(ip string) AudioBridgeRTPOption {
+	return func(a *AudioBridgeRTP) {
+		a.local = ip
+	}
+}
+
+//WithAudioBridgeRTPHost host of janus-gateway to send rtp, default is ip of join response
+//eg: public address of janus-gateway behind nat
+func WithAudioBridgeRTPHost(host string) AudioBridgeRTPOption {
+	return func(a *AudioBridgeRTP) {
+		a.host = host
+	}
+}
+
+//WithAudioBridgeRTPPayloadType payload type of opus, default janus-gateway choose (100)
+func WithAudioBridgeRTPPayloadType(pt uint8) AudioBridgeRTPOption {
+	return func(a *AudioBridgeRTP) {
+		a.pt = pt
+	}
+}
+
+//NewAudioBridgeRTP new plain rtp member of room, h is handle of janus.plugin.audiobridge
+func NewAudioBridgeRTP(ctx context.Context, h *jwsapi.Handle, room uint64, opts ...jaudiobridge.MemberOption) *AudioBridgeRTP {
+	return &AudioBridgeRTP{
+		ctx:     ctx,
+		jMember: jaudiobridge.NewMember(ctx, h, room, opts...),
+		local:   "127.0.0.1",
+	}
+}
+
+//Object return jaudiobridge.Member
+func (a *AudioBridgeRTP) Object() *jaudiobridge.Member {
+	return a.jMember
+}
+
+//ID return id info
+func (a *AudioBridgeRTP) ID() string {
+	return fmt.Sprintf("[AudioBridgeRTP.%d.%d]", a.jMember.Room(), a.jMember.ID())
+}
+
+//SetOption set option, before Join
+func (a *AudioBridgeRTP) SetOption(opts ...AudioBridgeRTPOption) *AudioBridgeRTP {
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+//Join bind local udp and join room as plain rtp participant
+//jaudiobridge.WithMessageOptionMuted, jaudiobridge.WithMessageOptionPin... for other params
+func (a *AudioBridgeRTP) Join(opts ...jwsapi.MessageOption) error {
+	sink, err := NewRTPSink(net.JoinHostPort(a.local, "0"))
+	if err != nil {
+		return errors.Wrap(err, "listen")
+	}
+	info, err := a.jMember.JoinRTP(a.ctx, jaudiobridge.RTPConfig{
+		IP:          a.local,
+		Port:        sink.Port(),
+		PayloadType: a.pt,
+	}, opts...)
+	if err != nil {
+		sink.Close()
+		return errors.Wrap(err, "join")
+	}
+
+	host := a.host
+	if host == "" {
+		if ip := net.ParseIP(info.IP); ip == nil || ip.IsUnspecified() {
+			sink.Close()
+			a.jMember.Leave()
+			return errors.Errorf("rtp ip %q of janus-gateway, WithAudioBridgeRTPHost required", info.IP)
+		}
+		host = info.IP
+	}
+	pt := info.PayloadType
+	if pt == 0 {
+		pt = a.pt
+	}
+	if pt == 0 {
+		pt = 100
+	}
+	source, err := streaming.NewRTPSource(a.ctx, host, &streaming.RTPStream{Port: info.Port, PT: pt, ClockRate: 48000}, nil)
+	if err != nil {
+		sink.Close()
+		a.jMember.Leave()
+		return errors.Wrap(err, "source")
+	}
+
+	a.mu.Lock()
+	a.sink = sink
+	a.source = source
+	a.mu.Unlock()
+	log.Info("join rtp", logging.F("audiobridge", a.ID()), logging.F("host", host), logging.F("port", info.Port), logging.F("local", sink.Port()))
+	return nil
+}
+
+//Source return source of opus to mix, WriteRTP(webrtc.RTPCodecTypeAudio, packet), PlayFile..., nil before Join
+func (a *AudioBridgeRTP) Source() *streaming.RTPSource {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.source
+}
+
+//ReadRTP read rtp of mix, only one goroutine
+func (a *AudioBridgeRTP) ReadRTP() (*rtp.Packet, error) {
+	a.mu.Lock()
+	sink := a.sink
+	a.mu.Unlock()
+	if sink == nil {
+		return nil, errors.New("not joined")
+	}
+	return sink.ReadRTP()
+}
+
+//SetMuted mute or unmute itself
+func (a *AudioBridgeRTP) SetMuted(muted bool) error {
+	return a.jMember.SetMuted(muted)
+}
+
+//ChangeRoom change to other room, udp is kept
+func (a *AudioBridgeRTP) ChangeRoom(room uint64, opts ...jwsapi.MessageOption) error {
+	return a.jMember.ChangeRoom(room, opts...)
+}
+
+//Leave leave room, close udp
+func (a *AudioBridgeRTP) Leave() error {
+	a.mu.Lock()
+	sink, source := a.sink, a.source
+	a.sink, a.source = nil, nil
+	a.mu.Unlock()
+	if source != nil {
+		source.Close()
+	}
+	if sink != nil {
+		sink.Close()
+	}
+	return a.jMember.Leave()
+}
@@ -0,0 +1,89 @@
+package audiobridge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/audiobridge"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jaudiobridge"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestAudioBridgeRTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.AudioBridgePlugin, janustest.NewAudioBridge())
+
+	admin := janustest.Attach(t, ctx, s, jaudiobridge.Plugin)
+	room, err := jaudiobridge.CreateRoom(admin, 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	speaker := audiobridge.NewAudioBridgeRTP(ctx, janustest.Attach(t, ctx, s, jaudiobridge.Plugin), room, jaudiobridge.WithMemberDisplay("speaker"))
+	if err := speaker.Join(); err != nil {
+		t.Fatalf("join speaker: %v", err)
+	}
+	defer speaker.Leave()
+	listener := audiobridge.NewAudioBridgeRTP(ctx, janustest.Attach(t, ctx, s, jaudiobridge.Plugin), room, jaudiobridge.WithMemberDisplay("listener")).
+		SetOption(audiobridge.WithAudioBridgeRTPPayloadType(111))
+	if err := listener.Join(); err != nil {
+		t.Fatalf("join listener: %v", err)
+	}
+	defer listener.Leave()
+
+	//mix of room is forwarded to sink too
+	sink, err := audiobridge.NewRTPSink("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if _, err := jaudiobridge.RTPForward(admin, room, &jaudiobridge.ForwardConfig{Host: sink.IP(), Port: sink.Port(), PT: 96}); err != nil {
+		t.Fatalf("rtp_forward: %v", err)
+	}
+
+	payload := []byte{0xf8, 0xff, 0xfe}
+	//payload type of received rtp is rewritten to listener (111) and forwarder (96)
+	received := make(chan uint8, 2)
+	readers := []struct {
+		read func() (*rtp.Packet, error)
+		pt   uint8
+	}{{listener.ReadRTP, 111}, {sink.ReadRTP, 96}}
+	for _, r := range readers {
+		go func(read func() (*rtp.Packet, error), pt uint8) {
+			packet, err := read()
+			if err != nil {
+				return
+			}
+			if string(packet.Payload) != string(payload) || packet.PayloadType != pt {
+				t.Errorf("received pt %d %x, want pt %d %x", packet.PayloadType, packet.Payload, pt, payload)
+			}
+			received <- pt
+		}(r.read, r.pt)
+	}
+
+	deadline := time.After(10 * time.Second)
+	source := speaker.Source()
+	for seq, got := uint16(1), 0; got < 2; seq++ {
+		err := source.WriteRTP(webrtc.RTPCodecTypeAudio, &rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 111, SequenceNumber: seq, Timestamp: uint32(seq) * 960, SSRC: 1234},
+			Payload: payload,
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case <-received:
+			got++
+		case <-deadline:
+			t.Fatalf("opus of speaker is received %d of 2", got)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
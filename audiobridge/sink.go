@@ -0,0 +1,97 @@
+package audiobridge
+
+import (
+	"net"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+	"github.com/pkg/errors"
+)
+
+//RTPSink receive rtp at local udp, eg: rtp_forward of audiobridge, mix to plain rtp participant
+//
+//	sink, _ := audiobridge.NewRTPSink("127.0.0.1:0")
+//	id, _ := jaudiobridge.RTPForward(h, room, &jaudiobridge.ForwardConfig{Host: sink.IP(), Port: sink.Port()})
+//	packet, _ := sink.ReadRTP()
+type RTPSink struct {
+	conn    *net.UDPConn
+	decrypt *srtp.Context
+
+	srtpSuite  int
+	srtpCrypto string
+}
+
+//RTPSinkOption option for RTPSink
+type RTPSinkOption func(*RTPSink)
+
+//WithRTPSinkSRTP receive srtp, same as srtp_suite and srtp_crypto of forwarder
+//only suite 80 (AES_CM_128_HMAC_SHA1_80) is supported
+func WithRTPSinkSRTP(suite int, crypto string) RTPSinkOption {
+	return func(s *RTPSink) {
+		s.srtpSuite = suite
+		s.srtpCrypto = crypto
+	}
+}
+
+//NewRTPSink listen udp at addr, eg: 127.0.0.1:0 for random port
+func NewRTPSink(addr string, opts ...RTPSinkOption) (*RTPSink, error) {
+	s := &RTPSink{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.srtpSuite > 0 || s.srtpCrypto != "" {
+		key, err := rtcsession.SRTPMasterKey(s.srtpSuite, s.srtpCrypto)
+		if err != nil {
+			return nil, err
+		}
+		if s.decrypt, err = srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+			return nil, errors.Wrap(err, "srtp")
+		}
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.conn, err = net.ListenUDP("udp", udpAddr); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//IP return local ip
+func (s *RTPSink) IP() string {
+	return s.conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+//Port return local port
+func (s *RTPSink) Port() int {
+	return s.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+//ReadRTP read next rtp, invalid packet is dropped, only one goroutine
+func (s *RTPSink) ReadRTP() (*rtp.Packet, error) {
+	buf := make([]byte, 1500)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		data := buf[:n]
+		if s.decrypt != nil {
+			if data, err = s.decrypt.DecryptRTP(nil, data, nil); err != nil {
+				continue
+			}
+		}
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(data); err != nil {
+			continue
+		}
+		return packet, nil
+	}
+}
+
+//Close close udp, ReadRTP return error
+func (s *RTPSink) Close() error {
+	return s.conn.Close()
+}
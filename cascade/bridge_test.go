@@ -0,0 +1,212 @@
+package cascade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+	"github.com/newzai/janus-go/videoroom"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+type gateway struct {
+	server *janustest.Server
+	room   *janustest.VideoRoom
+	sess   *jwsapi.Session
+}
+
+//newGateway fake gateway with videoroom and a session of it
+func newGateway(t *testing.T, ctx context.Context) *gateway {
+	t.Helper()
+	g := &gateway{server: janustest.NewServer(), room: janustest.NewVideoRoom()}
+	t.Cleanup(g.server.Close)
+	g.server.RegisterPlugin(janustest.VideoRoomPlugin, g.room)
+	conn := jwsapi.NewConnection(ctx, g.server.URL, 1)
+	for i := 0; i < 100 && !conn.Connected(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.sess = sess
+	return g
+}
+
+//display of publisher at gateway, empty if not joined
+func (g *gateway) display(t *testing.T, id uint64) string {
+	t.Helper()
+	h, err := g.sess.Attach(jvideoroom.Plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Detach()
+	parts, err := jvideoroom.Listparticipants(h, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range parts {
+		if part.ID == id {
+			return part.Display
+		}
+	}
+	return ""
+}
+
+func publish(t *testing.T, ctx context.Context, g *gateway, display string) *videoroom.Publisher {
+	t.Helper()
+	pub := videoroom.NewPublisher(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, g.server, jvideoroom.Plugin), 1234)
+	pub.Object().SetOption(jvideoroom.WithPublisherOptionDisplay(display))
+	if err := pub.Join(); err != nil {
+		t.Fatalf("join %s: %v", display, err)
+	}
+	if err := pub.Publish(true, true); err != nil {
+		t.Fatalf("publish %s: %v", display, err)
+	}
+	return pub
+}
+
+type feedEvent struct {
+	feed   uint64
+	mirror uint64
+}
+
+type bridgeEvents struct {
+	mirrored chan feedEvent
+	removed  chan uint64
+}
+
+func startBridge(t *testing.T, ctx context.Context, src *gateway, dst *gateway) (*Bridge, bridgeEvents) {
+	t.Helper()
+	events := bridgeEvents{mirrored: make(chan feedEvent, 8), removed: make(chan uint64, 8)}
+	b := NewBridge(ctx, janustest.NewAPI(), src.sess, 1234, dst.sess, 1234,
+		WithBridgeKeyFrame(0),
+		WithBridgeMirrored(func(feed uint64, mirror uint64) { events.mirrored <- feedEvent{feed, mirror} }),
+		WithBridgeRemoved(func(feed uint64) { events.removed <- feed }))
+	if err := b.Start(); err != nil {
+		t.Fatalf("start bridge: %v", err)
+	}
+	t.Cleanup(b.Stop)
+	return b, events
+}
+
+func (e bridgeEvents) waitMirrored(t *testing.T, feed uint64) uint64 {
+	t.Helper()
+	select {
+	case ev := <-e.mirrored:
+		if ev.feed != feed || ev.mirror == 0 {
+			t.Fatalf("mirrored %+v, want feed %d", ev, feed)
+		}
+		return ev.mirror
+	case <-time.After(10 * time.Second):
+		t.Fatalf("feed %d is not mirrored", feed)
+	}
+	return 0
+}
+
+func (e bridgeEvents) waitRemoved(t *testing.T, feed uint64) {
+	t.Helper()
+	select {
+	case removed := <-e.removed:
+		if removed != feed {
+			t.Fatalf("removed %d, want %d", removed, feed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("mirror of feed %d is not removed", feed)
+	}
+}
+
+func contains(ids []uint64, id uint64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBridge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, b := newGateway(t, ctx), newGateway(t, ctx)
+
+	ab, abEvents := startBridge(t, ctx, a, b)
+	ba, baEvents := startBridge(t, ctx, b, a)
+
+	//mirror on publish
+	alice := publish(t, ctx, a, "alice")
+	aliceID := alice.Object().ID()
+	mirror := abEvents.waitMirrored(t, aliceID)
+	if !contains(b.room.Publishers(1234), mirror) {
+		t.Fatalf("mirror %d is not published at destination: %v", mirror, b.room.Publishers(1234))
+	}
+	if display := b.display(t, mirror); display != DefaultTag+"alice" {
+		t.Errorf("display of mirror = %q", display)
+	}
+	if feeds := ab.Feeds(); len(feeds) != 1 || feeds[aliceID] != mirror {
+		t.Errorf("feeds = %v", feeds)
+	}
+
+	//media of source feed is published by mirror
+	track := alice.GetTrack(webrtc.RTPCodecTypeAudio)
+	deadline := time.Now().Add(10 * time.Second)
+	for seq := uint16(1); b.room.Packets(1234, mirror) == 0; seq++ {
+		if time.Now().After(deadline) {
+			t.Fatal("media is not mirrored")
+		}
+		track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), SequenceNumber: seq, Timestamp: uint32(seq) * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	//mirror is not mirrored back by reverse bridge, publisher of b is mirrored to a only
+	bob := publish(t, ctx, b, "bob")
+	bobID := bob.Object().ID()
+	bobMirror := baEvents.waitMirrored(t, bobID)
+	select {
+	case ev := <-baEvents.mirrored:
+		t.Fatalf("mirrored back %+v", ev)
+	case ev := <-abEvents.mirrored:
+		t.Fatalf("mirror of bob is mirrored back %+v", ev)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if feeds := ba.Feeds(); len(feeds) != 1 || feeds[bobID] != bobMirror {
+		t.Errorf("feeds of reverse bridge = %v", feeds)
+	}
+	if ids := a.room.Publishers(1234); len(ids) != 2 || !contains(ids, aliceID) || !contains(ids, bobMirror) {
+		t.Errorf("publishers of a = %v, want alice %d and mirror of bob %d", ids, aliceID, bobMirror)
+	}
+	if ids := b.room.Publishers(1234); len(ids) != 2 || !contains(ids, bobID) || !contains(ids, mirror) {
+		t.Errorf("publishers of b = %v, want bob %d and mirror of alice %d", ids, bobID, mirror)
+	}
+
+	//cleanup on unpublish
+	if err := alice.Unpublish(); err != nil {
+		t.Fatal(err)
+	}
+	abEvents.waitRemoved(t, aliceID)
+	if contains(b.room.Publishers(1234), mirror) || b.display(t, mirror) != "" {
+		t.Errorf("mirror %d is not left after unpublish", mirror)
+	}
+	if feeds := ab.Feeds(); len(feeds) != 0 {
+		t.Errorf("feeds after unpublish = %v", feeds)
+	}
+
+	//cleanup on leave
+	if err := bob.Object().Leave(); err != nil {
+		t.Fatal(err)
+	}
+	baEvents.waitRemoved(t, bobID)
+	if contains(a.room.Publishers(1234), bobMirror) || a.display(t, bobMirror) != "" {
+		t.Errorf("mirror %d is not left after leave", bobMirror)
+	}
+	if feeds := ba.Feeds(); len(feeds) != 0 {
+		t.Errorf("feeds after leave = %v", feeds)
+	}
+}
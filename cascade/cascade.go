@@ -0,0 +1,410 @@
+//Package cascade mirror all publishers of a videoroom at one janus-gateway into a room at another gateway
+//every source feed is pulled by videoroom.Subscriber and pushed by videoroom.Publisher
+//
+//	b := cascade.NewBridge(ctx, api, sessA, 1234, sessB, 5678)
+//	err := b.Start()
+//	...
+//	b.Stop()
+//
+//mirror publisher send opus and h264 only (see videoroom.Publisher), feeds of other codecs are not mirrored
+package cascade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/videoroom"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Named("cascade")
+
+//DefaultTag display prefix of mirror publisher, publisher has this prefix is not mirrored again
+const DefaultTag = "[cascade]"
+
+//Bridge mirror publishers of source room into destination room
+type Bridge struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	api     *webrtc.API
+	src     *jwsapi.Session
+	srcRoom uint64
+	dst     *jwsapi.Session
+	dstRoom uint64
+
+	tag           string
+	display       func(part jvideoroom.Participant) string
+	keyFrame      time.Duration
+	onMirrored    func(feed uint64, mirror uint64)
+	onRemoved     func(feed uint64)
+	watcherHandle *jwsapi.Handle
+	watcher       *jvideoroom.Publisher
+
+	mu      sync.Mutex
+	stopped bool
+	mirrors map[uint64]*mirror
+	wg      sync.WaitGroup
+}
+
+//BridgeOption option for Bridge
+type BridgeOption func(*Bridge)
+
+//WithBridgeTag set display prefix of mirror publisher for loop prevention, default is DefaultTag
+//bridges of reverse direction must using same tag
+func WithBridgeTag(tag string) BridgeOption {
+	return func(b *Bridge) {
+		b.tag = tag
+	}
+}
+
+//WithBridgeDisplay map display of source publisher to mirror publisher, tag is always prefixed
+func WithBridgeDisplay(display func(part jvideoroom.Participant) string) BridgeOption {
+	return func(b *Bridge) {
+		b.display = display
+	}
+}
+
+//WithBridgeKeyFrame request key frame of source feed every interval, for late subscribers of destination room
+//default is 5s, 0 to disable
+func WithBridgeKeyFrame(interval time.Duration) BridgeOption {
+	return func(b *Bridge) {
+		b.keyFrame = interval
+	}
+}
+
+//WithBridgeMirrored callback when source feed is mirrored as publisher of destination room
+func WithBridgeMirrored(callback func(feed uint64, mirror uint64)) BridgeOption {
+	return func(b *Bridge) {
+		b.onMirrored = callback
+	}
+}
+
+//WithBridgeRemoved callback when mirror of source feed is removed
+func WithBridgeRemoved(callback func(feed uint64)) BridgeOption {
+	return func(b *Bridge) {
+		b.onRemoved = callback
+	}
+}
+
+//NewBridge create bridge, src and dst are sessions of two gateways
+func NewBridge(ctx context.Context, api *webrtc.API, src *jwsapi.Session, srcRoom uint64, dst *jwsapi.Session, dstRoom uint64, opts ...BridgeOption) *Bridge {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &Bridge{
+		ctx:      ctx,
+		cancel:   cancel,
+		api:      api,
+		src:      src,
+		srcRoom:  srcRoom,
+		dst:      dst,
+		dstRoom:  dstRoom,
+		tag:      DefaultTag,
+		keyFrame: 5 * time.Second,
+		mirrors:  make(map[uint64]*mirror),
+	}
+	b.display = b.defaultDisplay
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *Bridge) defaultDisplay(part jvideoroom.Participant) string {
+	if display := part.Display; display != "" {
+		return display
+	}
+	return fmt.Sprintf("%d", part.ID)
+}
+
+//Start join source room as watcher(not publish), mirror publishers of it
+func (b *Bridge) Start() error {
+	h, err := b.src.Attach(jvideoroom.Plugin)
+	if err != nil {
+		return errors.Wrap(err, "attach watcher")
+	}
+	b.watcherHandle = h
+	b.watcher = jvideoroom.NewPublisher(b.ctx, h, b.srcRoom,
+		jvideoroom.WithPublisherOptionDisplay(b.tag+"watcher"),
+		jvideoroom.WithPublisherOptionNewPublisher(b.onNewPublisher),
+		jvideoroom.WithPublisherOptionUnpublished(b.onUnpublished),
+	)
+	if err := b.watcher.JoinContext(b.ctx); err != nil {
+		h.Detach()
+		return errors.Wrap(err, "watcher join")
+	}
+	return nil
+}
+
+//Stop remove all mirrors, leave source room
+func (b *Bridge) Stop() {
+	b.mu.Lock()
+	b.stopped = true
+	for feed, m := range b.mirrors {
+		delete(b.mirrors, feed)
+		m.stop()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+
+	if b.watcher != nil {
+		b.watcher.Leave()
+		b.watcherHandle.Detach()
+	}
+	b.cancel()
+}
+
+//Feeds mirrored feeds, source feed id -> mirror publisher id, 0 if mirror is not ready
+func (b *Bridge) Feeds() map[uint64]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	feeds := make(map[uint64]uint64, len(b.mirrors))
+	for feed, m := range b.mirrors {
+		feeds[feed] = m.mirrorID()
+	}
+	return feeds
+}
+
+//onNewPublisher called by event router of watcher, must not block
+func (b *Bridge) onNewPublisher(part jvideoroom.Participant) {
+	if strings.HasPrefix(part.Display, b.tag) {
+		//mirror of other bridge, stop loop
+		return
+	}
+	feed := part.ID
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.mirrors[feed]; ok || b.stopped {
+		return
+	}
+	m := &mirror{
+		bridge:  b,
+		feed:    feed,
+		part:    part,
+		display: b.tag + b.display(part),
+		done:    make(chan struct{}),
+	}
+	b.mirrors[feed] = m
+	b.wg.Add(1)
+	go m.run()
+}
+
+func (b *Bridge) onUnpublished(feed uint64) {
+	b.mu.Lock()
+	m, ok := b.mirrors[feed]
+	delete(b.mirrors, feed)
+	b.mu.Unlock()
+	if ok {
+		m.stop()
+	}
+}
+
+//remove mirror failed or hangup
+func (b *Bridge) remove(m *mirror) {
+	b.mu.Lock()
+	if b.mirrors[m.feed] == m {
+		delete(b.mirrors, m.feed)
+	}
+	b.mu.Unlock()
+	m.stop()
+}
+
+//mirror a source feed and its mirror publisher
+type mirror struct {
+	bridge  *Bridge
+	feed    uint64
+	part    jvideoroom.Participant
+	display string
+	done    chan struct{}
+	once    sync.Once
+
+	mu        sync.Mutex
+	pub       *videoroom.Publisher
+	published bool
+	sub       *videoroom.Subscriber
+}
+
+//mirror codecs, see videoroom.Publisher
+var mirrorCodecs = map[string]string{"audio": "opus", "video": "h264"}
+
+//mirrorKinds kinds of source feed to publish, error if codec of feed can not be mirrored
+func mirrorKinds(part jvideoroom.Participant) (audio bool, video bool, err error) {
+	codecs := make(map[string]string)
+	if streams := part.Streams; len(streams) > 0 {
+		for _, stream := range streams {
+			if _, ok := mirrorCodecs[stream.Type]; ok && !stream.Disabled {
+				codecs[stream.Type] = stream.Codec
+			}
+		}
+	} else {
+		//janus-gateway 0.x
+		if codec := part.AudioCodec; codec != "" {
+			codecs["audio"] = codec
+		}
+		if codec := part.VideoCodec; codec != "" {
+			codecs["video"] = codec
+		}
+	}
+	for kind, codec := range codecs {
+		if !strings.EqualFold(codec, mirrorCodecs[kind]) {
+			return false, false, errors.Errorf("%s codec %s is not %s", kind, codec, mirrorCodecs[kind])
+		}
+	}
+	_, audio = codecs["audio"]
+	_, video = codecs["video"]
+	if !audio && !video {
+		return false, false, errors.New("no audio or video")
+	}
+	return audio, video, nil
+}
+
+func (m *mirror) stop() {
+	m.once.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *mirror) mirrorID() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pub == nil {
+		return 0
+	}
+	return m.pub.Object().ID()
+}
+
+func (m *mirror) run() {
+	b := m.bridge
+	defer b.wg.Done()
+	//ctx of publisher and subscriber, cancel after handles detached, so events are read until detach
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+
+	log.Info("mirror feed", logging.F("room", b.srcRoom), logging.F("feed", m.feed), logging.F("display", m.display))
+	pubHandle, subHandle, err := m.start(ctx)
+	if err == nil {
+		if b.onMirrored != nil {
+			b.onMirrored(m.feed, m.mirrorID())
+		}
+		m.wait(ctx)
+	} else {
+		log.Warn("mirror feed failed", logging.F("room", b.srcRoom), logging.F("feed", m.feed), logging.F("err", err))
+		b.remove(m)
+	}
+
+	m.mu.Lock()
+	pub, sub, published := m.pub, m.sub, m.published
+	m.mu.Unlock()
+	if sub != nil {
+		sub.Leave()
+	}
+	if subHandle != nil {
+		subHandle.Detach()
+	}
+	if published {
+		pub.Unpublish()
+	}
+	if pub != nil {
+		pub.Object().Leave()
+	}
+	if pubHandle != nil {
+		pubHandle.Detach()
+	}
+	if err == nil && b.onRemoved != nil {
+		b.onRemoved(m.feed)
+	}
+	log.Info("mirror removed", logging.F("room", b.srcRoom), logging.F("feed", m.feed))
+}
+
+func (m *mirror) start(ctx context.Context) (pubHandle *jwsapi.Handle, subHandle *jwsapi.Handle, err error) {
+	b := m.bridge
+	audio, video, err := mirrorKinds(m.part)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubHandle, err = b.dst.Attach(jvideoroom.Plugin)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "attach publisher")
+	}
+	pub := videoroom.NewPublisher(ctx, b.api, pubHandle, b.dstRoom, jvideoroom.WithPublisherOptionDisplay(m.display))
+	if err = pub.Join(); err != nil {
+		return pubHandle, nil, errors.Wrap(err, "join")
+	}
+	m.mu.Lock()
+	m.pub = pub
+	m.mu.Unlock()
+	if err = pub.Publish(audio, video); err != nil {
+		return pubHandle, nil, err
+	}
+	m.mu.Lock()
+	m.published = true
+	m.mu.Unlock()
+
+	subHandle, err = b.src.Attach(jvideoroom.Plugin)
+	if err != nil {
+		return pubHandle, nil, errors.Wrap(err, "attach subscriber")
+	}
+	sub := videoroom.NewSubscriber(ctx, b.api, subHandle, b.srcRoom, m.feed)
+	if audio {
+		sub.SetOption(videoroom.WithSubscriberAudioTrack(m.forward(sub, pub.GetTrack(webrtc.RTPCodecTypeAudio))))
+	}
+	if video {
+		sub.SetOption(videoroom.WithSubscriberVideoTrack(m.forward(sub, pub.GetTrack(webrtc.RTPCodecTypeVideo))))
+	}
+	m.mu.Lock()
+	m.sub = sub
+	m.mu.Unlock()
+	if err = sub.Start(); err != nil {
+		return pubHandle, subHandle, err
+	}
+	return pubHandle, subHandle, nil
+}
+
+func (m *mirror) wait(ctx context.Context) {
+	var tick <-chan time.Time
+	if interval := m.bridge.keyFrame; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-tick:
+			m.mu.Lock()
+			sub := m.sub
+			m.mu.Unlock()
+			sub.RequestKeyFrame()
+		}
+	}
+}
+
+//forward rtp from source track to track of mirror publisher
+func (m *mirror) forward(sub *videoroom.Subscriber, sendTrack *videoroom.Track) func(context.Context, *webrtc.Track) {
+	return func(ctx context.Context, track *webrtc.Track) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			default:
+			}
+			packet, err := sub.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packet.SSRC = sendTrack.SSRC()
+			packet.PayloadType = sendTrack.PayloadType()
+			sendTrack.WriteRTP(packet)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package cascade
+
+import (
+	"testing"
+
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+)
+
+func stream(kind string, codec string) jvideoroom.Stream {
+	return jvideoroom.Stream{Type: kind, Codec: codec}
+}
+
+func TestMirrorKinds(t *testing.T) {
+	tests := []struct {
+		name         string
+		part         jvideoroom.Participant
+		audio, video bool
+		fail         bool
+	}{
+		{"audio only", jvideoroom.Participant{AudioCodec: "opus"}, true, false, false},
+		{"video only", jvideoroom.Participant{VideoCodec: "h264"}, false, true, false},
+		{"audio and video", jvideoroom.Participant{AudioCodec: "opus", VideoCodec: "h264"}, true, true, false},
+		{"vp8", jvideoroom.Participant{AudioCodec: "opus", VideoCodec: "vp8"}, false, false, true},
+		{"no media", jvideoroom.Participant{}, false, false, true},
+		{"streams", jvideoroom.Participant{Streams: []jvideoroom.Stream{stream("audio", "opus"), stream("data", "")}}, true, false, false},
+		{"disabled stream", jvideoroom.Participant{Streams: []jvideoroom.Stream{
+			stream("audio", "opus"),
+			{Type: "video", Codec: "vp8", Disabled: true},
+		}}, true, false, false},
+		{"streams of vp9", jvideoroom.Participant{Streams: []jvideoroom.Stream{stream("video", "vp9")}}, false, false, true},
+	}
+	for _, tt := range tests {
+		audio, video, err := mirrorKinds(tt.part)
+		if (err != nil) != tt.fail {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		if audio != tt.audio || video != tt.video {
+			t.Errorf("%s: audio %v video %v, want %v %v", tt.name, audio, video, tt.audio, tt.video)
+		}
+	}
+}
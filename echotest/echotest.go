@@ -0,0 +1,374 @@
+//Package echotest pion client of janus echotest plugin, see jwsapi/jplugin/jechotest
+package echotest
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jechotest"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Named("echotest")
+
+//echoMagic first bytes of synthetic payload
+var echoMagic = []byte("JGEC")
+
+const echoPayloadSize = 16 //magic(4) + seq(4) + send time(8)
+
+//EchoStats echo result of audio or video
+type EchoStats struct {
+	Sent     uint64
+	Received uint64
+	Loss     float64 //0.0 ~ 1.0
+	RTTMin   time.Duration
+	RTTAvg   time.Duration
+	RTTMax   time.Duration
+}
+
+//EchoResult result of echo self-test
+type EchoResult struct {
+	Audio EchoStats
+	Video EchoStats
+}
+
+type echoCounter struct {
+	sent     uint64
+	received uint64
+	rttSum   time.Duration
+	rttMin   time.Duration
+	rttMax   time.Duration
+}
+
+func (c *echoCounter) stats() EchoStats {
+	s := EchoStats{
+		Sent:     c.sent,
+		Received: c.received,
+		RTTMin:   c.rttMin,
+		RTTMax:   c.rttMax,
+	}
+	if c.received > 0 {
+		s.RTTAvg = c.rttSum / time.Duration(c.received)
+	}
+	if c.sent > 0 && c.received < c.sent {
+		s.Loss = float64(c.sent-c.received) / float64(c.sent)
+	}
+	return s
+}
+
+//EchoTest pion peer of janus echotest plugin, send synthetic rtp and check it comes back
+//synthetic payload is not decodable, only for janus-gateway smoke test
+type EchoTest struct {
+	rtc      *rtcsession.Session
+	jEcho    *jechotest.EchoTest
+	tracks   map[webrtc.RTPCodecType]*rtcsession.Track
+	pts      map[webrtc.RTPCodecType]uint8 //payload type of answer, payload is synthetic, any codec is fine
+	interval time.Duration
+	up       chan struct{}
+	upOnce   sync.Once
+
+	mu       sync.Mutex
+	start    time.Time
+	counters map[webrtc.RTPCodecType]*echoCounter
+}
+
+//EchoTestOption option for EchoTest
+type EchoTestOption func(*EchoTest)
+
+//WithEchoTestConfigure set webrtc configure
+func WithEchoTestConfigure(configure webrtc.Configuration) EchoTestOption {
+	return func(e *EchoTest) {
+		e.rtc.Configure = configure
+	}
+}
+
+//WithEchoTestInterval interval of synthetic rtp, default is 20ms
+func WithEchoTestInterval(interval time.Duration) EchoTestOption {
+	return func(e *EchoTest) {
+		e.interval = interval
+	}
+}
+
+//NewEchoTest new echotest, h is handle of janus.plugin.echotest
+func NewEchoTest(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, opts ...EchoTestOption) *EchoTest {
+	e := &EchoTest{
+		jEcho:    jechotest.NewEchoTest(ctx, h),
+		tracks:   make(map[webrtc.RTPCodecType]*rtcsession.Track),
+		interval: 20 * time.Millisecond,
+		up:       make(chan struct{}),
+		counters: map[webrtc.RTPCodecType]*echoCounter{
+			webrtc.RTPCodecTypeAudio: {},
+			webrtc.RTPCodecTypeVideo: {},
+		},
+	}
+	e.rtc = rtcsession.New(ctx, api, h, e.ID)
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+//Object return jechotest.EchoTest
+func (e *EchoTest) Object() *jechotest.EchoTest {
+	return e.jEcho
+}
+
+//ID return id info
+func (e *EchoTest) ID() string {
+	return fmt.Sprintf("[echotest.%d]", e.rtc.Handle.ID)
+}
+
+//Start negotiate with janus, send opus and h264 track
+//audio,video default is true, see jechotest.WithMessageOptionBitrate... for other params
+func (e *EchoTest) Start(opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := e.rtc.StartSpan(e.rtc.Ctx, "echotest.EchoTest.Start")
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	e.rtc.SetTraceLink(ctx)
+
+	var pc *webrtc.PeerConnection
+	err = e.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = e.rtc.API.NewPeerConnection(e.rtc.Configure)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "NewPeerConnection")
+	}
+	e.rtc.PC = pc
+
+	e.rtc.Handle.SetCallback(jwsapi.WithHandleHangup(e.onHangup))
+	e.rtc.Handle.SetCallback(jwsapi.WithHandleWebrtcup(e.onWebrtcup))
+
+	pc.OnTrack(e.onTrack)
+	pc.OnICECandidate(e.rtc.OnICECandidate)
+	pc.OnConnectionStateChange(e.onPeerConnectionState)
+
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		pt, label := uint8(webrtc.DefaultPayloadTypeOpus), "echoA0"
+		if kind == webrtc.RTPCodecTypeVideo {
+			pt, label = webrtc.DefaultPayloadTypeH264, "echoV0"
+		}
+		track, err := pc.NewTrack(pt, rand.Uint32(), kind.String(), label)
+		if err != nil {
+			pc.Close()
+			return errors.Wrapf(err, "NewTrack(%s)", kind)
+		}
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			pc.Close()
+			return errors.Wrapf(err, "AddTrack(%s)", kind)
+		}
+		e.tracks[kind] = rtcsession.NewTrack(track, nil)
+		go rtcsession.DrainSender(e.rtc.Ctx, sender)
+	}
+
+	var offer webrtc.SessionDescription
+	err = e.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateOffer")
+	}
+	err = e.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	answer, err := e.jEcho.StartContext(ctx, offer.SDP, opts...)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "echotest")
+	}
+	err = e.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+	e.pts = answerPayloadTypes(answer)
+	return nil
+}
+
+//Run Start, send synthetic rtp for duration after connected, wait echo for a second, then Stop
+//return round-trip latency and loss of audio and video
+func (e *EchoTest) Run(duration time.Duration, opts ...jwsapi.MessageOption) (*EchoResult, error) {
+	if err := e.Start(opts...); err != nil {
+		return nil, err
+	}
+	defer e.Stop()
+
+	select {
+	case <-e.up:
+	case <-e.rtc.Ctx.Done():
+		return nil, e.rtc.Ctx.Err()
+	case <-time.After(10 * time.Second):
+		return nil, errors.New("echotest PeerConnection not connected")
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	deadline := time.After(duration)
+	e.mu.Lock()
+	e.start = time.Now()
+	e.mu.Unlock()
+	seq := uint32(0)
+	for sending := true; sending; {
+		select {
+		case <-e.rtc.Ctx.Done():
+			return nil, e.rtc.Ctx.Err()
+		case <-deadline:
+			sending = false
+		case <-ticker.C:
+			seq++
+			e.send(seq)
+		}
+	}
+	select {
+	case <-e.rtc.Ctx.Done():
+		return nil, e.rtc.Ctx.Err()
+	case <-time.After(time.Second):
+	}
+	result := e.Result()
+	return &result, nil
+}
+
+//Stop close PeerConnection
+func (e *EchoTest) Stop() {
+	if e.rtc.PC != nil {
+		e.rtc.PC.Close()
+	}
+}
+
+//Result echo result
+func (e *EchoTest) Result() EchoResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return EchoResult{
+		Audio: e.counters[webrtc.RTPCodecTypeAudio].stats(),
+		Video: e.counters[webrtc.RTPCodecTypeVideo].stats(),
+	}
+}
+
+func (e *EchoTest) send(seq uint32) {
+	for kind, track := range e.tracks {
+		payload := make([]byte, echoPayloadSize)
+		copy(payload, echoMagic)
+		binary.BigEndian.PutUint32(payload[4:], seq)
+		binary.BigEndian.PutUint64(payload[8:], uint64(time.Since(e.start)))
+		clockRate := uint32(48000)
+		if kind == webrtc.RTPCodecTypeVideo {
+			clockRate = 90000
+		}
+		pt, ok := e.pts[kind]
+		if !ok {
+			pt = track.PayloadType()
+		}
+		packet := &rtp.Packet{
+			Header: rtp.Header{
+				Version:     2,
+				Marker:      kind == webrtc.RTPCodecTypeVideo,
+				PayloadType: pt,
+				SSRC:        track.SSRC(),
+				Timestamp:   uint32(time.Since(e.start).Seconds() * float64(clockRate)),
+			},
+			Payload: payload,
+		}
+		if err := track.WriteRTP(packet); err == nil {
+			e.mu.Lock()
+			e.counters[kind].sent++
+			e.mu.Unlock()
+		}
+	}
+}
+
+func (e *EchoTest) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+	log.Info("onTrack", logging.F("echotest", e.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+	go rtcsession.DrainReceiver(e.rtc.Ctx, receiver)
+	for {
+		packet, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		payload := packet.Payload
+		if len(payload) < echoPayloadSize || string(payload[:4]) != string(echoMagic) {
+			continue
+		}
+		e.mu.Lock()
+		rtt := time.Since(e.start) - time.Duration(binary.BigEndian.Uint64(payload[8:]))
+		c := e.counters[track.Kind()]
+		if c != nil {
+			c.received++
+			c.rttSum += rtt
+			if c.rttMin == 0 || rtt < c.rttMin {
+				c.rttMin = rtt
+			}
+			if rtt > c.rttMax {
+				c.rttMax = rtt
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
+func (e *EchoTest) onHangup(msg jwsapi.Message) {
+	if e.rtc.PC != nil {
+		e.rtc.PC.Close()
+	}
+}
+
+func (e *EchoTest) onWebrtcup(msg jwsapi.Message) {
+	log.Info("webrtcup", logging.F("echotest", e.ID()))
+}
+
+func (e *EchoTest) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("echotest", e.ID()), logging.F("state", state.String()))
+	if state == webrtc.PeerConnectionStateConnected {
+		e.upOnce.Do(func() {
+			close(e.up)
+		})
+	}
+}
+
+//answerPayloadTypes first payload type of audio and video in answer
+func answerPayloadTypes(answer string) map[webrtc.RTPCodecType]uint8 {
+	pts := make(map[webrtc.RTPCodecType]uint8)
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(answer)); err != nil {
+		return pts
+	}
+	for _, md := range sd.MediaDescriptions {
+		kind := webrtc.NewRTPCodecType(md.MediaName.Media)
+		if _, ok := pts[kind]; ok || kind == 0 || md.MediaName.Port.Value == 0 {
+			continue
+		}
+		for _, format := range md.MediaName.Formats {
+			if pt, err := strconv.ParseUint(format, 10, 8); err == nil {
+				pts[kind] = uint8(pt)
+				break
+			}
+		}
+	}
+	return pts
+}
@@ -0,0 +1,46 @@
+package echotest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/echotest"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+)
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.EchoTestPlugin, janustest.NewEchoTest())
+
+	h := janustest.Attach(t, ctx, s, janustest.EchoTestPlugin)
+
+	echo := echotest.NewEchoTest(ctx, janustest.NewAPI(), h)
+	defer echo.Stop()
+	result, err := echo.Run(time.Second)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for kind, stats := range map[string]echotest.EchoStats{"audio": result.Audio, "video": result.Video} {
+		if stats.Sent == 0 || stats.Received == 0 {
+			t.Errorf("%s: sent %d, received %d", kind, stats.Sent, stats.Received)
+		}
+	}
+
+	//configure without request name, error of plugin is *jplugin.Error
+	if err := echo.Object().SetAudio(false); err != nil {
+		t.Fatalf("SetAudio: %v", err)
+	}
+	err = echo.Object().Configure(jwsapi.WithMessageOption("video", "yes"))
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.EchoTestErrorInvalidElement {
+		t.Errorf("configure video of string: err = %v, want %d", err, janustest.EchoTestErrorInvalidElement)
+	}
+}
@@ -0,0 +1,87 @@
+//echotest smoke test of janus-gateway, send synthetic stream to janus.plugin.echotest and check it comes back
+//exit 1 if echo failed, loss or rtt exceed limit
+//
+//	go run ./examples/echotest -url ws://127.0.0.1:8188/janus -duration 5s -max-loss 0.05 -max-rtt 200ms
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/newzai/janus-go/echotest"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jechotest"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+func main() {
+	url := flag.String("url", "ws://127.0.0.1:8188/janus", "janus-gateway ws url")
+	duration := flag.Duration("duration", 5*time.Second, "send duration")
+	maxLoss := flag.Float64("max-loss", 0.05, "max loss of audio and video, 0.0 ~ 1.0")
+	maxRTT := flag.Duration("max-rtt", 200*time.Millisecond, "max average round-trip time")
+	bitrate := flag.Uint("bitrate", 0, "video bitrate cap, bps, 0 is no limit")
+	flag.Parse()
+
+	result, err := run(*url, *duration, uint32(*bitrate))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "echotest failed:", err)
+		os.Exit(1)
+	}
+	ok := check("audio", result.Audio, *maxLoss, *maxRTT)
+	ok = check("video", result.Video, *maxLoss, *maxRTT) && ok
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func run(url string, duration time.Duration, bitrate uint32) (*echotest.EchoResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), duration+30*time.Second)
+	defer cancel()
+
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m))
+
+	conn := jwsapi.NewConnection(ctx, url, 1)
+	sess, err := conn.Create()
+	if err != nil {
+		return nil, errors.Wrap(err, "Create Janus Session")
+	}
+	defer sess.Destroy()
+
+	handle, err := sess.Attach(jechotest.Plugin)
+	if err != nil {
+		return nil, errors.Wrap(err, "Create Janus Handle")
+	}
+	defer handle.Detach()
+
+	echo := echotest.NewEchoTest(ctx, api, handle)
+	var opts []jwsapi.MessageOption
+	if bitrate > 0 {
+		opts = append(opts, jechotest.WithMessageOptionBitrate(bitrate))
+	}
+	return echo.Run(duration, opts...)
+}
+
+func check(kind string, stats echotest.EchoStats, maxLoss float64, maxRTT time.Duration) bool {
+	fmt.Printf("%s: sent=%d received=%d loss=%.2f%% rtt min=%v avg=%v max=%v\n",
+		kind, stats.Sent, stats.Received, stats.Loss*100, stats.RTTMin, stats.RTTAvg, stats.RTTMax)
+	ok := true
+	if stats.Received == 0 {
+		fmt.Fprintf(os.Stderr, "%s: nothing echoed\n", kind)
+		return false
+	}
+	if stats.Loss > maxLoss {
+		fmt.Fprintf(os.Stderr, "%s: loss %.2f%% > %.2f%%\n", kind, stats.Loss*100, maxLoss*100)
+		ok = false
+	}
+	if stats.RTTAvg > maxRTT {
+		fmt.Fprintf(os.Stderr, "%s: rtt %v > %v\n", kind, stats.RTTAvg, maxRTT)
+		ok = false
+	}
+	return ok
+}
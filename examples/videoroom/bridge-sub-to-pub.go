@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"time"
@@ -35,15 +36,18 @@ func Init(portMin int, portMax int) {
 	api = webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting))
 }
 func main() {
+	url := flag.String("url", "ws://127.0.0.1:8088/janus", "janus-gateway ws url")
+	room := flag.Uint64("room", 1234, "videoroom room id")
+	flag.Parse()
+
 	logger, err := seelog.LoggerFromConfigAsFile("seelog.xml")
 	if err == nil {
 		logging.SetLogger(logger)
 	}
 	Init(20000, 40000)
-	url := "ws://127.0.0.1:8088/janus"
 	ctx, cancel := context.WithCancel(context.Background())
 
-	vrb := NewVideoRoomBridge(ctx, url, 1234)
+	vrb := NewVideoRoomBridge(ctx, *url, *room)
 
 	err = vrb.Start()
 	if err != nil {
@@ -99,7 +103,7 @@ func (vrb *VideoRoomBridge) onAudioTrack(ctx context.Context, track *webrtc.Trac
 		case <-ctx.Done():
 			return
 		default:
-			packet, err := track.ReadRTP()
+			packet, err := vrb.sub.ReadRTP(track)
 			if err != nil {
 				return
 			}
@@ -121,7 +125,7 @@ func (vrb *VideoRoomBridge) onVideoTrack(ctx context.Context, track *webrtc.Trac
 		case <-ctx.Done():
 			return
 		default:
-			packet, err := track.ReadRTP()
+			packet, err := vrb.sub.ReadRTP(track)
 			if err != nil {
 				return
 			}
@@ -168,7 +172,7 @@ func (vrb *VideoRoomBridge) onNewPublisher(part jvideoroom.Participant) {
 		return
 	}
 
-	pubID := part.ID()
+	pubID := part.ID
 
 	handle, err := vrb.sess.Attach("janus.plugin.videoroom")
 	if err != nil {
@@ -1,7 +1,8 @@
-package videoroom
+package rtcsession
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/pion/rtp"
@@ -101,7 +102,20 @@ func getCodecs(sd *sdp.SessionDescription) map[uint8]*webrtc.RTPCodec {
 	return allCodecs
 }
 
-func initAPI(remoteSDP string) *webrtc.API {
+//InitAPI api with opus and h264 of remote sdp, same payload type as janus-gateway
+func InitAPI(remoteSDP string) *webrtc.API {
+	return NewAPI(remoteSDP, DefaultSettingEngine(), "opus", "h264")
+}
+
+//DefaultSettingEngine setting with udp port range 20000-40000
+func DefaultSettingEngine() webrtc.SettingEngine {
+	setting := webrtc.SettingEngine{}
+	setting.SetEphemeralUDPPortRange(20000, 40000)
+	return setting
+}
+
+//NewAPI api with codecs of remote sdp, names is codec name, case insensitive
+func NewAPI(remoteSDP string, setting webrtc.SettingEngine, names ...string) *webrtc.API {
 	sd := sdp.SessionDescription{}
 	err := sd.Unmarshal([]byte(remoteSDP))
 	if err != nil {
@@ -112,20 +126,46 @@ func initAPI(remoteSDP string) *webrtc.API {
 	m := webrtc.MediaEngine{}
 
 	for _, codec := range cc {
-		switch codec.Type {
-		case webrtc.RTPCodecTypeAudio:
-			if strings.EqualFold(codec.Name, "opus") {
-				m.RegisterCodec(codec)
-			}
-		case webrtc.RTPCodecTypeVideo:
-			if strings.EqualFold(codec.Name, "h264") {
+		if codec.Type != webrtc.RTPCodecTypeAudio && codec.Type != webrtc.RTPCodecTypeVideo {
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(codec.Name, name) {
 				m.RegisterCodec(codec)
+				break
 			}
 		}
 	}
 
-	setting := webrtc.SettingEngine{}
-	setting.SetEphemeralUDPPortRange(20000, 40000)
-
 	return webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting))
 }
+
+//OfferPayloadType payload type of first codec of kind in offer and names, in order of m line
+func OfferPayloadType(offer string, kind webrtc.RTPCodecType, names ...string) (uint8, bool) {
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(offer)); err != nil {
+		return 0, false
+	}
+	cc := getCodecs(&sd)
+	for _, md := range sd.MediaDescriptions {
+		if webrtc.NewRTPCodecType(md.MediaName.Media) != kind || md.MediaName.Port.Value == 0 {
+			continue
+		}
+		for _, format := range md.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 8)
+			if err != nil {
+				continue
+			}
+			codec, ok := cc[uint8(pt)]
+			if !ok {
+				continue
+			}
+			for _, name := range names {
+				if strings.EqualFold(codec.Name, name) {
+					return uint8(pt), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,278 @@
+//Package rtcsession pion PeerConnection of janus handle, shared by pion clients of plugins,
+//eg: videoroom, echotest, streaming
+package rtcsession
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var log = logging.Named("rtcsession")
+
+//DefaultDataChannelLabel label of data channel created by janus-gateway
+const DefaultDataChannelLabel = "JanusDataChannel"
+
+//RecvCodecs codecs of janus offer accepted by receive only session, eg: StreamingViewer, RecordPlayPlayer
+var RecvCodecs = []string{"opus", "pcmu", "pcma", "g722", "vp8", "vp9", "h264"}
+
+//Session pion PeerConnection of janus handle
+type Session struct {
+	Ctx              context.Context
+	API              *webrtc.API
+	PC               *webrtc.PeerConnection
+	Configure        webrtc.Configuration
+	Handle           *jwsapi.Handle
+	RemoteCandidates chan jwsapi.Message
+
+	mu        sync.Mutex
+	traceLink trace.Link //negotiation span, linked by trickle spans of pion ICE goroutine
+
+	Collector     *Collector
+	StatsInterval time.Duration
+	OnStats       func(Stats)
+}
+
+//New create session of h, id is ID of Stats
+func New(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, id func() string) *Session {
+	return &Session{
+		Ctx:    ctx,
+		API:    api,
+		Handle: h,
+		Configure: webrtc.Configuration{
+			SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback,
+		},
+		RemoteCandidates: make(chan jwsapi.Message, 8),
+		Collector:        NewCollector(id),
+	}
+}
+
+//DoRemoteCandidate add remote candidates to PC until ctx done
+func (s *Session) DoRemoteCandidate(candidates chan jwsapi.Message) {
+	for {
+		select {
+		case <-s.Ctx.Done():
+			return
+		case msg, ok := <-candidates:
+			if !ok {
+				return
+			}
+			candidate, ok := msg.String("candidate")
+			if !ok {
+				continue
+			}
+			sdpMLineIndex, _ := msg.Uint16("sdpMLineIndex")
+			sdpMid, _ := msg.String("sdpMid")
+			iceCandidate := webrtc.ICECandidateInit{
+				Candidate:     candidate,
+				SDPMLineIndex: &sdpMLineIndex,
+				SDPMid:        &sdpMid,
+			}
+			//a bad candidate of janus must not stop the others
+			if err := s.PC.AddICECandidate(iceCandidate); err != nil {
+				log.Warn("AddICECandidate failed", logging.F("handle", s.Handle.ID), logging.F("candidate", candidate), logging.F("err", err))
+			}
+		}
+	}
+}
+
+func (s *Session) onCandidate(msg jwsapi.Message) {
+
+	s.RemoteCandidates <- msg
+
+}
+
+//OnTrickle trickle of janus, callback of handle
+func (s *Session) OnTrickle(msg jwsapi.Message) {
+	candidate, ok := msg.SubMessage("candidate")
+	if !ok {
+		return
+	}
+	if completed := msg.Bool("completed"); completed {
+		return
+	}
+	s.onCandidate(candidate)
+
+}
+
+//OnICECandidate trickle local candidate to janus, callback of PC
+func (s *Session) OnICECandidate(candidate *webrtc.ICECandidate) {
+
+	ctx, span := s.startTrickleSpan()
+	var err error
+	if candidate == nil {
+		err = s.Handle.TrickleContext(ctx, jwsapi.Message{
+			"completed": true,
+		})
+	} else {
+		c := candidate.ToJSON()
+		msg := jwsapi.Message{
+			"candidate": c.Candidate,
+		}
+		if c.SDPMLineIndex != nil {
+			msg["sdpMLineIndex"] = *c.SDPMLineIndex
+		}
+		if c.SDPMid != nil {
+			msg["sdpMid"] = *c.SDPMid
+		}
+		err = s.Handle.TrickleContext(ctx, msg)
+	}
+	EndSpan(span, err)
+}
+
+//OnMedia media event of janus, callback of handle
+func (s *Session) OnMedia(msg jwsapi.Message) {
+	s.Collector.OnMedia(msg)
+}
+
+//OnSlowLink slowlink event of janus, callback of handle
+func (s *Session) OnSlowLink(msg jwsapi.Message) {
+	s.Collector.OnSlowLink(msg)
+}
+
+//Stats return statistics snapshot
+func (s *Session) Stats() Stats {
+	return s.Collector.Snapshot()
+}
+
+//DoStats update bitrate,send SR, report stats by statsInterval
+func (s *Session) DoStats(pc *webrtc.PeerConnection) {
+
+	interval := s.StatsInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.Ctx.Done():
+			return
+		case <-ticker.C:
+			if pc.ConnectionState() == webrtc.PeerConnectionStateClosed {
+				return
+			}
+			s.Collector.Tick(pc)
+			if reports := s.Collector.SenderReports(); len(reports) > 0 {
+				pc.WriteRTCP(reports)
+			}
+			if s.OnStats != nil {
+				s.OnStats(s.Collector.Snapshot())
+			}
+		}
+	}
+}
+
+//AddSendTracks add opus and h264 track to pc, label is prefix of track label, eg: pion for pionA0, pionV0
+//flow of offer to janus, eg: Publisher, RecordPlayRecorder, pc is not closed if error
+func (s *Session) AddSendTracks(pc *webrtc.PeerConnection, label string) ([]*Track, []*webrtc.RTPSender, error) {
+	audioTrack, err := pc.NewTrack(webrtc.DefaultPayloadTypeOpus, rand.Uint32(), "audio", label+"A0")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewTrack(Audio)")
+	}
+	audioSender, err := pc.AddTrack(audioTrack)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "AddTrack(Audio)")
+	}
+	videoTrack, err := pc.NewTrack(webrtc.DefaultPayloadTypeH264, rand.Uint32(), "video", label+"V0")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewTrack(Video)")
+	}
+	videoSender, err := pc.AddTrack(videoTrack)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "AddTrack(Video)")
+	}
+	tracks := []*Track{NewTrack(audioTrack, s.Collector), NewTrack(videoTrack, s.Collector)}
+	return tracks, []*webrtc.RTPSender{audioSender, videoSender}, nil
+}
+
+//RecvAnswer answer offer of janus with receive only PeerConnection, codecs of offer in RecvCodecs are accepted
+//flow of offer from janus, eg: StreamingViewer, RecordPlayPlayer, s.PC is set
+func (s *Session) RecvAnswer(ctx context.Context, offer string, onTrack func(*webrtc.Track, *webrtc.RTPReceiver), onState func(webrtc.PeerConnectionState)) (string, error) {
+
+	//always answer, explicit dtls client(setup:active) match the role when janus-gateway is ice-lite
+	setting := DefaultSettingEngine()
+	setting.SetAnsweringDTLSRole(webrtc.DTLSRoleClient)
+	api := NewAPI(offer, setting, RecvCodecs...)
+	if api != nil {
+		s.API = api
+	}
+
+	var pc *webrtc.PeerConnection
+	err := s.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = s.API.NewPeerConnection(s.Configure)
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "NewPeerConnection")
+	}
+	s.PC = pc
+
+	pc.OnTrack(onTrack)
+	pc.OnConnectionStateChange(onState)
+	pc.OnICECandidate(s.OnICECandidate)
+
+	//offer of janus is sendonly, pion v2 answer inactive without local recvonly transceiver
+	recvOnly := webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if _, err = pc.AddTransceiver(kind, recvOnly); err != nil {
+			pc.Close()
+			return "", errors.Wrapf(err, "AddTransceiver(%s)", kind)
+		}
+	}
+
+	err = s.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  offer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return "", errors.Wrap(err, "pc.SetRemoteDescription")
+	}
+
+	var answer webrtc.SessionDescription
+	err = s.Trace(ctx, "webrtc.CreateAnswer", func() (err error) {
+		answer, err = pc.CreateAnswer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return "", errors.Wrap(err, "pc.CreateAnswer")
+	}
+	err = s.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(answer)
+	})
+	if err != nil {
+		pc.Close()
+		return "", errors.Wrap(err, "pc.SetLocalDescription")
+	}
+	return answer.SDP, nil
+}
+
+//DrainSender read rtcp of sender until ctx done or closed
+func DrainSender(ctx context.Context, sender *webrtc.RTPSender) {
+	for ctx.Err() == nil {
+		if _, err := sender.ReadRTCP(); err != nil {
+			return
+		}
+	}
+}
+
+//DrainReceiver read rtcp of receiver until ctx done or closed
+func DrainReceiver(ctx context.Context, receiver *webrtc.RTPReceiver) {
+	for ctx.Err() == nil {
+		if _, err := receiver.ReadRTCP(); err != nil {
+			return
+		}
+	}
+}
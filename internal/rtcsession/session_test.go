@@ -0,0 +1,58 @@
+package rtcsession
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestOnICECandidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := janustest.NewServer()
+	defer srv.Close()
+	srv.RegisterPlugin(janustest.EchoTestPlugin, janustest.NewEchoTest())
+
+	s := New(ctx, webrtc.NewAPI(), janustest.Attach(t, ctx, srv, janustest.EchoTestPlugin), nil)
+	s.OnICECandidate(&webrtc.ICECandidate{
+		Foundation: "1",
+		Priority:   2130706431,
+		Address:    "192.0.2.1",
+		Protocol:   webrtc.ICEProtocolUDP,
+		Port:       5000,
+		Typ:        webrtc.ICECandidateTypeHost,
+		Component:  1,
+	})
+	s.OnICECandidate(nil)
+
+	var trickles []jwsapi.Message
+	for i := 0; i < 100 && len(trickles) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		trickles = trickles[:0]
+		for _, msg := range srv.Received() {
+			if janus, _ := msg.String("janus"); janus == "trickle" {
+				trickles = append(trickles, msg)
+			}
+		}
+	}
+	if len(trickles) != 2 {
+		t.Fatalf("trickles = %v", trickles)
+	}
+	candidate, _ := trickles[0].SubMessage("candidate")
+	if value, _ := candidate.String("candidate"); value != "candidate:1 1 udp 2130706431 192.0.2.1 5000 typ host" {
+		t.Errorf("candidate = %q", value)
+	}
+	if index, ok := candidate.Uint16("sdpMLineIndex"); !ok || index != 0 {
+		t.Errorf("sdpMLineIndex = %d, %v", index, ok)
+	}
+	if mid, ok := candidate.String("sdpMid"); ok {
+		t.Errorf("sdpMid = %q, want none of pion candidate", mid)
+	}
+	if completed, _ := trickles[1].SubMessage("candidate"); !completed.Bool("completed") {
+		t.Errorf("last trickle = %v", trickles[1])
+	}
+}
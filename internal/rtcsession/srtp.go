@@ -0,0 +1,22 @@
+package rtcsession
+
+import (
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+//SRTPMasterKey master key and salt of base64 crypto, eg: srtp_crypto of rtp mountpoint and forwarder
+func SRTPMasterKey(suite int, crypto string) ([]byte, error) {
+	if suite != 80 {
+		return nil, errors.Errorf("srtp suite %d not supported", suite)
+	}
+	key, err := base64.StdEncoding.DecodeString(crypto)
+	if err != nil {
+		return nil, errors.Wrap(err, "srtp crypto")
+	}
+	if len(key) != 30 {
+		return nil, errors.Errorf("srtp crypto length %d, master key(16) + salt(14) required", len(key))
+	}
+	return key, nil
+}
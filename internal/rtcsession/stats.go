@@ -0,0 +1,393 @@
+package rtcsession
+
+import (
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats struct {
+	SSRC         uint32
+	Packets      uint64
+	Bytes        uint64
+	Bitrate      uint64        //bits per second, over the last stats interval
+	PacketsLost  int64         //publisher: reported by janus RR, subscriber: calc by sequence number
+	FractionLost float64       //0.0 ~ 1.0
+	Jitter       time.Duration //publisher: reported by janus RR, subscriber: calc by RFC3550
+	Frames       uint64        //video only, count by rtp marker
+	NACKs        uint64        //publisher: nack recv from janus
+	PLIs         uint64        //publisher: pli recv from janus, subscriber: pli send to janus
+}
+
+//SlowLink janus slowlink event
+type SlowLink struct {
+	Timestamp time.Time
+	Uplink    bool
+	Media     string
+	Lost      uint64
+}
+
+//Stats statistics snapshot of a publisher or subscriber
+type Stats struct {
+	Timestamp     time.Time
+	ID            string
+	Audio         StreamStats
+	Video         StreamStats
+	RTT           time.Duration //publisher only, calc by RR LSR/DLSR
+	BytesSent     uint64        //ice transport bytes
+	BytesReceived uint64        //ice transport bytes
+	//janus media event, janus is receiving audio/video from this peer
+	AudioReceiving bool
+	VideoReceiving bool
+	SlowLinks      uint64
+	LastSlowLink   SlowLink
+}
+
+type streamCounter struct {
+	stats     StreamStats
+	clockRate uint32
+	video     bool
+
+	//receive
+	started     bool
+	baseAt      time.Time //arrival of first packet, base of arrival in clock rate
+	baseSeq     uint16
+	maxSeq      uint16
+	cycles      uint32
+	lastTransit uint32
+	jitter      float64
+
+	//send
+	lastRTPTime uint32
+	lastRTPAt   time.Time
+
+	lastBytes uint64
+	lastTick  time.Time
+}
+
+func (sc *streamCounter) onSend(packet *rtp.Packet, now time.Time) {
+	sc.stats.SSRC = packet.SSRC
+	sc.stats.Packets++
+	sc.stats.Bytes += uint64(len(packet.Payload))
+	if sc.video && packet.Marker {
+		sc.stats.Frames++
+	}
+	sc.lastRTPTime = packet.Timestamp
+	sc.lastRTPAt = now
+}
+
+func (sc *streamCounter) onRecv(packet *rtp.Packet, now time.Time) {
+	sc.stats.SSRC = packet.SSRC
+	sc.stats.Packets++
+	sc.stats.Bytes += uint64(len(packet.Payload))
+	if sc.video && packet.Marker {
+		sc.stats.Frames++
+	}
+
+	if !sc.started {
+		sc.started = true
+		sc.baseAt = now
+		sc.baseSeq = packet.SequenceNumber
+		sc.maxSeq = packet.SequenceNumber
+	} else if diff := packet.SequenceNumber - sc.maxSeq; diff > 0 && diff < 0x8000 {
+		if packet.SequenceNumber < sc.maxSeq {
+			sc.cycles += 1 << 16
+		}
+		sc.maxSeq = packet.SequenceNumber
+	}
+	expected := int64(sc.cycles) + int64(sc.maxSeq) - int64(sc.baseSeq) + 1
+	sc.stats.PacketsLost = expected - int64(sc.stats.Packets)
+	if expected > 0 && sc.stats.PacketsLost > 0 {
+		sc.stats.FractionLost = float64(sc.stats.PacketsLost) / float64(expected)
+	} else {
+		sc.stats.FractionLost = 0
+	}
+
+	if sc.clockRate > 0 {
+		//arrival and rtp timestamp wrap at 32 bits, transit difference is modulo 2^32
+		elapsed := now.Sub(sc.baseAt)
+		arrival := uint64(elapsed/time.Second)*uint64(sc.clockRate) + uint64(elapsed%time.Second)*uint64(sc.clockRate)/uint64(time.Second)
+		transit := uint32(arrival) - packet.Timestamp
+		if sc.stats.Packets > 1 {
+			d := int64(int32(transit - sc.lastTransit))
+			if d < 0 {
+				d = -d
+			}
+			sc.jitter += (float64(d) - sc.jitter) / 16
+			sc.stats.Jitter = time.Duration(sc.jitter * float64(time.Second) / float64(sc.clockRate))
+		}
+		sc.lastTransit = transit
+	}
+}
+
+func (sc *streamCounter) onReceptionReport(report rtcp.ReceptionReport) {
+	sc.stats.PacketsLost = int64(report.TotalLost)
+	sc.stats.FractionLost = float64(report.FractionLost) / 256
+	if sc.clockRate > 0 {
+		sc.stats.Jitter = time.Duration(uint64(report.Jitter) * uint64(time.Second) / uint64(sc.clockRate))
+	}
+}
+
+func (sc *streamCounter) tick(now time.Time) {
+	if !sc.lastTick.IsZero() {
+		if elapsed := now.Sub(sc.lastTick); elapsed > 0 {
+			sc.stats.Bitrate = uint64(float64(sc.stats.Bytes-sc.lastBytes) * 8 / elapsed.Seconds())
+		}
+	}
+	sc.lastBytes = sc.stats.Bytes
+	sc.lastTick = now
+}
+
+func (sc *streamCounter) senderReport(now time.Time) *rtcp.SenderReport {
+	if sc.stats.Packets == 0 || sc.clockRate == 0 {
+		return nil
+	}
+	rtpTime := sc.lastRTPTime + uint32(now.Sub(sc.lastRTPAt).Seconds()*float64(sc.clockRate))
+	return &rtcp.SenderReport{
+		SSRC:        sc.stats.SSRC,
+		NTPTime:     ToNTP(now),
+		RTPTime:     rtpTime,
+		PacketCount: uint32(sc.stats.Packets),
+		OctetCount:  uint32(sc.stats.Bytes),
+	}
+}
+
+//Collector collect rtp/rtcp statistics for one PeerConnection
+type Collector struct {
+	mu             sync.Mutex
+	id             func() string
+	audio          streamCounter
+	video          streamCounter
+	rtt            time.Duration
+	sent           uint64
+	recv           uint64
+	srSent         map[uint32]time.Time //compact ntp(LSR) -> send time
+	audioReceiving bool
+	videoReceiving bool
+	slowLinks      uint64
+	lastSlowLink   SlowLink
+}
+
+//NewCollector create collector, id is ID of Stats
+func NewCollector(id func() string) *Collector {
+	return &Collector{
+		id:     id,
+		audio:  streamCounter{clockRate: 48000},
+		video:  streamCounter{clockRate: 90000, video: true},
+		srSent: make(map[uint32]time.Time),
+	}
+}
+
+func (c *Collector) stream(kind webrtc.RTPCodecType) *streamCounter {
+	if kind == webrtc.RTPCodecTypeVideo {
+		return &c.video
+	}
+	return &c.audio
+}
+
+func (c *Collector) streamBySSRC(ssrc uint32) *streamCounter {
+	if ssrc == 0 {
+		return nil
+	}
+	switch ssrc {
+	case c.audio.stats.SSRC:
+		return &c.audio
+	case c.video.stats.SSRC:
+		return &c.video
+	default:
+		return nil
+	}
+}
+
+//SetClockRate clock rate of kind, from codec of track
+func (c *Collector) SetClockRate(kind webrtc.RTPCodecType, clockRate uint32) {
+	if clockRate == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.stream(kind).clockRate = clockRate
+	c.mu.Unlock()
+}
+
+//OnSendRTP rtp sent of kind
+func (c *Collector) OnSendRTP(kind webrtc.RTPCodecType, packet *rtp.Packet) {
+	c.mu.Lock()
+	c.stream(kind).onSend(packet, time.Now())
+	c.mu.Unlock()
+}
+
+//OnRecvRTP rtp received of kind
+func (c *Collector) OnRecvRTP(kind webrtc.RTPCodecType, packet *rtp.Packet) {
+	c.mu.Lock()
+	c.stream(kind).onRecv(packet, time.Now())
+	c.mu.Unlock()
+}
+
+//OnSendRTCP rtcp sent, count NACK and PLI
+func (c *Collector) OnSendRTCP(packets []rtcp.Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, packet := range packets {
+		switch pkt := packet.(type) {
+		case *rtcp.TransportLayerNack:
+			if sc := c.streamBySSRC(pkt.MediaSSRC); sc != nil {
+				sc.stats.NACKs++
+			}
+		case *rtcp.PictureLossIndication:
+			if sc := c.streamBySSRC(pkt.MediaSSRC); sc != nil {
+				sc.stats.PLIs++
+			}
+		}
+	}
+}
+
+//OnRecvRTCP rtcp received, RR, SR, NACK and PLI
+func (c *Collector) OnRecvRTCP(packets []rtcp.Packet) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, packet := range packets {
+		switch pkt := packet.(type) {
+		case *rtcp.ReceiverReport:
+			c.onReceptionReports(pkt.Reports, now)
+		case *rtcp.SenderReport:
+			c.onReceptionReports(pkt.Reports, now)
+		case *rtcp.TransportLayerNack:
+			if sc := c.streamBySSRC(pkt.MediaSSRC); sc != nil {
+				sc.stats.NACKs++
+			}
+		case *rtcp.PictureLossIndication:
+			if sc := c.streamBySSRC(pkt.MediaSSRC); sc != nil {
+				sc.stats.PLIs++
+			}
+		}
+	}
+}
+
+func (c *Collector) onReceptionReports(reports []rtcp.ReceptionReport, now time.Time) {
+	for _, report := range reports {
+		sc := c.streamBySSRC(report.SSRC)
+		if sc == nil {
+			continue
+		}
+		sc.onReceptionReport(report)
+		if report.LastSenderReport == 0 {
+			continue
+		}
+		if sentAt, ok := c.srSent[report.LastSenderReport]; ok {
+			delay := time.Duration(uint64(report.Delay) * uint64(time.Second) / 65536)
+			if rtt := now.Sub(sentAt) - delay; rtt > 0 {
+				c.rtt = rtt
+			}
+		}
+	}
+}
+
+//OnMedia janus media event, {"janus":"media","type":"audio","receiving":true}
+func (c *Collector) OnMedia(msg jwsapi.Message) {
+	media, _ := msg.String("type")
+	receiving := msg.Bool("receiving")
+	c.mu.Lock()
+	switch media {
+	case "audio":
+		c.audioReceiving = receiving
+	case "video":
+		c.videoReceiving = receiving
+	}
+	c.mu.Unlock()
+}
+
+//OnSlowLink janus slowlink event, {"janus":"slowlink","uplink":true,"media":"video","lost":10}
+func (c *Collector) OnSlowLink(msg jwsapi.Message) {
+	media, _ := msg.String("media")
+	lost, ok := msg.Uint64("lost")
+	if !ok {
+		//janus 0.x report nacks
+		lost, _ = msg.Uint64("nacks")
+	}
+	c.mu.Lock()
+	c.slowLinks++
+	c.lastSlowLink = SlowLink{
+		Timestamp: time.Now(),
+		Uplink:    msg.Bool("uplink"),
+		Media:     media,
+		Lost:      lost,
+	}
+	c.mu.Unlock()
+}
+
+//SenderReports build SR for all sending streams, used to calc rtt
+func (c *Collector) SenderReports() []rtcp.Packet {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var packets []rtcp.Packet
+	for _, sc := range []*streamCounter{&c.audio, &c.video} {
+		if sr := sc.senderReport(now); sr != nil {
+			packets = append(packets, sr)
+			c.srSent[uint32(sr.NTPTime>>16)] = now
+		}
+	}
+	for lsr, sentAt := range c.srSent {
+		if now.Sub(sentAt) > time.Minute {
+			delete(c.srSent, lsr)
+		}
+	}
+	return packets
+}
+
+//Tick update bitrate and transport bytes
+func (c *Collector) Tick(pc *webrtc.PeerConnection) {
+	var transport webrtc.TransportStats
+	var hasTransport bool
+	if pc != nil {
+		for _, s := range pc.GetStats() {
+			if ts, ok := s.(webrtc.TransportStats); ok {
+				transport, hasTransport = ts, true
+			}
+		}
+	}
+	now := time.Now()
+	c.mu.Lock()
+	c.audio.tick(now)
+	c.video.tick(now)
+	if hasTransport {
+		c.sent = transport.BytesSent
+		c.recv = transport.BytesReceived
+	}
+	c.mu.Unlock()
+}
+
+//Snapshot statistics snapshot
+func (c *Collector) Snapshot() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Timestamp:      time.Now(),
+		ID:             c.id(),
+		Audio:          c.audio.stats,
+		Video:          c.video.stats,
+		RTT:            c.rtt,
+		BytesSent:      c.sent,
+		BytesReceived:  c.recv,
+		AudioReceiving: c.audioReceiving,
+		VideoReceiving: c.videoReceiving,
+		SlowLinks:      c.slowLinks,
+		LastSlowLink:   c.lastSlowLink,
+	}
+}
+
+//ToNTP ntp timestamp of t
+func ToNTP(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800
+	nsec := uint64(t.UnixNano())
+	sec := nsec/uint64(time.Second) + ntpEpochOffset
+	frac := (nsec % uint64(time.Second)) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
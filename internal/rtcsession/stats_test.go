@@ -0,0 +1,165 @@
+package rtcsession
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+type testPacket struct {
+	seq    uint16
+	ts     uint32
+	at     time.Duration //arrival after base
+	size   int
+	marker bool
+}
+
+func recvAll(sc *streamCounter, base time.Time, packets []testPacket) {
+	for _, p := range packets {
+		sc.onRecv(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: p.seq, Timestamp: p.ts, Marker: p.marker},
+			Payload: make([]byte, p.size),
+		}, base.Add(p.at))
+	}
+}
+
+//packets of seqs at fixed interval, rtp timestamp in step with arrival
+func paced(seqs []uint16, ts uint32, step uint32, interval time.Duration) []testPacket {
+	packets := make([]testPacket, 0, len(seqs))
+	for i, seq := range seqs {
+		packets = append(packets, testPacket{seq: seq, ts: ts + uint32(i)*step, at: time.Duration(i) * interval})
+	}
+	return packets
+}
+
+func TestStreamCounterLoss(t *testing.T) {
+	cases := []struct {
+		name     string
+		seqs     []uint16
+		lost     int64
+		fraction float64
+	}{
+		{"no loss", []uint16{1, 2, 3, 4}, 0, 0},
+		{"one lost", []uint16{1, 2, 3, 5, 6}, 1, 1.0 / 6},
+		{"burst lost", []uint16{10, 11, 15}, 3, 3.0 / 6},
+		{"reordered", []uint16{1, 3, 2, 4}, 0, 0},
+		{"duplicated", []uint16{1, 2, 2, 3}, -1, 0},
+		{"wrap", []uint16{65534, 65535, 0, 1}, 0, 0},
+		{"lost over wrap", []uint16{65534, 0, 1}, 1, 1.0 / 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sc := &streamCounter{clockRate: 90000, video: true}
+			recvAll(sc, time.Now(), paced(c.seqs, 0, 3000, 33*time.Millisecond))
+			if sc.stats.PacketsLost != c.lost {
+				t.Errorf("lost = %d, want %d", sc.stats.PacketsLost, c.lost)
+			}
+			if math.Abs(sc.stats.FractionLost-c.fraction) > 1e-9 {
+				t.Errorf("fraction = %f, want %f", sc.stats.FractionLost, c.fraction)
+			}
+		})
+	}
+}
+
+func TestStreamCounterJitter(t *testing.T) {
+	seqs := make([]uint16, 50)
+	for i := range seqs {
+		seqs[i] = uint16(i)
+	}
+	//every other packet arrives 10ms late, |D| is 10ms for all but the first
+	delayed := paced(seqs, 0, 1800, 20*time.Millisecond)
+	for i := range delayed {
+		if i%2 == 1 {
+			delayed[i].at += 10 * time.Millisecond
+		}
+	}
+	var want float64
+	for i := 1; i < len(seqs); i++ {
+		want += (900 - want) / 16
+	}
+
+	cases := []struct {
+		name    string
+		base    time.Time
+		packets []testPacket
+		jitter  time.Duration
+	}{
+		{"constant delay", time.Now(), paced(seqs, 1234, 1800, 20*time.Millisecond), 0},
+		//arrival in clock rate is far beyond int64 of unix nanoseconds * 90000
+		{"wall clock far away", time.Date(2262, 1, 1, 0, 0, 0, 0, time.UTC), paced(seqs, 0, 1800, 20*time.Millisecond), 0},
+		{"rtp timestamp wrap", time.Now(), paced(seqs, math.MaxUint32-10*1800, 1800, 20*time.Millisecond), 0},
+		{"alternate delay", time.Now(), delayed, time.Duration(want * float64(time.Second) / 90000)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sc := &streamCounter{clockRate: 90000, video: true}
+			recvAll(sc, c.base, c.packets)
+			if diff := sc.stats.Jitter - c.jitter; diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("jitter = %v, want %v", sc.stats.Jitter, c.jitter)
+			}
+		})
+	}
+}
+
+func TestStreamCounterFrames(t *testing.T) {
+	packets := []testPacket{
+		{seq: 1, ts: 0}, {seq: 2, ts: 0, marker: true},
+		{seq: 3, ts: 3000, marker: true},
+		{seq: 4, ts: 6000}, {seq: 5, ts: 6000}, {seq: 6, ts: 6000, marker: true},
+	}
+	cases := []struct {
+		name   string
+		video  bool
+		frames uint64
+	}{
+		{"video", true, 3},
+		{"audio", false, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recv := &streamCounter{clockRate: 90000, video: c.video}
+			recvAll(recv, time.Now(), packets)
+			send := &streamCounter{clockRate: 90000, video: c.video}
+			for _, p := range packets {
+				send.onSend(&rtp.Packet{Header: rtp.Header{SequenceNumber: p.seq, Marker: p.marker}}, time.Now())
+			}
+			if recv.stats.Frames != c.frames || send.stats.Frames != c.frames {
+				t.Errorf("frames = %d(recv), %d(send), want %d", recv.stats.Frames, send.stats.Frames, c.frames)
+			}
+		})
+	}
+}
+
+func TestStreamCounterBitrate(t *testing.T) {
+	cases := []struct {
+		name    string
+		sizes   []int
+		elapsed time.Duration
+		bitrate uint64
+	}{
+		{"idle", nil, time.Second, 0},
+		{"1000 bytes in 1s", []int{500, 500}, time.Second, 8000},
+		{"1000 bytes in 500ms", []int{250, 250, 500}, 500 * time.Millisecond, 16000},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			base := time.Now()
+			sc := &streamCounter{clockRate: 48000}
+			sc.tick(base)
+			for i, size := range c.sizes {
+				sc.onSend(&rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(i)}, Payload: make([]byte, size)}, base)
+			}
+			sc.tick(base.Add(c.elapsed))
+			if sc.stats.Bitrate != c.bitrate {
+				t.Errorf("bitrate = %d, want %d", sc.stats.Bitrate, c.bitrate)
+			}
+			//next interval without packets
+			sc.tick(base.Add(2 * c.elapsed))
+			if sc.stats.Bitrate != 0 {
+				t.Errorf("bitrate of idle interval = %d, want 0", sc.stats.Bitrate)
+			}
+		})
+	}
+}
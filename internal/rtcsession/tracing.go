@@ -0,0 +1,50 @@
+package rtcsession
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//StartSpan span of tracer of handle
+func (s *Session) StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.Handle.Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+//Trace run pion step f in child span
+func (s *Session) Trace(ctx context.Context, name string, f func() error) error {
+	_, span := s.StartSpan(ctx, name)
+	err := f()
+	EndSpan(span, err)
+	return err
+}
+
+//SetTraceLink span of ctx is linked by later trickle spans, it is ended when negotiation returns
+func (s *Session) SetTraceLink(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.traceLink = trace.LinkFromContext(ctx)
+}
+
+//startTrickleSpan root span of trickle from pion ICE goroutine, linked to negotiation span
+func (s *Session) startTrickleSpan() (context.Context, trace.Span) {
+	s.mu.Lock()
+	link := s.traceLink
+	s.mu.Unlock()
+	opts := []trace.SpanStartOption{trace.WithNewRoot()}
+	if link.SpanContext.IsValid() {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	return s.Handle.Tracer().Start(s.Ctx, "webrtc.Trickle", opts...)
+}
+
+//EndSpan record err and end span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,39 @@
+package rtcsession
+
+import (
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+//Track track
+type Track struct {
+	track *webrtc.Track
+	seqNo uint16
+	stats *Collector
+}
+
+//NewTrack new track, packets sent are counted by stats, can be nil
+func NewTrack(track *webrtc.Track, stats *Collector) *Track {
+	return &Track{track: track, stats: stats}
+}
+
+//WriteRTP write rtp
+func (t *Track) WriteRTP(packet *rtp.Packet) error {
+	t.seqNo++
+	packet.SequenceNumber = t.seqNo
+	err := t.track.WriteRTP(packet)
+	if err == nil && t.stats != nil {
+		t.stats.OnSendRTP(t.track.Kind(), packet)
+	}
+	return err
+}
+
+//SSRC return ssrc
+func (t *Track) SSRC() uint32 {
+	return t.track.SSRC()
+}
+
+//PayloadType return payload type, WriteRTP not set it for packet
+func (t *Track) PayloadType() uint8 {
+	return t.track.PayloadType()
+}
@@ -0,0 +1,199 @@
+package jwsapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+//Envelope typed janus message, fields of other messages are ignored, see Message for all fields
+type Envelope struct {
+	Janus       string          `json:"janus"`
+	Transaction string          `json:"transaction,omitempty"`
+	SessionID   uint64          `json:"session_id,omitempty"`
+	Sender      uint64          `json:"sender,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"` //success of create, attach
+	PluginData  *PluginData     `json:"plugindata,omitempty"`
+	JSEP        *JSEP           `json:"jsep,omitempty"`
+	Candidate   *Candidate      `json:"candidate,omitempty"`
+	Error       *ErrorInfo      `json:"error,omitempty"`
+	Reason      string          `json:"reason,omitempty"` //hangup
+}
+
+//PluginData plugindata of success and event
+type PluginData struct {
+	Plugin string  `json:"plugin"`
+	Data   Message `json:"data"` //numbers are json.Number
+}
+
+//Err error of plugin, error_code and error of data, nil if not
+func (p *PluginData) Err() error {
+	if p == nil {
+		return nil
+	}
+	code, hasCode := p.Data.Uint64("error_code")
+	if _, ok := p.Data["error"]; !ok && !hasCode {
+		return nil
+	}
+	reason, _ := p.Data.String("error")
+	if reason == "" {
+		reason = "unknown error"
+	}
+	return &ErrorInfo{Code: int(code), Reason: reason}
+}
+
+//Decode decode Data to v, v is pointer of typed struct
+func (p *PluginData) Decode(v interface{}) error {
+	if p == nil {
+		return errors.New("not plugindata")
+	}
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return errors.Wrap(err, "decode plugindata")
+	}
+	if err := decodeJSON(data, v); err != nil {
+		return errors.Wrap(err, "decode plugindata")
+	}
+	return nil
+}
+
+//JSEP offer or answer of message
+type JSEP struct {
+	Type    string `json:"type"` //offer, answer
+	SDP     string `json:"sdp"`
+	Trickle *bool  `json:"trickle,omitempty"`
+}
+
+//Candidate trickle candidate, Completed is true if end of candidates
+type Candidate struct {
+	Candidate     string `json:"candidate,omitempty"`
+	SDPMid        string `json:"sdpMid,omitempty"`
+	SDPMLineIndex *int   `json:"sdpMLineIndex,omitempty"`
+	Completed     bool   `json:"completed,omitempty"`
+}
+
+//ErrorInfo error of janus error message, or error_code and error of plugindata
+type ErrorInfo struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+//Error reason of error
+func (e *ErrorInfo) Error() string {
+	return e.Reason
+}
+
+//DecodeEnvelope decode janus message, error if data is not message of janus
+func DecodeEnvelope(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := decodeJSON(data, e); err != nil {
+		return nil, errors.Wrap(err, "decode envelope")
+	}
+	if e.Janus == "" {
+		return nil, errors.New("decode envelope: janus is empty")
+	}
+	return e, nil
+}
+
+//Envelope typed envelope of m
+func (m *Message) Envelope() (*Envelope, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode message")
+	}
+	return DecodeEnvelope(data)
+}
+
+//decodeMessage decode received frame once, fields of envelope are checked before Message is returned,
+//so accessors of Message see expected types
+func decodeMessage(data []byte) (*Message, error) {
+	msg := make(Message)
+	if err := decodeJSON(data, &msg); err != nil {
+		return nil, errors.Wrap(err, "decode message")
+	}
+	if err := checkEnvelope(msg); err != nil {
+		return nil, errors.Wrap(err, "decode message")
+	}
+	return &msg, nil
+}
+
+//checkEnvelope check types of decoded fields as Envelope
+func checkEnvelope(m Message) error {
+	if janus, ok := m[attrType].(string); !ok || janus == "" {
+		return errors.New("janus is not string")
+	}
+	for _, key := range []string{attrTransaction, "reason"} {
+		if err := checkType(m, key, isString); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"session_id", "sender"} {
+		if err := checkType(m, key, isUint64); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{attrPluginData, "jsep", "candidate", "error"} {
+		if err := checkType(m, key, isObject); err != nil {
+			return err
+		}
+	}
+	if pd, ok := m.SubMessage(attrPluginData); ok {
+		if err := checkType(pd, "plugin", isString); err != nil {
+			return err
+		}
+		if err := checkType(pd, "data", isObject); err != nil {
+			return err
+		}
+	}
+	if jsep, ok := m.SubMessage("jsep"); ok {
+		for _, key := range []string{"type", "sdp"} {
+			if err := checkType(jsep, key, isString); err != nil {
+				return err
+			}
+		}
+	}
+	if errInfo, ok := m.SubMessage("error"); ok {
+		if err := checkType(errInfo, "code", isUint64); err != nil {
+			return err
+		}
+		if err := checkType(errInfo, "reason", isString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkType value of key is nil or is expected type
+func checkType(m Message, key string, is func(interface{}) bool) error {
+	if v, ok := m[key]; ok && v != nil && !is(v) {
+		return errors.Errorf("unexpected type %T of %s", v, key)
+	}
+	return nil
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func isUint64(v interface{}) bool {
+	n, ok := v.(json.Number)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseUint(string(n), 10, 64)
+	return err == nil
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,42 @@
+package jwsapi
+
+import "testing"
+
+func TestDecodeMessage(t *testing.T) {
+	frames := []string{
+		`{"janus":"ack","transaction":"t1","session_id":1}`,
+		`{"janus":"event","session_id":1,"sender":2,"plugindata":{"plugin":"janus.plugin.videoroom","data":{"videoroom":"event","publishers":[1,"x"]}},"jsep":{"type":"offer","sdp":"v=0"}}`,
+		`{"janus":"error","transaction":"t2","error":{"code":458,"reason":"No such session"}}`,
+		`{"janus":"trickle","session_id":1,"sender":2,"candidate":{"completed":true}}`,
+		`{"janus":"hangup","session_id":1,"sender":2,"reason":"DTLS alert","session_id":null}`,
+		`{"janus":"success","transaction":"t3","data":{"id":3}}`,
+		`{"janus":5}`,
+		`{"janus":""}`,
+		`{"transaction":"t4"}`,
+		`{"janus":"ack","transaction":[]}`,
+		`{"janus":"ack","session_id":"x"}`,
+		`{"janus":"ack","session_id":-1}`,
+		`{"janus":"event","plugindata":"y"}`,
+		`{"janus":"event","plugindata":{"plugin":1,"data":{}}}`,
+		`{"janus":"event","plugindata":{"plugin":"x","data":[]}}`,
+		`{"janus":"event","jsep":{"type":"offer","sdp":5}}`,
+		`{"janus":"error","error":"not object"}`,
+		`{"janus":"error","error":{"code":"x","reason":"r"}}`,
+		`{"janus":"trickle","candidate":"x"}`,
+		`{"janus":"hangup","reason":1}`,
+		`{"janus":"ack"`,
+		`[]`,
+	}
+	//decodeMessage accept same frames as DecodeEnvelope
+	for _, frame := range frames {
+		_, envErr := DecodeEnvelope([]byte(frame))
+		msg, err := decodeMessage([]byte(frame))
+		if (err == nil) != (envErr == nil) {
+			t.Errorf("%s: decodeMessage err %v, DecodeEnvelope err %v", frame, err, envErr)
+			continue
+		}
+		if err == nil && msg.Type() == "" {
+			t.Errorf("%s: type is empty", frame)
+		}
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/logging/redact"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -26,6 +28,9 @@ var (
 	space   = []byte{' '}
 )
 
+//ErrTimeout request or message response timeout
+var ErrTimeout = errors.New("timeout")
+
 type onResponse func(*Message)
 type cstate int
 
@@ -35,6 +40,19 @@ const (
 	closed       cstate = 3
 )
 
+func (s cstate) String() string {
+	switch s {
+	case connectioing:
+		return "connecting"
+	case connectioned:
+		return "connected"
+	case closed:
+		return "closed"
+	default:
+		return "n/a"
+	}
+}
+
 type connState struct {
 	state cstate
 	ts    time.Time
@@ -51,6 +69,7 @@ func getTID() string {
 type Connection struct {
 	ctx                 context.Context
 	isDestroy           int32
+	connected           int32
 	id                  int
 	url                 string
 	cc                  int // connection count
@@ -69,10 +88,36 @@ type Connection struct {
 	sessions            map[uint64]*Session
 	sessionCalcels      map[uint64]context.CancelFunc
 	state               connState
+	connects            int
+	metrics             Metrics
+	tracer              trace.Tracer
+	log                 *logging.Logger
+	wire                *logging.Logger //wire payload, subsystem jwsapi.wire, level trace
+	redactor            *redact.Redactor
+	redactorSet         bool //WithConnectionRedactor is set, nil redactor disable redaction
+	tap                 Tap
+}
+
+//ConnectionOption option for Connection
+type ConnectionOption func(*Connection)
+
+//WithConnectionRedactor set redactor for wire payload log and dump, default is redact.Default(), nil to disable redaction
+func WithConnectionRedactor(redactor *redact.Redactor) ConnectionOption {
+	return func(c *Connection) {
+		c.redactor = redactor
+		c.redactorSet = true
+	}
+}
+
+//WithConnectionMetrics set metrics hook
+func WithConnectionMetrics(metrics Metrics) ConnectionOption {
+	return func(c *Connection) {
+		c.metrics = metrics
+	}
 }
 
 //NewConnection create new janus gateway connection
-func NewConnection(ctx context.Context, url string, id int) *Connection {
+func NewConnection(ctx context.Context, url string, id int, opts ...ConnectionOption) *Connection {
 	conn := &Connection{
 		ctx:                 ctx,
 		isDestroy:           0,
@@ -93,6 +138,14 @@ func NewConnection(ctx context.Context, url string, id int) *Connection {
 			state: connectioing,
 			ts:    time.Now(),
 		},
+		metrics: nopMetrics{},
+		tracer:  defaultTracer(),
+		log:     logging.Named("jwsapi").With(logging.F("conn", fmt.Sprintf("%s@%d", url, id))),
+		wire:    logging.Named("jwsapi.wire").With(logging.F("conn", fmt.Sprintf("%s@%d", url, id))),
+	}
+
+	for _, opt := range opts {
+		opt(conn)
 	}
 
 	go conn.execLoop()
@@ -105,6 +158,16 @@ func (c *Connection) ID() string {
 	return fmt.Sprintf("[%s@%d_%d]", c.url, c.id, c.cc)
 }
 
+//URL return janus gateway url
+func (c *Connection) URL() string {
+	return c.url
+}
+
+//Connected ws connection is connected
+func (c *Connection) Connected() bool {
+	return atomic.LoadInt32(&c.connected) == 1
+}
+
 //IsDestroy is destroy for this object
 func (c *Connection) IsDestroy() bool {
 	if atomic.LoadInt32(&c.isDestroy) == 1 {
@@ -113,17 +176,27 @@ func (c *Connection) IsDestroy() bool {
 	return false
 }
 
+//getRedactor redactor of WithConnectionRedactor, nil is do nothing, or current redact.Default()
+func (c *Connection) getRedactor() *redact.Redactor {
+	if c.redactorSet {
+		return c.redactor
+	}
+	return redact.Default()
+}
+
 func (c *Connection) tryConnection() {
 
 	c.cc++
 	c.connCtx, c.connCancel = context.WithCancel(c.ctx)
 
-	websocket.DefaultDialer.Subprotocols = []string{"janus-protocol"}
-	websocket.DefaultDialer.HandshakeTimeout = time.Duration(c.retrySeconds) * time.Second
-	conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
+	//not modify websocket.DefaultDialer, connections of Pool dial at same time
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = []string{"janus-protocol"}
+	dialer.HandshakeTimeout = time.Duration(c.retrySeconds) * time.Second
+	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
 
-		logging.Warnf("%s connection err:%v", c.ID(), err)
+		c.log.Warn("connection err", logging.F("cc", c.cc), logging.F("err", err))
 		<-time.After(time.Duration(c.retrySeconds) * time.Second)
 		select {
 		case <-c.ctx.Done():
@@ -134,7 +207,7 @@ func (c *Connection) tryConnection() {
 
 	c.conn = conn
 	go c.readDump(c.conn)
-	go c.writeDump(c.conn)
+	go c.writeDump(c.connCtx, c.conn)
 	c.connStateChan <- connectioned
 
 	return
@@ -143,7 +216,7 @@ func (c *Connection) tryConnection() {
 func (c *Connection) readDump(conn *websocket.Conn) {
 
 	defer func() {
-		logging.Infof("%s readDump End", c.ID())
+		c.log.Info("readDump End")
 		conn.Close()
 	}()
 	conn.SetReadLimit(c.readBufferLimit)
@@ -154,49 +227,62 @@ func (c *Connection) readDump(conn *websocket.Conn) {
 		default:
 			_, data, err := conn.ReadMessage()
 			if err != nil {
-				logging.Warnf("%s read err %s", c.ID(), err)
+				c.log.Warn("read err", logging.F("err", err))
 				c.connStateChan <- closed
 				return
 			}
-			logging.Infof("%s recv %s", c.ID(), string(data))
+			c.onFrame(DirectionRecv, data)
+			if c.wire.Enabled(logging.LevelTrace) {
+				c.wire.Trace("recv", logging.F("payload", c.getRedactor().String(data)))
+			}
 			message := bytes.TrimSpace(bytes.Replace(data, newline, space, -1))
 
-			decoder := json.NewDecoder(bytes.NewBuffer(message))
-			decoder.UseNumber()
-			msg := make(Message)
-			err = decoder.Decode(&msg)
+			msg, err := decodeMessage(message)
 			if err == nil {
 				select {
-				case c.recvChan <- &msg:
+				case c.recvChan <- msg:
 				default:
-					t, ok := msg.Transaction()
-					logging.Warnf("%s post message %s.%s(%t) failed", msg.Type(), t, ok)
+					t, _ := msg.Transaction()
+					c.log.Warn("post message failed", logging.F("janus", msg.Type()), logging.F("transaction", t))
+					c.metrics.MessageDropped(c.url)
 				}
 			} else {
-				logging.Errorf("%s Decode err %v", c.ID(), err)
+				c.log.Error("decode err", logging.F("err", err))
 			}
 		}
 	}
 }
 
-func (c *Connection) writeDump(conn *websocket.Conn) {
+//writeDump ctx is connCtx, end when conn closed, so not steal message of next conn
+func (c *Connection) writeDump(ctx context.Context, conn *websocket.Conn) {
 
 	defer func() {
-		logging.Infof("%s writeDump End", c.ID())
+		c.log.Info("writeDump End")
 	}()
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		case data := <-c.sendChan:
 			conn.SetWriteDeadline(time.Now().Add(time.Duration(c.writeTimeoutSeconds) * time.Second))
 			err := conn.WriteMessage(websocket.TextMessage, data)
 			if err != nil {
-				logging.Errorf("%s write err %v", c.ID(), err)
+				if ctx.Err() != nil {
+					//conn has closed, give back to next conn
+					select {
+					case c.sendChan <- data:
+					default:
+					}
+					return
+				}
+				c.log.Error("write err", logging.F("err", err))
 				c.connStateChan <- closed
 				return
 			}
-			logging.Infof("%s write ok %s", c.ID(), string(data))
+			c.onFrame(DirectionSend, data)
+			if c.wire.Enabled(logging.LevelTrace) {
+				c.wire.Trace("send", logging.F("payload", c.getRedactor().String(data)))
+			}
 
 		}
 	}
@@ -206,11 +292,10 @@ func (c *Connection) execLoop() {
 
 	ticker := time.NewTicker(10 * time.Second)
 	defer func() {
-		logging.Infof("%s exec is Done", c.ID())
+		c.log.Info("exec is Done")
 		atomic.StoreInt32(&c.isDestroy, 1)
-		close(c.tasks)
-		close(c.recvChan)
-		close(c.sendChan)
+		atomic.StoreInt32(&c.connected, 0)
+		//tasks, recvChan, sendChan are not closed, they may be sent by other goroutine
 		ticker.Stop()
 	}()
 
@@ -225,22 +310,28 @@ func (c *Connection) execLoop() {
 				//conn is disconnection to long time. release all session
 
 				for sid := range c.sessions {
-					logging.Warnf("%s delSession[%d] all for disconnection too long", c.ID(), sid)
+					c.log.Warn("delSession for disconnection too long", logging.F("session", sid))
 					go c.delSession(sid)
 				}
 			}
 		case state := <-c.connStateChan:
 
-			logging.Infof("%s retrying %d", c.ID(), state)
+			c.log.Info("conn state changed", logging.F("state", state), logging.F("cc", c.cc))
 			c.state.state = state
 			c.state.ts = time.Now()
 			switch state {
 			case closed:
+				atomic.StoreInt32(&c.connected, 0)
 				c.connCancel()
 
 				go c.tryConnection()
 			case connectioned:
 				//链接建立..
+				atomic.StoreInt32(&c.connected, 1)
+				if c.connects > 0 {
+					c.metrics.Reconnected(c.url)
+				}
+				c.connects++
 
 				for _, sess := range c.sessions {
 					go sess.claim()
@@ -263,7 +354,7 @@ func (c *Connection) execLoop() {
 				if ok {
 					trans(msg)
 				} else {
-					logging.Warnf("%s can't find trans by %s", c.ID(), tid)
+					c.log.Warn("can't find transaction", logging.F("transaction", tid))
 				}
 			} else {
 				sid, ok := msg.SessionID()
@@ -273,10 +364,10 @@ func (c *Connection) execLoop() {
 					if ok {
 						sess.onMessage(c, msg)
 					} else {
-						logging.Warnf("%s can't find session by %d", c.ID(), sid)
+						c.log.Warn("can't find session", logging.F("session", sid))
 					}
 				} else {
-					logging.Warnf("%s unknown doing msg %v", c.ID(), msg)
+					c.log.Warn("unknown doing msg", logging.F("janus", msg.Type()))
 				}
 			}
 		}
@@ -298,6 +389,7 @@ func (c *Connection) addSession(s *Session, cancel context.CancelFunc) {
 	c.run(func(cc *Connection) {
 		cc.sessions[s.ID] = s
 		cc.sessionCalcels[s.ID] = cancel
+		cc.metrics.SessionCreated(cc.url)
 	})
 }
 
@@ -307,6 +399,7 @@ func (c *Connection) delSession(sid uint64) {
 			cancel()
 			delete(cc.sessions, sid)
 			delete(cc.sessionCalcels, sid)
+			cc.metrics.SessionDestroyed(cc.url)
 		}
 
 	})
@@ -332,72 +425,166 @@ func (c *Connection) sendMessage(tid string, msg Message, callback onResponse) {
 
 //Request send request ,has success response
 func (c *Connection) Request(request Message) (*Message, error) {
+	return c.request(context.Background(), request, "")
+}
+
+//RequestContext send request, has success response
+//ctx using for cancel and trace
+func (c *Connection) RequestContext(ctx context.Context, request Message) (*Message, error) {
+	return c.request(ctx, request, "")
+}
+
+func (c *Connection) request(ctx context.Context, request Message, plugin string) (*Message, error) {
+	info := newRequestInfo(c.url, request, plugin)
+	ctx, span := c.startSpan(ctx, info)
+	start := time.Now()
+	rsp, err := c.doRequest(ctx, request)
+	c.metrics.RequestDone(info, time.Since(start), err)
+	c.logRequest(info, request, time.Since(start), err)
+	endSpan(span, request, err)
+	return rsp, err
+}
+
+func (c *Connection) logRequest(info RequestInfo, msg Message, duration time.Duration, err error) {
+	level := logging.LevelDebug
+	if err != nil {
+		level = logging.LevelWarn
+	}
+	if !c.log.Enabled(level) {
+		return
+	}
+	fields := []logging.Field{logging.F("janus", info.Janus)}
+	if info.Request != "" {
+		fields = append(fields, logging.F("request", info.Request))
+	}
+	if info.Plugin != "" {
+		fields = append(fields, logging.F("plugin", info.Plugin))
+	}
+	if tid, ok := msg.String(attrTransaction); ok {
+		fields = append(fields, logging.F("transaction", tid))
+	}
+	if sid, ok := msg[attrSessionID]; ok {
+		fields = append(fields, logging.F("session", sid))
+	}
+	if hid, ok := msg[attrHandleID]; ok {
+		fields = append(fields, logging.F("handle", hid))
+	}
+	fields = append(fields, logging.F("duration", duration))
+	if err != nil {
+		fields = append(fields, logging.F("err", err))
+		c.log.Warn("request failed", fields...)
+		return
+	}
+	c.log.Debug("request done", fields...)
+}
+
+func (c *Connection) doRequest(ctx context.Context, request Message) (*Message, error) {
 	if c.IsDestroy() {
 		return nil, errors.New("conn is destroy")
 	}
-	var tid string
-	if _, ok := request[attrTransaction]; !ok {
+	tid, ok := request.Transaction()
+	if !ok {
 		tid = getTID()
 		request[attrTransaction] = tid
-	} else {
-		tid = request[attrTransaction].(string)
 	}
 	defer func() {
 		c.delTransaction(tid)
 	}()
-	result := make(chan *Message)
+	result := make(chan *Message, 1)
 
 	c.sendMessage(tid, request, func(rsp *Message) {
-		result <- rsp
+		select {
+		case result <- rsp:
+		default:
+		}
 	})
 	select {
 	case rsp := <-result:
 		return rsp, rsp.Error()
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-time.After(3 * time.Second):
 	}
-	return nil, errors.New("timeout")
+	return nil, ErrTimeout
 
 }
 
 //Message send message, has ack, event response
 func (c *Connection) Message(msg Message) (*Message, error) {
+	return c.message(context.Background(), msg, "")
+}
+
+//MessageContext send message, has ack, event response
+//ctx using for cancel and trace
+func (c *Connection) MessageContext(ctx context.Context, msg Message) (*Message, error) {
+	return c.message(ctx, msg, "")
+}
+
+func (c *Connection) message(ctx context.Context, msg Message, plugin string) (*Message, error) {
+	info := newRequestInfo(c.url, msg, plugin)
+	ctx, span := c.startSpan(ctx, info)
+	start := time.Now()
+	rsp, err := c.doMessage(ctx, msg)
+	c.metrics.RequestDone(info, time.Since(start), err)
+	c.logRequest(info, msg, time.Since(start), err)
+	endSpan(span, msg, err)
+	return rsp, err
+}
+
+func (c *Connection) doMessage(ctx context.Context, msg Message) (*Message, error) {
 	if c.IsDestroy() {
 		return nil, errors.New("conn is destroy")
 	}
 
-	var tid string
-	if _, ok := msg[attrTransaction]; !ok {
+	tid, ok := msg.Transaction()
+	if !ok {
 		tid = getTID()
 		msg[attrTransaction] = tid
-	} else {
-		tid = msg[attrTransaction].(string)
 	}
 
 	defer func() {
 		c.delTransaction(tid)
 	}()
-	result := make(chan *Message)
+	result := make(chan *Message, 2)
 
 	c.sendMessage(tid, msg, func(rsp *Message) {
-		result <- rsp
+		select {
+		case result <- rsp:
+		default:
+		}
 	})
 	select {
 	case rsp := <-result:
 		if rsp.IsError() {
-			return nil, rsp.Error()
+			return rsp, rsp.Error()
 		}
+		trace.SpanFromContext(ctx).AddEvent("ack")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-time.After(3 * time.Second):
-		return nil, errors.New("timeout")
+		return nil, ErrTimeout
 	}
 
 	select {
 	case rsp := <-result:
 		return rsp, rsp.Error()
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-time.After(3 * time.Second):
-		return nil, errors.New("timeout")
+		return nil, ErrTimeout
 	}
 }
 
+//Info send info request, response is server_info
+func (c *Connection) Info() (*Message, error) {
+	return c.InfoContext(context.Background())
+}
+
+//InfoContext send info request, response is server_info
+func (c *Connection) InfoContext(ctx context.Context) (*Message, error) {
+	return c.request(ctx, Message{attrType: "info"}, "")
+}
+
 //Create ceeate New Session
 func (c *Connection) Create() (*Session, error) {
 
@@ -2,10 +2,12 @@ package jwsapi
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 
 	"github.com/newzai/janus-go/logging"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //Handle janus gateway plugin handle
@@ -14,7 +16,15 @@ type Handle struct {
 	ID        uint64
 	isDestroy int32
 	s         *Session
+	plugin    string
 	Events    chan *Message
+	cancel    context.CancelFunc
+	log       *logging.Logger
+
+	mu      sync.Mutex
+	queue   []*Message //messages from session, dispatched by execLoop, at most handleQueueSize
+	dropped uint64     //messages dropped for queue is full
+	notify  chan struct{}
 
 	onWebrtcup func(Message)
 	onMedia    func(Message)
@@ -61,14 +71,23 @@ func WithHandleTrickle(callback func(Message)) HandleCallbackOption {
 	}
 }
 
-//NewHandle new handle.
+const (
+	handleEventsSize = 32
+	handleQueueWarn  = 128  //warn when queue of handle grow to 128, 256, 512...
+	handleQueueSize  = 1024 //messages are dropped when queue is full
+)
+
+//NewHandle new handle, Events is buffered, messages are queued until Events is read,
+//messages are dropped when queue is full, see Metrics.MessageDropped
 func NewHandle(ctx context.Context, id uint64, sess *Session) *Handle {
 
 	h := &Handle{
 		ctx:    ctx,
 		ID:     id,
 		s:      sess,
-		Events: make(chan *Message),
+		Events: make(chan *Message, handleEventsSize),
+		notify: make(chan struct{}, 1),
+		log:    sess.log.With(logging.F("handle", id)),
 	}
 
 	go h.execLoop()
@@ -84,6 +103,16 @@ func (h *Handle) IsDestroy() bool {
 	return false
 }
 
+//Plugin return plugin name
+func (h *Handle) Plugin() string {
+	return h.plugin
+}
+
+//Tracer return opentelemetry tracer of the connection
+func (h *Handle) Tracer() trace.Tracer {
+	return h.s.conn.tracer
+}
+
 //SetCallback set callback using WithHandleWebrtcup,WithHandleMedia...
 func (h *Handle) SetCallback(opts ...HandleCallbackOption) {
 
@@ -94,6 +123,11 @@ func (h *Handle) SetCallback(opts ...HandleCallbackOption) {
 
 //Request send request, has success response
 func (h *Handle) Request(body Message) (*Message, error) {
+	return h.RequestContext(context.Background(), body)
+}
+
+//RequestContext send request, has success response
+func (h *Handle) RequestContext(ctx context.Context, body Message) (*Message, error) {
 	if h.IsDestroy() {
 		return nil, errors.New("has detach")
 	}
@@ -104,11 +138,16 @@ func (h *Handle) Request(body Message) (*Message, error) {
 		attrBody:     body,
 	}
 
-	return h.s.Request(msg)
+	return h.s.request(ctx, msg, h.plugin)
 }
 
 //Message send message to janus, has ack ,event resposne
 func (h *Handle) Message(body Message) (*Message, error) {
+	return h.MessageContext(context.Background(), body)
+}
+
+//MessageContext send message to janus, has ack ,event resposne
+func (h *Handle) MessageContext(ctx context.Context, body Message) (*Message, error) {
 	if h.IsDestroy() {
 		return nil, errors.New("has detach")
 	}
@@ -117,11 +156,16 @@ func (h *Handle) Message(body Message) (*Message, error) {
 		attrHandleID: h.ID,
 		attrBody:     body,
 	}
-	return h.s.Message(msg)
+	return h.s.message(ctx, msg, h.plugin)
 }
 
 //JsepMessage jsep use to send Offer or Answer
 func (h *Handle) JsepMessage(body Message, jsep Message) (*Message, error) {
+	return h.JsepMessageContext(context.Background(), body, jsep)
+}
+
+//JsepMessageContext jsep use to send Offer or Answer
+func (h *Handle) JsepMessageContext(ctx context.Context, body Message, jsep Message) (*Message, error) {
 	if h.IsDestroy() {
 		return nil, errors.New("has detach")
 	}
@@ -132,19 +176,25 @@ func (h *Handle) JsepMessage(body Message, jsep Message) (*Message, error) {
 		attrBody:     body,
 		attrJSEP:     jsep,
 	}
-	return h.s.Message(msg)
+	return h.s.message(ctx, msg, h.plugin)
 
 }
 
 //Trickle send local candidae
 func (h *Handle) Trickle(candidate Message) error {
+	return h.TrickleContext(context.Background(), candidate)
+}
+
+//TrickleContext send local candidae
+func (h *Handle) TrickleContext(ctx context.Context, candidate Message) error {
 
 	msg := Message{
-		attrType:    "trickle",
-		"candidate": candidate,
+		attrType:     "trickle",
+		attrHandleID: h.ID,
+		"candidate":  candidate,
 	}
 
-	_, err := h.s.Request(msg)
+	_, err := h.s.request(ctx, msg, h.plugin)
 	return err
 }
 
@@ -154,20 +204,69 @@ func (h *Handle) Detach() error {
 		attrType:     "detach",
 		attrHandleID: h.ID,
 	}
-	_, err := h.s.Request(msg)
+	_, err := h.s.request(context.Background(), msg, h.plugin)
 
+	//stop dispatch of queued messages
+	if h.cancel != nil {
+		h.cancel()
+	}
 	h.s.delHandle(h.ID)
 
 	return err
 }
 
+//onMessage queue msg, called by execLoop of connection, never wait reader of Events
+//msg is dropped when queue is full, so a handle not read can't stall other handles
 func (h *Handle) onMessage(msg *Message) {
 	if h.IsDestroy() {
 		return
 	}
+	h.mu.Lock()
+	n := len(h.queue)
+	if n >= handleQueueSize {
+		h.dropped++
+		dropped := h.dropped
+		h.mu.Unlock()
+		if dropped&(dropped-1) == 0 {
+			h.log.Warn("queue is full, message dropped", logging.F("janus", msg.Type()), logging.F("dropped", dropped))
+		}
+		h.s.conn.metrics.MessageDropped(h.s.conn.url)
+		return
+	}
+	h.queue = append(h.queue, msg)
+	n++
+	h.mu.Unlock()
+	if n >= handleQueueWarn && n&(n-1) == 0 {
+		h.log.Warn("queue is growing, Events is not read", logging.F("size", n))
+	}
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+}
+
+//next pop queued message, nil if empty
+func (h *Handle) next() *Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.queue) == 0 {
+		return nil
+	}
+	msg := h.queue[0]
+	h.queue[0] = nil
+	h.queue = h.queue[1:]
+	return msg
+}
+
+//dispatch msg to Events or callbacks, called by execLoop of h, false if h is done
+func (h *Handle) dispatch(msg *Message) bool {
 	switch msg.Type() {
 	case "event":
-		h.Events <- msg
+		select {
+		case h.Events <- msg:
+		case <-h.ctx.Done():
+			return false
+		}
 	case "webrtcup":
 		if h.onWebrtcup != nil {
 			h.onWebrtcup(*msg)
@@ -176,6 +275,10 @@ func (h *Handle) onMessage(msg *Message) {
 		if h.onMedia != nil {
 			h.onMedia(*msg)
 		}
+	case "slowlink":
+		if h.onSlowLink != nil {
+			h.onSlowLink(*msg)
+		}
 	case "trickle":
 		if h.onTrickle != nil {
 			h.onTrickle(*msg)
@@ -185,12 +288,12 @@ func (h *Handle) onMessage(msg *Message) {
 			h.onHangup(*msg)
 		}
 	}
-
+	return true
 }
 
 func (h *Handle) execLoop() {
 	defer func() {
-		logging.Infof("Handle[%d.%d] End", h.s.ID, h.ID)
+		h.log.Info("Handle End", logging.F("plugin", h.plugin))
 		atomic.StoreInt32(&h.isDestroy, 1)
 		close(h.Events)
 
@@ -202,7 +305,12 @@ func (h *Handle) execLoop() {
 				h.onHangup(Message{attrType: "hangup", "reason": "ctx.Done"})
 			}
 			return
-
+		case <-h.notify:
+			for msg := h.next(); msg != nil; msg = h.next() {
+				if !h.dispatch(msg) {
+					break
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,852 @@
+package janustest
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//AudioBridgePlugin name of audiobridge plugin
+const AudioBridgePlugin = "janus.plugin.audiobridge"
+
+//audiobridge error code
+const (
+	AudioBridgeErrorInvalidRequest = 482
+	AudioBridgeErrorMissingElement = 483
+	AudioBridgeErrorInvalidElement = 484
+	AudioBridgeErrorNoSuchRoom     = 485
+	AudioBridgeErrorRoomExists     = 486
+	AudioBridgeErrorUnauthorized   = 487
+	AudioBridgeErrorNoSuchUser     = 488
+	AudioBridgeErrorNotJoined      = 490
+	AudioBridgeErrorAlreadyJoined  = 491
+	AudioBridgeErrorIDExists       = 492
+	AudioBridgeErrorInvalidSDP     = 493
+	AudioBridgeErrorUnknown        = 499
+)
+
+//synchronous requests of audiobridge, others are ack + event
+var audioBridgeSyncRequests = map[string]bool{
+	"create": true, "edit": true, "destroy": true, "exists": true, "list": true, "listparticipants": true,
+	"mute": true, "unmute": true, "kick": true, "play_file": true, "stop_file": true, "is_playing": true,
+	"rtp_forward": true, "stop_rtp_forward": true, "listforwarders": true,
+}
+
+const abValueKey = "audiobridge"
+
+//AudioBridge fake audiobridge plugin, rooms in memory, opus PeerConnection(pion, ice-lite)
+//audio is not mixed, rtp of unmuted participant is relayed to others with ssrc/pt rewrite
+//talking events are pushed by Talk, play_file only keep file id
+//plain rtp participant (rtp of join) listen udp at 127.0.0.1, rtp_forward send relayed rtp to host:port (srtp suite 80)
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.AudioBridgePlugin, janustest.NewAudioBridge())
+type AudioBridge struct {
+	setting webrtc.SettingEngine
+
+	mu     sync.Mutex
+	nextID uint64
+	rooms  map[uint64]*abRoom
+}
+
+type abRoom struct {
+	id           uint64
+	description  string
+	secret       string
+	pin          string
+	isPrivate    bool
+	samplingRate uint64
+	talkEvents   bool
+	participants map[uint64]*abParticipant
+	files        map[string]string //file_id -> filename
+	forwarders   map[uint64]*abForwarder
+	packets      int
+}
+
+type abParticipant struct {
+	handle  *Handle
+	room    *abRoom
+	id      uint64
+	display string
+	muted   bool
+	talking bool
+	pc      *webrtc.PeerConnection
+	track   *webrtc.Track
+	//plain rtp
+	conn *net.UDPConn
+	peer *net.UDPAddr //nil, not receive mix
+	ssrc uint32
+	pt   uint8
+}
+
+//abForwarder rtp_forward of room
+type abForwarder struct {
+	id       uint64
+	host     string
+	port     int
+	ssrc     uint32
+	pt       uint8
+	codec    string
+	alwaysOn bool
+	conn     *net.UDPConn
+
+	mu      sync.Mutex
+	encrypt *srtp.Context
+}
+
+func (f *abForwarder) info() jwsapi.Message {
+	return jwsapi.Message{
+		"stream_id": f.id,
+		"ip":        f.host,
+		"port":      f.port,
+		"ssrc":      f.ssrc,
+		"codec":     f.codec,
+		"ptype":     f.pt,
+		"srtp":      f.encrypt != nil,
+		"always_on": f.alwaysOn,
+	}
+}
+
+func (f *abForwarder) writeRTP(packet rtp.Packet) {
+	packet.SSRC = f.ssrc
+	packet.PayloadType = f.pt
+	data, err := packet.Marshal()
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.encrypt != nil {
+		if data, err = f.encrypt.EncryptRTP(nil, data, nil); err != nil {
+			return
+		}
+	}
+	f.conn.Write(data)
+}
+
+func (p *abParticipant) info() jwsapi.Message {
+	info := jwsapi.Message{
+		"id":    p.id,
+		"setup": p.pc != nil || p.conn != nil,
+		"muted": p.muted,
+	}
+	if p.display != "" {
+		info["display"] = p.display
+	}
+	if p.room.talkEvents {
+		info["talking"] = p.talking
+	}
+	return info
+}
+
+//notify push event to all participants of room except one
+func (r *abRoom) notify(except *abParticipant, data jwsapi.Message) {
+	for _, p := range r.participants {
+		if p != except {
+			p.handle.Event(data, nil)
+		}
+	}
+}
+
+//others info of participants except one
+func (r *abRoom) others(except *abParticipant) []interface{} {
+	ids := make([]uint64, 0, len(r.participants))
+	for id := range r.participants {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	list := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if p := r.participants[id]; p != except {
+			list = append(list, p.info())
+		}
+	}
+	return list
+}
+
+//AudioBridgeOption option for AudioBridge
+type AudioBridgeOption func(*AudioBridge)
+
+//WithAudioBridgeSettingEngine set pion setting engine, eg: port range, lite is always true
+func WithAudioBridgeSettingEngine(setting webrtc.SettingEngine) AudioBridgeOption {
+	return func(ab *AudioBridge) {
+		ab.setting = setting
+	}
+}
+
+//NewAudioBridge create fake audiobridge plugin
+func NewAudioBridge(opts ...AudioBridgeOption) *AudioBridge {
+	ab := &AudioBridge{
+		nextID: 1 << 20,
+		rooms:  make(map[uint64]*abRoom),
+	}
+	ab.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(ab)
+	}
+	ab.setting.SetLite(true)
+	return ab
+}
+
+//Rooms return ids of all rooms
+func (ab *AudioBridge) Rooms() []uint64 {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	ids := make([]uint64, 0, len(ab.rooms))
+	for id := range ab.rooms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Participants return ids of participants in room
+func (ab *AudioBridge) Participants(room uint64) []uint64 {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	r, ok := ab.rooms[room]
+	if !ok {
+		return nil
+	}
+	ids := make([]uint64, 0, len(r.participants))
+	for id := range r.participants {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Packets return count of rtp received from participants of room
+func (ab *AudioBridge) Packets(room uint64) int {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	if r, ok := ab.rooms[room]; ok {
+		return r.packets
+	}
+	return 0
+}
+
+//Talk push talking or stopped-talking of participant id to room, as janus-gateway detect audio level
+func (ab *AudioBridge) Talk(room uint64, id uint64, talking bool) error {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	r, ok := ab.rooms[room]
+	if !ok {
+		return errors.Errorf("no such room %d", room)
+	}
+	p, ok := r.participants[id]
+	if !ok {
+		return errors.Errorf("no such user %d", id)
+	}
+	p.talking = talking
+	event := "stopped-talking"
+	if talking {
+		event = "talking"
+	}
+	r.notify(nil, jwsapi.Message{"audiobridge": event, "room": room, "id": id})
+	return nil
+}
+
+//HandleMessage implements Plugin
+func (ab *AudioBridge) HandleMessage(req *Request) {
+	name := req.Name()
+	if audioBridgeSyncRequests[name] {
+		data, err := ab.handleSync(req, name)
+		if err != nil {
+			data = audioBridgeError(err)
+		}
+		req.Success(data)
+		return
+	}
+	req.Ack()
+	go func() {
+		data, jsep, err := ab.handleAsync(req, name)
+		if err != nil {
+			data = audioBridgeError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (ab *AudioBridge) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	ab.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if p, ok := h.Value(abValueKey).(*abParticipant); ok {
+		pc = p.pc
+	}
+	ab.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (ab *AudioBridge) HandleDetach(h *Handle) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	if p, ok := h.Value(abValueKey).(*abParticipant); ok {
+		ab.leave(p)
+	}
+	h.SetValue(abValueKey, nil)
+}
+
+func (ab *AudioBridge) handleSync(req *Request, name string) (jwsapi.Message, error) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	switch name {
+	case "create":
+		return ab.create(req)
+	case "list":
+		ids := make([]uint64, 0, len(ab.rooms))
+		for id := range ab.rooms {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		list := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			r := ab.rooms[id]
+			if r.isPrivate {
+				continue
+			}
+			list = append(list, jwsapi.Message{
+				"room":             r.id,
+				"description":      r.description,
+				"pin_required":     r.pin != "",
+				"sampling_rate":    r.samplingRate,
+				"num_participants": len(r.participants),
+				"record":           false,
+			})
+		}
+		return jwsapi.Message{"audiobridge": "success", "list": list}, nil
+	}
+
+	room, ok := req.Body.Uint64("room")
+	if !ok {
+		return nil, newVRError(AudioBridgeErrorMissingElement, "Missing element (room)")
+	}
+	r, ok := ab.rooms[room]
+	if name == "exists" {
+		return jwsapi.Message{"audiobridge": "success", "room": room, "exists": ok}, nil
+	}
+	if !ok {
+		return nil, newVRError(AudioBridgeErrorNoSuchRoom, "No such room (%d)", room)
+	}
+	switch name {
+	case "listparticipants":
+		return jwsapi.Message{"audiobridge": "participants", "room": room, "participants": r.others(nil)}, nil
+	case "is_playing":
+		fileID, _ := req.Body.String("file_id")
+		_, playing := r.files[fileID]
+		return jwsapi.Message{"audiobridge": "success", "room": room, "file_id": fileID, "playing": playing}, nil
+	}
+	if secret, _ := req.Body.String("secret"); r.secret != "" && secret != r.secret {
+		return nil, newVRError(AudioBridgeErrorUnauthorized, "Unauthorized (wrong secret)")
+	}
+
+	switch name {
+	case "rtp_forward":
+		return ab.rtpForward(r, req)
+	case "stop_rtp_forward":
+		id, _ := req.Body.Uint64("stream_id")
+		f, ok := r.forwarders[id]
+		if !ok {
+			return nil, newVRError(AudioBridgeErrorNoSuchUser, "No such stream (%d)", id)
+		}
+		f.conn.Close()
+		delete(r.forwarders, id)
+		return jwsapi.Message{"audiobridge": "success", "room": room, "stream_id": id}, nil
+	case "listforwarders":
+		ids := make([]uint64, 0, len(r.forwarders))
+		for id := range r.forwarders {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		list := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			list = append(list, r.forwarders[id].info())
+		}
+		return jwsapi.Message{"audiobridge": "forwarders", "room": room, "rtp_forwarders": list}, nil
+	case "edit":
+		if description, ok := req.Body.String("new_description"); ok {
+			r.description = description
+		}
+		if secret, ok := req.Body.String("new_secret"); ok {
+			r.secret = secret
+		}
+		if pin, ok := req.Body.String("new_pin"); ok {
+			r.pin = pin
+		}
+		if _, ok := req.Body["new_is_private"]; ok {
+			r.isPrivate = req.Body.Bool("new_is_private")
+		}
+		return jwsapi.Message{"audiobridge": "edited", "room": room}, nil
+	case "destroy":
+		r.notify(nil, jwsapi.Message{"audiobridge": "destroyed", "room": room})
+		for _, p := range r.participants {
+			ab.hangup(p)
+			p.handle.SetValue(abValueKey, nil)
+		}
+		for _, f := range r.forwarders {
+			f.conn.Close()
+		}
+		delete(ab.rooms, room)
+		return jwsapi.Message{"audiobridge": "destroyed", "room": room}, nil
+	case "mute", "unmute", "kick":
+		id, ok := req.Body.Uint64("id")
+		if !ok {
+			return nil, newVRError(AudioBridgeErrorMissingElement, "Missing element (id)")
+		}
+		p, ok := r.participants[id]
+		if !ok {
+			return nil, newVRError(AudioBridgeErrorNoSuchUser, "No such user %d in room %d", id, room)
+		}
+		if name == "kick" {
+			r.notify(nil, jwsapi.Message{"audiobridge": "event", "room": room, "kicked": id})
+			delete(r.participants, id)
+			ab.hangup(p)
+			p.handle.SetValue(abValueKey, nil)
+			p.handle.Hangup("Kicked")
+		} else {
+			p.muted = name == "mute"
+			r.notify(nil, jwsapi.Message{"audiobridge": "event", "room": room, "participants": []interface{}{p.info()}})
+		}
+		return jwsapi.Message{"audiobridge": "success", "room": room}, nil
+	case "play_file":
+		filename, _ := req.Body.String("filename")
+		if filename == "" {
+			return nil, newVRError(AudioBridgeErrorMissingElement, "Missing element (filename)")
+		}
+		fileID, _ := req.Body.String("file_id")
+		if fileID == "" {
+			fileID = fmt.Sprintf("%d", rand.Uint32())
+		}
+		if _, ok := r.files[fileID]; ok {
+			return nil, newVRError(AudioBridgeErrorInvalidElement, "File ID exists")
+		}
+		r.files[fileID] = filename
+		r.notify(nil, jwsapi.Message{"audiobridge": "announcement-started", "room": room, "file_id": fileID})
+		return jwsapi.Message{"audiobridge": "success", "room": room, "file_id": fileID}, nil
+	case "stop_file":
+		fileID, _ := req.Body.String("file_id")
+		if _, ok := r.files[fileID]; !ok {
+			return nil, newVRError(AudioBridgeErrorInvalidElement, "No such file")
+		}
+		delete(r.files, fileID)
+		r.notify(nil, jwsapi.Message{"audiobridge": "announcement-stopped", "room": room, "file_id": fileID})
+		return jwsapi.Message{"audiobridge": "success", "room": room, "file_id": fileID}, nil
+	}
+	return nil, newVRError(AudioBridgeErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+//create lock by caller
+func (ab *AudioBridge) create(req *Request) (jwsapi.Message, error) {
+	room, ok := req.Body.Uint64("room")
+	if !ok {
+		ab.nextID++
+		room = ab.nextID
+	}
+	if _, exists := ab.rooms[room]; exists {
+		return nil, newVRError(AudioBridgeErrorRoomExists, "Room %d already exists", room)
+	}
+	r := &abRoom{
+		id:           room,
+		isPrivate:    req.Body.Bool("is_private"),
+		samplingRate: 16000,
+		talkEvents:   req.Body.Bool("audiolevel_event"),
+		participants: make(map[uint64]*abParticipant),
+		files:        make(map[string]string),
+		forwarders:   make(map[uint64]*abForwarder),
+	}
+	r.description, _ = req.Body.String("description")
+	if r.description == "" {
+		r.description = fmt.Sprintf("Room %d", room)
+	}
+	r.secret, _ = req.Body.String("secret")
+	r.pin, _ = req.Body.String("pin")
+	if rate, ok := req.Body.Uint64("sampling_rate"); ok {
+		r.samplingRate = rate
+	}
+	ab.rooms[room] = r
+	return jwsapi.Message{"audiobridge": "created", "room": room, "permanent": false}, nil
+}
+
+func (ab *AudioBridge) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	switch name {
+	case "join":
+		return ab.join(req)
+	case "configure":
+		return ab.configure(req)
+	case "changeroom":
+		return ab.changeRoom(req)
+	case "leave":
+		ab.mu.Lock()
+		defer ab.mu.Unlock()
+		p, ok := req.Handle.Value(abValueKey).(*abParticipant)
+		if !ok {
+			return nil, nil, newVRError(AudioBridgeErrorNotJoined, "Can't leave (not in a room)")
+		}
+		ab.leave(p)
+		req.Handle.SetValue(abValueKey, nil)
+		return jwsapi.Message{"audiobridge": "left", "room": p.room.id, "id": p.id}, nil, nil
+	}
+	return nil, nil, newVRError(AudioBridgeErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+//enter add participant to room, lock by caller
+func (ab *AudioBridge) enter(req *Request, p *abParticipant) error {
+	room, ok := req.Body.Uint64("room")
+	if !ok {
+		return newVRError(AudioBridgeErrorMissingElement, "Missing element (room)")
+	}
+	r, ok := ab.rooms[room]
+	if !ok {
+		return newVRError(AudioBridgeErrorNoSuchRoom, "No such room (%d)", room)
+	}
+	if pin, _ := req.Body.String("pin"); r.pin != "" && pin != r.pin {
+		return newVRError(AudioBridgeErrorUnauthorized, "Unauthorized (wrong pin)")
+	}
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		ab.nextID++
+		id = ab.nextID
+	}
+	if _, exists := r.participants[id]; exists {
+		return newVRError(AudioBridgeErrorIDExists, "User ID %d already exists", id)
+	}
+	if display, ok := req.Body.String("display"); ok {
+		p.display = display
+	}
+	if _, ok := req.Body["muted"]; ok {
+		p.muted = req.Body.Bool("muted")
+	}
+	p.id = id
+	p.room = r
+	p.talking = false
+	r.participants[id] = p
+	r.notify(p, jwsapi.Message{"audiobridge": "event", "room": room, "participants": []interface{}{p.info()}})
+	return nil
+}
+
+//leave remove participant from room and notify others, close PeerConnection, lock by caller
+func (ab *AudioBridge) leave(p *abParticipant) {
+	if p.room != nil {
+		delete(p.room.participants, p.id)
+		p.room.notify(nil, jwsapi.Message{"audiobridge": "event", "room": p.room.id, "leaving": p.id})
+	}
+	ab.hangup(p)
+}
+
+//hangup close PeerConnection or udp of participant, lock by caller
+func (ab *AudioBridge) hangup(p *abParticipant) {
+	if p.pc != nil {
+		go p.pc.Close()
+		p.pc = nil
+		p.track = nil
+	}
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.peer = nil
+	}
+}
+
+//rtpForward add forwarder to room, lock by caller
+func (ab *AudioBridge) rtpForward(r *abRoom, req *Request) (jwsapi.Message, error) {
+	host, _ := req.Body.String("host")
+	port, _ := req.Body.Uint64("port")
+	if host == "" || port == 0 {
+		return nil, newVRError(AudioBridgeErrorMissingElement, "Missing element (host or port)")
+	}
+	f := &abForwarder{
+		host:     host,
+		port:     int(port),
+		ssrc:     rand.Uint32(),
+		pt:       100,
+		codec:    "opus",
+		alwaysOn: req.Body.Bool("always_on"),
+	}
+	if ssrc, ok := req.Body.Uint32("ssrc"); ok {
+		f.ssrc = ssrc
+	}
+	if pt, ok := req.Body.Uint64("ptype"); ok {
+		f.pt = uint8(pt)
+	}
+	if codec, ok := req.Body.String("codec"); ok {
+		f.codec = codec
+	}
+	if _, ok := req.Body["srtp_suite"]; ok {
+		key, err := srtpMasterKey(AudioBridgeErrorInvalidElement, req.Body, "srtp_suite", "srtp_crypto")
+		if err != nil {
+			return nil, err
+		}
+		if f.encrypt, err = srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+			return nil, newVRError(AudioBridgeErrorInvalidElement, "Invalid element (srtp_crypto)")
+		}
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, fmt.Sprintf("%d", port)))
+	if err == nil {
+		f.conn, err = net.DialUDP("udp", nil, addr)
+	}
+	if err != nil {
+		return nil, newVRError(AudioBridgeErrorUnknown, "Error creating forwarder: %v", err)
+	}
+	ab.nextID++
+	f.id = ab.nextID
+	r.forwarders[f.id] = f
+	return jwsapi.Message{"audiobridge": "success", "room": r.id, "stream_id": f.id, "host": host, "port": port}, nil
+}
+
+//plainRTP bind udp for rtp participant, lock by caller
+func (ab *AudioBridge) plainRTP(p *abParticipant, config jwsapi.Message) (jwsapi.Message, error) {
+	p.pt = 100
+	if pt, ok := config.Uint64("payload_type"); ok {
+		p.pt = uint8(pt)
+	}
+	if ip, ok := config.String("ip"); ok && ip != "" {
+		port, _ := config.Uint64("port")
+		addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(ip, fmt.Sprintf("%d", port)))
+		if err != nil {
+			return nil, newVRError(AudioBridgeErrorInvalidElement, "Invalid element (rtp.ip)")
+		}
+		p.peer = addr
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, newVRError(AudioBridgeErrorUnknown, "Error binding rtp: %v", err)
+	}
+	p.conn = conn
+	p.ssrc = rand.Uint32()
+	go ab.relay(p, func() (*rtp.Packet, error) {
+		buf := make([]byte, 1500)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			packet := &rtp.Packet{}
+			if err := packet.Unmarshal(buf[:n]); err == nil {
+				return packet, nil
+			}
+		}
+	})
+	return jwsapi.Message{"ip": "127.0.0.1", "port": conn.LocalAddr().(*net.UDPAddr).Port, "payload_type": p.pt}, nil
+}
+
+func (ab *AudioBridge) join(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	ab.mu.Lock()
+	if _, ok := req.Handle.Value(abValueKey).(*abParticipant); ok {
+		ab.mu.Unlock()
+		return nil, nil, newVRError(AudioBridgeErrorAlreadyJoined, "Already in a room (use changeroom to join another one)")
+	}
+	p := &abParticipant{handle: req.Handle}
+	var info jwsapi.Message
+	if config, ok := req.Body.SubMessage("rtp"); ok {
+		var err error
+		if info, err = ab.plainRTP(p, config); err != nil {
+			ab.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+	if err := ab.enter(req, p); err != nil {
+		ab.hangup(p)
+		ab.mu.Unlock()
+		return nil, nil, err
+	}
+	data := jwsapi.Message{"audiobridge": "joined", "room": p.room.id, "id": p.id, "participants": p.room.others(p)}
+	if info != nil {
+		data["rtp"] = info
+	}
+	req.Handle.SetValue(abValueKey, p)
+	ab.mu.Unlock()
+
+	jsep, err := ab.negotiate(p, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, jsep, nil
+}
+
+func (ab *AudioBridge) configure(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	ab.mu.Lock()
+	p, ok := req.Handle.Value(abValueKey).(*abParticipant)
+	if !ok {
+		ab.mu.Unlock()
+		return nil, nil, newVRError(AudioBridgeErrorNotJoined, "Can't configure (not in a room)")
+	}
+	changed := false
+	if _, ok := req.Body["muted"]; ok {
+		p.muted = req.Body.Bool("muted")
+		changed = true
+	}
+	if display, ok := req.Body.String("display"); ok {
+		p.display = display
+		changed = true
+	}
+	if changed {
+		p.room.notify(p, jwsapi.Message{"audiobridge": "event", "room": p.room.id, "participants": []interface{}{p.info()}})
+	}
+	room := p.room.id
+	ab.mu.Unlock()
+
+	jsep, err := ab.negotiate(p, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jwsapi.Message{"audiobridge": "event", "room": room, "result": "ok"}, jsep, nil
+}
+
+func (ab *AudioBridge) changeRoom(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	p, ok := req.Handle.Value(abValueKey).(*abParticipant)
+	if !ok {
+		return nil, nil, newVRError(AudioBridgeErrorNotJoined, "Can't change room (not in a room)")
+	}
+	room, _ := req.Body.Uint64("room")
+	if _, ok := ab.rooms[room]; !ok {
+		return nil, nil, newVRError(AudioBridgeErrorNoSuchRoom, "No such room (%d)", room)
+	}
+	old := p.room
+	delete(old.participants, p.id)
+	old.notify(nil, jwsapi.Message{"audiobridge": "event", "room": old.id, "leaving": p.id})
+	if err := ab.enter(req, p); err != nil {
+		//back to old room, as janus-gateway is still in old room on error
+		p.room = old
+		old.participants[p.id] = p
+		return nil, nil, err
+	}
+	return jwsapi.Message{"audiobridge": "roomchanged", "room": p.room.id, "id": p.id, "participants": p.room.others(p)}, nil, nil
+}
+
+//negotiate answer opus offer of participant, nil if no jsep
+func (ab *AudioBridge) negotiate(p *abParticipant, req *Request) (jwsapi.Message, error) {
+	if req.JSEP == nil {
+		return nil, nil
+	}
+	if jtype, _ := req.JSEP.String("type"); jtype != "offer" {
+		return nil, newVRError(AudioBridgeErrorInvalidSDP, "Unsupported SDP type '%s'", jtype)
+	}
+	offer, _ := req.JSEP.String("sdp")
+	offered, err := sdpCodecs(offer)
+	if err != nil {
+		return nil, newVRError(AudioBridgeErrorInvalidSDP, "Error parsing offer: %v", err)
+	}
+	codec, ok := offered["opus"]
+	if !ok {
+		return nil, newVRError(AudioBridgeErrorInvalidSDP, "Opus not in offer")
+	}
+	pc, err := newPeerConnection(ab.setting, map[webrtc.RTPCodecType]vrCodec{webrtc.RTPCodecTypeAudio: codec})
+	if err != nil {
+		return nil, newVRError(AudioBridgeErrorUnknown, "NewPeerConnection: %v", err)
+	}
+	track, err := pc.NewTrack(codec.pt, rand.Uint32(), "audio", "janusaudio")
+	if err == nil {
+		_, err = pc.AddTrack(track)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, newVRError(AudioBridgeErrorUnknown, "AddTrack: %v", err)
+	}
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		go drainRTCP(receiver, nil)
+		ab.relay(p, remote.ReadRTP)
+	})
+	watchPeerConnection(req.Handle, pc)
+
+	answer, err := negotiateAnswer(pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, newVRError(AudioBridgeErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+	ab.mu.Lock()
+	if p.pc != nil {
+		go p.pc.Close()
+	}
+	p.pc = pc
+	p.track = track
+	ab.mu.Unlock()
+	return jwsapi.Message{"type": "answer", "sdp": answer}, nil
+}
+
+//relay write rtp of unmuted participant to others of the same room and forwarders
+func (ab *AudioBridge) relay(p *abParticipant, read func() (*rtp.Packet, error)) {
+	var targets []func(rtp.Packet)
+	for {
+		packet, err := read()
+		if err != nil {
+			return
+		}
+		ab.mu.Lock()
+		targets = targets[:0]
+		if p.room != nil {
+			p.room.packets++
+			if !p.muted {
+				for _, other := range p.room.participants {
+					if other != p {
+						if t := other.writer(); t != nil {
+							targets = append(targets, t)
+						}
+					}
+				}
+				for _, f := range p.room.forwarders {
+					targets = append(targets, f.writeRTP)
+				}
+			}
+		}
+		ab.mu.Unlock()
+
+		for _, t := range targets {
+			t(*packet)
+		}
+	}
+}
+
+//writer write rtp to participant with ssrc/pt rewrite, nil if no media, lock by caller
+func (p *abParticipant) writer() func(rtp.Packet) {
+	if track := p.track; track != nil {
+		return func(packet rtp.Packet) {
+			packet.SSRC = track.SSRC()
+			packet.PayloadType = track.PayloadType()
+			track.WriteRTP(&packet)
+		}
+	}
+	if conn, peer, ssrc, pt := p.conn, p.peer, p.ssrc, p.pt; conn != nil && peer != nil {
+		return func(packet rtp.Packet) {
+			packet.SSRC = ssrc
+			packet.PayloadType = pt
+			if data, err := packet.Marshal(); err == nil {
+				conn.WriteToUDP(data, peer)
+			}
+		}
+	}
+	return nil
+}
+
+func audioBridgeError(err error) jwsapi.Message {
+	code := AudioBridgeErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"audiobridge": "event",
+		"error_code":  code,
+		"error":       err.Error(),
+	}
+}
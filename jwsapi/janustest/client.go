@@ -0,0 +1,38 @@
+package janustest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/webrtc/v2"
+)
+
+//Attach connect to s, create session and attach handle of plugin, t fails on error
+//connection is closed when ctx is done
+func Attach(t testing.TB, ctx context.Context, s *Server, plugin string, opts ...jwsapi.ConnectionOption) *jwsapi.Handle {
+	t.Helper()
+	conn := jwsapi.NewConnection(ctx, s.URL, 1, opts...)
+	for i := 0; i < 100 && !conn.Connected(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	h, err := sess.Attach(plugin)
+	if err != nil {
+		t.Fatalf("attach %s: %v", plugin, err)
+	}
+	return h
+}
+
+//NewAPI pion api of clients for fake plugins, default codecs, udp4 only
+func NewAPI() *webrtc.API {
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	setting := webrtc.SettingEngine{}
+	setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	return webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting))
+}
@@ -0,0 +1,238 @@
+package janustest
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/webrtc/v2"
+)
+
+//EchoTestPlugin name of echotest plugin
+const EchoTestPlugin = "janus.plugin.echotest"
+
+//echotest error code
+const (
+	EchoTestErrorUnknown        = 411
+	EchoTestErrorInvalidElement = 413
+	EchoTestErrorInvalidSDP     = 414
+)
+
+const etValueKey = "echotest"
+
+//EchoTest fake echotest plugin, negotiate with pion PeerConnection(ice-lite)
+//and send rtp back to the peer, audio or video can be disabled by configure
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.EchoTestPlugin, janustest.NewEchoTest())
+type EchoTest struct {
+	setting webrtc.SettingEngine
+
+	mu      sync.Mutex
+	packets int
+}
+
+type etSession struct {
+	mu     sync.Mutex
+	pc     *webrtc.PeerConnection
+	tracks map[webrtc.RTPCodecType]*webrtc.Track
+	enable map[webrtc.RTPCodecType]bool
+}
+
+//EchoTestOption option for EchoTest
+type EchoTestOption func(*EchoTest)
+
+//WithEchoTestSettingEngine set pion setting engine, eg: port range, lite is always true
+func WithEchoTestSettingEngine(setting webrtc.SettingEngine) EchoTestOption {
+	return func(et *EchoTest) {
+		et.setting = setting
+	}
+}
+
+//NewEchoTest create fake echotest plugin
+func NewEchoTest(opts ...EchoTestOption) *EchoTest {
+	et := &EchoTest{}
+	et.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(et)
+	}
+	et.setting.SetLite(true)
+	return et
+}
+
+//Packets number of rtp packets echoed
+func (et *EchoTest) Packets() int {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	return et.packets
+}
+
+//HandleMessage implements Plugin
+func (et *EchoTest) HandleMessage(req *Request) {
+	req.Ack()
+	go func() {
+		jsep, err := et.handle(req)
+		if err != nil {
+			req.Event(echoTestError(err), nil)
+			return
+		}
+		req.Event(jwsapi.Message{"echotest": "event", "result": "ok"}, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (et *EchoTest) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	s, _ := h.Value(etValueKey).(*etSession)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	pc := s.pc
+	s.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (et *EchoTest) HandleDetach(h *Handle) {
+	if s, _ := h.Value(etValueKey).(*etSession); s != nil {
+		s.mu.Lock()
+		if s.pc != nil {
+			go s.pc.Close()
+			s.pc = nil
+		}
+		s.mu.Unlock()
+	}
+	h.SetValue(etValueKey, nil)
+}
+
+func (et *EchoTest) handle(req *Request) (jwsapi.Message, error) {
+	s, _ := req.Handle.Value(etValueKey).(*etSession)
+	if s == nil {
+		s = &etSession{
+			tracks: make(map[webrtc.RTPCodecType]*webrtc.Track),
+			enable: map[webrtc.RTPCodecType]bool{
+				webrtc.RTPCodecTypeAudio: true,
+				webrtc.RTPCodecTypeVideo: true,
+			},
+		}
+		req.Handle.SetValue(etValueKey, s)
+	}
+	s.mu.Lock()
+	for key, kind := range map[string]webrtc.RTPCodecType{"audio": webrtc.RTPCodecTypeAudio, "video": webrtc.RTPCodecTypeVideo} {
+		if _, ok := req.Body[key]; !ok {
+			continue
+		}
+		enable, ok := req.Body[key].(bool)
+		if !ok {
+			s.mu.Unlock()
+			return nil, newVRError(EchoTestErrorInvalidElement, "Invalid element (%s should be a boolean)", key)
+		}
+		s.enable[kind] = enable
+	}
+	s.mu.Unlock()
+
+	if req.JSEP == nil {
+		return nil, nil
+	}
+	if jtype, _ := req.JSEP.String("type"); jtype != "offer" {
+		return nil, newVRError(EchoTestErrorInvalidSDP, "Unsupported SDP type '%s'", jtype)
+	}
+	offer, _ := req.JSEP.String("sdp")
+	offered, err := sdpCodecs(offer)
+	if err != nil {
+		return nil, newVRError(EchoTestErrorInvalidSDP, "Error parsing offer: %v", err)
+	}
+	//first codec of each kind in offer
+	codecs := make(map[webrtc.RTPCodecType]vrCodec)
+	for _, name := range []string{"opus", "pcmu", "pcma", "g722", "vp8", "vp9", "h264"} {
+		if codec, ok := offered[name]; ok {
+			if _, ok := codecs[codec.kind]; !ok {
+				codecs[codec.kind] = codec
+			}
+		}
+	}
+	if len(codecs) == 0 {
+		return nil, newVRError(EchoTestErrorInvalidSDP, "No supported codec in offer")
+	}
+
+	pc, err := newPeerConnection(et.setting, codecs)
+	if err != nil {
+		return nil, newVRError(EchoTestErrorUnknown, "NewPeerConnection: %v", err)
+	}
+	tracks := make(map[webrtc.RTPCodecType]*webrtc.Track)
+	for kind, codec := range codecs {
+		track, err := pc.NewTrack(codec.pt, rand.Uint32(), kind.String(), "janus"+kind.String())
+		if err == nil {
+			_, err = pc.AddTrack(track)
+		}
+		if err != nil {
+			pc.Close()
+			return nil, newVRError(EchoTestErrorUnknown, "AddTrack: %v", err)
+		}
+		tracks[kind] = track
+	}
+	pc.OnTrack(func(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		go drainRTCP(receiver, nil)
+		et.echo(s, track)
+	})
+	watchPeerConnection(req.Handle, pc)
+
+	answer, err := negotiateAnswer(pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, newVRError(EchoTestErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+	s.mu.Lock()
+	if s.pc != nil {
+		go s.pc.Close()
+	}
+	s.pc = pc
+	s.tracks = tracks
+	s.mu.Unlock()
+	return jwsapi.Message{"type": "answer", "sdp": answer}, nil
+}
+
+//echo send rtp of track back with ssrc, payload type of local track
+func (et *EchoTest) echo(s *etSession, track *webrtc.Track) {
+	kind := track.Kind()
+	for {
+		packet, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		send, enable := s.tracks[kind], s.enable[kind]
+		s.mu.Unlock()
+		if send == nil || !enable {
+			continue
+		}
+		packet.SSRC = send.SSRC()
+		packet.PayloadType = send.PayloadType()
+		if send.WriteRTP(packet) == nil {
+			et.mu.Lock()
+			et.packets++
+			et.mu.Unlock()
+		}
+	}
+}
+
+func echoTestError(err error) jwsapi.Message {
+	code := EchoTestErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"echotest":   "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
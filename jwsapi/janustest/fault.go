@@ -0,0 +1,123 @@
+package janustest
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+)
+
+//FaultAction action of fault
+type FaultAction int
+
+const (
+	//FaultDrop drop the request, no response
+	FaultDrop FaultAction = iota
+	//FaultDelay handle the request after Fault.Delay
+	FaultDelay
+	//FaultDisconnect close ws connection instead of response
+	FaultDisconnect
+	//FaultMalformed response Fault.Payload or a broken json
+	FaultMalformed
+)
+
+//Matcher match received request
+type Matcher func(msg jwsapi.Message) bool
+
+//MatchAny match all request
+func MatchAny() Matcher {
+	return func(jwsapi.Message) bool { return true }
+}
+
+//MatchJanus match request by janus, eg: keepalive, attach, message
+func MatchJanus(janus string) Matcher {
+	return func(msg jwsapi.Message) bool {
+		value, _ := msg.String("janus")
+		return value == janus
+	}
+}
+
+//MatchRequest match plugin message by body.request, eg: join, configure
+func MatchRequest(request string) Matcher {
+	return func(msg jwsapi.Message) bool {
+		body, ok := msg.SubMessage("body")
+		if !ok {
+			return false
+		}
+		value, _ := body.String("request")
+		return value == request
+	}
+}
+
+//Fault fault injected to received request
+type Fault struct {
+	//Match nil match all
+	Match  Matcher
+	Action FaultAction
+	//Delay for FaultDelay
+	Delay time.Duration
+	//Payload for FaultMalformed, default is a truncated json
+	Payload []byte
+	//Count fault times, 0 is always
+	Count int
+
+	hits int64
+}
+
+//Hits times of fault taken
+func (f *Fault) Hits() int {
+	return int(atomic.LoadInt64(&f.hits))
+}
+
+func (f *Fault) take(msg jwsapi.Message) bool {
+	if f.Count > 0 && atomic.LoadInt64(&f.hits) >= int64(f.Count) {
+		return false
+	}
+	if f.Match != nil && !f.Match(msg) {
+		return false
+	}
+	atomic.AddInt64(&f.hits, 1)
+	return true
+}
+
+//AddFault add fault, faults are checked in order, first match is taken
+func (s *Server) AddFault(f *Fault) {
+	s.mu.Lock()
+	s.faults = append(s.faults, f)
+	s.mu.Unlock()
+}
+
+//ClearFaults remove all faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	s.faults = nil
+	s.mu.Unlock()
+}
+
+//DropNext drop next n requests matched
+func (s *Server) DropNext(match Matcher, n int) *Fault {
+	f := &Fault{Match: match, Action: FaultDrop, Count: n}
+	s.AddFault(f)
+	return f
+}
+
+//DelayNext delay next n requests matched
+func (s *Server) DelayNext(match Matcher, n int, delay time.Duration) *Fault {
+	f := &Fault{Match: match, Action: FaultDelay, Count: n, Delay: delay}
+	s.AddFault(f)
+	return f
+}
+
+//DisconnectOn close connection when next n requests matched
+func (s *Server) DisconnectOn(match Matcher, n int) *Fault {
+	f := &Fault{Match: match, Action: FaultDisconnect, Count: n}
+	s.AddFault(f)
+	return f
+}
+
+//MalformedNext response malformed json for next n requests matched
+func (s *Server) MalformedNext(match Matcher, n int) *Fault {
+	f := &Fault{Match: match, Action: FaultMalformed, Count: n}
+	s.AddFault(f)
+	return f
+}
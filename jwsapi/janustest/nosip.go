@@ -0,0 +1,542 @@
+package janustest
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//NoSIPPlugin name of nosip plugin
+const NoSIPPlugin = "janus.plugin.nosip"
+
+//nosip error code
+const (
+	NoSIPErrorInvalidRequest = 442
+	NoSIPErrorMissingElement = 443
+	NoSIPErrorInvalidElement = 444
+	NoSIPErrorWrongState     = 445
+	NoSIPErrorMissingSDP     = 446
+	NoSIPErrorInvalidSDP     = 447
+	NoSIPErrorIOError        = 448
+	NoSIPErrorUnknown        = 499
+)
+
+const noSIPValueKey = "nosip"
+
+//NoSIP fake nosip plugin, translate webrtc to plain rtp at 127.0.0.1
+//webrtc peer is pion PeerConnection(ice-lite), rtp and PLI are relayed between webrtc peer and rtp peer
+//srtp is not supported
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.NoSIPPlugin, janustest.NewNoSIP())
+type NoSIP struct {
+	setting webrtc.SettingEngine
+
+	mu sync.Mutex
+}
+
+//nsSession nosip session of handle
+type nsSession struct {
+	handle    *Handle
+	pc        *webrtc.PeerConnection
+	media     map[webrtc.RTPCodecType]*nsMedia
+	answer    string //answer to webrtc peer, sent when barebone answer is processed
+	offerer   bool   //webrtc peer is offerer
+	recording bool
+	packets   int //rtp relayed
+}
+
+//nsMedia udp sockets of one media
+type nsMedia struct {
+	codec      vrCodec
+	rtpConn    *net.UDPConn
+	rtcpConn   *net.UDPConn
+	remote     *net.UDPAddr //rtp of rtp peer
+	remoteRTCP *net.UDPAddr //rtcp of rtp peer
+	track      *webrtc.Track
+	ssrc       uint32 //ssrc of webrtc peer
+}
+
+//NoSIPOption option for NoSIP
+type NoSIPOption func(*NoSIP)
+
+//WithNoSIPSettingEngine set pion setting engine, ice-lite is always enabled
+func WithNoSIPSettingEngine(setting webrtc.SettingEngine) NoSIPOption {
+	return func(ns *NoSIP) {
+		ns.setting = setting
+	}
+}
+
+//NewNoSIP create fake nosip plugin
+func NewNoSIP(opts ...NoSIPOption) *NoSIP {
+	ns := &NoSIP{}
+	ns.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(ns)
+	}
+	ns.setting.SetLite(true)
+	return ns
+}
+
+//Packets count of rtp relayed of handle, both directions
+func (ns *NoSIP) Packets(h *Handle) int {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if sess, ok := h.Value(noSIPValueKey).(*nsSession); ok {
+		return sess.packets
+	}
+	return 0
+}
+
+//Recording recording of handle is started
+func (ns *NoSIP) Recording(h *Handle) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if sess, ok := h.Value(noSIPValueKey).(*nsSession); ok {
+		return sess.recording
+	}
+	return false
+}
+
+//HandleMessage implements Plugin
+func (ns *NoSIP) HandleMessage(req *Request) {
+	req.Ack()
+	go func() {
+		data, jsep, err := ns.handleAsync(req, req.Name())
+		if err != nil {
+			data = noSIPError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (ns *NoSIP) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	ns.mu.Lock()
+	sess, ok := h.Value(noSIPValueKey).(*nsSession)
+	ns.mu.Unlock()
+	if ok && sess.pc.RemoteDescription() != nil {
+		sess.pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (ns *NoSIP) HandleDetach(h *Handle) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if sess, ok := h.Value(noSIPValueKey).(*nsSession); ok {
+		sess.close()
+	}
+	h.SetValue(noSIPValueKey, nil)
+}
+
+func (ns *NoSIP) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	if _, ok := req.Body.String("srtp"); ok {
+		return nil, nil, newVRError(NoSIPErrorInvalidElement, "Invalid element (srtp not supported)")
+	}
+	switch name {
+	case "generate":
+		return ns.generate(req)
+	case "process":
+		return ns.process(req)
+	case "hangup":
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		if sess, ok := req.Handle.Value(noSIPValueKey).(*nsSession); ok {
+			sess.close()
+			req.Handle.SetValue(noSIPValueKey, nil)
+		}
+		return noSIPEvent(jwsapi.Message{"event": "hangingup"}), nil, nil
+	case "recording":
+		return ns.recording(req)
+	}
+	return nil, nil, newVRError(NoSIPErrorInvalidRequest, "Unknown request (%s)", name)
+}
+
+//generate webrtc offer: barebone offer, webrtc answer is pending until process
+//webrtc answer of process offer: barebone answer
+func (ns *NoSIP) generate(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	if req.JSEP == nil {
+		return nil, nil, newVRError(NoSIPErrorMissingSDP, "Missing SDP")
+	}
+	jsepType, _ := req.JSEP.String("type")
+	jsep, _ := req.JSEP.String("sdp")
+	ns.mu.Lock()
+	sess, ok := req.Handle.Value(noSIPValueKey).(*nsSession)
+	ns.mu.Unlock()
+
+	switch jsepType {
+	case "offer":
+		if ok {
+			return nil, nil, newVRError(NoSIPErrorWrongState, "Session already negotiated")
+		}
+		codecs := make(map[webrtc.RTPCodecType]vrCodec)
+		for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+			if codec, ok := firstCodec(jsep, kind); ok {
+				codecs[kind] = codec
+			}
+		}
+		if len(codecs) == 0 {
+			return nil, nil, newVRError(NoSIPErrorInvalidSDP, "No audio or video codec in offer")
+		}
+		sess, err := ns.newSession(req.Handle, codecs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sess.answer, err = negotiateAnswer(sess.pc, jsep); err != nil {
+			sess.close()
+			return nil, nil, newVRError(NoSIPErrorInvalidSDP, "Error negotiating: %v", err)
+		}
+		sess.offerer = true
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		if _, ok := req.Handle.Value(noSIPValueKey).(*nsSession); ok {
+			sess.close()
+			return nil, nil, newVRError(NoSIPErrorWrongState, "Session already negotiated")
+		}
+		req.Handle.SetValue(noSIPValueKey, sess)
+		return noSIPEvent(jwsapi.Message{"event": "generated", "type": "offer", "sdp": sess.barebone()}), nil, nil
+	case "answer":
+		if !ok || sess.offerer || sess.pc.RemoteDescription() != nil {
+			return nil, nil, newVRError(NoSIPErrorWrongState, "No offer to answer")
+		}
+		if err := sess.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: jsep}); err != nil {
+			return nil, nil, newVRError(NoSIPErrorInvalidSDP, "Error negotiating: %v", err)
+		}
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		return noSIPEvent(jwsapi.Message{"event": "generated", "type": "answer", "sdp": sess.barebone()}), nil, nil
+	}
+	return nil, nil, newVRError(NoSIPErrorInvalidSDP, "Invalid SDP type (%s)", jsepType)
+}
+
+//process barebone offer: webrtc offer; barebone answer of generate offer: pending webrtc answer
+func (ns *NoSIP) process(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	sdpType, ok := req.Body.String("type")
+	if !ok {
+		return nil, nil, newVRError(NoSIPErrorMissingElement, "Missing element (type)")
+	}
+	barebone, ok := req.Body.String("sdp")
+	if !ok {
+		return nil, nil, newVRError(NoSIPErrorMissingElement, "Missing element (sdp)")
+	}
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(barebone)); err != nil {
+		return nil, nil, newVRError(NoSIPErrorInvalidSDP, "Invalid SDP: %v", err)
+	}
+	ns.mu.Lock()
+	sess, ok := req.Handle.Value(noSIPValueKey).(*nsSession)
+	ns.mu.Unlock()
+
+	switch sdpType {
+	case "offer":
+		if ok {
+			return nil, nil, newVRError(NoSIPErrorWrongState, "Session already negotiated")
+		}
+		codecs := make(map[webrtc.RTPCodecType]vrCodec)
+		for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+			if codec, ok := firstCodec(barebone, kind); ok {
+				codecs[kind] = codec
+			}
+		}
+		if len(codecs) == 0 {
+			return nil, nil, newVRError(NoSIPErrorInvalidSDP, "No audio or video codec in offer")
+		}
+		sess, err := ns.newSession(req.Handle, codecs)
+		if err != nil {
+			return nil, nil, err
+		}
+		offer, err := sess.pc.CreateOffer(nil)
+		if err == nil {
+			err = sess.pc.SetLocalDescription(offer)
+		}
+		if err != nil {
+			sess.close()
+			return nil, nil, newVRError(NoSIPErrorUnknown, "Error creating offer: %v", err)
+		}
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		if _, ok := req.Handle.Value(noSIPValueKey).(*nsSession); ok {
+			sess.close()
+			return nil, nil, newVRError(NoSIPErrorWrongState, "Session already negotiated")
+		}
+		sess.connect(&sd)
+		req.Handle.SetValue(noSIPValueKey, sess)
+		return noSIPEvent(jwsapi.Message{"event": "processed"}),
+			jwsapi.Message{"type": "offer", "sdp": sess.pc.LocalDescription().SDP}, nil
+	case "answer":
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		if !ok || !sess.offerer || sess.answer == "" {
+			return nil, nil, newVRError(NoSIPErrorWrongState, "No offer to process answer")
+		}
+		sess.connect(&sd)
+		answer := sess.answer
+		sess.answer = ""
+		return noSIPEvent(jwsapi.Message{"event": "processed"}),
+			jwsapi.Message{"type": "answer", "sdp": answer}, nil
+	}
+	return nil, nil, newVRError(NoSIPErrorInvalidElement, "Invalid element (type %s)", sdpType)
+}
+
+func (ns *NoSIP) recording(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	action, ok := req.Body.String("action")
+	if !ok {
+		return nil, nil, newVRError(NoSIPErrorMissingElement, "Missing element (action)")
+	}
+	if action != "start" && action != "stop" {
+		return nil, nil, newVRError(NoSIPErrorInvalidElement, "Invalid action (should be start|stop)")
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	sess, ok := req.Handle.Value(noSIPValueKey).(*nsSession)
+	if !ok {
+		return nil, nil, newVRError(NoSIPErrorWrongState, "Not in a session")
+	}
+	sess.recording = action == "start"
+	return noSIPEvent(jwsapi.Message{"event": "recordingupdated"}), nil, nil
+}
+
+//newSession PeerConnection with codecs and udp sockets of every codec
+func (ns *NoSIP) newSession(h *Handle, codecs map[webrtc.RTPCodecType]vrCodec) (*nsSession, error) {
+	pc, err := newPeerConnection(ns.setting, codecs)
+	if err != nil {
+		return nil, newVRError(NoSIPErrorUnknown, "Error creating PeerConnection: %v", err)
+	}
+	sess := &nsSession{
+		handle: h,
+		pc:     pc,
+		media:  make(map[webrtc.RTPCodecType]*nsMedia),
+	}
+	for kind, codec := range codecs {
+		m := &nsMedia{codec: codec}
+		sess.media[kind] = m
+		if m.rtpConn, err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}); err == nil {
+			m.rtcpConn, err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		}
+		if err != nil {
+			sess.close()
+			return nil, newVRError(NoSIPErrorIOError, "Error opening sockets: %v", err)
+		}
+		if m.track, err = pc.NewTrack(codec.pt, rand.Uint32(), kind.String(), "janusnosip"); err == nil {
+			var sender *webrtc.RTPSender
+			if sender, err = pc.AddTrack(m.track); err == nil {
+				go drainRTCP(sender, ns.keyFrameRequester(m))
+			}
+		}
+		if err != nil {
+			sess.close()
+			return nil, newVRError(NoSIPErrorUnknown, "Error adding track: %v", errors.Wrap(err, kind.String()))
+		}
+		go ns.relayRTP(sess, m)
+		go ns.relayRTCP(sess, m)
+	}
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		m, ok := sess.media[remote.Kind()]
+		if !ok {
+			return
+		}
+		ns.mu.Lock()
+		m.ssrc = remote.SSRC()
+		ns.mu.Unlock()
+		go drainRTCP(receiver, nil)
+		ns.relayTrack(sess, m, remote)
+	})
+	watchPeerConnection(h, pc)
+	return sess, nil
+}
+
+//relayTrack rtp of webrtc peer to rtp peer
+func (ns *NoSIP) relayTrack(sess *nsSession, m *nsMedia, remote *webrtc.Track) {
+	for {
+		packet, err := remote.ReadRTP()
+		if err != nil {
+			return
+		}
+		ns.mu.Lock()
+		addr := m.remote
+		if addr != nil {
+			sess.packets++
+		}
+		ns.mu.Unlock()
+		if addr == nil {
+			continue
+		}
+		if data, err := packet.Marshal(); err == nil {
+			m.rtpConn.WriteToUDP(data, addr)
+		}
+	}
+}
+
+//relayRTP rtp of rtp peer to webrtc peer
+func (ns *NoSIP) relayRTP(sess *nsSession, m *nsMedia) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := m.rtpConn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		ns.mu.Lock()
+		sess.packets++
+		ns.mu.Unlock()
+		packet.SSRC = m.track.SSRC()
+		packet.PayloadType = m.track.PayloadType()
+		m.track.WriteRTP(packet)
+	}
+}
+
+//relayRTCP PLI of rtp peer to webrtc peer
+func (ns *NoSIP) relayRTCP(sess *nsSession, m *nsMedia) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := m.rtcpConn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		packets, err := rtcp.Unmarshal(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, packet := range packets {
+			if _, ok := packet.(*rtcp.PictureLossIndication); ok {
+				ns.mu.Lock()
+				ssrc := m.ssrc
+				ns.mu.Unlock()
+				if ssrc != 0 {
+					sess.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+				}
+				break
+			}
+		}
+	}
+}
+
+//keyFrameRequester forward PLI of webrtc peer to rtp peer
+func (ns *NoSIP) keyFrameRequester(m *nsMedia) func([]rtcp.Packet) {
+	return func(packets []rtcp.Packet) {
+		for _, packet := range packets {
+			if _, ok := packet.(*rtcp.PictureLossIndication); !ok {
+				continue
+			}
+			ns.mu.Lock()
+			addr := m.remoteRTCP
+			ns.mu.Unlock()
+			if addr == nil {
+				return
+			}
+			if data, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: m.track.SSRC()}}); err == nil {
+				m.rtcpConn.WriteToUDP(data, addr)
+			}
+			return
+		}
+	}
+}
+
+//connect set remote addresses of media by barebone sdp of rtp peer, lock by caller
+func (sess *nsSession) connect(sd *sdp.SessionDescription) {
+	address := ""
+	if sd.ConnectionInformation != nil && sd.ConnectionInformation.Address != nil {
+		address = sd.ConnectionInformation.Address.Address
+	}
+	for _, md := range sd.MediaDescriptions {
+		m, ok := sess.media[webrtc.NewRTPCodecType(md.MediaName.Media)]
+		if !ok || md.MediaName.Port.Value == 0 {
+			continue
+		}
+		ip := address
+		if md.ConnectionInformation != nil && md.ConnectionInformation.Address != nil {
+			ip = md.ConnectionInformation.Address.Address
+		}
+		port := md.MediaName.Port.Value
+		rtcpPort := port + 1
+		if value, ok := md.Attribute("rtcp"); ok {
+			if fields := strings.Fields(value); len(fields) > 0 {
+				if p, err := strconv.Atoi(fields[0]); err == nil {
+					rtcpPort = p
+				}
+			}
+		}
+		m.remote = &net.UDPAddr{IP: net.ParseIP(ip), Port: port}
+		m.remoteRTCP = &net.UDPAddr{IP: net.ParseIP(ip), Port: rtcpPort}
+	}
+}
+
+//barebone sdp of local sockets, lock by caller
+func (sess *nsSession) barebone() string {
+	var b strings.Builder
+	id := rand.Uint32()
+	fmt.Fprintf(&b, "v=0\r\no=- %d %d IN IP4 127.0.0.1\r\ns=Janus NoSIP plugin\r\nc=IN IP4 127.0.0.1\r\nt=0 0\r\n", id, id)
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		m, ok := sess.media[kind]
+		if !ok {
+			continue
+		}
+		name := m.codec.name
+		if kind == webrtc.RTPCodecTypeVideo || name == "pcmu" || name == "pcma" || name == "g722" {
+			name = strings.ToUpper(name)
+		}
+		fmt.Fprintf(&b, "m=%s %d RTP/AVP %d\r\n", kind, m.rtpConn.LocalAddr().(*net.UDPAddr).Port, m.codec.pt)
+		fmt.Fprintf(&b, "a=rtpmap:%d %s/%d", m.codec.pt, name, m.codec.clockRate)
+		if m.codec.name == "opus" {
+			b.WriteString("/2")
+		}
+		b.WriteString("\r\n")
+		fmt.Fprintf(&b, "a=rtcp:%d\r\n", m.rtcpConn.LocalAddr().(*net.UDPAddr).Port)
+		b.WriteString("a=sendrecv\r\n")
+	}
+	return b.String()
+}
+
+//close PeerConnection and sockets
+func (sess *nsSession) close() {
+	for _, m := range sess.media {
+		if m.rtpConn != nil {
+			m.rtpConn.Close()
+		}
+		if m.rtcpConn != nil {
+			m.rtcpConn.Close()
+		}
+	}
+	go sess.pc.Close()
+}
+
+func noSIPEvent(result jwsapi.Message) jwsapi.Message {
+	return jwsapi.Message{
+		"nosip":  "event",
+		"result": result,
+	}
+}
+
+func noSIPError(err error) jwsapi.Message {
+	code := NoSIPErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"nosip":      "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
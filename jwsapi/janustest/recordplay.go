@@ -0,0 +1,532 @@
+package janustest
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//RecordPlayPlugin name of record&play plugin
+const RecordPlayPlugin = "janus.plugin.recordplay"
+
+//recordplay error code
+const (
+	RecordPlayErrorInvalidRequest   = 413
+	RecordPlayErrorInvalidElement   = 414
+	RecordPlayErrorMissingElement   = 415
+	RecordPlayErrorNotFound         = 416
+	RecordPlayErrorInvalidRecording = 417
+	RecordPlayErrorInvalidState     = 418
+	RecordPlayErrorInvalidSDP       = 419
+	RecordPlayErrorRecordingExists  = 420
+	RecordPlayErrorUnknown          = 499
+)
+
+//synchronous requests of recordplay, others are ack + event
+var recordPlaySyncRequests = map[string]bool{
+	"list": true, "update": true,
+}
+
+const rpValueKey = "recordplay"
+
+//RecordPlay fake record&play plugin, recordings are rtp packets in memory
+//record answer offer with pion PeerConnection(ice-lite) and keep received rtp with arrival time,
+//recording is listed when stopped, play replay rtp with the same timing then send done
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.RecordPlayPlugin, janustest.NewRecordPlay())
+type RecordPlay struct {
+	setting webrtc.SettingEngine
+
+	mu         sync.Mutex
+	recordings map[uint64]*rpRecording
+}
+
+type rpRecording struct {
+	id        uint64
+	name      string
+	date      string
+	codecs    map[webrtc.RTPCodecType]vrCodec
+	packets   []rpPacket
+	completed bool //stopped, listed and playable
+}
+
+//rpPacket rtp of recording, at is offset from first packet
+type rpPacket struct {
+	kind   webrtc.RTPCodecType
+	at     time.Duration
+	packet rtp.Packet
+}
+
+//rpSession recorder or player of handle
+type rpSession struct {
+	handle    *Handle
+	pc        *webrtc.PeerConnection
+	recording *rpRecording
+	recorder  bool
+	tracks    map[webrtc.RTPCodecType]*webrtc.Track //player
+	stop      chan struct{}                         //player, closed by stop
+}
+
+//RecordPlayOption option for RecordPlay
+type RecordPlayOption func(*RecordPlay)
+
+//WithRecordPlaySettingEngine set pion setting engine, ice-lite is always enabled
+func WithRecordPlaySettingEngine(setting webrtc.SettingEngine) RecordPlayOption {
+	return func(rp *RecordPlay) {
+		rp.setting = setting
+	}
+}
+
+//NewRecordPlay create fake record&play plugin
+func NewRecordPlay(opts ...RecordPlayOption) *RecordPlay {
+	rp := &RecordPlay{
+		recordings: make(map[uint64]*rpRecording),
+	}
+	rp.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(rp)
+	}
+	rp.setting.SetLite(true)
+	return rp
+}
+
+//Recordings ids of completed recordings, sorted
+func (rp *RecordPlay) Recordings() []uint64 {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	ids := make([]uint64, 0, len(rp.recordings))
+	for id, r := range rp.recordings {
+		if r.completed {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Packets count of rtp packets of recording
+func (rp *RecordPlay) Packets(id uint64) int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if r, ok := rp.recordings[id]; ok {
+		return len(r.packets)
+	}
+	return 0
+}
+
+//HandleMessage implements Plugin
+func (rp *RecordPlay) HandleMessage(req *Request) {
+	name := req.Name()
+	if recordPlaySyncRequests[name] {
+		rp.handleSync(req, name)
+		return
+	}
+	req.Ack()
+	go func() {
+		data, jsep, err := rp.handleAsync(req, name)
+		if err != nil {
+			data = recordPlayError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (rp *RecordPlay) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	rp.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if sess, ok := h.Value(rpValueKey).(*rpSession); ok {
+		pc = sess.pc
+	}
+	rp.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (rp *RecordPlay) HandleDetach(h *Handle) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if sess, ok := h.Value(rpValueKey).(*rpSession); ok {
+		rp.end(sess)
+	}
+	h.SetValue(rpValueKey, nil)
+}
+
+func (rp *RecordPlay) handleSync(req *Request, name string) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	switch name {
+	case "list":
+		ids := make([]uint64, 0, len(rp.recordings))
+		for id := range rp.recordings {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		list := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			r := rp.recordings[id]
+			if !r.completed {
+				continue
+			}
+			info := jwsapi.Message{
+				"id":    r.id,
+				"name":  r.name,
+				"date":  r.date,
+				"audio": false,
+				"video": false,
+				"data":  false,
+			}
+			for kind, codec := range r.codecs {
+				info[kind.String()] = true
+				info[kind.String()+"_codec"] = codec.name
+			}
+			list = append(list, info)
+		}
+		req.Success(jwsapi.Message{
+			"recordplay": "list",
+			"list":       list,
+		})
+	case "update":
+		req.Success(jwsapi.Message{"recordplay": "ok"})
+	}
+}
+
+func (rp *RecordPlay) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	switch name {
+	case "record":
+		return rp.record(req)
+	case "play":
+		return rp.play(req)
+	case "start":
+		return rp.start(req)
+	case "configure":
+		return rp.configure(req)
+	case "stop":
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		sess, ok := req.Handle.Value(rpValueKey).(*rpSession)
+		if !ok {
+			return nil, nil, newVRError(RecordPlayErrorInvalidState, "Not recording or playing")
+		}
+		rp.end(sess)
+		req.Handle.SetValue(rpValueKey, nil)
+		return recordPlayEvent(jwsapi.Message{"status": "stopped", "id": sess.recording.id}), nil, nil
+	}
+	return nil, nil, newVRError(RecordPlayErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+func (rp *RecordPlay) record(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	name, ok := req.Body.String("name")
+	if !ok || name == "" {
+		return nil, nil, newVRError(RecordPlayErrorMissingElement, "Missing element (name)")
+	}
+	if req.JSEP == nil {
+		return nil, nil, newVRError(RecordPlayErrorInvalidSDP, "Missing SDP")
+	}
+	offer, _ := req.JSEP.String("sdp")
+	codecs, err := rp.recordCodecs(offer, req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rp.mu.Lock()
+	if _, ok := req.Handle.Value(rpValueKey).(*rpSession); ok {
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorInvalidState, "Already recording or playing")
+	}
+	id, ok := req.Body.Uint64("id")
+	if !ok || id == 0 {
+		for id == 0 || rp.recordings[id] != nil {
+			id = uint64(rand.Int63n(1<<53-1) + 1)
+		}
+	}
+	if _, ok := rp.recordings[id]; ok {
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorRecordingExists, "Recording %d already exists", id)
+	}
+	r := &rpRecording{
+		id:     id,
+		name:   name,
+		date:   time.Now().Format("2006-01-02 15:04:05"),
+		codecs: codecs,
+	}
+	rp.recordings[id] = r
+	rp.mu.Unlock()
+
+	pc, err := newPeerConnection(rp.setting, codecs)
+	if err != nil {
+		rp.mu.Lock()
+		delete(rp.recordings, id)
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorUnknown, "Error creating PeerConnection: %v", err)
+	}
+	for kind := range codecs {
+		if _, err := pc.AddTransceiver(kind, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+			pc.Close()
+			rp.mu.Lock()
+			delete(rp.recordings, id)
+			rp.mu.Unlock()
+			return nil, nil, newVRError(RecordPlayErrorUnknown, "Error adding transceiver: %v", err)
+		}
+	}
+	sess := &rpSession{handle: req.Handle, pc: pc, recording: r, recorder: true}
+	start := time.Now()
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		go drainRTCP(receiver, nil)
+		for {
+			packet, err := remote.ReadRTP()
+			if err != nil {
+				return
+			}
+			rp.mu.Lock()
+			if r.completed {
+				rp.mu.Unlock()
+				return
+			}
+			if len(r.packets) == 0 {
+				start = time.Now()
+			}
+			r.packets = append(r.packets, rpPacket{kind: remote.Kind(), at: time.Since(start), packet: *packet})
+			rp.mu.Unlock()
+		}
+	})
+	watchPeerConnection(req.Handle, pc)
+
+	answer, err := negotiateAnswer(pc, offer)
+	if err != nil {
+		pc.Close()
+		rp.mu.Lock()
+		delete(rp.recordings, id)
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	req.Handle.SetValue(rpValueKey, sess)
+	return recordPlayEvent(jwsapi.Message{"status": "recording", "id": id}),
+		jwsapi.Message{"type": "answer", "sdp": answer}, nil
+}
+
+//recordCodecs audio and video codec of offer, audiocodec, videocodec of body are preferred
+func (rp *RecordPlay) recordCodecs(offer string, body jwsapi.Message) (map[webrtc.RTPCodecType]vrCodec, error) {
+	all, err := sdpCodecs(offer)
+	if err != nil {
+		return nil, newVRError(RecordPlayErrorInvalidSDP, "Invalid SDP: %v", err)
+	}
+	codecs := make(map[webrtc.RTPCodecType]vrCodec)
+	for kind, key := range map[webrtc.RTPCodecType]string{webrtc.RTPCodecTypeAudio: "audiocodec", webrtc.RTPCodecTypeVideo: "videocodec"} {
+		if name, ok := body.String(key); ok {
+			codec, ok := all[name]
+			if !ok || codec.kind != kind {
+				return nil, newVRError(RecordPlayErrorInvalidElement, "Invalid element (%s %s not in offer)", key, name)
+			}
+			codecs[kind] = codec
+			continue
+		}
+		if codec, ok := firstCodec(offer, kind); ok {
+			codecs[kind] = codec
+		}
+	}
+	if len(codecs) == 0 {
+		return nil, newVRError(RecordPlayErrorInvalidSDP, "No audio or video codec in offer")
+	}
+	return codecs, nil
+}
+
+func (rp *RecordPlay) configure(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	sess, ok := req.Handle.Value(rpValueKey).(*rpSession)
+	if !ok || !sess.recorder {
+		return nil, nil, newVRError(RecordPlayErrorInvalidState, "Not recording")
+	}
+	settings := jwsapi.Message{}
+	for _, key := range []string{"video-bitrate-max", "video-keyframe-interval"} {
+		if value, ok := req.Body.Uint64(key); ok {
+			settings[key] = value
+		}
+	}
+	return recordPlayEvent(jwsapi.Message{"status": "configured", "settings": settings}), nil, nil
+}
+
+func (rp *RecordPlay) play(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		return nil, nil, newVRError(RecordPlayErrorMissingElement, "Missing element (id)")
+	}
+	rp.mu.Lock()
+	if _, ok := req.Handle.Value(rpValueKey).(*rpSession); ok {
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorInvalidState, "Already recording or playing")
+	}
+	r, ok := rp.recordings[id]
+	if !ok || !r.completed {
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorNotFound, "No such recording")
+	}
+	if len(r.packets) == 0 {
+		rp.mu.Unlock()
+		return nil, nil, newVRError(RecordPlayErrorInvalidRecording, "Error opening recording files")
+	}
+	rp.mu.Unlock()
+
+	pc, err := newPeerConnection(rp.setting, r.codecs)
+	if err != nil {
+		return nil, nil, newVRError(RecordPlayErrorUnknown, "Error creating PeerConnection: %v", err)
+	}
+	sess := &rpSession{
+		handle:    req.Handle,
+		pc:        pc,
+		recording: r,
+		tracks:    make(map[webrtc.RTPCodecType]*webrtc.Track),
+		stop:      make(chan struct{}),
+	}
+	for kind, codec := range r.codecs {
+		track, err := pc.NewTrack(codec.pt, rand.Uint32(), kind.String(), "janusplay")
+		if err == nil {
+			var sender *webrtc.RTPSender
+			if sender, err = pc.AddTrack(track); err == nil {
+				go drainRTCP(sender, nil)
+			}
+		}
+		if err != nil {
+			pc.Close()
+			return nil, nil, newVRError(RecordPlayErrorUnknown, "Error adding track: %v", errors.Wrap(err, kind.String()))
+		}
+		sess.tracks[kind] = track
+	}
+	watchPeerConnection(req.Handle, pc)
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, nil, newVRError(RecordPlayErrorUnknown, "Error creating offer: %v", err)
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if _, ok := req.Handle.Value(rpValueKey).(*rpSession); ok {
+		pc.Close()
+		return nil, nil, newVRError(RecordPlayErrorInvalidState, "Already recording or playing")
+	}
+	req.Handle.SetValue(rpValueKey, sess)
+	return recordPlayEvent(jwsapi.Message{"status": "preparing", "id": id}),
+		jwsapi.Message{"type": "offer", "sdp": pc.LocalDescription().SDP}, nil
+}
+
+func (rp *RecordPlay) start(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	if req.JSEP == nil {
+		return nil, nil, newVRError(RecordPlayErrorInvalidSDP, "Missing SDP")
+	}
+	answer, _ := req.JSEP.String("sdp")
+	rp.mu.Lock()
+	sess, ok := req.Handle.Value(rpValueKey).(*rpSession)
+	rp.mu.Unlock()
+	if !ok || sess.recorder || sess.pc.RemoteDescription() != nil {
+		return nil, nil, newVRError(RecordPlayErrorInvalidState, "Not a playout session, can't start")
+	}
+	if err := sess.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		return nil, nil, newVRError(RecordPlayErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+	go rp.playout(sess)
+	return recordPlayEvent(jwsapi.Message{"status": "playing"}), nil, nil
+}
+
+//playout wait PeerConnection connected, send rtp of recording with the same timing, then done and close
+func (rp *RecordPlay) playout(sess *rpSession) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for sess.pc.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		select {
+		case <-sess.stop:
+			return
+		case <-ticker.C:
+			if state := sess.pc.ConnectionState(); state == webrtc.PeerConnectionStateClosed || state == webrtc.PeerConnectionStateFailed {
+				return
+			}
+		}
+	}
+
+	rp.mu.Lock()
+	packets := sess.recording.packets
+	rp.mu.Unlock()
+	start := time.Now()
+	for _, p := range packets {
+		if wait := p.at - time.Since(start); wait > 0 {
+			select {
+			case <-sess.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+		track := sess.tracks[p.kind]
+		packet := p.packet
+		packet.SSRC = track.SSRC()
+		packet.PayloadType = track.PayloadType()
+		track.WriteRTP(&packet)
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	select {
+	case <-sess.stop:
+		return
+	default:
+	}
+	sess.handle.Event(recordPlayEvent(jwsapi.Message{"status": "done", "id": sess.recording.id}), nil)
+	rp.end(sess)
+	sess.handle.SetValue(rpValueKey, nil)
+}
+
+//end complete recording or stop playout, close PeerConnection, lock by caller
+func (rp *RecordPlay) end(sess *rpSession) {
+	if sess.recorder {
+		sess.recording.completed = true
+	} else {
+		select {
+		case <-sess.stop:
+		default:
+			close(sess.stop)
+		}
+	}
+	go sess.pc.Close()
+}
+
+func recordPlayEvent(result jwsapi.Message) jwsapi.Message {
+	return jwsapi.Message{
+		"recordplay": "event",
+		"result":     result,
+	}
+}
+
+func recordPlayError(err error) jwsapi.Message {
+	code := RecordPlayErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"recordplay": "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
@@ -0,0 +1,246 @@
+package janustest
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jrecord"
+	"github.com/pkg/errors"
+)
+
+//Replayer replay recorded frames as janus-gateway, set by WithServerReplayer
+//every request of client is matched with next recorded send frame by janus, body.request, session_id, handle_id,
+//then following recorded recv frames are send to client, transaction is mapped to the client's
+//session and handle id are same as recording, so replay is deterministic
+type Replayer struct {
+	records []jrecord.Record
+	timing  bool
+	ignore  map[string]bool
+
+	mu      sync.Mutex
+	pos     int
+	tids    map[string]string //recorded -> live
+	skipped map[string]bool   //recorded transaction of ignored request
+	errs    []error
+	done    chan struct{}
+}
+
+//ReplayOption option for Replayer
+type ReplayOption func(*Replayer)
+
+//WithReplayConn only replay frames of conn, eg: ws://127.0.0.1:8188/janus@1
+func WithReplayConn(conn string) ReplayOption {
+	return func(r *Replayer) {
+		records := make([]jrecord.Record, 0, len(r.records))
+		for _, record := range r.records {
+			if record.Conn == conn {
+				records = append(records, record)
+			}
+		}
+		r.records = records
+	}
+}
+
+//WithReplayTiming keep recorded interval between request and its following frames, default is send immediately
+func WithReplayTiming(timing bool) ReplayOption {
+	return func(r *Replayer) {
+		r.timing = timing
+	}
+}
+
+//WithReplayIgnore requests not matched, eg: keepalive(default), they are acked and recorded ones are skipped
+func WithReplayIgnore(janus ...string) ReplayOption {
+	return func(r *Replayer) {
+		r.ignore = make(map[string]bool, len(janus))
+		for _, j := range janus {
+			r.ignore[j] = true
+		}
+	}
+}
+
+//NewReplayer create replayer from records, see jrecord.Load
+func NewReplayer(records []jrecord.Record, opts ...ReplayOption) *Replayer {
+	r := &Replayer{
+		records: records,
+		ignore:  map[string]bool{"keepalive": true},
+		tids:    make(map[string]string),
+		skipped: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.skip()
+	return r
+}
+
+//WithServerReplayer replay recording instead of handle request by plugins
+func WithServerReplayer(r *Replayer) ServerOption {
+	return func(s *Server) {
+		s.replayer = r
+	}
+}
+
+//Done closed when all records are replayed
+func (r *Replayer) Done() <-chan struct{} {
+	return r.done
+}
+
+//Remain count of records not replayed
+func (r *Replayer) Remain() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records) - r.pos
+}
+
+//Err return first mismatch, nil if client send same requests as recording
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) > 0 {
+		return r.errs[0]
+	}
+	return nil
+}
+
+//Errors return all mismatches
+func (r *Replayer) Errors() []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]error(nil), r.errs...)
+}
+
+//skip ignored send frames and their responses, close done at end, lock by caller
+func (r *Replayer) skip() {
+	for ; r.pos < len(r.records); r.pos++ {
+		record := &r.records[r.pos]
+		msg, err := record.Message()
+		if err != nil {
+			return
+		}
+		janus, _ := msg.String("janus")
+		tid, _ := msg.String("transaction")
+		if record.Direction == jwsapi.DirectionSend && r.ignore[janus] {
+			if tid != "" {
+				r.skipped[tid] = true
+			}
+			continue
+		}
+		if record.Direction == jwsapi.DirectionRecv && tid != "" && r.skipped[tid] {
+			continue
+		}
+		return
+	}
+	select {
+	case <-r.done:
+	default:
+		close(r.done)
+	}
+}
+
+func (r *Replayer) fail(err error) {
+	r.errs = append(r.errs, err)
+}
+
+func (r *Replayer) onMessage(c *Conn, msg jwsapi.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	janus, _ := msg.String("janus")
+	tid, _ := msg.String("transaction")
+	if r.ignore[janus] {
+		rsp := jwsapi.Message{"janus": "ack", "transaction": tid}
+		if sid, ok := msg.SessionID(); ok {
+			rsp["session_id"] = sid
+		}
+		c.send(rsp)
+		return
+	}
+	if r.pos >= len(r.records) {
+		r.fail(errors.Errorf("unexpected %s after end of recording", janus))
+		return
+	}
+	record := r.records[r.pos]
+	if record.Direction != jwsapi.DirectionSend {
+		r.fail(errors.Errorf("unexpected %s at record %d, expect recv frame", janus, r.pos))
+		return
+	}
+	expect, err := record.Message()
+	if err != nil {
+		r.fail(errors.Wrapf(err, "record %d", r.pos))
+		return
+	}
+	if err := matchRequest(expect, msg); err != nil {
+		r.fail(errors.Wrapf(err, "record %d", r.pos))
+		return
+	}
+	if recorded, ok := expect.String("transaction"); ok && tid != "" {
+		r.tids[recorded] = tid
+	}
+
+	start := record.Time
+	now := time.Now()
+	for r.pos++; r.pos < len(r.records); r.pos++ {
+		r.skip()
+		if r.pos >= len(r.records) {
+			return
+		}
+		record := r.records[r.pos]
+		if record.Direction == jwsapi.DirectionSend {
+			return
+		}
+		at := now
+		if r.timing {
+			at = now.Add(record.Time.Sub(start))
+		}
+		c.writeAt(r.mapFrame(record), at)
+	}
+	r.skip()
+}
+
+//mapFrame replace recorded transaction with client's
+func (r *Replayer) mapFrame(record jrecord.Record) []byte {
+	msg, err := record.Message()
+	if err != nil {
+		return record.Data()
+	}
+	recorded, ok := msg.String("transaction")
+	if !ok {
+		return record.Data()
+	}
+	live, ok := r.tids[recorded]
+	if !ok {
+		return record.Data()
+	}
+	msg["transaction"] = live
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return record.Data()
+	}
+	return data
+}
+
+func matchRequest(expect jwsapi.Message, msg jwsapi.Message) error {
+	for _, key := range []string{"janus", "session_id", "handle_id", "plugin"} {
+		if !sameValue(expect[key], msg[key]) {
+			return errors.Errorf("%s mismatch, expect %v, got %v", key, expect[key], msg[key])
+		}
+	}
+	expectBody, _ := expect.SubMessage("body")
+	body, _ := msg.SubMessage("body")
+	if !sameValue(expectBody["request"], body["request"]) {
+		return errors.Errorf("request mismatch, expect %v, got %v", expectBody["request"], body["request"])
+	}
+	return nil
+}
+
+func sameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,111 @@
+package janustest_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jrecord"
+)
+
+//scenario client behaviour, return what client observed
+func scenario(t *testing.T, url string, request string, opts ...jwsapi.ConnectionOption) []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := jwsapi.NewConnection(ctx, url, 1, opts...)
+	waitFor(t, "connected", conn.Connected)
+
+	var observed []string
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	observed = append(observed, fmt.Sprintf("session %d", sess.ID))
+	h, err := sess.Attach(testPlugin)
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	observed = append(observed, fmt.Sprintf("handle %d", h.ID))
+
+	rsp, err := h.MessageContext(ctx, jwsapi.Message{"request": request})
+	if err != nil {
+		return append(observed, "message error: "+err.Error())
+	}
+	pluginData := rsp.PluginData()
+	data := pluginData.Data()
+	event, _ := data.String("test")
+	name, _ := data.String("request")
+	observed = append(observed, fmt.Sprintf("%s %s %s", rsp.Type(), event, name))
+
+	if err := h.Detach(); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if err := sess.Destroy(); err != nil {
+		t.Fatalf("destroy: %v", err)
+	}
+	return append(observed, "destroyed")
+}
+
+//record scenario against live server, return observed and recorded frames
+func record(t *testing.T) ([]string, []jrecord.Record) {
+	t.Helper()
+	s := newServer(t)
+	var buf bytes.Buffer
+	rec := jrecord.NewRecorder(&buf)
+	live := scenario(t, s.URL, "ping", jwsapi.WithConnectionTap(rec))
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := jrecord.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 {
+		t.Fatal("nothing recorded")
+	}
+	return live, records
+}
+
+func TestReplay(t *testing.T) {
+	live, records := record(t)
+
+	//no plugin is registered, all responses come from recording
+	replayer := janustest.NewReplayer(records)
+	replay := janustest.NewServer(janustest.WithServerReplayer(replayer))
+	defer replay.Close()
+	replayed := scenario(t, replay.URL, "ping")
+
+	if !reflect.DeepEqual(live, replayed) {
+		t.Errorf("replayed %v, live %v", replayed, live)
+	}
+	if err := replayer.Err(); err != nil {
+		t.Errorf("replay mismatch: %v", err)
+	}
+	select {
+	case <-replayer.Done():
+	case <-time.After(time.Second):
+		t.Errorf("%d records are not replayed", replayer.Remain())
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	_, records := record(t)
+	replayer := janustest.NewReplayer(records)
+	replay := janustest.NewServer(janustest.WithServerReplayer(replayer))
+	defer replay.Close()
+
+	//request is different from recording, event is not replayed
+	observed := scenario(t, replay.URL, "pong")
+	if observed[len(observed)-1] != "message error: timeout" {
+		t.Errorf("observed %v, want message timeout", observed)
+	}
+	if replayer.Err() == nil {
+		t.Error("mismatch is not reported")
+	}
+}
@@ -0,0 +1,414 @@
+//Package janustest in-process fake janus-gateway for testing
+//speak janus-protocol over websocket, handle create,attach,claim,keepalive,detach,destroy,trickle,info
+//plugin message is handled by registered Plugin, see RegisterPlugin
+//fault can be injected by AddFault, Disconnect, SendRaw
+//recorded session(see jrecord) can be replayed by WithServerReplayer
+package janustest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/newzai/janus-go/jwsapi"
+)
+
+//janus error code
+const (
+	ErrorUnknown            = 490
+	ErrorInvalidJSON        = 454
+	ErrorMissingRequest     = 452
+	ErrorUnknownRequest     = 453
+	ErrorSessionNotFound    = 458
+	ErrorHandleNotFound     = 459
+	ErrorPluginNotFound     = 460
+	ErrorPluginMessage      = 464
+	ErrorInvalidRequestPath = 457
+)
+
+//Server fake janus-gateway
+type Server struct {
+	//URL ws url, eg: ws://127.0.0.1:12345/janus
+	URL string
+
+	httpServer *httptest.Server
+	upgrader   websocket.Upgrader
+
+	mu       sync.Mutex
+	nextID   uint64
+	sessions map[uint64]*Session
+	plugins  map[string]Plugin
+	conns    map[*Conn]struct{}
+	faults   []*Fault
+	received []jwsapi.Message
+	delay    time.Duration
+	info     jwsapi.Message
+	replayer *Replayer
+}
+
+//ServerOption option for server
+type ServerOption func(*Server)
+
+//WithServerDelay delay all response and event
+func WithServerDelay(delay time.Duration) ServerOption {
+	return func(s *Server) {
+		s.delay = delay
+	}
+}
+
+//WithServerInfo set extra fields of info(server_info) response
+func WithServerInfo(info jwsapi.Message) ServerOption {
+	return func(s *Server) {
+		for k, v := range info {
+			s.info[k] = v
+		}
+	}
+}
+
+//NewServer start a fake janus-gateway at 127.0.0.1
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{"janus-protocol"},
+			CheckOrigin:  func(r *http.Request) bool { return true },
+		},
+		nextID:   1000,
+		sessions: make(map[uint64]*Session),
+		plugins:  make(map[string]Plugin),
+		conns:    make(map[*Conn]struct{}),
+		info: jwsapi.Message{
+			"name":    "janustest",
+			"version": 1000,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = "ws" + strings.TrimPrefix(s.httpServer.URL, "http") + "/janus"
+	return s
+}
+
+//Close disconnect all client and stop server
+func (s *Server) Close() {
+	s.Disconnect()
+	s.httpServer.Close()
+}
+
+//RegisterPlugin register plugin, eg: janus.plugin.videoroom
+func (s *Server) RegisterPlugin(name string, plugin Plugin) {
+	s.mu.Lock()
+	s.plugins[name] = plugin
+	s.mu.Unlock()
+}
+
+//SetDelay delay all response and event
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	s.delay = delay
+	s.mu.Unlock()
+}
+
+//Disconnect close all ws connections, sessions are kept for claim
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+//SendRaw send raw data to all connections, eg: malformed json
+func (s *Server) SendRaw(data []byte) {
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.write(data)
+	}
+}
+
+//Received return all received messages
+func (s *Server) Received() []jwsapi.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]jwsapi.Message(nil), s.received...)
+}
+
+//Connections return count of ws connections
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+//Session get session by id
+func (s *Server) Session(id uint64) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+//Sessions return all session
+func (s *Server) Sessions() []*Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+//DestroySession destroy session at server side, eg: session timeout
+func (s *Server) DestroySession(id uint64) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if ok {
+		sess.destroy()
+	}
+}
+
+func (s *Server) newID() uint64 {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) getDelay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delay
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := newConn(s, ws)
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.onData(c, data)
+	}
+}
+
+func (s *Server) onData(c *Conn, data []byte) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	msg := make(jwsapi.Message)
+	if err := decoder.Decode(&msg); err != nil {
+		c.send(errorMessage("", ErrorInvalidJSON, "JSON error: "+err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	s.received = append(s.received, msg)
+	var fault *Fault
+	for _, f := range s.faults {
+		if f.take(msg) {
+			fault = f
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		switch fault.Action {
+		case FaultDrop:
+			return
+		case FaultDisconnect:
+			c.Close()
+			return
+		case FaultMalformed:
+			payload := fault.Payload
+			if payload == nil {
+				payload = []byte(`{"janus":"success","transaction":`)
+			}
+			c.write(payload)
+			return
+		case FaultDelay:
+			time.AfterFunc(fault.Delay, func() {
+				s.onMessage(c, msg)
+			})
+			return
+		}
+	}
+	s.onMessage(c, msg)
+}
+
+func (s *Server) onMessage(c *Conn, msg jwsapi.Message) {
+	if s.replayer != nil {
+		s.replayer.onMessage(c, msg)
+		return
+	}
+	tid, _ := msg.String("transaction")
+	janus, ok := msg.String("janus")
+	if !ok {
+		c.send(errorMessage(tid, ErrorMissingRequest, "Missing mandatory element (janus)"))
+		return
+	}
+
+	if janus == "create" {
+		s.mu.Lock()
+		sess := &Session{
+			ID:      s.newID(),
+			server:  s,
+			conn:    c,
+			handles: make(map[uint64]*Handle),
+		}
+		s.sessions[sess.ID] = sess
+		s.mu.Unlock()
+		c.send(jwsapi.Message{
+			"janus":       "success",
+			"transaction": tid,
+			"data":        jwsapi.Message{"id": sess.ID},
+		})
+		return
+	}
+	if janus == "info" {
+		rsp := jwsapi.Message{
+			"janus":       "server_info",
+			"transaction": tid,
+		}
+		s.mu.Lock()
+		for k, v := range s.info {
+			rsp[k] = v
+		}
+		plugins := jwsapi.Message{}
+		for name := range s.plugins {
+			plugins[name] = jwsapi.Message{"name": name}
+		}
+		rsp["plugins"] = plugins
+		s.mu.Unlock()
+		c.send(rsp)
+		return
+	}
+
+	sid, ok := msg.SessionID()
+	if !ok {
+		c.send(errorMessage(tid, ErrorInvalidRequestPath, "Unhandled request '"+janus+"' at this path"))
+		return
+	}
+	s.mu.Lock()
+	sess, ok := s.sessions[sid]
+	s.mu.Unlock()
+	if !ok {
+		c.send(errorMessage(tid, ErrorSessionNotFound, "No such session"))
+		return
+	}
+	sess.onMessage(c, janus, tid, msg)
+}
+
+//Conn a ws connection
+//messages are written in order by writeLoop, delayed by Server.SetDelay
+type Conn struct {
+	server *Server
+	ws     *websocket.Conn
+	out    chan outgoing
+	done   chan struct{}
+	once   sync.Once
+}
+
+type outgoing struct {
+	data []byte
+	at   time.Time
+}
+
+func newConn(s *Server, ws *websocket.Conn) *Conn {
+	c := &Conn{
+		server: s,
+		ws:     ws,
+		out:    make(chan outgoing, 1024),
+		done:   make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c
+}
+
+//Close close the ws connection
+func (c *Conn) Close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+func (c *Conn) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case item := <-c.out:
+			if wait := time.Until(item.at); wait > 0 {
+				select {
+				case <-c.done:
+					return
+				case <-time.After(wait):
+				}
+			}
+			if err := c.ws.WriteMessage(websocket.TextMessage, item.data); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) write(data []byte) {
+	c.writeAt(data, time.Now())
+}
+
+func (c *Conn) writeAt(data []byte, at time.Time) {
+	select {
+	case <-c.done:
+	case c.out <- outgoing{data: data, at: at.Add(c.server.getDelay())}:
+	}
+}
+
+func (c *Conn) send(msg jwsapi.Message) {
+	data, _ := json.Marshal(msg)
+	c.write(data)
+}
+
+func errorMessage(tid string, code int, reason string) jwsapi.Message {
+	msg := jwsapi.Message{
+		"janus": "error",
+		"error": jwsapi.Message{
+			"code":   code,
+			"reason": reason,
+		},
+	}
+	if tid != "" {
+		msg["transaction"] = tid
+	}
+	return msg
+}
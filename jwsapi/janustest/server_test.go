@@ -0,0 +1,179 @@
+package janustest_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+)
+
+const testPlugin = "janus.plugin.test"
+
+//echoPlugin ack message and push body back as event
+var echoPlugin = janustest.PluginFunc(func(req *janustest.Request) {
+	req.Ack()
+	go req.Event(jwsapi.Message{"test": "event", "request": req.Name()}, nil)
+})
+
+func newServer(t *testing.T) *janustest.Server {
+	t.Helper()
+	s := janustest.NewServer()
+	t.Cleanup(s.Close)
+	s.RegisterPlugin(testPlugin, echoPlugin)
+	return s
+}
+
+func connect(t *testing.T, s *janustest.Server) *jwsapi.Connection {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	conn := jwsapi.NewConnection(ctx, s.URL, 1)
+	waitFor(t, "connected", conn.Connected)
+	return conn
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait %s timeout", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func received(s *janustest.Server, janus string, sid uint64) int {
+	n := 0
+	for _, msg := range s.Received() {
+		id, _ := msg.SessionID()
+		if msg.Type() == janus && id == sid {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDisconnectClaim(t *testing.T) {
+	s := newServer(t)
+	conn := connect(t, s)
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := sess.Attach(testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Disconnect()
+	waitFor(t, "claim", func() bool { return received(s, "claim", sess.ID) == 1 })
+	waitFor(t, "reconnected", conn.Connected)
+	if _, ok := s.Session(sess.ID); !ok {
+		t.Fatal("session is lost at server")
+	}
+
+	//handle of claimed session still receive events
+	rsp, err := h.MessageContext(context.Background(), jwsapi.Message{"request": "ping"})
+	if err != nil {
+		t.Fatalf("message after claim: %v", err)
+	}
+	pluginData := rsp.PluginData()
+	data := pluginData.Data()
+	if request, _ := data.String("request"); request != "ping" {
+		t.Errorf("event request = %q, want ping", request)
+	}
+	if sess.IsDestroy() {
+		t.Error("session is destroyed after claim")
+	}
+}
+
+func TestFaultDrop(t *testing.T) {
+	s := newServer(t)
+	conn := connect(t, s)
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := s.DropNext(janustest.MatchJanus("attach"), 1)
+	if _, err := sess.Attach(testPlugin); err != jwsapi.ErrTimeout {
+		t.Fatalf("attach err = %v, want ErrTimeout", err)
+	}
+	if f.Hits() != 1 {
+		t.Errorf("fault hits = %d, want 1", f.Hits())
+	}
+	if _, err := sess.Attach(testPlugin); err != nil {
+		t.Fatalf("attach after drop: %v", err)
+	}
+}
+
+func TestFaultMalformed(t *testing.T) {
+	s := newServer(t)
+	conn := connect(t, s)
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := sess.Attach(testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//truncated json, and json of unexpected types
+	s.MalformedNext(janustest.MatchJanus("attach"), 1)
+	s.AddFault(&janustest.Fault{
+		Match:   janustest.MatchRequest("typed"),
+		Action:  janustest.FaultMalformed,
+		Payload: []byte(`{"janus":5,"transaction":[],"session_id":"x","plugindata":"y"}`),
+		Count:   1,
+	})
+	if _, err := sess.Attach(testPlugin); err != jwsapi.ErrTimeout {
+		t.Errorf("attach err = %v, want ErrTimeout", err)
+	}
+	if _, err := h.MessageContext(context.Background(), jwsapi.Message{"request": "typed"}); err != jwsapi.ErrTimeout {
+		t.Errorf("message err = %v, want ErrTimeout", err)
+	}
+	s.SendRaw([]byte(`{"janus":"event","session_id":` + strconv.FormatUint(sess.ID, 10) + `,"sender":` + strconv.FormatUint(h.ID, 10) + `,"plugindata":{"plugin":"x","data":[]}}`))
+	s.SendRaw([]byte(`{"janus":"error","transaction":"unknown","error":"not object"}`))
+
+	if !conn.Connected() {
+		t.Fatal("connection is broken by malformed json")
+	}
+	if _, err := sess.Attach(testPlugin); err != nil {
+		t.Fatalf("attach after malformed: %v", err)
+	}
+}
+
+func TestDestroySession(t *testing.T) {
+	s := newServer(t)
+	conn := connect(t, s)
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := sess.Attach(testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.DestroySession(sess.ID)
+	if _, ok := s.Session(sess.ID); ok {
+		t.Fatal("session is kept at server")
+	}
+	_, err = sess.Attach(testPlugin)
+	var info *jwsapi.ErrorInfo
+	if !errors.As(err, &info) || info.Code != janustest.ErrorSessionNotFound {
+		t.Fatalf("attach err = %v, want %d", err, janustest.ErrorSessionNotFound)
+	}
+
+	//claim of destroyed session failed after reconnection, session and handle are released
+	s.Disconnect()
+	waitFor(t, "claim", func() bool { return received(s, "claim", sess.ID) == 1 })
+	waitFor(t, "session released", sess.IsDestroy)
+	waitFor(t, "handle released", h.IsDestroy)
+}
@@ -0,0 +1,364 @@
+package janustest
+
+import (
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+)
+
+//Plugin fake janus plugin, handle "message" request of handle
+//Plugin may also implement AttachHandler, DetachHandler, TrickleHandler
+type Plugin interface {
+	HandleMessage(req *Request)
+}
+
+//PluginFunc func as Plugin
+type PluginFunc func(req *Request)
+
+//HandleMessage implements Plugin
+func (f PluginFunc) HandleMessage(req *Request) {
+	f(req)
+}
+
+//AttachHandler called when handle attached to plugin
+type AttachHandler interface {
+	HandleAttach(h *Handle)
+}
+
+//DetachHandler called when handle detached or session destroyed
+type DetachHandler interface {
+	HandleDetach(h *Handle)
+}
+
+//TrickleHandler called when receive trickle of handle, candidate is nil when completed
+type TrickleHandler interface {
+	HandleTrickle(h *Handle, candidate jwsapi.Message)
+}
+
+//Session fake janus session
+type Session struct {
+	ID uint64
+
+	server  *Server
+	mu      sync.Mutex
+	conn    *Conn
+	handles map[uint64]*Handle
+}
+
+//Handles return all handles of session
+func (s *Session) Handles() []*Handle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handles := make([]*Handle, 0, len(s.handles))
+	for _, h := range s.handles {
+		handles = append(handles, h)
+	}
+	return handles
+}
+
+//Handle get handle by id
+func (s *Session) Handle(id uint64) (*Handle, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.handles[id]
+	return h, ok
+}
+
+func (s *Session) getConn() *Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn
+}
+
+func (s *Session) send(msg jwsapi.Message) {
+	msg["session_id"] = s.ID
+	if c := s.getConn(); c != nil {
+		c.send(msg)
+	}
+}
+
+//Timeout push timeout event and destroy session
+func (s *Session) Timeout() {
+	s.send(jwsapi.Message{"janus": "timeout"})
+	s.server.DestroySession(s.ID)
+}
+
+func (s *Session) destroy() {
+	s.mu.Lock()
+	handles := s.handles
+	s.handles = make(map[uint64]*Handle)
+	s.mu.Unlock()
+	for _, h := range handles {
+		h.detach()
+	}
+}
+
+func (s *Session) onMessage(c *Conn, janus string, tid string, msg jwsapi.Message) {
+	success := jwsapi.Message{
+		"janus":       "success",
+		"transaction": tid,
+	}
+	switch janus {
+	case "keepalive":
+		c.send(jwsapi.Message{
+			"janus":       "ack",
+			"session_id":  s.ID,
+			"transaction": tid,
+		})
+		return
+	case "claim":
+		s.mu.Lock()
+		s.conn = c
+		s.mu.Unlock()
+		success["session_id"] = s.ID
+		c.send(success)
+		return
+	case "destroy":
+		s.server.mu.Lock()
+		delete(s.server.sessions, s.ID)
+		s.server.mu.Unlock()
+		s.destroy()
+		success["session_id"] = s.ID
+		c.send(success)
+		return
+	case "attach":
+		name, _ := msg.String("plugin")
+		s.server.mu.Lock()
+		plugin, ok := s.server.plugins[name]
+		var id uint64
+		if ok {
+			id = s.server.newID()
+		}
+		s.server.mu.Unlock()
+		if !ok {
+			c.send(errorMessage(tid, ErrorPluginNotFound, "No such plugin '"+name+"'"))
+			return
+		}
+		h := &Handle{
+			ID:      id,
+			Plugin:  name,
+			Session: s,
+			plugin:  plugin,
+		}
+		s.mu.Lock()
+		s.handles[id] = h
+		s.mu.Unlock()
+		if attacher, ok := plugin.(AttachHandler); ok {
+			attacher.HandleAttach(h)
+		}
+		success["session_id"] = s.ID
+		success["data"] = jwsapi.Message{"id": id}
+		c.send(success)
+		return
+	}
+
+	hid, ok := msg.Uint64("handle_id")
+	if !ok {
+		c.send(errorMessage(tid, ErrorInvalidRequestPath, "Unhandled request '"+janus+"' at this path"))
+		return
+	}
+	h, ok := s.Handle(hid)
+	if !ok {
+		c.send(errorMessage(tid, ErrorHandleNotFound, "No such handle in session"))
+		return
+	}
+
+	switch janus {
+	case "detach":
+		s.mu.Lock()
+		delete(s.handles, hid)
+		s.mu.Unlock()
+		h.detach()
+		success["session_id"] = s.ID
+		c.send(success)
+	case "trickle":
+		if trickler, ok := h.plugin.(TrickleHandler); ok {
+			if candidate, ok := msg.SubMessage("candidate"); ok {
+				if candidate.Bool("completed") {
+					candidate = nil
+				}
+				trickler.HandleTrickle(h, candidate)
+			}
+		}
+		c.send(jwsapi.Message{
+			"janus":       "ack",
+			"session_id":  s.ID,
+			"transaction": tid,
+		})
+	case "message":
+		body, ok := msg.SubMessage("body")
+		if !ok {
+			c.send(errorMessage(tid, ErrorMissingRequest, "Missing mandatory element (body)"))
+			return
+		}
+		jsep, _ := msg.SubMessage("jsep")
+		h.plugin.HandleMessage(&Request{
+			Handle:      h,
+			Transaction: tid,
+			Body:        body,
+			JSEP:        jsep,
+		})
+	case "hangup":
+		h.Hangup("Janus API")
+		success["session_id"] = s.ID
+		c.send(success)
+	default:
+		c.send(errorMessage(tid, ErrorUnknownRequest, "Unknown request '"+janus+"'"))
+	}
+}
+
+//Handle fake janus plugin handle
+type Handle struct {
+	ID      uint64
+	Plugin  string
+	Session *Session
+
+	plugin Plugin
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+//SetValue store plugin private value at handle
+func (h *Handle) SetValue(key string, value interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.values == nil {
+		h.values = make(map[string]interface{})
+	}
+	h.values[key] = value
+}
+
+//Value get plugin private value
+func (h *Handle) Value(key string) interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.values[key]
+}
+
+func (h *Handle) detach() {
+	if detacher, ok := h.plugin.(DetachHandler); ok {
+		detacher.HandleDetach(h)
+	}
+	h.Push("detached", nil)
+}
+
+//Push push a handle event, eg: webrtcup,media,slowlink,hangup,trickle
+func (h *Handle) Push(janus string, fields jwsapi.Message) {
+	msg := jwsapi.Message{}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	msg["janus"] = janus
+	msg["sender"] = h.ID
+	h.Session.send(msg)
+}
+
+//Event push asynchronous plugin event without transaction, jsep is optional
+func (h *Handle) Event(data jwsapi.Message, jsep jwsapi.Message) {
+	h.event("", data, jsep)
+}
+
+func (h *Handle) event(tid string, data jwsapi.Message, jsep jwsapi.Message) {
+	msg := jwsapi.Message{
+		"plugindata": jwsapi.Message{
+			"plugin": h.Plugin,
+			"data":   data,
+		},
+	}
+	if tid != "" {
+		msg["transaction"] = tid
+	}
+	if jsep != nil {
+		msg["jsep"] = jsep
+	}
+	h.Push("event", msg)
+}
+
+//WebrtcUp push webrtcup
+func (h *Handle) WebrtcUp() {
+	h.Push("webrtcup", nil)
+}
+
+//Media push media event
+func (h *Handle) Media(kind string, receiving bool) {
+	h.Push("media", jwsapi.Message{"type": kind, "receiving": receiving})
+}
+
+//SlowLink push slowlink event
+func (h *Handle) SlowLink(uplink bool, media string, lost int) {
+	h.Push("slowlink", jwsapi.Message{"uplink": uplink, "media": media, "lost": lost})
+}
+
+//Hangup push hangup event
+func (h *Handle) Hangup(reason string) {
+	h.Push("hangup", jwsapi.Message{"reason": reason})
+}
+
+//Trickle push remote candidate, nil candidate means completed
+func (h *Handle) Trickle(candidate jwsapi.Message) {
+	if candidate == nil {
+		candidate = jwsapi.Message{"completed": true}
+	}
+	h.Push("trickle", jwsapi.Message{"candidate": candidate})
+}
+
+//Request "message" request of handle
+type Request struct {
+	Handle      *Handle
+	Transaction string
+	Body        jwsapi.Message
+	JSEP        jwsapi.Message
+}
+
+//Name value of body.request
+func (r *Request) Name() string {
+	name, _ := r.Body.String("request")
+	return name
+}
+
+//Success response synchronous request with plugindata
+func (r *Request) Success(data jwsapi.Message) {
+	r.Handle.Push("success", jwsapi.Message{
+		"transaction": r.Transaction,
+		"plugindata": jwsapi.Message{
+			"plugin": r.Handle.Plugin,
+			"data":   data,
+		},
+	})
+}
+
+//Ack ack asynchronous request, Event must be called later
+func (r *Request) Ack() {
+	r.Handle.Session.send(jwsapi.Message{
+		"janus":       "ack",
+		"transaction": r.Transaction,
+	})
+}
+
+//Event event of asynchronous request, jsep is optional
+func (r *Request) Event(data jwsapi.Message, jsep jwsapi.Message) {
+	r.Handle.event(r.Transaction, data, jsep)
+}
+
+//Respond ack and event of asynchronous request
+func (r *Request) Respond(data jwsapi.Message, jsep jwsapi.Message) {
+	r.Ack()
+	r.Event(data, jsep)
+}
+
+//PluginError response plugin error, eg: {"videoroom":"event","error_code":426,"error":"No such room"}
+func (r *Request) PluginError(code int, reason string, fields jwsapi.Message) {
+	data := jwsapi.Message{}
+	for k, v := range fields {
+		data[k] = v
+	}
+	data["error_code"] = code
+	data["error"] = reason
+	r.Success(data)
+}
+
+//Error response janus error
+func (r *Request) Error(code int, reason string) {
+	msg := errorMessage(r.Transaction, code, reason)
+	r.Handle.Session.send(msg)
+}
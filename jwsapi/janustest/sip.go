@@ -0,0 +1,634 @@
+package janustest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//SIPPlugin name of sip plugin
+const SIPPlugin = "janus.plugin.sip"
+
+//sip error code
+const (
+	SIPErrorInvalidRequest    = 442
+	SIPErrorMissingElement    = 443
+	SIPErrorInvalidElement    = 444
+	SIPErrorAlreadyRegistered = 445
+	SIPErrorInvalidAddress    = 446
+	SIPErrorWrongState        = 447
+	SIPErrorMissingSDP        = 448
+	SIPErrorUnknown           = 499
+)
+
+const sipValueKey = "sip"
+
+//SIP fake sip plugin, accounts registered at the fake are the sip network
+//call between handles of the fake, caller and callee negotiate with pion PeerConnection(ice-lite), audio is relayed
+//call to uri not registered is hangup with 404, busy callee with 486
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.SIPPlugin, janustest.NewSIP())
+type SIP struct {
+	setting  webrtc.SettingEngine
+	accounts map[string]string //username -> secret
+
+	mu      sync.Mutex
+	users   map[string]*sipUser //registered
+	nextRef uint64
+}
+
+type sipUser struct {
+	handle     *Handle
+	username   string
+	display    string
+	registered bool
+	call       *sipCall
+}
+
+type sipCall struct {
+	id       string
+	caller   *sipUser
+	callee   *sipUser
+	codec    vrCodec
+	legs     map[*sipUser]*sipLeg
+	accepted bool
+	held     bool
+}
+
+//sipLeg PeerConnection between the fake and user
+type sipLeg struct {
+	pc     *webrtc.PeerConnection
+	track  *webrtc.Track
+	answer string //answer to caller, sent when accepted
+}
+
+//peer other user of call
+func (c *sipCall) peer(u *sipUser) *sipUser {
+	if c.caller == u {
+		return c.callee
+	}
+	return c.caller
+}
+
+//SIPOption option for SIP
+type SIPOption func(*SIP)
+
+//WithSIPSettingEngine set pion setting engine, ice-lite is always enabled
+func WithSIPSettingEngine(setting webrtc.SettingEngine) SIPOption {
+	return func(s *SIP) {
+		s.setting = setting
+	}
+}
+
+//WithSIPAccount account with secret, register with wrong secret is registration_failed 403
+//any username can register if no account
+func WithSIPAccount(username string, secret string) SIPOption {
+	return func(s *SIP) {
+		s.accounts[username] = secret
+	}
+}
+
+//NewSIP create fake sip plugin
+func NewSIP(opts ...SIPOption) *SIP {
+	s := &SIP{
+		accounts: make(map[string]string),
+		users:    make(map[string]*sipUser),
+	}
+	s.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.setting.SetLite(true)
+	return s
+}
+
+//Registered registered usernames
+func (s *SIP) Registered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usernames := make([]string, 0, len(s.users))
+	for username := range s.users {
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+//HandleMessage implements Plugin
+func (s *SIP) HandleMessage(req *Request) {
+	req.Ack()
+	go func() {
+		data, jsep, err := s.handleAsync(req, req.Name())
+		if err != nil {
+			data = sipError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (s *SIP) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if u, ok := h.Value(sipValueKey).(*sipUser); ok && u.call != nil {
+		if leg, ok := u.call.legs[u]; ok {
+			pc = leg.pc
+		}
+	}
+	s.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (s *SIP) HandleDetach(h *Handle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := h.Value(sipValueKey).(*sipUser); ok {
+		if u.call != nil {
+			s.endCall(u.call, u, 200, "Session Terminated")
+		}
+		if s.users[u.username] == u {
+			delete(s.users, u.username)
+		}
+	}
+	h.SetValue(sipValueKey, nil)
+}
+
+func (s *SIP) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	switch name {
+	case "register":
+		return s.register(req)
+	case "unregister":
+		return s.unregister(req)
+	case "call":
+		return s.call(req)
+	case "accept":
+		return s.accept(req)
+	case "decline":
+		return s.decline(req)
+	case "hangup":
+		return s.hangup(req)
+	case "hold", "unhold":
+		return s.hold(req, name == "hold")
+	case "dtmf_info":
+		return s.dtmfInfo(req)
+	case "transfer":
+		return s.transfer(req)
+	}
+	return nil, nil, newVRError(SIPErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+//user of handle, created by first request
+func (s *SIP) user(h *Handle) *sipUser {
+	u, ok := h.Value(sipValueKey).(*sipUser)
+	if !ok {
+		u = &sipUser{handle: h}
+		h.SetValue(sipValueKey, u)
+	}
+	return u
+}
+
+func (s *SIP) register(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	username, ok := req.Body.String("username")
+	if !ok {
+		return nil, nil, newVRError(SIPErrorMissingElement, "Missing element (username)")
+	}
+	if !strings.HasPrefix(username, "sip:") && !strings.HasPrefix(username, "sips:") {
+		return nil, nil, newVRError(SIPErrorInvalidAddress, "Invalid user address %s", username)
+	}
+	guest := false
+	if t, _ := req.Body.String("type"); t == "guest" {
+		guest = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(req.Handle)
+	if u.registered && !req.Body.Bool("refresh") {
+		return nil, nil, newVRError(SIPErrorAlreadyRegistered, "Already registered (%s)", u.username)
+	}
+	if other, ok := s.users[username]; ok && other != u {
+		return nil, nil, newVRError(SIPErrorAlreadyRegistered, "Already registered (%s)", username)
+	}
+	if secret, ok := s.accounts[username]; !guest && len(s.accounts) > 0 && (!ok || req.Body["secret"] != secret) {
+		u.handle.Event(sipEvent(jwsapi.Message{"event": "registration_failed", "code": 403, "reason": "Forbidden"}), nil)
+		return sipEvent(jwsapi.Message{"event": "registering"}), nil, nil
+	}
+	u.username = username
+	u.display, _ = req.Body.String("display_name")
+	if guest {
+		//guest is not registered to sip server, only call
+		return sipEvent(jwsapi.Message{"event": "registered", "username": username, "register_sent": false}), nil, nil
+	}
+	u.registered = true
+	s.users[username] = u
+	u.handle.Event(sipEvent(jwsapi.Message{"event": "registered", "username": username, "register_sent": true}), nil)
+	return sipEvent(jwsapi.Message{"event": "registering"}), nil, nil
+}
+
+func (s *SIP) unregister(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(req.Handle)
+	if !u.registered {
+		return nil, nil, newVRError(SIPErrorWrongState, "Wrong state (not registered)")
+	}
+	u.registered = false
+	delete(s.users, u.username)
+	u.handle.Event(sipEvent(jwsapi.Message{"event": "unregistered", "username": u.username}), nil)
+	return sipEvent(jwsapi.Message{"event": "unregistering"}), nil, nil
+}
+
+func (s *SIP) call(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	uri, ok := req.Body.String("uri")
+	if !ok {
+		return nil, nil, newVRError(SIPErrorMissingElement, "Missing element (uri)")
+	}
+	if !strings.HasPrefix(uri, "sip:") && !strings.HasPrefix(uri, "sips:") {
+		return nil, nil, newVRError(SIPErrorInvalidAddress, "Invalid user address %s", uri)
+	}
+	if req.JSEP == nil {
+		return nil, nil, newVRError(SIPErrorMissingSDP, "Missing SDP")
+	}
+	offer, _ := req.JSEP.String("sdp")
+	codec, ok := firstCodec(offer, webrtc.RTPCodecTypeAudio)
+	if !ok {
+		return nil, nil, newVRError(SIPErrorMissingSDP, "No audio codec in offer")
+	}
+	callID, _ := req.Body.String("call_id")
+	if callID == "" {
+		callID = uuid.New().String()
+	}
+
+	s.mu.Lock()
+	caller := s.user(req.Handle)
+	if caller.username == "" {
+		s.mu.Unlock()
+		return nil, nil, newVRError(SIPErrorWrongState, "Wrong state (register first)")
+	}
+	if caller.call != nil {
+		s.mu.Unlock()
+		return nil, nil, newVRError(SIPErrorWrongState, "Wrong state (already in a call)")
+	}
+	call := &sipCall{id: callID, caller: caller, codec: codec, legs: make(map[*sipUser]*sipLeg)}
+	caller.call = call
+	callee, ok := s.users[uri]
+	s.mu.Unlock()
+
+	calling := sipEvent(jwsapi.Message{"event": "calling", "call_id": callID})
+	calling["call_id"] = callID
+	switch {
+	case !ok:
+		go s.reject(call, 404, "Not Found")
+		return calling, nil, nil
+	case callee == caller:
+		go s.reject(call, 482, "Loop Detected")
+		return calling, nil, nil
+	}
+
+	//leg of caller, answer is sent when accepted
+	callerLeg, err := s.newLeg(req.Handle, call)
+	if err == nil {
+		callerLeg.answer, err = negotiateAnswer(callerLeg.pc, offer)
+	}
+	if err != nil {
+		s.mu.Lock()
+		caller.call = nil
+		s.mu.Unlock()
+		if callerLeg != nil {
+			callerLeg.pc.Close()
+		}
+		return nil, nil, newVRError(SIPErrorUnknown, "Error negotiating: %v", err)
+	}
+	//leg of callee, offer of incomingcall
+	calleeLeg, err := s.newLeg(callee.handle, call)
+	var calleeOffer webrtc.SessionDescription
+	if err == nil {
+		if calleeOffer, err = calleeLeg.pc.CreateOffer(nil); err == nil {
+			err = calleeLeg.pc.SetLocalDescription(calleeOffer)
+		}
+	}
+	if err != nil {
+		s.mu.Lock()
+		caller.call = nil
+		s.mu.Unlock()
+		callerLeg.pc.Close()
+		if calleeLeg != nil {
+			calleeLeg.pc.Close()
+		}
+		return nil, nil, newVRError(SIPErrorUnknown, "Error negotiating: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if caller.call != call {
+		//hangup during negotiation
+		callerLeg.pc.Close()
+		calleeLeg.pc.Close()
+		return calling, nil, nil
+	}
+	call.legs[caller] = callerLeg
+	if callee.call != nil || s.users[uri] != callee {
+		calleeLeg.pc.Close()
+		go s.reject(call, 486, "Busy Here")
+		return calling, nil, nil
+	}
+	call.callee = callee
+	call.legs[callee] = calleeLeg
+	callee.call = call
+	incoming := sipEvent(jwsapi.Message{
+		"event":       "incomingcall",
+		"username":    caller.username,
+		"displayname": caller.display,
+		"callee":      uri,
+		"call_id":     callID,
+	})
+	incoming["call_id"] = callID
+	callee.handle.Event(incoming, jwsapi.Message{"type": "offer", "sdp": calleeLeg.pc.LocalDescription().SDP})
+	caller.handle.Event(sipEvent(jwsapi.Message{"event": "ringing", "call_id": callID}), nil)
+	return calling, nil, nil
+}
+
+//reject call before callee, caller get hangup with code
+func (s *SIP) reject(call *sipCall, code int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if call.caller.call == call {
+		s.endCall(call, nil, code, reason)
+	}
+}
+
+//newLeg PeerConnection with codec of call, audio is relayed to other leg
+func (s *SIP) newLeg(h *Handle, call *sipCall) (*sipLeg, error) {
+	pc, err := newPeerConnection(s.setting, map[webrtc.RTPCodecType]vrCodec{webrtc.RTPCodecTypeAudio: call.codec})
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	track, err := pc.NewTrack(call.codec.pt, rand.Uint32(), "audio", "janussip")
+	if err == nil {
+		_, err = pc.AddTrack(track)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, errors.Wrap(err, "AddTrack")
+	}
+	leg := &sipLeg{pc: pc, track: track}
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		go drainRTCP(receiver, nil)
+		s.relay(call, leg, remote)
+	})
+	watchPeerConnection(h, pc)
+	return leg, nil
+}
+
+//relay rtp of leg to other leg of accepted call, not on hold
+func (s *SIP) relay(call *sipCall, from *sipLeg, remote *webrtc.Track) {
+	for {
+		packet, err := remote.ReadRTP()
+		if err != nil {
+			return
+		}
+		var target *webrtc.Track
+		s.mu.Lock()
+		if call.accepted && !call.held {
+			for _, leg := range call.legs {
+				if leg != from {
+					target = leg.track
+				}
+			}
+		}
+		s.mu.Unlock()
+		if target != nil {
+			packet.SSRC = target.SSRC()
+			packet.PayloadType = target.PayloadType()
+			target.WriteRTP(packet)
+		}
+	}
+}
+
+//endCall close legs, notify hangup to users of call except by, lock by caller
+func (s *SIP) endCall(call *sipCall, by *sipUser, code int, reason string) {
+	for _, u := range []*sipUser{call.caller, call.callee} {
+		if u == nil || u.call != call {
+			continue
+		}
+		u.call = nil
+		if u != by {
+			hangup := sipEvent(jwsapi.Message{"event": "hangup", "call_id": call.id, "code": code, "reason": reason})
+			hangup["call_id"] = call.id
+			u.handle.Event(hangup, nil)
+		}
+	}
+	for _, leg := range call.legs {
+		go leg.pc.Close()
+	}
+	call.legs = make(map[*sipUser]*sipLeg)
+}
+
+//current call of handle, lock by caller
+func (s *SIP) current(h *Handle) (*sipUser, *sipCall, error) {
+	u, ok := h.Value(sipValueKey).(*sipUser)
+	if !ok || u.call == nil {
+		return nil, nil, newVRError(SIPErrorWrongState, "Wrong state (not in a call)")
+	}
+	return u, u.call, nil
+}
+
+func (s *SIP) accept(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	if req.JSEP == nil {
+		return nil, nil, newVRError(SIPErrorMissingSDP, "Missing SDP")
+	}
+	answer, _ := req.JSEP.String("sdp")
+	s.mu.Lock()
+	u, call, err := s.current(req.Handle)
+	if err == nil && (call.callee != u || call.accepted) {
+		err = newVRError(SIPErrorWrongState, "Wrong state (no incoming call)")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return nil, nil, err
+	}
+	leg := call.legs[u]
+	s.mu.Unlock()
+
+	if err := leg.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		return nil, nil, newVRError(SIPErrorMissingSDP, "Error negotiating: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.call != call {
+		return nil, nil, newVRError(SIPErrorWrongState, "Wrong state (not in a call)")
+	}
+	call.accepted = true
+	accepted := sipEvent(jwsapi.Message{"event": "accepted", "username": u.username, "call_id": call.id})
+	accepted["call_id"] = call.id
+	call.caller.handle.Event(accepted, jwsapi.Message{"type": "answer", "sdp": call.legs[call.caller].answer})
+	return sipEvent(jwsapi.Message{"event": "accepting", "call_id": call.id}), nil, nil
+}
+
+func (s *SIP) decline(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	code := 486
+	if value, ok := req.Body.Uint64("code"); ok {
+		code = int(value)
+	}
+	if code < 400 || code > 699 {
+		return nil, nil, newVRError(SIPErrorInvalidElement, "Invalid SIP response code specified")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, call, err := s.current(req.Handle)
+	if err == nil && (call.callee != u || call.accepted) {
+		err = newVRError(SIPErrorWrongState, "Wrong state (no incoming call)")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	s.endCall(call, nil, code, sipReason(code))
+	return sipEvent(jwsapi.Message{"event": "declining", "code": code}), nil, nil
+}
+
+func (s *SIP) hangup(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, call, err := s.current(req.Handle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if call.accepted {
+		s.endCall(call, nil, 200, "Session Terminated")
+	} else {
+		//CANCEL before answer
+		s.endCall(call, nil, 487, "Request Terminated")
+	}
+	return sipEvent(jwsapi.Message{"event": "hangingup"}), nil, nil
+}
+
+func (s *SIP) hold(req *Request, hold bool) (jwsapi.Message, jwsapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, call, err := s.current(req.Handle)
+	if err == nil && !call.accepted {
+		err = newVRError(SIPErrorWrongState, "Wrong state (not in a call)")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	call.held = hold
+	if hold {
+		direction, _ := req.Body.String("direction")
+		if direction == "" {
+			direction = "sendonly"
+		}
+		return sipEvent(jwsapi.Message{"event": "holding", "direction": direction}), nil, nil
+	}
+	return sipEvent(jwsapi.Message{"event": "resuming"}), nil, nil
+}
+
+func (s *SIP) dtmfInfo(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	digit, ok := req.Body.String("digit")
+	if !ok || len(digit) != 1 || !strings.Contains("0123456789*#ABCDabcd", digit) {
+		return nil, nil, newVRError(SIPErrorInvalidElement, "Invalid element (digit should be one character)")
+	}
+	duration := uint64(160)
+	if value, ok := req.Body.Uint64("duration"); ok {
+		duration = value
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, call, err := s.current(req.Handle)
+	if err == nil && !call.accepted {
+		err = newVRError(SIPErrorWrongState, "Wrong state (not in a call)")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	call.peer(u).handle.Event(sipEvent(jwsapi.Message{
+		"event":   "info",
+		"sender":  u.username,
+		"type":    "application/dtmf-relay",
+		"content": fmt.Sprintf("Signal=%s\r\nDuration=%d", digit, duration),
+	}), nil)
+	return jwsapi.Message{"sip": "event", "call_id": call.id}, nil, nil
+}
+
+func (s *SIP) transfer(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	uri, ok := req.Body.String("uri")
+	if !ok {
+		return nil, nil, newVRError(SIPErrorMissingElement, "Missing element (uri)")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, call, err := s.current(req.Handle)
+	if err == nil && !call.accepted {
+		err = newVRError(SIPErrorWrongState, "Wrong state (not in a call)")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	s.nextRef++
+	refer := jwsapi.Message{
+		"event":       "transfer",
+		"refer_id":    s.nextRef,
+		"refer_to":    uri,
+		"referred_by": u.username,
+	}
+	if replace, ok := req.Body.String("replace"); ok {
+		refer["replaces"] = replace
+	}
+	call.peer(u).handle.Event(sipEvent(refer), nil)
+	return sipEvent(jwsapi.Message{"event": "transferring"}), nil, nil
+}
+
+func sipReason(code int) string {
+	switch code {
+	case 403:
+		return "Forbidden"
+	case 404:
+		return "Not Found"
+	case 480:
+		return "Temporarily Unavailable"
+	case 486:
+		return "Busy Here"
+	case 487:
+		return "Request Terminated"
+	case 603:
+		return "Decline"
+	}
+	return "Declined"
+}
+
+func sipEvent(result jwsapi.Message) jwsapi.Message {
+	return jwsapi.Message{
+		"sip":    "event",
+		"result": result,
+	}
+}
+
+func sipError(err error) jwsapi.Message {
+	code := SIPErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"sip":        "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
@@ -0,0 +1,776 @@
+package janustest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//StreamingPlugin name of streaming plugin
+const StreamingPlugin = "janus.plugin.streaming"
+
+//streaming error code
+const (
+	StreamingErrorInvalidRequest   = 452
+	StreamingErrorMissingElement   = 453
+	StreamingErrorInvalidElement   = 454
+	StreamingErrorNoSuchMountpoint = 455
+	StreamingErrorCantCreate       = 456
+	StreamingErrorUnauthorized     = 457
+	StreamingErrorCantSwitch       = 458
+	StreamingErrorInvalidState     = 460
+	StreamingErrorUnknown          = 470
+)
+
+//synchronous requests of streaming, others are ack + event
+var streamingSyncRequests = map[string]bool{
+	"list": true, "info": true, "create": true, "destroy": true, "recording": true, "enable": true, "disable": true,
+}
+
+const stValueKey = "streaming"
+
+//Streaming fake streaming plugin
+//rtp mountpoint listen udp at 127.0.0.1 and forward rtp to viewers (pion PeerConnection, ice-lite)
+//audiortcpport/videortcpport are bound for rtcp of source, PLI is sent to source when viewer start
+//srtpsuite 80 and srtpcrypto decrypt incoming srtp, suite 32 is not supported
+//live and ondemand mountpoint are kept in memory, file is not read
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.StreamingPlugin, janustest.NewStreaming())
+type Streaming struct {
+	setting webrtc.SettingEngine
+
+	mu          sync.Mutex
+	nextID      uint64
+	mountpoints map[uint64]*stMountpoint
+}
+
+type stMountpoint struct {
+	id          uint64
+	mtype       string
+	name        string
+	description string
+	isPrivate   bool
+	secret      string
+	pin         string
+	enabled     bool
+	recording   bool
+	codecs      map[webrtc.RTPCodecType]vrCodec
+	conns       map[webrtc.RTPCodecType]*net.UDPConn
+	rtcpConns   map[webrtc.RTPCodecType]*net.UDPConn
+	rtcpPeers   map[webrtc.RTPCodecType]*net.UDPAddr //source address of rtcp, for feedback
+	ssrcs       map[webrtc.RTPCodecType]uint32       //latest ssrc of source
+	srtpKey     []byte                               //master key + salt, nil for plain rtp
+	feedback    *srtp.Context                        //encrypt rtcp feedback to source
+	packets     int
+	rtcpPackets int
+
+	viewers map[*stViewer]struct{}
+}
+
+func (mp *stMountpoint) info() jwsapi.Message {
+	info := jwsapi.Message{
+		"id":          mp.id,
+		"type":        mp.mtype,
+		"name":        mp.name,
+		"description": mp.description,
+		"is_private":  mp.isPrivate,
+		"enabled":     mp.enabled,
+		"viewers":     len(mp.viewers),
+	}
+	for kind, codec := range mp.codecs {
+		info[kind.String()] = true
+		info[kind.String()+"_codec"] = codec.name
+	}
+	for kind, conn := range mp.conns {
+		info[kind.String()+"_port"] = conn.LocalAddr().(*net.UDPAddr).Port
+	}
+	for kind, conn := range mp.rtcpConns {
+		info[kind.String()+"_rtcp_port"] = conn.LocalAddr().(*net.UDPAddr).Port
+	}
+	return info
+}
+
+type stViewer struct {
+	handle  *Handle
+	mp      *stMountpoint
+	pc      *webrtc.PeerConnection
+	tracks  map[webrtc.RTPCodecType]*webrtc.Track
+	enabled map[webrtc.RTPCodecType]bool
+	started bool
+	paused  bool
+}
+
+//StreamingOption option for Streaming
+type StreamingOption func(*Streaming)
+
+//WithStreamingSettingEngine set pion setting engine, eg: port range, lite is always true
+func WithStreamingSettingEngine(setting webrtc.SettingEngine) StreamingOption {
+	return func(st *Streaming) {
+		st.setting = setting
+	}
+}
+
+//NewStreaming create fake streaming plugin
+func NewStreaming(opts ...StreamingOption) *Streaming {
+	st := &Streaming{
+		nextID:      1 << 20,
+		mountpoints: make(map[uint64]*stMountpoint),
+	}
+	st.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(st)
+	}
+	st.setting.SetLite(true)
+	return st
+}
+
+//Mountpoints return ids of all mountpoints
+func (st *Streaming) Mountpoints() []uint64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ids := make([]uint64, 0, len(st.mountpoints))
+	for id := range st.mountpoints {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Viewers number of viewers of mountpoint
+func (st *Streaming) Viewers(id uint64) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if mp, ok := st.mountpoints[id]; ok {
+		return len(mp.viewers)
+	}
+	return 0
+}
+
+//Packets number of rtp packets received by mountpoint
+func (st *Streaming) Packets(id uint64) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if mp, ok := st.mountpoints[id]; ok {
+		return mp.packets
+	}
+	return 0
+}
+
+//RTCPPackets return count of rtcp received at rtcp ports of mountpoint
+func (st *Streaming) RTCPPackets(id uint64) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if mp, ok := st.mountpoints[id]; ok {
+		return mp.rtcpPackets
+	}
+	return 0
+}
+
+//Recording mountpoint is recording
+func (st *Streaming) Recording(id uint64) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if mp, ok := st.mountpoints[id]; ok {
+		return mp.recording
+	}
+	return false
+}
+
+//HandleMessage implements Plugin
+func (st *Streaming) HandleMessage(req *Request) {
+	name := req.Name()
+	if streamingSyncRequests[name] {
+		data, err := st.handleSync(req, name)
+		if err != nil {
+			data = streamingError(err)
+		}
+		req.Success(data)
+		return
+	}
+	req.Ack()
+	go func() {
+		data, jsep, err := st.handleAsync(req, name)
+		if err != nil {
+			data = streamingError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+		if err == nil && name == "start" {
+			req.Handle.Event(jwsapi.Message{"streaming": "event", "result": jwsapi.Message{"status": "started"}}, nil)
+		}
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (st *Streaming) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if v, ok := h.Value(stValueKey).(*stViewer); ok {
+		pc = v.pc
+	}
+	st.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (st *Streaming) HandleDetach(h *Handle) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if v, ok := h.Value(stValueKey).(*stViewer); ok {
+		st.stopViewer(v)
+	}
+	h.SetValue(stValueKey, nil)
+}
+
+func (st *Streaming) handleSync(req *Request, name string) (jwsapi.Message, error) {
+	if name == "create" {
+		return st.create(req)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if name == "list" {
+		list := make([]interface{}, 0, len(st.mountpoints))
+		for _, mp := range st.sortedMountpoints() {
+			if !mp.isPrivate {
+				list = append(list, mp.info())
+			}
+		}
+		return jwsapi.Message{"streaming": "list", "list": list}, nil
+	}
+
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		return nil, newVRError(StreamingErrorMissingElement, "Missing element (id)")
+	}
+	mp, ok := st.mountpoints[id]
+	if !ok {
+		return nil, newVRError(StreamingErrorNoSuchMountpoint, "No such mountpoint/stream %d", id)
+	}
+	if name == "info" {
+		return jwsapi.Message{"streaming": "info", "info": mp.info()}, nil
+	}
+	if secret, _ := req.Body.String("secret"); mp.secret != "" && secret != mp.secret {
+		return nil, newVRError(StreamingErrorUnauthorized, "Unauthorized (wrong secret)")
+	}
+	switch name {
+	case "destroy":
+		st.destroy(mp)
+		return jwsapi.Message{"streaming": "destroyed", "destroyed": id}, nil
+	case "enable":
+		mp.enabled = true
+	case "disable":
+		mp.enabled = false
+		for v := range mp.viewers {
+			st.stopViewer(v)
+			v.handle.Hangup("Mountpoint disabled")
+		}
+	case "recording":
+		action, _ := req.Body.String("action")
+		switch action {
+		case "start":
+			mp.recording = true
+		case "stop":
+			mp.recording = false
+		default:
+			return nil, newVRError(StreamingErrorInvalidElement, "Invalid action (should be start|stop)")
+		}
+	}
+	return jwsapi.Message{"streaming": "ok"}, nil
+}
+
+func (st *Streaming) sortedMountpoints() []*stMountpoint {
+	mps := make([]*stMountpoint, 0, len(st.mountpoints))
+	for _, mp := range st.mountpoints {
+		mps = append(mps, mp)
+	}
+	sort.Slice(mps, func(i, j int) bool { return mps[i].id < mps[j].id })
+	return mps
+}
+
+func (st *Streaming) create(req *Request) (jwsapi.Message, error) {
+	mtype, _ := req.Body.String("type")
+	mp := &stMountpoint{
+		mtype:     mtype,
+		isPrivate: req.Body.Bool("is_private"),
+		enabled:   true,
+		codecs:    make(map[webrtc.RTPCodecType]vrCodec),
+		conns:     make(map[webrtc.RTPCodecType]*net.UDPConn),
+		rtcpConns: make(map[webrtc.RTPCodecType]*net.UDPConn),
+		rtcpPeers: make(map[webrtc.RTPCodecType]*net.UDPAddr),
+		ssrcs:     make(map[webrtc.RTPCodecType]uint32),
+		viewers:   make(map[*stViewer]struct{}),
+	}
+	mp.name, _ = req.Body.String("name")
+	mp.description, _ = req.Body.String("description")
+	mp.secret, _ = req.Body.String("secret")
+	mp.pin, _ = req.Body.String("pin")
+
+	switch mtype {
+	case "rtp":
+		if _, ok := req.Body["srtpsuite"]; ok {
+			key, err := srtpMasterKey(StreamingErrorInvalidElement, req.Body, "srtpsuite", "srtpcrypto")
+			if err != nil {
+				return nil, err
+			}
+			mp.srtpKey = key
+			if mp.feedback, err = mp.srtpContext(); err != nil {
+				return nil, newVRError(StreamingErrorInvalidElement, "Invalid element (srtpcrypto)")
+			}
+		}
+		for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+			if !req.Body.Bool(kind.String()) {
+				continue
+			}
+			codec, err := rtpmapCodec(kind, req.Body)
+			if err != nil {
+				mp.close()
+				return nil, err
+			}
+			port, _ := req.Body.Uint64(kind.String() + "port")
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)})
+			if err != nil {
+				mp.close()
+				return nil, newVRError(StreamingErrorCantCreate, "Can't bind %s port %d: %v", kind, port, err)
+			}
+			mp.codecs[kind] = codec
+			mp.conns[kind] = conn
+			if port, ok := req.Body.Uint64(kind.String() + "rtcpport"); ok {
+				conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)})
+				if err != nil {
+					mp.close()
+					return nil, newVRError(StreamingErrorCantCreate, "Can't bind %s rtcp port %d: %v", kind, port, err)
+				}
+				mp.rtcpConns[kind] = conn
+			}
+		}
+		if len(mp.codecs) == 0 {
+			return nil, newVRError(StreamingErrorCantCreate, "Can't add 'rtp' stream, no audio or video have to be streamed...")
+		}
+	case "live", "ondemand":
+		if filename, _ := req.Body.String("filename"); filename == "" {
+			return nil, newVRError(StreamingErrorMissingElement, "Missing element (filename)")
+		}
+		if req.Body.Bool("audio") {
+			mp.codecs[webrtc.RTPCodecTypeAudio] = vrCodec{kind: webrtc.RTPCodecTypeAudio, name: "pcmu", pt: 0, clockRate: 8000}
+		}
+	default:
+		return nil, newVRError(StreamingErrorInvalidElement, "Invalid element (type)")
+	}
+
+	st.mu.Lock()
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		st.nextID++
+		id = st.nextID
+	}
+	if _, exists := st.mountpoints[id]; exists {
+		st.mu.Unlock()
+		mp.close()
+		return nil, newVRError(StreamingErrorCantCreate, "A stream with the provided ID already exists")
+	}
+	mp.id = id
+	if mp.name == "" {
+		mp.name = fmt.Sprintf("%d", id)
+	}
+	if mp.description == "" {
+		mp.description = mp.name
+	}
+	st.mountpoints[id] = mp
+	info := mp.info()
+	st.mu.Unlock()
+
+	for kind, conn := range mp.conns {
+		go st.relay(mp, kind, conn)
+	}
+	for kind, conn := range mp.rtcpConns {
+		go st.readRTCP(mp, kind, conn)
+	}
+	return jwsapi.Message{
+		"streaming": "created",
+		"created":   mp.name,
+		"permanent": false,
+		"stream":    info,
+	}, nil
+}
+
+//rtpmapCodec codec of audiopt, audiortpmap or videopt, videortpmap
+func rtpmapCodec(kind webrtc.RTPCodecType, body jwsapi.Message) (vrCodec, error) {
+	pt, ok := body.Uint64(kind.String() + "pt")
+	if !ok {
+		return vrCodec{}, newVRError(StreamingErrorMissingElement, "Missing element (%spt)", kind)
+	}
+	rtpmap, _ := body.String(kind.String() + "rtpmap")
+	parts := strings.Split(rtpmap, "/")
+	name := strings.ToLower(parts[0])
+	if _, ok := codecFactories[name]; !ok || len(parts) < 2 {
+		return vrCodec{}, newVRError(StreamingErrorInvalidElement, "Invalid element (%srtpmap)", kind)
+	}
+	clockRate, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return vrCodec{}, newVRError(StreamingErrorInvalidElement, "Invalid element (%srtpmap)", kind)
+	}
+	return vrCodec{kind: kind, name: name, pt: uint8(pt), clockRate: uint32(clockRate)}, nil
+}
+
+//srtpMasterKey master key and salt of crypto, suiteKey and cryptoKey are names of plugin, eg: srtpsuite, srtpcrypto
+func srtpMasterKey(code int, body jwsapi.Message, suiteKey string, cryptoKey string) ([]byte, error) {
+	if suite, _ := body.Uint64(suiteKey); suite != 80 {
+		return nil, newVRError(code, "Invalid element (%s), only 80 is supported", suiteKey)
+	}
+	crypto, _ := body.String(cryptoKey)
+	key, err := base64.StdEncoding.DecodeString(crypto)
+	if err != nil || len(key) != 30 {
+		return nil, newVRError(code, "Invalid element (%s)", cryptoKey)
+	}
+	return key, nil
+}
+
+//srtpContext new srtp context for one direction, nil if not srtp
+func (mp *stMountpoint) srtpContext() (*srtp.Context, error) {
+	if mp.srtpKey == nil {
+		return nil, nil
+	}
+	return srtp.CreateContext(mp.srtpKey[:16], mp.srtpKey[16:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+}
+
+func (mp *stMountpoint) close() {
+	for kind, conn := range mp.conns {
+		conn.Close()
+		delete(mp.conns, kind)
+	}
+	for kind, conn := range mp.rtcpConns {
+		conn.Close()
+		delete(mp.rtcpConns, kind)
+	}
+}
+
+//destroy lock by caller
+func (st *Streaming) destroy(mp *stMountpoint) {
+	for v := range mp.viewers {
+		st.stopViewer(v)
+		v.handle.Hangup("Mountpoint destroyed")
+	}
+	mp.close()
+	delete(st.mountpoints, mp.id)
+}
+
+//relay read rtp from udp, write to viewers
+func (st *Streaming) relay(mp *stMountpoint, kind webrtc.RTPCodecType, conn *net.UDPConn) {
+	decrypt, err := mp.srtpContext()
+	if err != nil {
+		return
+	}
+	buf := make([]byte, 1500)
+	var targets []*webrtc.Track
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		data := buf[:n]
+		if decrypt != nil {
+			if data, err = decrypt.DecryptRTP(nil, data, nil); err != nil {
+				continue
+			}
+		}
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(data); err != nil {
+			continue
+		}
+		st.mu.Lock()
+		mp.packets++
+		mp.ssrcs[kind] = packet.SSRC
+		targets = targets[:0]
+		if mp.enabled {
+			for v := range mp.viewers {
+				if v.started && !v.paused && v.enabled[kind] {
+					if t, ok := v.tracks[kind]; ok {
+						targets = append(targets, t)
+					}
+				}
+			}
+		}
+		st.mu.Unlock()
+
+		for _, t := range targets {
+			out := *packet
+			out.SSRC = t.SSRC()
+			out.PayloadType = t.PayloadType()
+			t.WriteRTP(&out)
+		}
+	}
+}
+
+//readRTCP read rtcp of source, keep address of source for feedback
+func (st *Streaming) readRTCP(mp *stMountpoint, kind webrtc.RTPCodecType, conn *net.UDPConn) {
+	decrypt, err := mp.srtpContext()
+	if err != nil {
+		return
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		data := buf[:n]
+		if decrypt != nil {
+			if data, err = decrypt.DecryptRTCP(nil, data, nil); err != nil {
+				continue
+			}
+		}
+		if _, err := rtcp.Unmarshal(data); err != nil {
+			continue
+		}
+		st.mu.Lock()
+		mp.rtcpPackets++
+		mp.rtcpPeers[kind] = addr
+		st.mu.Unlock()
+	}
+}
+
+//requestKeyFrame send PLI to source of video, lock by caller
+func (mp *stMountpoint) requestKeyFrame() {
+	conn, addr, ssrc := mp.rtcpConns[webrtc.RTPCodecTypeVideo], mp.rtcpPeers[webrtc.RTPCodecTypeVideo], mp.ssrcs[webrtc.RTPCodecTypeVideo]
+	if conn == nil || addr == nil {
+		return
+	}
+	data, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+	if err != nil {
+		return
+	}
+	if mp.feedback != nil {
+		if data, err = mp.feedback.EncryptRTCP(nil, data, nil); err != nil {
+			return
+		}
+	}
+	conn.WriteToUDP(data, addr)
+}
+
+func (st *Streaming) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	if name == "watch" {
+		return st.watch(req)
+	}
+	st.mu.Lock()
+	v, _ := req.Handle.Value(stValueKey).(*stViewer)
+	st.mu.Unlock()
+	if v == nil {
+		return nil, nil, newVRError(StreamingErrorInvalidState, "Can't %s: not watching", name)
+	}
+	switch name {
+	case "start":
+		return st.start(v, req)
+	case "pause":
+		st.mu.Lock()
+		v.paused = true
+		st.mu.Unlock()
+		return streamingStatus("pausing"), nil, nil
+	case "configure":
+		st.mu.Lock()
+		for _, key := range []string{"audio", "video"} {
+			if _, ok := req.Body[key]; ok {
+				v.enabled[webrtc.NewRTPCodecType(key)] = req.Body.Bool(key)
+			}
+		}
+		st.mu.Unlock()
+		return jwsapi.Message{"streaming": "event", "result": "ok"}, nil, nil
+	case "switch":
+		return st.switchViewer(v, req)
+	case "stop":
+		st.mu.Lock()
+		st.stopViewer(v)
+		req.Handle.SetValue(stValueKey, nil)
+		st.mu.Unlock()
+		return streamingStatus("stopping"), nil, nil
+	}
+	return nil, nil, newVRError(StreamingErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+func (st *Streaming) watch(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		return nil, nil, newVRError(StreamingErrorMissingElement, "Missing element (id)")
+	}
+	st.mu.Lock()
+	if _, ok := req.Handle.Value(stValueKey).(*stViewer); ok {
+		st.mu.Unlock()
+		return nil, nil, newVRError(StreamingErrorInvalidState, "Already watching a stream")
+	}
+	mp, ok := st.mountpoints[id]
+	if !ok {
+		st.mu.Unlock()
+		return nil, nil, newVRError(StreamingErrorNoSuchMountpoint, "No such mountpoint/stream %d", id)
+	}
+	if pin, _ := req.Body.String("pin"); mp.pin != "" && pin != mp.pin {
+		st.mu.Unlock()
+		return nil, nil, newVRError(StreamingErrorUnauthorized, "Unauthorized (wrong pin)")
+	}
+	if !mp.enabled {
+		st.mu.Unlock()
+		return nil, nil, newVRError(StreamingErrorUnauthorized, "Stream not enabled")
+	}
+	codecs := make(map[webrtc.RTPCodecType]vrCodec, len(mp.codecs))
+	for kind, codec := range mp.codecs {
+		if _, ok := req.Body[kind.String()]; ok && !req.Body.Bool(kind.String()) {
+			continue
+		}
+		codecs[kind] = codec
+	}
+	st.mu.Unlock()
+	if len(codecs) == 0 {
+		return nil, nil, newVRError(StreamingErrorInvalidElement, "No media to watch")
+	}
+
+	v := &stViewer{
+		handle:  req.Handle,
+		mp:      mp,
+		tracks:  make(map[webrtc.RTPCodecType]*webrtc.Track),
+		enabled: make(map[webrtc.RTPCodecType]bool),
+	}
+	pc, err := newPeerConnection(st.setting, codecs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		codec, ok := codecs[kind]
+		if !ok {
+			continue
+		}
+		label := fmt.Sprintf("janus%d", id)
+		track, err := pc.NewTrack(codec.pt, rand.Uint32(), kind.String()+label, label)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "NewTrack")
+		}
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "AddTrack")
+		}
+		go drainRTCP(sender, nil)
+		v.tracks[kind] = track
+		v.enabled[kind] = true
+	}
+	watchPeerConnection(req.Handle, pc)
+
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "CreateOffer")
+	}
+
+	st.mu.Lock()
+	if st.mountpoints[id] != mp {
+		st.mu.Unlock()
+		pc.Close()
+		return nil, nil, newVRError(StreamingErrorNoSuchMountpoint, "No such mountpoint/stream %d", id)
+	}
+	v.pc = pc
+	mp.viewers[v] = struct{}{}
+	req.Handle.SetValue(stValueKey, v)
+	st.mu.Unlock()
+
+	return streamingStatus("preparing"), jwsapi.Message{
+		"type": "offer",
+		"sdp":  pc.LocalDescription().SDP,
+	}, nil
+}
+
+func (st *Streaming) start(v *stViewer, req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	st.mu.Lock()
+	pc := v.pc
+	st.mu.Unlock()
+	if pc == nil {
+		return nil, nil, newVRError(StreamingErrorInvalidState, "Can't start: stream stopped")
+	}
+	if req.JSEP != nil {
+		if jtype, _ := req.JSEP.String("type"); jtype != "answer" {
+			return nil, nil, newVRError(StreamingErrorInvalidElement, "Unsupported SDP type '%s'", jtype)
+		}
+		answer, _ := req.JSEP.String("sdp")
+		err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer})
+		if err != nil {
+			return nil, nil, newVRError(StreamingErrorInvalidElement, "Error negotiating: %v", err)
+		}
+	}
+	st.mu.Lock()
+	v.started = true
+	v.paused = false
+	v.mp.requestKeyFrame()
+	st.mu.Unlock()
+	return streamingStatus("starting"), nil, nil
+}
+
+func (st *Streaming) switchViewer(v *stViewer, req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	id, ok := req.Body.Uint64("id")
+	if !ok {
+		return nil, nil, newVRError(StreamingErrorMissingElement, "Missing element (id)")
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	mp, ok := st.mountpoints[id]
+	if !ok {
+		return nil, nil, newVRError(StreamingErrorNoSuchMountpoint, "No such mountpoint/stream %d", id)
+	}
+	for kind, track := range v.tracks {
+		codec, ok := mp.codecs[kind]
+		if !ok || !strings.EqualFold(codec.name, track.Codec().Name) {
+			return nil, nil, newVRError(StreamingErrorCantSwitch, "Can't switch to mountpoint %d, codecs are different", id)
+		}
+	}
+	delete(v.mp.viewers, v)
+	v.mp = mp
+	mp.viewers[v] = struct{}{}
+	return jwsapi.Message{"streaming": "event", "switched": "ok", "id": id}, nil, nil
+}
+
+//stopViewer lock by caller
+func (st *Streaming) stopViewer(v *stViewer) {
+	delete(v.mp.viewers, v)
+	v.started = false
+	if v.pc != nil {
+		go v.pc.Close()
+		v.pc = nil
+	}
+}
+
+func streamingStatus(status string) jwsapi.Message {
+	return jwsapi.Message{"streaming": "event", "result": jwsapi.Message{"status": status}}
+}
+
+func streamingError(err error) jwsapi.Message {
+	code := StreamingErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"streaming":  "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
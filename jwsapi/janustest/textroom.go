@@ -0,0 +1,568 @@
+package janustest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//TextRoomPlugin name of textroom plugin
+const TextRoomPlugin = "janus.plugin.textroom"
+
+//textroom error code
+const (
+	TextRoomErrorInvalidJSON    = 412
+	TextRoomErrorMissingElement = 413
+	TextRoomErrorInvalidElement = 414
+	TextRoomErrorInvalidRequest = 415
+	TextRoomErrorAlreadySetup   = 416
+	TextRoomErrorNoSuchRoom     = 417
+	TextRoomErrorRoomExists     = 418
+	TextRoomErrorUnauthorized   = 419
+	TextRoomErrorUsernameExists = 420
+	TextRoomErrorAlreadyInRoom  = 421
+	TextRoomErrorNotInRoom      = 422
+	TextRoomErrorNoSuchUser     = 423
+	TextRoomErrorUnknown        = 499
+)
+
+//synchronous requests of textroom by janus api, setup and ack are ack + event
+var textRoomSyncRequests = map[string]bool{
+	"create": true, "edit": true, "destroy": true, "exists": true, "list": true, "listparticipants": true,
+	"allowed": true, "kick": true, "announcement": true,
+}
+
+const trValueKey = "textroom"
+
+//TextRoom fake textroom plugin, rooms in memory
+//setup offer data channel (pion PeerConnection, ice-lite), ack set answer
+//join, message, announcement, leave are handled over data channel with transaction
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.TextRoomPlugin, janustest.NewTextRoom())
+type TextRoom struct {
+	setting webrtc.SettingEngine
+
+	mu     sync.Mutex
+	nextID uint64
+	rooms  map[uint64]*trRoom
+}
+
+type trRoom struct {
+	id           uint64
+	description  string
+	secret       string
+	pin          string
+	isPrivate    bool
+	checkAllowed bool
+	allowed      map[string]bool
+	participants map[string]*trParticipant
+}
+
+type trParticipant struct {
+	conn     *trConn
+	username string
+	display  string
+}
+
+func (p *trParticipant) info() jwsapi.Message {
+	info := jwsapi.Message{"username": p.username}
+	if p.display != "" {
+		info["display"] = p.display
+	}
+	return info
+}
+
+//trConn PeerConnection and data channel of handle
+type trConn struct {
+	handle *Handle
+	pc     *webrtc.PeerConnection
+	dc     *webrtc.DataChannel
+	rooms  map[uint64]*trParticipant
+}
+
+//send text to data channel, dropped if not open
+func (c *trConn) send(msg jwsapi.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.dc.SendText(string(data))
+}
+
+//notify send to all participants of room except one, lock by caller
+func (r *trRoom) notify(except *trParticipant, msg jwsapi.Message) {
+	for _, p := range r.participants {
+		if p != except {
+			p.conn.send(msg)
+		}
+	}
+}
+
+func (r *trRoom) list(except *trParticipant) []interface{} {
+	names := make([]string, 0, len(r.participants))
+	for name := range r.participants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		if p := r.participants[name]; p != except {
+			list = append(list, p.info())
+		}
+	}
+	return list
+}
+
+//TextRoomOption option for TextRoom
+type TextRoomOption func(*TextRoom)
+
+//WithTextRoomSettingEngine set pion setting engine, eg: port range, lite is always true
+func WithTextRoomSettingEngine(setting webrtc.SettingEngine) TextRoomOption {
+	return func(tr *TextRoom) {
+		tr.setting = setting
+	}
+}
+
+//NewTextRoom create fake textroom plugin
+func NewTextRoom(opts ...TextRoomOption) *TextRoom {
+	tr := &TextRoom{
+		nextID: 1 << 20,
+		rooms:  make(map[uint64]*trRoom),
+	}
+	tr.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(tr)
+	}
+	tr.setting.SetLite(true)
+	return tr
+}
+
+//Rooms return ids of all rooms
+func (tr *TextRoom) Rooms() []uint64 {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	ids := make([]uint64, 0, len(tr.rooms))
+	for id := range tr.rooms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Participants return usernames of participants in room
+func (tr *TextRoom) Participants(room uint64) []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	r, ok := tr.rooms[room]
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(r.participants))
+	for name := range r.participants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//HandleMessage implements Plugin
+func (tr *TextRoom) HandleMessage(req *Request) {
+	name := req.Name()
+	if textRoomSyncRequests[name] {
+		tr.mu.Lock()
+		data, err := tr.handleRequest(nil, name, req.Body)
+		tr.mu.Unlock()
+		if err != nil {
+			data = textRoomError(err)
+		}
+		req.Success(data)
+		return
+	}
+	req.Ack()
+	go func() {
+		var jsep jwsapi.Message
+		var err error
+		switch name {
+		case "setup":
+			jsep, err = tr.setup(req)
+		case "ack":
+			err = tr.ack(req)
+		default:
+			err = newVRError(TextRoomErrorInvalidRequest, "Unknown request '%s'", name)
+		}
+		if err != nil {
+			req.Event(textRoomError(err), nil)
+			return
+		}
+		req.Event(jwsapi.Message{"textroom": "event", "result": "ok"}, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (tr *TextRoom) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	tr.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if c, ok := h.Value(trValueKey).(*trConn); ok {
+		pc = c.pc
+	}
+	tr.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (tr *TextRoom) HandleDetach(h *Handle) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if c, ok := h.Value(trValueKey).(*trConn); ok {
+		tr.hangup(c)
+	}
+	h.SetValue(trValueKey, nil)
+}
+
+//hangup leave all rooms and close PeerConnection, lock by caller
+func (tr *TextRoom) hangup(c *trConn) {
+	for room, p := range c.rooms {
+		if r, ok := tr.rooms[room]; ok {
+			delete(r.participants, p.username)
+			r.notify(nil, jwsapi.Message{"textroom": "leave", "room": room, "username": p.username})
+		}
+	}
+	c.rooms = make(map[uint64]*trParticipant)
+	go c.pc.Close()
+}
+
+func (tr *TextRoom) setup(req *Request) (jwsapi.Message, error) {
+	tr.mu.Lock()
+	_, exists := req.Handle.Value(trValueKey).(*trConn)
+	tr.mu.Unlock()
+	if exists {
+		return nil, newVRError(TextRoomErrorAlreadySetup, "PeerConnection already setup")
+	}
+	pc, err := newPeerConnection(tr.setting, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	c := &trConn{
+		handle: req.Handle,
+		pc:     pc,
+		rooms:  make(map[uint64]*trParticipant),
+	}
+	dc, err := pc.CreateDataChannel("JanusDataChannel", nil)
+	if err != nil {
+		pc.Close()
+		return nil, errors.Wrap(err, "CreateDataChannel")
+	}
+	c.dc = dc
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		tr.onData(c, msg.Data)
+	})
+	watchPeerConnection(req.Handle, pc)
+
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, errors.Wrap(err, "CreateOffer")
+	}
+	tr.mu.Lock()
+	req.Handle.SetValue(trValueKey, c)
+	tr.mu.Unlock()
+	return jwsapi.Message{"type": "offer", "sdp": pc.LocalDescription().SDP}, nil
+}
+
+func (tr *TextRoom) ack(req *Request) error {
+	tr.mu.Lock()
+	c, ok := req.Handle.Value(trValueKey).(*trConn)
+	tr.mu.Unlock()
+	if !ok {
+		return newVRError(TextRoomErrorInvalidRequest, "PeerConnection not setup")
+	}
+	if req.JSEP == nil {
+		return newVRError(TextRoomErrorMissingElement, "Missing SDP")
+	}
+	if jtype, _ := req.JSEP.String("type"); jtype != "answer" {
+		return newVRError(TextRoomErrorInvalidElement, "Unsupported SDP type '%s'", jtype)
+	}
+	answer, _ := req.JSEP.String("sdp")
+	if err := c.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		return newVRError(TextRoomErrorInvalidElement, "Error negotiating: %v", err)
+	}
+	return nil
+}
+
+//onData request over data channel, response with transaction
+func (tr *TextRoom) onData(c *trConn, data []byte) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	body := jwsapi.Message{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		c.send(textRoomError(newVRError(TextRoomErrorInvalidJSON, "JSON error: %v", err)))
+		return
+	}
+	tid, _ := body.String("transaction")
+	name, _ := body.String("textroom")
+	rsp, err := tr.handleRequest(c, name, body)
+	if err != nil {
+		rsp = textRoomError(err)
+	}
+	if tid == "" {
+		return
+	}
+	rsp["transaction"] = tid
+	if _, ok := body["ack"]; ok && !body.Bool("ack") && err == nil {
+		return
+	}
+	c.send(rsp)
+}
+
+//handleRequest request of janus api (c is nil) or data channel, lock by caller
+func (tr *TextRoom) handleRequest(c *trConn, name string, body jwsapi.Message) (jwsapi.Message, error) {
+	switch name {
+	case "create":
+		return tr.create(body)
+	case "list":
+		ids := make([]uint64, 0, len(tr.rooms))
+		for id := range tr.rooms {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		list := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			r := tr.rooms[id]
+			if r.isPrivate {
+				continue
+			}
+			list = append(list, jwsapi.Message{
+				"room":             r.id,
+				"description":      r.description,
+				"pin_required":     r.pin != "",
+				"num_participants": len(r.participants),
+			})
+		}
+		return jwsapi.Message{"textroom": "success", "list": list}, nil
+	}
+
+	room, ok := body.Uint64("room")
+	if !ok {
+		return nil, newVRError(TextRoomErrorMissingElement, "Missing element (room)")
+	}
+	r, ok := tr.rooms[room]
+	if name == "exists" {
+		return jwsapi.Message{"textroom": "success", "room": room, "exists": ok}, nil
+	}
+	if !ok {
+		return nil, newVRError(TextRoomErrorNoSuchRoom, "No such room (%d)", room)
+	}
+
+	switch name {
+	case "listparticipants":
+		return jwsapi.Message{"textroom": "success", "room": room, "participants": r.list(nil)}, nil
+	case "join":
+		return tr.join(c, r, body)
+	case "message":
+		return tr.message(c, r, body)
+	case "leave":
+		if c == nil {
+			break
+		}
+		p, ok := c.rooms[room]
+		if !ok {
+			return nil, newVRError(TextRoomErrorNotInRoom, "Not in room %d", room)
+		}
+		delete(c.rooms, room)
+		delete(r.participants, p.username)
+		r.notify(nil, jwsapi.Message{"textroom": "leave", "room": room, "username": p.username})
+		return jwsapi.Message{"textroom": "success"}, nil
+	}
+
+	if secret, _ := body.String("secret"); r.secret != "" && secret != r.secret {
+		return nil, newVRError(TextRoomErrorUnauthorized, "Unauthorized (wrong secret)")
+	}
+	switch name {
+	case "edit":
+		if description, ok := body.String("new_description"); ok {
+			r.description = description
+		}
+		if secret, ok := body.String("new_secret"); ok {
+			r.secret = secret
+		}
+		if pin, ok := body.String("new_pin"); ok {
+			r.pin = pin
+		}
+		if _, ok := body["new_is_private"]; ok {
+			r.isPrivate = body.Bool("new_is_private")
+		}
+		return jwsapi.Message{"textroom": "success", "room": room}, nil
+	case "destroy":
+		r.notify(nil, jwsapi.Message{"textroom": "destroyed", "room": room})
+		for _, p := range r.participants {
+			delete(p.conn.rooms, room)
+		}
+		delete(tr.rooms, room)
+		return jwsapi.Message{"textroom": "success", "room": room}, nil
+	case "announcement":
+		text, _ := body.String("text")
+		if text == "" {
+			return nil, newVRError(TextRoomErrorMissingElement, "Missing element (text)")
+		}
+		r.notify(nil, jwsapi.Message{"textroom": "announcement", "room": room, "date": textRoomDate(), "text": text})
+		return jwsapi.Message{"textroom": "success"}, nil
+	case "kick":
+		username, _ := body.String("username")
+		p, ok := r.participants[username]
+		if !ok {
+			return nil, newVRError(TextRoomErrorNoSuchUser, "No such participant %s in room %d", username, room)
+		}
+		r.notify(nil, jwsapi.Message{"textroom": "kicked", "room": room, "username": username})
+		delete(r.participants, username)
+		delete(p.conn.rooms, room)
+		return jwsapi.Message{"textroom": "success"}, nil
+	case "allowed":
+		action, _ := body.String("action")
+		switch action {
+		case "enable", "disable":
+			r.checkAllowed = action == "enable"
+		case "add", "remove":
+			for _, token := range body.Array("allowed") {
+				if s, ok := token.(string); ok {
+					if action == "add" {
+						r.allowed[s] = true
+					} else {
+						delete(r.allowed, s)
+					}
+				}
+			}
+		default:
+			return nil, newVRError(TextRoomErrorInvalidElement, "Unsupported action '%s' (allowed)", action)
+		}
+		return jwsapi.Message{"textroom": "success", "room": room}, nil
+	}
+	return nil, newVRError(TextRoomErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+//create lock by caller
+func (tr *TextRoom) create(body jwsapi.Message) (jwsapi.Message, error) {
+	room, ok := body.Uint64("room")
+	if !ok {
+		tr.nextID++
+		room = tr.nextID
+	}
+	if _, exists := tr.rooms[room]; exists {
+		return nil, newVRError(TextRoomErrorRoomExists, "Room %d already exists", room)
+	}
+	r := &trRoom{
+		id:           room,
+		isPrivate:    body.Bool("is_private"),
+		allowed:      make(map[string]bool),
+		participants: make(map[string]*trParticipant),
+	}
+	r.description, _ = body.String("description")
+	if r.description == "" {
+		r.description = fmt.Sprintf("Room %d", room)
+	}
+	r.secret, _ = body.String("secret")
+	r.pin, _ = body.String("pin")
+	tr.rooms[room] = r
+	return jwsapi.Message{"textroom": "success", "room": room, "permanent": false}, nil
+}
+
+//join lock by caller
+func (tr *TextRoom) join(c *trConn, r *trRoom, body jwsapi.Message) (jwsapi.Message, error) {
+	if c == nil {
+		return nil, newVRError(TextRoomErrorInvalidRequest, "Unknown request 'join'")
+	}
+	if pin, _ := body.String("pin"); r.pin != "" && pin != r.pin {
+		return nil, newVRError(TextRoomErrorUnauthorized, "Unauthorized (wrong pin)")
+	}
+	if token, _ := body.String("token"); r.checkAllowed && !r.allowed[token] {
+		return nil, newVRError(TextRoomErrorUnauthorized, "Unauthorized (not in the allowed list)")
+	}
+	if _, ok := c.rooms[r.id]; ok {
+		return nil, newVRError(TextRoomErrorAlreadyInRoom, "Already in room %d", r.id)
+	}
+	username, _ := body.String("username")
+	if username == "" {
+		return nil, newVRError(TextRoomErrorMissingElement, "Missing element (username)")
+	}
+	if _, ok := r.participants[username]; ok {
+		return nil, newVRError(TextRoomErrorUsernameExists, "Username '%s' already taken", username)
+	}
+	p := &trParticipant{conn: c, username: username}
+	p.display, _ = body.String("display")
+	r.notify(nil, jwsapi.Message{"textroom": "join", "room": r.id, "username": username, "display": p.display})
+	r.participants[username] = p
+	c.rooms[r.id] = p
+	return jwsapi.Message{"textroom": "success", "participants": r.list(p)}, nil
+}
+
+//message lock by caller
+func (tr *TextRoom) message(c *trConn, r *trRoom, body jwsapi.Message) (jwsapi.Message, error) {
+	if c == nil {
+		return nil, newVRError(TextRoomErrorInvalidRequest, "Unknown request 'message'")
+	}
+	p, ok := c.rooms[r.id]
+	if !ok {
+		return nil, newVRError(TextRoomErrorNotInRoom, "Not in room %d", r.id)
+	}
+	text, _ := body.String("text")
+	if text == "" {
+		return nil, newVRError(TextRoomErrorMissingElement, "Missing element (text)")
+	}
+	var tos []string
+	if to, ok := body.String("to"); ok {
+		tos = append(tos, to)
+	}
+	for _, to := range body.Array("tos") {
+		if s, ok := to.(string); ok {
+			tos = append(tos, s)
+		}
+	}
+	event := jwsapi.Message{"textroom": "message", "room": r.id, "from": p.username, "date": textRoomDate(), "text": text, "whisper": len(tos) > 0}
+	if len(tos) == 0 {
+		r.notify(nil, event)
+	} else {
+		for _, to := range tos {
+			if target, ok := r.participants[to]; ok {
+				target.conn.send(event)
+			}
+		}
+	}
+	return jwsapi.Message{"textroom": "success"}, nil
+}
+
+func textRoomDate() string {
+	return time.Now().Format("2006-01-02T15:04:05-0700")
+}
+
+func textRoomError(err error) jwsapi.Message {
+	code := TextRoomErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"textroom":   "error",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
@@ -0,0 +1,494 @@
+package janustest
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//VideoCallPlugin name of videocall plugin
+const VideoCallPlugin = "janus.plugin.videocall"
+
+//videocall error code
+const (
+	VideoCallErrorInvalidRequest    = 472
+	VideoCallErrorRegisterFirst     = 473
+	VideoCallErrorInvalidElement    = 474
+	VideoCallErrorMissingElement    = 475
+	VideoCallErrorUsernameTaken     = 476
+	VideoCallErrorAlreadyRegistered = 477
+	VideoCallErrorNoSuchUsername    = 478
+	VideoCallErrorUseEchoTest       = 479
+	VideoCallErrorAlreadyInCall     = 480
+	VideoCallErrorNoCall            = 481
+	VideoCallErrorMissingSDP        = 482
+	VideoCallErrorInvalidSDP        = 483
+	VideoCallErrorUnknown           = 499
+)
+
+const videoCallValueKey = "videocall"
+
+//VideoCall fake videocall plugin, 1:1 call between registered usernames
+//caller and callee negotiate with pion PeerConnection(ice-lite), audio and video are relayed, PLI is forwarded
+//call to busy username is hangup with User busy
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.VideoCallPlugin, janustest.NewVideoCall())
+type VideoCall struct {
+	setting webrtc.SettingEngine
+
+	mu    sync.Mutex
+	users map[string]*vcUser //registered
+}
+
+type vcUser struct {
+	handle   *Handle
+	username string
+	audio    bool //relay audio of user to peer
+	video    bool //relay video of user to peer
+	call     *vcCall
+}
+
+type vcCall struct {
+	caller   *vcUser
+	callee   *vcUser
+	codecs   map[webrtc.RTPCodecType]vrCodec
+	legs     map[*vcUser]*vcLeg
+	accepted bool
+}
+
+//vcLeg PeerConnection between the fake and user
+type vcLeg struct {
+	pc     *webrtc.PeerConnection
+	tracks map[webrtc.RTPCodecType]*webrtc.Track
+	ssrcs  map[webrtc.RTPCodecType]uint32 //ssrc of user
+	answer string                         //answer to caller, sent when accepted
+}
+
+//peer other user of call
+func (c *vcCall) peer(u *vcUser) *vcUser {
+	if c.caller == u {
+		return c.callee
+	}
+	return c.caller
+}
+
+//VideoCallOption option for VideoCall
+type VideoCallOption func(*VideoCall)
+
+//WithVideoCallSettingEngine set pion setting engine, ice-lite is always enabled
+func WithVideoCallSettingEngine(setting webrtc.SettingEngine) VideoCallOption {
+	return func(vc *VideoCall) {
+		vc.setting = setting
+	}
+}
+
+//NewVideoCall create fake videocall plugin
+func NewVideoCall(opts ...VideoCallOption) *VideoCall {
+	vc := &VideoCall{
+		users: make(map[string]*vcUser),
+	}
+	vc.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(vc)
+	}
+	vc.setting.SetLite(true)
+	return vc
+}
+
+//Registered registered usernames, sorted
+func (vc *VideoCall) Registered() []string {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.list()
+}
+
+func (vc *VideoCall) list() []string {
+	usernames := make([]string, 0, len(vc.users))
+	for username := range vc.users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
+//HandleMessage implements Plugin
+func (vc *VideoCall) HandleMessage(req *Request) {
+	req.Ack()
+	go func() {
+		data, jsep, err := vc.handleAsync(req, req.Name())
+		if err != nil {
+			data = videoCallError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (vc *VideoCall) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	vc.mu.Lock()
+	var pc *webrtc.PeerConnection
+	if u, ok := h.Value(videoCallValueKey).(*vcUser); ok && u.call != nil {
+		if leg, ok := u.call.legs[u]; ok {
+			pc = leg.pc
+		}
+	}
+	vc.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (vc *VideoCall) HandleDetach(h *Handle) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if u, ok := h.Value(videoCallValueKey).(*vcUser); ok {
+		if u.call != nil {
+			vc.endCall(u.call, u, "Remote hangup")
+		}
+		if vc.users[u.username] == u {
+			delete(vc.users, u.username)
+		}
+	}
+	h.SetValue(videoCallValueKey, nil)
+}
+
+func (vc *VideoCall) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	switch name {
+	case "list":
+		vc.mu.Lock()
+		defer vc.mu.Unlock()
+		return videoCallEvent(jwsapi.Message{"list": vc.list()}), nil, nil
+	case "register":
+		return vc.register(req)
+	case "call":
+		return vc.call(req)
+	case "accept":
+		return vc.accept(req)
+	case "set":
+		return vc.set(req)
+	case "hangup":
+		return vc.hangup(req)
+	}
+	return nil, nil, newVRError(VideoCallErrorInvalidRequest, "Unknown request (%s)", name)
+}
+
+//registered user of handle, lock by caller
+func (vc *VideoCall) registered(h *Handle) (*vcUser, error) {
+	u, ok := h.Value(videoCallValueKey).(*vcUser)
+	if !ok {
+		return nil, newVRError(VideoCallErrorRegisterFirst, "Register a username first")
+	}
+	return u, nil
+}
+
+func (vc *VideoCall) register(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	username, ok := req.Body.String("username")
+	if !ok || username == "" {
+		return nil, nil, newVRError(VideoCallErrorMissingElement, "Missing element (username)")
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if u, ok := req.Handle.Value(videoCallValueKey).(*vcUser); ok {
+		return nil, nil, newVRError(VideoCallErrorAlreadyRegistered, "Already registered (%s)", u.username)
+	}
+	if _, ok := vc.users[username]; ok {
+		return nil, nil, newVRError(VideoCallErrorUsernameTaken, "Username '%s' already taken", username)
+	}
+	u := &vcUser{handle: req.Handle, username: username, audio: true, video: true}
+	vc.users[username] = u
+	req.Handle.SetValue(videoCallValueKey, u)
+	return videoCallEvent(jwsapi.Message{"event": "registered", "username": username}), nil, nil
+}
+
+func (vc *VideoCall) call(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	username, ok := req.Body.String("username")
+	if !ok || username == "" {
+		return nil, nil, newVRError(VideoCallErrorMissingElement, "Missing element (username)")
+	}
+
+	vc.mu.Lock()
+	caller, err := vc.registered(req.Handle)
+	if err != nil {
+		vc.mu.Unlock()
+		return nil, nil, err
+	}
+	callee, ok := vc.users[username]
+	switch {
+	case caller.call != nil:
+		err = newVRError(VideoCallErrorAlreadyInCall, "Already in a call")
+	case !ok:
+		err = newVRError(VideoCallErrorNoSuchUsername, "Username '%s' doesn't exist", username)
+	case callee == caller:
+		err = newVRError(VideoCallErrorUseEchoTest, "You can't call yourself... use the EchoTest for that")
+	case callee.call != nil:
+		vc.mu.Unlock()
+		return videoCallEvent(jwsapi.Message{"event": "hangup", "username": caller.username, "reason": "User busy"}), nil, nil
+	case req.JSEP == nil:
+		err = newVRError(VideoCallErrorMissingSDP, "Missing SDP")
+	}
+	vc.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offer, _ := req.JSEP.String("sdp")
+	codecs := make(map[webrtc.RTPCodecType]vrCodec)
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if codec, ok := firstCodec(offer, kind); ok {
+			codecs[kind] = codec
+		}
+	}
+	if len(codecs) == 0 {
+		return nil, nil, newVRError(VideoCallErrorInvalidSDP, "No audio or video codec in offer")
+	}
+	call := &vcCall{caller: caller, callee: callee, codecs: codecs, legs: make(map[*vcUser]*vcLeg)}
+
+	//leg of caller, answer is sent when accepted
+	callerLeg, err := vc.newLeg(caller, call)
+	if err == nil {
+		callerLeg.answer, err = negotiateAnswer(callerLeg.pc, offer)
+	}
+	if err != nil {
+		if callerLeg != nil {
+			callerLeg.pc.Close()
+		}
+		return nil, nil, newVRError(VideoCallErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+	//leg of callee, offer of incomingcall
+	calleeLeg, err := vc.newLeg(callee, call)
+	var calleeOffer webrtc.SessionDescription
+	if err == nil {
+		if calleeOffer, err = calleeLeg.pc.CreateOffer(nil); err == nil {
+			err = calleeLeg.pc.SetLocalDescription(calleeOffer)
+		}
+	}
+	if err != nil {
+		callerLeg.pc.Close()
+		if calleeLeg != nil {
+			calleeLeg.pc.Close()
+		}
+		return nil, nil, newVRError(VideoCallErrorUnknown, "Error negotiating: %v", err)
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if caller.call != nil || callee.call != nil || vc.users[username] != callee || vc.users[caller.username] != caller {
+		//state changed during negotiation
+		callerLeg.pc.Close()
+		calleeLeg.pc.Close()
+		return videoCallEvent(jwsapi.Message{"event": "hangup", "username": caller.username, "reason": "User busy"}), nil, nil
+	}
+	call.legs[caller] = callerLeg
+	call.legs[callee] = calleeLeg
+	caller.call = call
+	callee.call = call
+	callee.handle.Event(videoCallEvent(jwsapi.Message{
+		"event":    "incomingcall",
+		"username": caller.username,
+	}), jwsapi.Message{"type": "offer", "sdp": calleeLeg.pc.LocalDescription().SDP})
+	return videoCallEvent(jwsapi.Message{"event": "calling"}), nil, nil
+}
+
+//newLeg PeerConnection with codecs of call, media is relayed to other leg by kind
+func (vc *VideoCall) newLeg(u *vcUser, call *vcCall) (*vcLeg, error) {
+	pc, err := newPeerConnection(vc.setting, call.codecs)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	leg := &vcLeg{
+		pc:     pc,
+		tracks: make(map[webrtc.RTPCodecType]*webrtc.Track),
+		ssrcs:  make(map[webrtc.RTPCodecType]uint32),
+	}
+	for kind, codec := range call.codecs {
+		track, err := pc.NewTrack(codec.pt, rand.Uint32(), kind.String(), "janusvideocall")
+		if err != nil {
+			pc.Close()
+			return nil, errors.Wrap(err, "NewTrack")
+		}
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			pc.Close()
+			return nil, errors.Wrap(err, "AddTrack")
+		}
+		leg.tracks[kind] = track
+		go drainRTCP(sender, vc.keyFrameRequester(call, u))
+	}
+	pc.OnTrack(func(remote *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		vc.mu.Lock()
+		leg.ssrcs[remote.Kind()] = remote.SSRC()
+		vc.mu.Unlock()
+		go drainRTCP(receiver, nil)
+		vc.relay(call, u, remote)
+	})
+	watchPeerConnection(u.handle, pc)
+	return leg, nil
+}
+
+//relay rtp of user to leg of peer in accepted call, unless muted by set
+func (vc *VideoCall) relay(call *vcCall, from *vcUser, remote *webrtc.Track) {
+	kind := remote.Kind()
+	for {
+		packet, err := remote.ReadRTP()
+		if err != nil {
+			return
+		}
+		var target *webrtc.Track
+		vc.mu.Lock()
+		if call.accepted && ((kind == webrtc.RTPCodecTypeAudio && from.audio) || (kind == webrtc.RTPCodecTypeVideo && from.video)) {
+			if leg, ok := call.legs[call.peer(from)]; ok {
+				target = leg.tracks[kind]
+			}
+		}
+		vc.mu.Unlock()
+		if target != nil {
+			packet.SSRC = target.SSRC()
+			packet.PayloadType = target.PayloadType()
+			target.WriteRTP(packet)
+		}
+	}
+}
+
+//keyFrameRequester forward PLI of user to video of peer
+func (vc *VideoCall) keyFrameRequester(call *vcCall, u *vcUser) func([]rtcp.Packet) {
+	return func(packets []rtcp.Packet) {
+		for _, packet := range packets {
+			if _, ok := packet.(*rtcp.PictureLossIndication); !ok {
+				continue
+			}
+			var pc *webrtc.PeerConnection
+			var ssrc uint32
+			vc.mu.Lock()
+			if leg, ok := call.legs[call.peer(u)]; ok {
+				pc, ssrc = leg.pc, leg.ssrcs[webrtc.RTPCodecTypeVideo]
+			}
+			vc.mu.Unlock()
+			if pc != nil && ssrc != 0 {
+				pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+			}
+			return
+		}
+	}
+}
+
+//endCall close legs, notify hangup to users of call except by, lock by caller
+func (vc *VideoCall) endCall(call *vcCall, by *vcUser, reason string) {
+	for _, u := range []*vcUser{call.caller, call.callee} {
+		if u.call != call {
+			continue
+		}
+		u.call = nil
+		if u != by {
+			u.handle.Event(videoCallEvent(jwsapi.Message{"event": "hangup", "username": call.peer(u).username, "reason": reason}), nil)
+		}
+	}
+	for _, leg := range call.legs {
+		go leg.pc.Close()
+	}
+	call.legs = make(map[*vcUser]*vcLeg)
+}
+
+func (vc *VideoCall) accept(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	vc.mu.Lock()
+	u, err := vc.registered(req.Handle)
+	if err == nil && (u.call == nil || u.call.callee != u || u.call.accepted) {
+		err = newVRError(VideoCallErrorNoCall, "No incoming call to accept")
+	}
+	if err == nil && req.JSEP == nil {
+		err = newVRError(VideoCallErrorMissingSDP, "Missing SDP")
+	}
+	if err != nil {
+		vc.mu.Unlock()
+		return nil, nil, err
+	}
+	call := u.call
+	leg := call.legs[u]
+	vc.mu.Unlock()
+
+	answer, _ := req.JSEP.String("sdp")
+	if err := leg.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		return nil, nil, newVRError(VideoCallErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	if u.call != call {
+		return nil, nil, newVRError(VideoCallErrorNoCall, "No incoming call to accept")
+	}
+	call.accepted = true
+	call.caller.handle.Event(videoCallEvent(jwsapi.Message{"event": "accepted", "username": u.username}),
+		jwsapi.Message{"type": "answer", "sdp": call.legs[call.caller].answer})
+	return videoCallEvent(jwsapi.Message{"event": "accepted"}), nil, nil
+}
+
+func (vc *VideoCall) set(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	for _, key := range []string{"audio", "video", "record"} {
+		if value, ok := req.Body[key]; ok {
+			if _, ok := value.(bool); !ok {
+				return nil, nil, newVRError(VideoCallErrorInvalidElement, "Invalid element (%s should be a boolean)", key)
+			}
+		}
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	u, err := vc.registered(req.Handle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := req.Body["audio"]; ok {
+		u.audio = req.Body.Bool("audio")
+	}
+	if _, ok := req.Body["video"]; ok {
+		u.video = req.Body.Bool("video")
+	}
+	return videoCallEvent(jwsapi.Message{"event": "set"}), nil, nil
+}
+
+func (vc *VideoCall) hangup(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	u, err := vc.registered(req.Handle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.call == nil {
+		return nil, nil, newVRError(VideoCallErrorNoCall, "No call to hangup")
+	}
+	peer := u.call.peer(u)
+	vc.endCall(u.call, u, "Remote hangup")
+	return videoCallEvent(jwsapi.Message{"event": "hangup", "username": peer.username, "reason": "Explicit hangup"}), nil, nil
+}
+
+func videoCallEvent(result jwsapi.Message) jwsapi.Message {
+	return jwsapi.Message{
+		"videocall": "event",
+		"result":    result,
+	}
+}
+
+func videoCallError(err error) jwsapi.Message {
+	code := VideoCallErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"videocall":  "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
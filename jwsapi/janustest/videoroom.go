@@ -0,0 +1,1236 @@
+package janustest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pion/rtcp"
+	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+//VideoRoomPlugin name of videoroom plugin
+const VideoRoomPlugin = "janus.plugin.videoroom"
+
+//videoroom error code
+const (
+	VideoRoomErrorUnknown          = 499
+	VideoRoomErrorInvalidRequest   = 423
+	VideoRoomErrorJoinFirst        = 424
+	VideoRoomErrorAlreadyJoined    = 425
+	VideoRoomErrorNoSuchRoom       = 426
+	VideoRoomErrorRoomExists       = 427
+	VideoRoomErrorNoSuchFeed       = 428
+	VideoRoomErrorMissingElement   = 429
+	VideoRoomErrorInvalidSDPType   = 431
+	VideoRoomErrorAlreadyPublished = 434
+	VideoRoomErrorIDExists         = 436
+	VideoRoomErrorInvalidSDP       = 437
+)
+
+//synchronous requests of videoroom, others are ack + event
+var videoRoomSyncRequests = map[string]bool{
+	"create": true, "destroy": true, "exists": true, "list": true, "listparticipants": true,
+	"add_remote_publisher": true, "update_remote_publisher": true, "remove_remote_publisher": true,
+	"publish_remotely": true, "unpublish_remotely": true, "list_remotes": true,
+}
+
+//VideoRoom fake videoroom plugin
+//keep rooms, publishers, subscribers in memory,
+//negotiate with pion PeerConnection(ice-lite), and forward rtp from publisher to subscribers
+//remote publishers (janus-gateway 1.x) are listed and notified, rtp of publish_remotely is not sent
+//
+//	s := janustest.NewServer()
+//	s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+type VideoRoom struct {
+	setting    webrtc.SettingEngine
+	audioCodec string
+	videoCodec string
+
+	mu       sync.Mutex
+	nextID   uint64
+	nextPort uint16 //port of remote publisher
+	rooms    map[uint64]*vrRoom
+}
+
+//VideoRoomOption option for VideoRoom
+type VideoRoomOption func(*VideoRoom)
+
+//WithVideoRoomSettingEngine set pion setting engine, eg: port range, lite is always true
+func WithVideoRoomSettingEngine(setting webrtc.SettingEngine) VideoRoomOption {
+	return func(vr *VideoRoom) {
+		vr.setting = setting
+	}
+}
+
+//WithVideoRoomCodecs set default audiocodec, videocodec of rooms, eg: "opus", "h264,vp8"
+//default is "opus", "h264,vp8,vp9" for videoroom.Publisher send h264
+func WithVideoRoomCodecs(audioCodec string, videoCodec string) VideoRoomOption {
+	return func(vr *VideoRoom) {
+		vr.audioCodec = audioCodec
+		vr.videoCodec = videoCodec
+	}
+}
+
+//WithVideoRoomRoom create room at start, like rooms in janus.plugin.videoroom.jcfg
+func WithVideoRoomRoom(room uint64, description string) VideoRoomOption {
+	return func(vr *VideoRoom) {
+		vr.rooms[room] = &vrRoom{id: room, description: description}
+	}
+}
+
+//NewVideoRoom create fake videoroom plugin, room 1234 is created by default
+func NewVideoRoom(opts ...VideoRoomOption) *VideoRoom {
+	vr := &VideoRoom{
+		audioCodec: "opus",
+		videoCodec: "h264,vp8,vp9",
+		nextID:     1 << 20,
+		nextPort:   20000,
+		rooms: map[uint64]*vrRoom{
+			1234: {id: 1234, description: "Demo Room"},
+		},
+	}
+	vr.setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	for _, opt := range opts {
+		opt(vr)
+	}
+	vr.setting.SetLite(true)
+	for _, r := range vr.rooms {
+		vr.initRoom(r, "", "")
+	}
+	return vr
+}
+
+func (vr *VideoRoom) initRoom(r *vrRoom, audioCodec string, videoCodec string) {
+	if audioCodec == "" {
+		audioCodec = vr.audioCodec
+	}
+	if videoCodec == "" {
+		videoCodec = vr.videoCodec
+	}
+	r.codecs = map[webrtc.RTPCodecType][]string{
+		webrtc.RTPCodecTypeAudio: strings.Split(strings.ToLower(audioCodec), ","),
+		webrtc.RTPCodecTypeVideo: strings.Split(strings.ToLower(videoCodec), ","),
+	}
+	r.publishers = make(map[uint64]*vrPublisher)
+}
+
+//Rooms return ids of all rooms
+func (vr *VideoRoom) Rooms() []uint64 {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	ids := make([]uint64, 0, len(vr.rooms))
+	for id := range vr.rooms {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+//Publishers return ids of published publishers in room
+func (vr *VideoRoom) Publishers(room uint64) []uint64 {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	r, ok := vr.rooms[room]
+	if !ok {
+		return nil
+	}
+	ids := make([]uint64, 0, len(r.publishers))
+	for _, p := range r.sortedPublishers() {
+		if p.published {
+			ids = append(ids, p.id)
+		}
+	}
+	return ids
+}
+
+//Subscribers return count of subscribers of feed
+func (vr *VideoRoom) Subscribers(room uint64, feed uint64) int {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	if r, ok := vr.rooms[room]; ok {
+		if p, ok := r.publishers[feed]; ok {
+			return len(p.subscribers)
+		}
+	}
+	return 0
+}
+
+//Packets return rtp packets received from feed
+func (vr *VideoRoom) Packets(room uint64, feed uint64) int {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	if r, ok := vr.rooms[room]; ok {
+		if p, ok := r.publishers[feed]; ok {
+			return p.packets
+		}
+	}
+	return 0
+}
+
+type vrRoom struct {
+	id          uint64
+	description string
+	isPrivate   bool //not listed
+	codecs      map[webrtc.RTPCodecType][]string
+	publishers  map[uint64]*vrPublisher
+}
+
+func (r *vrRoom) sortedPublishers() []*vrPublisher {
+	pubs := make([]*vrPublisher, 0, len(r.publishers))
+	for _, p := range r.publishers {
+		pubs = append(pubs, p)
+	}
+	sort.Slice(pubs, func(i, j int) bool { return pubs[i].id < pubs[j].id })
+	return pubs
+}
+
+//notify push event to all publishers of room except one
+func (r *vrRoom) notify(except *vrPublisher, data jwsapi.Message) {
+	for _, p := range r.publishers {
+		if p != except && p.handle != nil {
+			p.handle.Event(data, nil)
+		}
+	}
+}
+
+type vrPublisher struct {
+	handle    *Handle
+	room      *vrRoom
+	id        uint64
+	display   string
+	pc        *webrtc.PeerConnection
+	published bool
+	codecs    map[webrtc.RTPCodecType]vrCodec
+	ssrcs     map[webrtc.RTPCodecType]uint32
+	media     map[webrtc.RTPCodecType]bool
+	data      bool //data channel is published
+	packets   int
+	remote    bool          //remote publisher of add_remote_publisher, without handle, media is not received
+	streams   []interface{} //streams of remote publisher
+	remotes   map[string]vrRemote
+
+	subscribers map[*vrSubscriber]struct{}
+}
+
+//vrRemote publish_remotely of publisher, only recorded, rtp is not sent
+type vrRemote struct {
+	host     string
+	port     uint16
+	rtcpPort uint16
+}
+
+func (p *vrPublisher) info() jwsapi.Message {
+	info := jwsapi.Message{
+		"id":      p.id,
+		"display": p.display,
+	}
+	if codec, ok := p.codecs[webrtc.RTPCodecTypeAudio]; ok {
+		info["audio_codec"] = codec.name
+	}
+	if codec, ok := p.codecs[webrtc.RTPCodecTypeVideo]; ok {
+		info["video_codec"] = codec.name
+	}
+	if p.remote {
+		info["streams"] = p.streams
+		return info
+	}
+	//streams like janus-gateway 1.x, m-line of offer is audio, video, data
+	streams := make([]interface{}, 0, 3)
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if codec, ok := p.codecs[kind]; ok {
+			mindex := len(streams)
+			streams = append(streams, jwsapi.Message{"type": kind.String(), "mindex": mindex, "mid": strconv.Itoa(mindex), "codec": codec.name})
+		}
+	}
+	if p.data {
+		mindex := len(streams)
+		streams = append(streams, jwsapi.Message{"type": "data", "mindex": mindex, "mid": strconv.Itoa(mindex)})
+	}
+	if len(streams) > 0 {
+		info["streams"] = streams
+	}
+	return info
+}
+
+type vrSubscriber struct {
+	handle  *Handle
+	room    *vrRoom
+	feed    *vrPublisher
+	pc      *webrtc.PeerConnection
+	tracks  map[webrtc.RTPCodecType]*webrtc.Track
+	dc      *webrtc.DataChannel
+	enabled map[webrtc.RTPCodecType]bool
+	started bool
+	paused  bool
+}
+
+//participant stored at Handle
+const vrValueKey = "videoroom"
+
+func (vr *VideoRoom) newID() uint64 {
+	vr.nextID++
+	return vr.nextID
+}
+
+//HandleMessage implements Plugin
+func (vr *VideoRoom) HandleMessage(req *Request) {
+	name := req.Name()
+	if videoRoomSyncRequests[name] {
+		vr.handleSync(req, name)
+		return
+	}
+	req.Ack()
+	go func() {
+		data, jsep, err := vr.handleAsync(req, name)
+		if err != nil {
+			data = videoRoomError(err)
+			jsep = nil
+		}
+		req.Event(data, jsep)
+	}()
+}
+
+//HandleTrickle implements TrickleHandler
+func (vr *VideoRoom) HandleTrickle(h *Handle, candidate jwsapi.Message) {
+	if candidate == nil {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	vr.mu.Lock()
+	var pc *webrtc.PeerConnection
+	switch part := h.Value(vrValueKey).(type) {
+	case *vrPublisher:
+		pc = part.pc
+	case *vrSubscriber:
+		pc = part.pc
+	}
+	vr.mu.Unlock()
+	if pc != nil && pc.RemoteDescription() != nil {
+		//ice-lite, remote candidates is optional, ignore bad candidate
+		pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: value})
+	}
+}
+
+//HandleDetach implements DetachHandler
+func (vr *VideoRoom) HandleDetach(h *Handle) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	switch part := h.Value(vrValueKey).(type) {
+	case *vrPublisher:
+		vr.leavePublisher(part)
+	case *vrSubscriber:
+		vr.leaveSubscriber(part)
+	}
+	h.SetValue(vrValueKey, nil)
+}
+
+type vrError struct {
+	code   int
+	reason string
+}
+
+func (e *vrError) Error() string {
+	return e.reason
+}
+
+func newVRError(code int, format string, args ...interface{}) error {
+	return &vrError{code: code, reason: fmt.Sprintf(format, args...)}
+}
+
+func videoRoomError(err error) jwsapi.Message {
+	code := VideoRoomErrorUnknown
+	if e, ok := err.(*vrError); ok {
+		code = e.code
+	}
+	return jwsapi.Message{
+		"videoroom":  "event",
+		"error_code": code,
+		"error":      err.Error(),
+	}
+}
+
+func (vr *VideoRoom) handleSync(req *Request, name string) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	room, hasRoom := req.Body.Uint64("room")
+	switch name {
+	case "create":
+		if !hasRoom {
+			room = vr.newID()
+		}
+		if _, ok := vr.rooms[room]; ok {
+			req.Success(videoRoomError(newVRError(VideoRoomErrorRoomExists, "Room %d already exists", room)))
+			return
+		}
+		description, _ := req.Body.String("description")
+		if description == "" {
+			description = fmt.Sprintf("Room %d", room)
+		}
+		r := &vrRoom{id: room, description: description, isPrivate: req.Body.Bool("is_private")}
+		audioCodec, _ := req.Body.String("audiocodec")
+		videoCodec, _ := req.Body.String("videocodec")
+		vr.initRoom(r, audioCodec, videoCodec)
+		vr.rooms[room] = r
+		req.Success(jwsapi.Message{
+			"videoroom": "created",
+			"room":      room,
+			"permanent": false,
+		})
+	case "exists":
+		_, ok := vr.rooms[room]
+		req.Success(jwsapi.Message{
+			"videoroom": "success",
+			"room":      room,
+			"exists":    ok,
+		})
+	case "list":
+		ids := make([]uint64, 0, len(vr.rooms))
+		for id, r := range vr.rooms {
+			if !r.isPrivate {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		list := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			r := vr.rooms[id]
+			list = append(list, jwsapi.Message{
+				"room":             r.id,
+				"description":      r.description,
+				"num_participants": len(r.publishers),
+				"max_publishers":   100,
+			})
+		}
+		req.Success(jwsapi.Message{
+			"videoroom": "success",
+			"list":      list,
+		})
+	case "add_remote_publisher", "update_remote_publisher", "remove_remote_publisher",
+		"publish_remotely", "unpublish_remotely", "list_remotes":
+		r, ok := vr.rooms[room]
+		if !ok {
+			req.Success(videoRoomError(newVRError(VideoRoomErrorNoSuchRoom, "No such room (%d)", room)))
+			return
+		}
+		data, err := vr.handleRemote(r, req, name)
+		if err != nil {
+			data = videoRoomError(err)
+		}
+		req.Success(data)
+	case "listparticipants", "destroy":
+		r, ok := vr.rooms[room]
+		if !ok {
+			req.Success(videoRoomError(newVRError(VideoRoomErrorNoSuchRoom, "No such room (%d)", room)))
+			return
+		}
+		if name == "destroy" {
+			r.notify(nil, jwsapi.Message{"videoroom": "destroyed", "room": room})
+			for _, p := range r.sortedPublishers() {
+				vr.unpublish(p)
+				if p.handle != nil {
+					p.handle.SetValue(vrValueKey, nil)
+				}
+			}
+			delete(vr.rooms, room)
+			req.Success(jwsapi.Message{"videoroom": "destroyed", "room": room})
+			return
+		}
+		parts := make([]interface{}, 0, len(r.publishers))
+		for _, p := range r.sortedPublishers() {
+			parts = append(parts, jwsapi.Message{
+				"id":        p.id,
+				"display":   p.display,
+				"publisher": p.published,
+			})
+		}
+		req.Success(jwsapi.Message{
+			"videoroom":    "participants",
+			"room":         room,
+			"participants": parts,
+		})
+	}
+}
+
+//handleRemote remote publisher requests of janus-gateway 1.x, lock by caller
+//remote publishers are listed and notified like publishers, publish_remotely is recorded for list_remotes
+func (vr *VideoRoom) handleRemote(r *vrRoom, req *Request, name string) (jwsapi.Message, error) {
+	switch name {
+	case "add_remote_publisher":
+		streams := req.Body.Array("streams")
+		if len(streams) == 0 {
+			return nil, newVRError(VideoRoomErrorMissingElement, "Missing element (streams)")
+		}
+		id, ok := req.Body.Uint64("id")
+		if ok {
+			if _, exists := r.publishers[id]; exists {
+				return nil, newVRError(VideoRoomErrorIDExists, "User ID %d already exists", id)
+			}
+		} else {
+			id = vr.newID()
+		}
+		display, _ := req.Body.String("display")
+		p := &vrPublisher{
+			room:        r,
+			id:          id,
+			display:     display,
+			published:   true,
+			remote:      true,
+			streams:     streams,
+			codecs:      make(map[webrtc.RTPCodecType]vrCodec),
+			ssrcs:       make(map[webrtc.RTPCodecType]uint32),
+			media:       make(map[webrtc.RTPCodecType]bool),
+			subscribers: make(map[*vrSubscriber]struct{}),
+		}
+		r.publishers[id] = p
+		vr.nextPort += 2
+		r.notify(p, jwsapi.Message{"videoroom": "event", "room": r.id, "publishers": []interface{}{p.info()}})
+		return jwsapi.Message{
+			"videoroom": "success",
+			"room":      r.id,
+			"id":        id,
+			"ip":        "127.0.0.1",
+			"port":      vr.nextPort,
+			"rtcp_port": vr.nextPort + 1,
+		}, nil
+	case "update_remote_publisher", "remove_remote_publisher":
+		id, _ := req.Body.Uint64("id")
+		p, ok := r.publishers[id]
+		if !ok || !p.remote {
+			return nil, newVRError(VideoRoomErrorNoSuchFeed, "No such remote publisher (%d)", id)
+		}
+		if name == "remove_remote_publisher" {
+			vr.leavePublisher(p)
+			return jwsapi.Message{"videoroom": "success", "room": r.id, "id": id}, nil
+		}
+		if streams := req.Body.Array("streams"); len(streams) > 0 {
+			p.streams = streams
+		}
+		if display, ok := req.Body.String("display"); ok {
+			p.display = display
+		}
+		r.notify(p, jwsapi.Message{"videoroom": "event", "room": r.id, "publishers": []interface{}{p.info()}})
+		return jwsapi.Message{"videoroom": "success", "room": r.id, "id": id}, nil
+	}
+
+	id, _ := req.Body.Uint64("publisher_id")
+	p, ok := r.publishers[id]
+	if !ok || !p.published {
+		return nil, newVRError(VideoRoomErrorNoSuchFeed, "No such feed (%d)", id)
+	}
+	remoteID, _ := req.Body.String("remote_id")
+	switch name {
+	case "publish_remotely":
+		host, _ := req.Body.String("host")
+		port, _ := req.Body.Uint16("port")
+		if remoteID == "" || host == "" || port == 0 {
+			return nil, newVRError(VideoRoomErrorMissingElement, "Missing element (remote_id, host or port)")
+		}
+		if _, exists := p.remotes[remoteID]; exists {
+			return nil, newVRError(VideoRoomErrorIDExists, "Remote %s already exists", remoteID)
+		}
+		rtcpPort, _ := req.Body.Uint16("rtcp_port")
+		if p.remotes == nil {
+			p.remotes = make(map[string]vrRemote)
+		}
+		p.remotes[remoteID] = vrRemote{host: host, port: port, rtcpPort: rtcpPort}
+		return jwsapi.Message{"videoroom": "success", "room": r.id, "publisher_id": id, "remote_id": remoteID}, nil
+	case "unpublish_remotely":
+		if _, exists := p.remotes[remoteID]; !exists {
+			return nil, newVRError(VideoRoomErrorNoSuchFeed, "No such remote %s", remoteID)
+		}
+		delete(p.remotes, remoteID)
+		return jwsapi.Message{"videoroom": "success", "room": r.id, "publisher_id": id, "remote_id": remoteID}, nil
+	default: //list_remotes
+		ids := make([]string, 0, len(p.remotes))
+		for remoteID := range p.remotes {
+			ids = append(ids, remoteID)
+		}
+		sort.Strings(ids)
+		list := make([]interface{}, 0, len(ids))
+		for _, remoteID := range ids {
+			remote := p.remotes[remoteID]
+			list = append(list, jwsapi.Message{
+				"remote_id": remoteID,
+				"host":      remote.host,
+				"port":      remote.port,
+				"rtcp_port": remote.rtcpPort,
+			})
+		}
+		return jwsapi.Message{"videoroom": "success", "room": r.id, "publisher_id": id, "list": list}, nil
+	}
+}
+
+func (vr *VideoRoom) handleAsync(req *Request, name string) (jwsapi.Message, jwsapi.Message, error) {
+	h := req.Handle
+
+	vr.mu.Lock()
+	part := h.Value(vrValueKey)
+	vr.mu.Unlock()
+
+	switch name {
+	case "join":
+		if part != nil {
+			return nil, nil, newVRError(VideoRoomErrorAlreadyJoined, "Already in as a participant on this handle")
+		}
+		ptype, _ := req.Body.String("ptype")
+		switch ptype {
+		case "publisher":
+			return vr.joinPublisher(req)
+		case "subscriber", "listener":
+			return vr.joinSubscriber(req)
+		default:
+			return nil, nil, newVRError(VideoRoomErrorInvalidRequest, "Invalid element (ptype)")
+		}
+	}
+
+	switch p := part.(type) {
+	case *vrPublisher:
+		switch name {
+		case "configure", "publish":
+			return vr.configurePublisher(p, req)
+		case "unpublish":
+			vr.mu.Lock()
+			defer vr.mu.Unlock()
+			if !p.published {
+				return nil, nil, newVRError(VideoRoomErrorUnknown, "Can't unpublish, not published")
+			}
+			vr.unpublish(p)
+			return jwsapi.Message{"videoroom": "event", "room": p.room.id, "unpublished": "ok"}, nil, nil
+		case "leave":
+			vr.mu.Lock()
+			defer vr.mu.Unlock()
+			vr.leavePublisher(p)
+			h.SetValue(vrValueKey, nil)
+			return jwsapi.Message{"videoroom": "event", "room": p.room.id, "leaving": "ok"}, nil, nil
+		}
+	case *vrSubscriber:
+		switch name {
+		case "start":
+			return vr.startSubscriber(p, req)
+		case "pause":
+			vr.mu.Lock()
+			p.paused = true
+			vr.mu.Unlock()
+			return jwsapi.Message{"videoroom": "event", "room": p.room.id, "paused": "ok"}, nil, nil
+		case "configure":
+			vr.mu.Lock()
+			for kind, key := range map[webrtc.RTPCodecType]string{webrtc.RTPCodecTypeAudio: "audio", webrtc.RTPCodecTypeVideo: "video"} {
+				if _, ok := req.Body[key]; ok {
+					p.enabled[kind] = req.Body.Bool(key)
+				}
+			}
+			vr.mu.Unlock()
+			return jwsapi.Message{"videoroom": "event", "room": p.room.id, "configured": "ok"}, nil, nil
+		case "switch":
+			return vr.switchSubscriber(p, req)
+		case "leave":
+			vr.mu.Lock()
+			defer vr.mu.Unlock()
+			vr.leaveSubscriber(p)
+			h.SetValue(vrValueKey, nil)
+			return jwsapi.Message{"videoroom": "event", "room": p.room.id, "left": "ok"}, nil, nil
+		}
+	case nil:
+		return nil, nil, newVRError(VideoRoomErrorJoinFirst, "Join first")
+	}
+	return nil, nil, newVRError(VideoRoomErrorInvalidRequest, "Unknown request '%s'", name)
+}
+
+func (vr *VideoRoom) joinPublisher(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	roomID, _ := req.Body.Uint64("room")
+	r, ok := vr.rooms[roomID]
+	if !ok {
+		return nil, nil, newVRError(VideoRoomErrorNoSuchRoom, "No such room (%d)", roomID)
+	}
+	id, ok := req.Body.Uint64("id")
+	if ok {
+		if _, exists := r.publishers[id]; exists {
+			return nil, nil, newVRError(VideoRoomErrorIDExists, "User ID %d already exists", id)
+		}
+	} else {
+		id = vr.newID()
+	}
+	display, _ := req.Body.String("display")
+	p := &vrPublisher{
+		handle:      req.Handle,
+		room:        r,
+		id:          id,
+		display:     display,
+		codecs:      make(map[webrtc.RTPCodecType]vrCodec),
+		ssrcs:       make(map[webrtc.RTPCodecType]uint32),
+		media:       make(map[webrtc.RTPCodecType]bool),
+		subscribers: make(map[*vrSubscriber]struct{}),
+	}
+
+	publishers := make([]interface{}, 0, len(r.publishers))
+	for _, other := range r.sortedPublishers() {
+		if other.published {
+			publishers = append(publishers, other.info())
+		}
+	}
+	r.publishers[id] = p
+	req.Handle.SetValue(vrValueKey, p)
+
+	return jwsapi.Message{
+		"videoroom":   "joined",
+		"room":        r.id,
+		"description": r.description,
+		"id":          id,
+		"private_id":  rand.Uint32(),
+		"publishers":  publishers,
+	}, nil, nil
+}
+
+func (vr *VideoRoom) configurePublisher(p *vrPublisher, req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	if display, ok := req.Body.String("display"); ok {
+		vr.mu.Lock()
+		p.display = display
+		vr.mu.Unlock()
+	}
+	data := jwsapi.Message{"videoroom": "event", "room": p.room.id, "configured": "ok"}
+	if req.JSEP == nil {
+		return data, nil, nil
+	}
+	if jtype, _ := req.JSEP.String("type"); jtype != "offer" {
+		return nil, nil, newVRError(VideoRoomErrorInvalidSDPType, "Unsupported SDP type '%s'", jtype)
+	}
+	vr.mu.Lock()
+	published := p.published
+	vr.mu.Unlock()
+	if published {
+		return nil, nil, newVRError(VideoRoomErrorAlreadyPublished, "Can't publish, already published")
+	}
+	offer, _ := req.JSEP.String("sdp")
+	offered, err := sdpCodecs(offer)
+	if err != nil {
+		return nil, nil, newVRError(VideoRoomErrorInvalidSDP, "Error parsing offer: %v", err)
+	}
+	//choose codec like janus, first codec of room in offer
+	codecs := make(map[webrtc.RTPCodecType]vrCodec)
+	for kind, names := range p.room.codecs {
+		for _, name := range names {
+			if codec, ok := offered[name]; ok && codec.kind == kind {
+				codecs[kind] = codec
+				break
+			}
+		}
+	}
+	if len(codecs) == 0 {
+		return nil, nil, newVRError(VideoRoomErrorInvalidSDP, "No codec of room in offer")
+	}
+	hasData := strings.Contains(offer, "m=application")
+
+	pc, err := newPeerConnection(vr.setting, codecs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if _, ok := codecs[kind]; !ok {
+			continue
+		}
+		if _, err := pc.AddTransceiver(kind, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "AddTransceiver")
+		}
+	}
+	pc.OnTrack(func(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+		go vr.forward(p, pc, track, receiver)
+	})
+	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			vr.forwardData(p, pc, msg)
+		})
+	})
+	watchPeerConnection(p.handle, pc)
+
+	answer, err := negotiateAnswer(pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, nil, newVRError(VideoRoomErrorInvalidSDP, "Error negotiating: %v", err)
+	}
+
+	vr.mu.Lock()
+	if p.handle.Value(vrValueKey) != p {
+		//leave during negotiation
+		vr.mu.Unlock()
+		pc.Close()
+		return nil, nil, newVRError(VideoRoomErrorJoinFirst, "Join first")
+	}
+	p.pc = pc
+	p.published = true
+	p.codecs = codecs
+	p.data = hasData
+	for kind, codec := range codecs {
+		data[kind.String()+"_codec"] = codec.name
+	}
+	p.room.notify(p, jwsapi.Message{
+		"videoroom":  "event",
+		"room":       p.room.id,
+		"publishers": []interface{}{p.info()},
+	})
+	vr.mu.Unlock()
+
+	return data, jwsapi.Message{"type": "answer", "sdp": answer}, nil
+}
+
+//unpublish close PeerConnection of publisher, lock by caller
+func (vr *VideoRoom) unpublish(p *vrPublisher) {
+	if !p.published {
+		return
+	}
+	p.published = false
+	p.codecs = make(map[webrtc.RTPCodecType]vrCodec)
+	p.ssrcs = make(map[webrtc.RTPCodecType]uint32)
+	p.media = make(map[webrtc.RTPCodecType]bool)
+	p.data = false
+	p.remotes = nil
+	if p.pc != nil {
+		go p.pc.Close()
+		p.pc = nil
+	}
+	for sub := range p.subscribers {
+		sub.feed = nil
+		sub.handle.Hangup("Publisher unpublished")
+	}
+	p.subscribers = make(map[*vrSubscriber]struct{})
+	p.room.notify(p, jwsapi.Message{"videoroom": "event", "room": p.room.id, "unpublished": p.id})
+}
+
+//leavePublisher lock by caller
+func (vr *VideoRoom) leavePublisher(p *vrPublisher) {
+	if p.room.publishers[p.id] != p {
+		return
+	}
+	vr.unpublish(p)
+	delete(p.room.publishers, p.id)
+	p.room.notify(p, jwsapi.Message{"videoroom": "event", "room": p.room.id, "leaving": p.id})
+}
+
+func (vr *VideoRoom) joinSubscriber(req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	roomID, _ := req.Body.Uint64("room")
+	feedID, ok := req.Body.Uint64("feed")
+	if !ok {
+		return nil, nil, newVRError(VideoRoomErrorMissingElement, "Missing element (feed)")
+	}
+
+	vr.mu.Lock()
+	r, ok := vr.rooms[roomID]
+	if !ok {
+		vr.mu.Unlock()
+		return nil, nil, newVRError(VideoRoomErrorNoSuchRoom, "No such room (%d)", roomID)
+	}
+	feed, ok := r.publishers[feedID]
+	if !ok || !feed.published {
+		vr.mu.Unlock()
+		return nil, nil, newVRError(VideoRoomErrorNoSuchFeed, "No such feed (%d)", feedID)
+	}
+	if feed.remote {
+		vr.mu.Unlock()
+		return nil, nil, newVRError(VideoRoomErrorUnknown, "Can't subscribe remote publisher (%d), media is not received", feedID)
+	}
+	codecs := make(map[webrtc.RTPCodecType]vrCodec, len(feed.codecs))
+	for kind, codec := range feed.codecs {
+		codecs[kind] = codec
+	}
+	display := feed.display
+	hasData := feed.data
+	vr.mu.Unlock()
+
+	sub := &vrSubscriber{
+		handle:  req.Handle,
+		room:    r,
+		tracks:  make(map[webrtc.RTPCodecType]*webrtc.Track),
+		enabled: make(map[webrtc.RTPCodecType]bool),
+	}
+	pc, err := newPeerConnection(vr.setting, codecs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewPeerConnection")
+	}
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		codec, ok := codecs[kind]
+		if !ok {
+			continue
+		}
+		sub.enabled[kind] = true
+		if _, ok := req.Body[kind.String()]; ok {
+			sub.enabled[kind] = req.Body.Bool(kind.String())
+		}
+		label := fmt.Sprintf("janus%d", feedID)
+		track, err := pc.NewTrack(codec.pt, rand.Uint32(), kind.String()+label, label)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "NewTrack")
+		}
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "AddTrack")
+		}
+		go drainRTCP(sender, vr.keyFrameRequester(sub))
+		sub.tracks[kind] = track
+	}
+	//data default is true like janus
+	if _, ok := req.Body["data"]; hasData && (!ok || req.Body.Bool("data")) {
+		dc, err := pc.CreateDataChannel("JanusDataChannel", nil)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "CreateDataChannel")
+		}
+		sub.dc = dc
+	}
+	watchPeerConnection(req.Handle, pc)
+
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "CreateOffer")
+	}
+
+	vr.mu.Lock()
+	if !feed.published || r.publishers[feedID] != feed {
+		vr.mu.Unlock()
+		pc.Close()
+		return nil, nil, newVRError(VideoRoomErrorNoSuchFeed, "No such feed (%d)", feedID)
+	}
+	sub.pc = pc
+	sub.feed = feed
+	feed.subscribers[sub] = struct{}{}
+	req.Handle.SetValue(vrValueKey, sub)
+	vr.mu.Unlock()
+
+	return jwsapi.Message{
+		"videoroom": "attached",
+		"room":      roomID,
+		"id":        feedID,
+		"display":   display,
+	}, jwsapi.Message{
+		"type": "offer",
+		"sdp":  pc.LocalDescription().SDP,
+	}, nil
+}
+
+func (vr *VideoRoom) startSubscriber(sub *vrSubscriber, req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	if req.JSEP != nil {
+		//videoroom.Subscriber send an offer as answer, so sdp type is not checked
+		answer, _ := req.JSEP.String("sdp")
+		err := sub.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer})
+		if err != nil {
+			return nil, nil, newVRError(VideoRoomErrorInvalidSDP, "Error negotiating: %v", err)
+		}
+	}
+	vr.mu.Lock()
+	sub.started = true
+	sub.paused = false
+	feed := sub.feed
+	vr.mu.Unlock()
+	if feed != nil {
+		vr.requestKeyFrame(feed)
+	}
+	return jwsapi.Message{"videoroom": "event", "room": sub.room.id, "started": "ok"}, nil, nil
+}
+
+func (vr *VideoRoom) switchSubscriber(sub *vrSubscriber, req *Request) (jwsapi.Message, jwsapi.Message, error) {
+	feedID, ok := req.Body.Uint64("feed")
+	if !ok {
+		return nil, nil, newVRError(VideoRoomErrorMissingElement, "Missing element (feed)")
+	}
+	vr.mu.Lock()
+	feed, ok := sub.room.publishers[feedID]
+	if !ok || !feed.published {
+		vr.mu.Unlock()
+		return nil, nil, newVRError(VideoRoomErrorNoSuchFeed, "No such feed (%d)", feedID)
+	}
+	for kind, track := range sub.tracks {
+		codec, ok := feed.codecs[kind]
+		if !ok || !strings.EqualFold(codec.name, track.Codec().Name) {
+			vr.mu.Unlock()
+			return nil, nil, newVRError(VideoRoomErrorInvalidRequest, "The two publishers are not using the same codecs")
+		}
+	}
+	if sub.feed != nil {
+		delete(sub.feed.subscribers, sub)
+	}
+	sub.feed = feed
+	feed.subscribers[sub] = struct{}{}
+	for _, key := range []string{"audio", "video"} {
+		if _, ok := req.Body[key]; ok {
+			kind := webrtc.NewRTPCodecType(key)
+			sub.enabled[kind] = req.Body.Bool(key)
+		}
+	}
+	display := feed.display
+	vr.mu.Unlock()
+
+	vr.requestKeyFrame(feed)
+	return jwsapi.Message{
+		"videoroom": "event",
+		"room":      sub.room.id,
+		"switched":  "ok",
+		"id":        feedID,
+		"display":   display,
+	}, nil, nil
+}
+
+//leaveSubscriber lock by caller
+func (vr *VideoRoom) leaveSubscriber(sub *vrSubscriber) {
+	if sub.feed != nil {
+		delete(sub.feed.subscribers, sub)
+		sub.feed = nil
+	}
+	if sub.pc != nil {
+		go sub.pc.Close()
+		sub.pc = nil
+	}
+}
+
+//forward read rtp from publisher track, write to subscribers
+func (vr *VideoRoom) forward(p *vrPublisher, pc *webrtc.PeerConnection, track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+	kind := track.Kind()
+	vr.mu.Lock()
+	p.ssrcs[kind] = track.SSRC()
+	vr.mu.Unlock()
+	go drainRTCP(receiver, nil)
+
+	var targets []*webrtc.Track
+	for {
+		packet, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+
+		vr.mu.Lock()
+		if p.pc != pc {
+			vr.mu.Unlock()
+			return
+		}
+		p.packets++
+		first := !p.media[kind]
+		p.media[kind] = true
+		targets = targets[:0]
+		for sub := range p.subscribers {
+			if sub.started && !sub.paused && sub.enabled[kind] {
+				if t, ok := sub.tracks[kind]; ok {
+					targets = append(targets, t)
+				}
+			}
+		}
+		vr.mu.Unlock()
+
+		if first {
+			p.handle.Media(kind.String(), true)
+		}
+		for _, t := range targets {
+			out := *packet
+			out.SSRC = t.SSRC()
+			out.PayloadType = t.PayloadType()
+			t.WriteRTP(&out)
+		}
+	}
+}
+
+//forwardData relay data channel message from publisher to subscribers
+func (vr *VideoRoom) forwardData(p *vrPublisher, pc *webrtc.PeerConnection, msg webrtc.DataChannelMessage) {
+	vr.mu.Lock()
+	if p.pc != pc {
+		vr.mu.Unlock()
+		return
+	}
+	var targets []*webrtc.DataChannel
+	for sub := range p.subscribers {
+		if sub.started && !sub.paused && sub.dc != nil {
+			targets = append(targets, sub.dc)
+		}
+	}
+	vr.mu.Unlock()
+
+	for _, dc := range targets {
+		if msg.IsString {
+			dc.SendText(string(msg.Data))
+		} else {
+			dc.Send(msg.Data)
+		}
+	}
+}
+
+//keyFrameRequester forward PLI,FIR of subscriber to its feed
+func (vr *VideoRoom) keyFrameRequester(sub *vrSubscriber) func([]rtcp.Packet) {
+	return func(packets []rtcp.Packet) {
+		for _, packet := range packets {
+			if _, ok := packet.(*rtcp.PictureLossIndication); ok {
+				vr.mu.Lock()
+				feed := sub.feed
+				vr.mu.Unlock()
+				if feed != nil {
+					vr.requestKeyFrame(feed)
+				}
+				return
+			}
+		}
+	}
+}
+
+func (vr *VideoRoom) requestKeyFrame(p *vrPublisher) {
+	vr.mu.Lock()
+	pc := p.pc
+	ssrc := p.ssrcs[webrtc.RTPCodecTypeVideo]
+	vr.mu.Unlock()
+	if pc != nil && ssrc != 0 {
+		pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+	}
+}
+
+//watchPeerConnection push webrtcup and hangup like janus
+func watchPeerConnection(h *Handle, pc *webrtc.PeerConnection) {
+	var once sync.Once
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			h.WebrtcUp()
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
+			once.Do(func() {
+				h.Hangup("Close PC")
+			})
+			if state == webrtc.PeerConnectionStateFailed {
+				go pc.Close()
+			}
+		}
+	})
+}
+
+type rtcpReader interface {
+	ReadRTCP() ([]rtcp.Packet, error)
+}
+
+func drainRTCP(reader rtcpReader, callback func([]rtcp.Packet)) {
+	for {
+		packets, err := reader.ReadRTCP()
+		if err != nil {
+			return
+		}
+		if callback != nil {
+			callback(packets)
+		}
+	}
+}
+
+//negotiateAnswer set remote offer, create answer, candidates are included in answer
+func negotiateAnswer(pc *webrtc.PeerConnection, offer string) (string, error) {
+	err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer})
+	if err != nil {
+		return "", errors.Wrap(err, "SetRemoteDescription")
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", errors.Wrap(err, "CreateAnswer")
+	}
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return "", errors.Wrap(err, "SetLocalDescription")
+	}
+	return pc.LocalDescription().SDP, nil
+}
+
+type vrCodec struct {
+	kind      webrtc.RTPCodecType
+	name      string //lower case like janus, eg: opus, h264
+	pt        uint8
+	clockRate uint32
+}
+
+var codecFactories = map[string]func(uint8, uint32) *webrtc.RTPCodec{
+	"opus": webrtc.NewRTPOpusCodec,
+	"pcmu": webrtc.NewRTPPCMUCodec,
+	"pcma": webrtc.NewRTPPCMACodec,
+	"g722": webrtc.NewRTPG722Codec,
+	"vp8":  webrtc.NewRTPVP8Codec,
+	"vp9":  webrtc.NewRTPVP9Codec,
+	"h264": webrtc.NewRTPH264Codec,
+}
+
+//newPeerConnection ice-lite PeerConnection only with codecs, using payload type of offer
+func newPeerConnection(setting webrtc.SettingEngine, codecs map[webrtc.RTPCodecType]vrCodec) (*webrtc.PeerConnection, error) {
+	m := webrtc.MediaEngine{}
+	for _, codec := range codecs {
+		m.RegisterCodec(codecFactories[codec.name](codec.pt, codec.clockRate))
+	}
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting))
+	return api.NewPeerConnection(webrtc.Configuration{SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback})
+}
+
+//firstCodec first codec of kind in offer supported by the fake, in order of m line
+func firstCodec(offer string, kind webrtc.RTPCodecType) (vrCodec, bool) {
+	codecs, err := sdpCodecs(offer)
+	if err != nil {
+		return vrCodec{}, false
+	}
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(offer)); err != nil {
+		return vrCodec{}, false
+	}
+	for _, md := range sd.MediaDescriptions {
+		if webrtc.NewRTPCodecType(md.MediaName.Media) != kind || md.MediaName.Port.Value == 0 {
+			continue
+		}
+		for _, format := range md.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 8)
+			if err != nil {
+				continue
+			}
+			for _, codec := range codecs {
+				if codec.kind == kind && codec.pt == uint8(pt) {
+					return codec, true
+				}
+			}
+		}
+	}
+	return vrCodec{}, false
+}
+
+//sdpCodecs supported codecs of sdp by name, first payload type is used
+func sdpCodecs(sdpString string) (map[string]vrCodec, error) {
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(sdpString)); err != nil {
+		return nil, err
+	}
+	codecs := make(map[string]vrCodec)
+	for _, md := range sd.MediaDescriptions {
+		kind := webrtc.NewRTPCodecType(md.MediaName.Media)
+		if kind == 0 || md.MediaName.Port.Value == 0 {
+			continue
+		}
+		for _, format := range md.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 8)
+			if err != nil {
+				continue
+			}
+			codec, err := sd.GetCodecForPayloadType(uint8(pt))
+			if err != nil {
+				continue
+			}
+			name := strings.ToLower(codec.Name)
+			if _, ok := codecFactories[name]; !ok {
+				continue
+			}
+			if _, ok := codecs[name]; !ok {
+				codecs[name] = vrCodec{kind: kind, name: name, pt: codec.PayloadType, clockRate: codec.ClockRate}
+			}
+		}
+	}
+	return codecs, nil
+}
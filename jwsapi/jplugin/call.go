@@ -0,0 +1,69 @@
+package jplugin
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pkg/errors"
+)
+
+//Response typed response of Call
+type Response[T any] struct {
+	Data    T               //plugindata.data
+	Event   string          //name of event, see Plugin.EventName
+	JSEP    *JSEP           //jsep of event, nil if not
+	Message *jwsapi.Message //raw response
+}
+
+//Call send req to h of plugin p and decode plugindata.data to T
+//synchronous request (Plugin.IsSync) wait success, others wait ack and event of transaction,
+//jsep is sent with message if not nil, opts set other fields of body, eg: WithMessageOptionSecret
+//request of empty name is sent without request field, eg: echotest
+//error of plugindata and janus is *Error
+func Call[T any](ctx context.Context, h *jwsapi.Handle, p *Plugin, req Request, jsep *JSEP, opts ...jwsapi.MessageOption) (*Response[T], error) {
+	name := req.Request()
+	body, err := Encode(req)
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+	if name != "" {
+		body[jwsapi.AttrRequest] = name
+	}
+	for _, opt := range opts {
+		opt(body)
+	}
+
+	var rsp *jwsapi.Message
+	switch {
+	case p.IsSync(name):
+		rsp, err = h.RequestContext(ctx, body)
+	case jsep != nil:
+		msg := jwsapi.Message{"type": jsep.Type, "sdp": jsep.SDP}
+		if jsep.Trickle != nil {
+			msg["trickle"] = *jsep.Trickle
+		}
+		rsp, err = h.JsepMessageContext(ctx, body, msg)
+	default:
+		rsp, err = h.MessageContext(ctx, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse[T](p, rsp)
+}
+
+func decodeResponse[T any](p *Plugin, rsp *jwsapi.Message) (*Response[T], error) {
+	data := pluginData(rsp)
+	if data == nil {
+		return nil, errors.New("not plugindata")
+	}
+	r := &Response[T]{
+		Event:   p.EventName(data),
+		JSEP:    jsep(rsp),
+		Message: rsp,
+	}
+	if err := Decode(data, &r.Data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,68 @@
+package jplugin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+)
+
+const testPlugin = "janus.plugin.test"
+
+var descriptor = jplugin.NewPlugin(testPlugin, "test", "sync")
+
+type testRequest struct {
+	name string
+}
+
+func (r testRequest) Request() string { return r.name }
+
+func newTestHandle(t *testing.T, plugin janustest.PluginFunc) *jwsapi.Handle {
+	t.Helper()
+	s := janustest.NewServer()
+	t.Cleanup(s.Close)
+	s.RegisterPlugin(testPlugin, plugin)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	h := janustest.Attach(t, ctx, s, testPlugin)
+	return h
+}
+
+func TestCallError(t *testing.T) {
+	h := newTestHandle(t, func(req *janustest.Request) {
+		switch req.Name() {
+		case "sync":
+			req.PluginError(426, "No such room", nil)
+		case "janus":
+			//error instead of ack
+			req.Error(janustest.ErrorInvalidRequestPath, "Unhandled request")
+		default:
+			req.Ack()
+			req.Event(jwsapi.Message{"test": "event", "error_code": 427, "error": "Room exists"}, nil)
+		}
+	})
+
+	tests := []struct {
+		request string
+		code    int
+		reason  string
+	}{
+		{"sync", 426, "No such room"},
+		{"janus", janustest.ErrorInvalidRequestPath, "Unhandled request"},
+		{"async", 427, "Room exists"},
+	}
+	for _, tt := range tests {
+		_, err := jplugin.Call[struct{}](context.Background(), h, descriptor, testRequest{tt.request}, nil)
+		var info *jplugin.Error
+		if !errors.As(err, &info) {
+			t.Errorf("%s: err = %v, want *jplugin.Error", tt.request, err)
+			continue
+		}
+		if info.Code != tt.code || err.Error() != tt.reason {
+			t.Errorf("%s: err = %d %q, want %d %q", tt.request, info.Code, err.Error(), tt.code, tt.reason)
+		}
+	}
+}
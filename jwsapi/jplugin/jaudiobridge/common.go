@@ -0,0 +1,251 @@
+//Package jaudiobridge janus-gateway audiobridge plugin, mixing room management and member
+//see https://janus.conf.meetecho.com/docs/audiobridge.html
+package jaudiobridge
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Plugin janus-gateway audiobridge plugin name
+const Plugin = "janus.plugin.audiobridge"
+
+//Descriptor audiobridge plugin descriptor for jplugin.Call, jplugin.Router
+var Descriptor = jplugin.NewPlugin(Plugin, "audiobridge",
+	"create", "edit", "destroy", "exists", "list", "listparticipants", "mute", "unmute", "kick",
+	"play_file", "stop_file", "is_playing", "rtp_forward", "stop_rtp_forward", "listforwarders")
+
+//WithMessageOptionSecret set secret of room, for edit,destroy,mute,unmute,kick,play_file,stop_file
+func WithMessageOptionSecret(secret string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["secret"] = secret
+	}
+}
+
+//WithMessageOptionPin set pin of room, for create,join,changeroom
+func WithMessageOptionPin(pin string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["pin"] = pin
+	}
+}
+
+//WithMessageOptionPermanent save to config file, for create,edit,destroy
+func WithMessageOptionPermanent(permanent bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["permanent"] = permanent
+	}
+}
+
+//WithMessageOptionDescription set description of room, for create
+func WithMessageOptionDescription(description string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["description"] = description
+	}
+}
+
+//WithMessageOptionSamplingRate set sampling rate of room, 8000, 12000, 16000, 24000, 48000, for create
+func WithMessageOptionSamplingRate(rate uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["sampling_rate"] = rate
+	}
+}
+
+//WithMessageOptionAudioLevelEvent enable talking events of room, for create
+//active packets (audio_active_packets) and average level (audio_level_average) use room default if 0
+func WithMessageOptionAudioLevelEvent(activePackets uint32, levelAverage uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audiolevel_event"] = true
+		if activePackets > 0 {
+			msg["audio_active_packets"] = activePackets
+		}
+		if levelAverage > 0 {
+			msg["audio_level_average"] = levelAverage
+		}
+	}
+}
+
+//WithMessageOptionDisplay set display of member, for join,configure,changeroom
+func WithMessageOptionDisplay(display string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["display"] = display
+	}
+}
+
+//WithMessageOptionMuted join or configure muted
+func WithMessageOptionMuted(muted bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["muted"] = muted
+	}
+}
+
+//WithMessageOptionQuality opus complexity 1~10, for join,configure
+func WithMessageOptionQuality(quality uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["quality"] = quality
+	}
+}
+
+//WithMessageOptionBitrate opus bitrate of mixed stream to member, bps, for join,configure
+func WithMessageOptionBitrate(bitrate uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["bitrate"] = bitrate
+	}
+}
+
+//WithMessageOptionVolume volume of member in mix, percent, 100 is no change, for join,configure
+func WithMessageOptionVolume(volume uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["volume"] = volume
+	}
+}
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type roomRequest struct {
+	Room uint64 `json:"room"`
+	name string
+}
+
+func (r roomRequest) Request() string { return r.name }
+
+type createRequest struct {
+	Room uint64 `json:"room,omitempty"`
+}
+
+func (createRequest) Request() string { return "create" }
+
+type participantRequest struct {
+	Room uint64 `json:"room"`
+	ID   uint64 `json:"id"`
+	name string
+}
+
+func (r participantRequest) Request() string { return r.name }
+
+type playFileRequest struct {
+	Room     uint64 `json:"room"`
+	Filename string `json:"filename"`
+	FileID   string `json:"file_id,omitempty"`
+	Loop     bool   `json:"loop"`
+}
+
+func (playFileRequest) Request() string { return "play_file" }
+
+type stopFileRequest struct {
+	Room   uint64 `json:"room"`
+	FileID string `json:"file_id"`
+}
+
+func (stopFileRequest) Request() string { return "stop_file" }
+
+//CreateRoom create room, room is 0 janus-gateway choose a random id
+//return id of room
+func CreateRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) (uint64, error) {
+	rsp, err := jplugin.Call[struct {
+		Room uint64 `json:"room"`
+	}](context.Background(), h, Descriptor, createRequest{Room: room}, nil, opts...)
+	if err != nil {
+		return 0, err
+	}
+	if rsp.Data.Room == 0 {
+		return 0, errors.New("not room")
+	}
+	return rsp.Data.Room, nil
+}
+
+//EditRoom edit room, jwsapi.WithMessageOption("new_description","xx") for new_description, new_secret, new_pin, new_is_private
+func EditRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "edit"}, opts...)
+}
+
+//DestroyRoom destroy room, participants are notified
+func DestroyRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "destroy"}, opts...)
+}
+
+//Exists room exists
+func Exists(h *jwsapi.Handle, room uint64) (bool, error) {
+	rsp, err := jplugin.Call[struct {
+		Exists bool `json:"exists"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "exists"}, nil)
+	if err != nil {
+		return false, err
+	}
+	return rsp.Data.Exists, nil
+}
+
+//List list all public rooms
+func List(h *jwsapi.Handle) ([]Room, error) {
+	rsp, err := jplugin.Call[struct {
+		List []jwsapi.Message `json:"list"`
+	}](context.Background(), h, Descriptor, request{"list"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make([]Room, 0, len(rsp.Data.List))
+	for _, room := range rsp.Data.List {
+		if room != nil {
+			rooms = append(rooms, Room{room})
+		}
+	}
+	return rooms, nil
+}
+
+//ListParticipants list participants of room
+func ListParticipants(h *jwsapi.Handle, room uint64) ([]Participant, error) {
+	rsp, err := jplugin.Call[struct {
+		Participants []interface{} `json:"participants"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "listparticipants"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return participants(rsp.Data.Participants), nil
+}
+
+//Mute mute participant id of room
+func Mute(h *jwsapi.Handle, room uint64, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, participantRequest{Room: room, ID: id, name: "mute"}, opts...)
+}
+
+//Unmute unmute participant id of room
+func Unmute(h *jwsapi.Handle, room uint64, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, participantRequest{Room: room, ID: id, name: "unmute"}, opts...)
+}
+
+//Kick kick participant id out of room
+func Kick(h *jwsapi.Handle, room uint64, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, participantRequest{Room: room, ID: id, name: "kick"}, opts...)
+}
+
+//PlayFile play opus file(.opus) into room, fileID is empty janus-gateway choose a random id
+//return id of file, for StopFile
+func PlayFile(h *jwsapi.Handle, room uint64, filename string, fileID string, loop bool, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[struct {
+		FileID string `json:"file_id"`
+	}](context.Background(), h, Descriptor, playFileRequest{Room: room, Filename: filename, FileID: fileID, Loop: loop}, nil, opts...)
+	if err != nil {
+		return "", err
+	}
+	if rsp.Data.FileID == "" {
+		return "", errors.New("not file_id")
+	}
+	return rsp.Data.FileID, nil
+}
+
+//StopFile stop file of PlayFile
+func StopFile(h *jwsapi.Handle, room uint64, fileID string, opts ...jwsapi.MessageOption) error {
+	return call(h, stopFileRequest{Room: room, FileID: fileID}, opts...)
+}
+
+//call request without result
+func call(h *jwsapi.Handle, req jplugin.Request, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
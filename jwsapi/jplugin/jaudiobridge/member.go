@@ -0,0 +1,357 @@
+package jaudiobridge
+
+import (
+	"context"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Member member of audiobridge room, join, configure, changeroom, leave
+type Member struct {
+	handle *jwsapi.Handle
+
+	mu      sync.Mutex
+	room    uint64
+	id      uint64
+	display string
+	parts   map[uint64]Participant
+	//callback
+	onParticipant func(Participant)
+	onLeaving     func(uint64)
+	onKicked      func(uint64)
+	onTalking     func(TalkingEvent)
+	onDestroyed   func(uint64)
+	onEvent       func(jwsapi.Message)
+}
+
+//MemberOption option for Member
+type MemberOption func(*Member)
+
+//WithMemberID client set member id, optional
+func WithMemberID(id uint64) MemberOption {
+	return func(m *Member) {
+		m.id = id
+	}
+}
+
+//WithMemberDisplay set display of member, optional
+func WithMemberDisplay(display string) MemberOption {
+	return func(m *Member) {
+		m.display = display
+	}
+}
+
+//WithMemberParticipant callback when other participant joined or updated (muted, display, setup)
+func WithMemberParticipant(callback func(Participant)) MemberOption {
+	return func(m *Member) {
+		m.onParticipant = callback
+	}
+}
+
+//WithMemberLeaving callback when other participant leaving
+func WithMemberLeaving(callback func(id uint64)) MemberOption {
+	return func(m *Member) {
+		m.onLeaving = callback
+	}
+}
+
+//WithMemberKicked callback when participant kicked, id is Member.ID() if itself is kicked
+func WithMemberKicked(callback func(id uint64)) MemberOption {
+	return func(m *Member) {
+		m.onKicked = callback
+	}
+}
+
+//WithMemberTalking callback of talking and stopped-talking, audiolevel_event of room must be enabled
+func WithMemberTalking(callback func(TalkingEvent)) MemberOption {
+	return func(m *Member) {
+		m.onTalking = callback
+	}
+}
+
+//WithMemberDestroyed callback when room destroyed
+func WithMemberDestroyed(callback func(room uint64)) MemberOption {
+	return func(m *Member) {
+		m.onDestroyed = callback
+	}
+}
+
+//WithMemberEvent callback of other asynchronous event, eg: announcement-started
+func WithMemberEvent(callback func(jwsapi.Message)) MemberOption {
+	return func(m *Member) {
+		m.onEvent = callback
+	}
+}
+
+//NewMember create member of room, h is handle of janus.plugin.audiobridge
+func NewMember(ctx context.Context, h *jwsapi.Handle, room uint64, opts ...MemberOption) *Member {
+	m := &Member{
+		handle: h,
+		room:   room,
+		parts:  make(map[uint64]Participant),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(func(e *jplugin.Event) {
+		m.onPluginEvent(e.Data)
+	})
+	go r.Run(ctx, h)
+	return m
+}
+
+//ID return member id, assigned by janus-gateway after join
+func (m *Member) ID() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.id
+}
+
+//Room return room, changed by ChangeRoom
+func (m *Member) Room() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.room
+}
+
+//Display return display
+func (m *Member) Display() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.display
+}
+
+//Handle return handle
+func (m *Member) Handle() *jwsapi.Handle {
+	return m.handle
+}
+
+//SetOption set callback, eg: WithMemberTalking
+func (m *Member) SetOption(opts ...MemberOption) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, opt := range opts {
+		opt(m)
+	}
+}
+
+//Participants return other participants of room
+func (m *Member) Participants() []Participant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts := make([]Participant, 0, len(m.parts))
+	for _, part := range m.parts {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+//Join join room without PeerConnection, Configure with offer later
+//WithMessageOptionPin, WithMessageOptionMuted... for other params
+func (m *Member) Join(opts ...jwsapi.MessageOption) error {
+	_, err := m.JoinContext(context.Background(), "", opts...)
+	return err
+}
+
+//JoinContext join room, offer is not empty negotiate PeerConnection at once, ctx using for cancel and trace
+//return sdp(answer) if offer is not empty
+func (m *Member) JoinContext(ctx context.Context, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := m.join(ctx, offer, opts...)
+	if err != nil {
+		return "", err
+	}
+	return answerOf(rsp, offer)
+}
+
+type joinRequest struct {
+	Room    uint64 `json:"room"`
+	ID      uint64 `json:"id,omitempty"`
+	Display string `json:"display,omitempty"`
+	name    string
+}
+
+func (r joinRequest) Request() string { return r.name }
+
+//joinRequest join or changeroom of room, with id and display of member
+func (m *Member) joinRequest(name string, room uint64) joinRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return joinRequest{Room: room, ID: m.id, Display: m.display, name: name}
+}
+
+//join send join, return response if joined
+func (m *Member) join(ctx context.Context, offer string, opts ...jwsapi.MessageOption) (*jplugin.Response[jwsapi.Message], error) {
+	rsp, err := m.call(ctx, m.joinRequest("join", m.Room()), offer, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Event != "joined" {
+		return nil, errors.Errorf("unexpected %s", rsp.Event)
+	}
+	m.onJoined(rsp.Data)
+	return rsp, nil
+}
+
+//Configure configure member, offer is not empty (re)negotiate PeerConnection
+//WithMessageOptionMuted, WithMessageOptionDisplay, WithMessageOptionVolume... for params
+//return sdp(answer) if offer is not empty
+func (m *Member) Configure(offer string, opts ...jwsapi.MessageOption) (string, error) {
+	return m.ConfigureContext(context.Background(), offer, opts...)
+}
+
+//ConfigureContext configure member, ctx using for cancel and trace
+func (m *Member) ConfigureContext(ctx context.Context, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := m.call(ctx, request{"configure"}, offer, opts...)
+	if err != nil {
+		return "", err
+	}
+	body := jwsapi.Message{}
+	for _, opt := range opts {
+		opt(body)
+	}
+	if display, ok := body.String("display"); ok {
+		m.mu.Lock()
+		m.display = display
+		m.mu.Unlock()
+	}
+	return answerOf(rsp, offer)
+}
+
+//SetMuted mute or unmute itself
+func (m *Member) SetMuted(muted bool) error {
+	_, err := m.Configure("", WithMessageOptionMuted(muted))
+	return err
+}
+
+//ChangeRoom leave current room and join room, PeerConnection is kept
+func (m *Member) ChangeRoom(room uint64, opts ...jwsapi.MessageOption) error {
+	rsp, err := m.call(context.Background(), m.joinRequest("changeroom", room), "", opts...)
+	if err != nil {
+		return err
+	}
+	if rsp.Event != "roomchanged" {
+		return errors.Errorf("unexpected %s", rsp.Event)
+	}
+	m.mu.Lock()
+	m.parts = make(map[uint64]Participant)
+	m.mu.Unlock()
+	m.onJoined(rsp.Data)
+	return nil
+}
+
+//Leave leave the room, PeerConnection is closed by janus-gateway
+func (m *Member) Leave() error {
+	_, err := m.call(context.Background(), request{"leave"}, "")
+	if err == nil {
+		m.mu.Lock()
+		m.parts = make(map[uint64]Participant)
+		m.mu.Unlock()
+	}
+	return err
+}
+
+//call send req with offer if not empty
+func (m *Member) call(ctx context.Context, req jplugin.Request, offer string, opts ...jwsapi.MessageOption) (*jplugin.Response[jwsapi.Message], error) {
+	var jsep *jplugin.JSEP
+	if offer != "" {
+		jsep = &jplugin.JSEP{Type: "offer", SDP: offer}
+	}
+	return jplugin.Call[jwsapi.Message](ctx, m.handle, Descriptor, req, jsep, opts...)
+}
+
+func answerOf(rsp *jplugin.Response[jwsapi.Message], offer string) (string, error) {
+	if offer == "" {
+		return "", nil
+	}
+	if rsp.JSEP == nil {
+		return "", errors.New("not jsep")
+	}
+	if rsp.JSEP.Type != "answer" {
+		return "", errors.New("jsep type error")
+	}
+	if rsp.JSEP.SDP == "" {
+		return "", errors.New("not sdp")
+	}
+	return rsp.JSEP.SDP, nil
+}
+
+//onJoined joined or roomchanged
+func (m *Member) onJoined(data jwsapi.Message) {
+	m.mu.Lock()
+	if room, ok := data.Uint64("room"); ok {
+		m.room = room
+	}
+	if id, ok := data.Uint64("id"); ok {
+		m.id = id
+	}
+	m.mu.Unlock()
+	m.onParticipants(data.Array("participants"))
+}
+
+func (m *Member) onParticipants(list []interface{}) {
+	for _, part := range participants(list) {
+		id := part.ID()
+		if id == 0 {
+			continue
+		}
+		m.mu.Lock()
+		m.parts[id] = part
+		callback := m.onParticipant
+		m.mu.Unlock()
+		if callback != nil {
+			callback(part)
+		}
+	}
+}
+
+func (m *Member) onPluginEvent(event jwsapi.Message) {
+	name, _ := event.String("audiobridge")
+	room, _ := event.Uint64("room")
+	m.mu.Lock()
+	onLeaving, onKicked, onTalking, onDestroyed, onEvent := m.onLeaving, m.onKicked, m.onTalking, m.onDestroyed, m.onEvent
+	m.mu.Unlock()
+
+	switch name {
+	case "talking", "stopped-talking":
+		if onTalking != nil {
+			id, _ := event.Uint64("id")
+			onTalking(TalkingEvent{Room: room, ID: id, Talking: name == "talking"})
+		}
+		return
+	case "destroyed":
+		if onDestroyed != nil {
+			onDestroyed(room)
+		}
+		return
+	case "event":
+		if list := event.Array("participants"); list != nil {
+			m.onParticipants(list)
+			return
+		}
+		if leaving, ok := event.Uint64("leaving"); ok {
+			m.mu.Lock()
+			delete(m.parts, leaving)
+			m.mu.Unlock()
+			if onLeaving != nil {
+				onLeaving(leaving)
+			}
+			return
+		}
+		if kicked, ok := event.Uint64("kicked"); ok {
+			m.mu.Lock()
+			delete(m.parts, kicked)
+			m.mu.Unlock()
+			if onKicked != nil {
+				onKicked(kicked)
+			}
+			return
+		}
+	}
+	if onEvent != nil {
+		onEvent(event)
+	}
+}
@@ -0,0 +1,86 @@
+package jaudiobridge
+
+import "github.com/newzai/janus-go/jwsapi"
+
+//Room room info of list
+type Room struct {
+	jwsapi.Message
+}
+
+//ID room id
+func (r *Room) ID() uint64 {
+	room, _ := r.Uint64("room")
+	return room
+}
+
+//Description room description
+func (r *Room) Description() string {
+	description, _ := r.String("description")
+	return description
+}
+
+//SamplingRate sampling rate of mix
+func (r *Room) SamplingRate() uint32 {
+	rate, _ := r.Uint32("sampling_rate")
+	return rate
+}
+
+//PinRequired pin is required to join
+func (r *Room) PinRequired() bool {
+	return r.Bool("pin_required")
+}
+
+//NumParticipants count of participants
+func (r *Room) NumParticipants() int {
+	num, _ := r.Uint64("num_participants")
+	return int(num)
+}
+
+//Participant participant info of listparticipants, joined and event
+type Participant struct {
+	jwsapi.Message
+}
+
+//ID participant id
+func (p *Participant) ID() uint64 {
+	id, _ := p.Uint64("id")
+	return id
+}
+
+//Display display of participant
+func (p *Participant) Display() string {
+	display, _ := p.String("display")
+	return display
+}
+
+//Setup PeerConnection of participant is up
+func (p *Participant) Setup() bool {
+	return p.Bool("setup")
+}
+
+//Muted participant is muted
+func (p *Participant) Muted() bool {
+	return p.Bool("muted")
+}
+
+//Talking participant is talking, only if audiolevel_event of room is enabled
+func (p *Participant) Talking() bool {
+	return p.Bool("talking")
+}
+
+//TalkingEvent talking or stopped-talking of participant
+type TalkingEvent struct {
+	Room    uint64
+	ID      uint64
+	Talking bool //false, stopped-talking
+}
+
+func participants(list []interface{}) []Participant {
+	parts := make([]Participant, 0, len(list))
+	for _, item := range list {
+		if part, ok := item.(map[string]interface{}); ok {
+			parts = append(parts, Participant{jwsapi.Message(part)})
+		}
+	}
+	return parts
+}
@@ -0,0 +1,208 @@
+package jaudiobridge
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//RTPConfig plain rtp participant, join without PeerConnection
+type RTPConfig struct {
+	IP            string //ip to receive mix, empty janus-gateway only receive rtp of participant
+	Port          int    //port to receive mix
+	PayloadType   uint8  //0, janus-gateway default (100)
+	AudioLevelExt uint8  //id of ssrc-audio-level extension, 0 not used
+	FEC           bool   //opus inband fec
+}
+
+func (c *RTPConfig) message() jwsapi.Message {
+	msg := jwsapi.Message{}
+	if c.IP != "" {
+		msg["ip"] = c.IP
+		msg["port"] = c.Port
+	}
+	if c.PayloadType > 0 {
+		msg["payload_type"] = c.PayloadType
+	}
+	if c.AudioLevelExt > 0 {
+		msg["audiolevel_ext"] = c.AudioLevelExt
+	}
+	if c.FEC {
+		msg["fec"] = true
+	}
+	return msg
+}
+
+//RTPInfo janus-gateway side of plain rtp participant, rtp of participant should be sent to IP:Port
+type RTPInfo struct {
+	IP          string
+	Port        int
+	PayloadType uint8
+}
+
+//JoinRTP join room as plain rtp participant, opus only
+//WithMessageOptionPin, WithMessageOptionMuted... for other params
+func (m *Member) JoinRTP(ctx context.Context, config RTPConfig, opts ...jwsapi.MessageOption) (*RTPInfo, error) {
+	opts = append([]jwsapi.MessageOption{jwsapi.WithMessageOption("rtp", config.message())}, opts...)
+	rsp, err := m.join(ctx, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	rtp, ok := rsp.Data.SubMessage("rtp")
+	if !ok {
+		return nil, errors.New("not rtp")
+	}
+	info := &RTPInfo{}
+	info.IP, _ = rtp.String("ip")
+	port, ok := rtp.Uint64("port")
+	if !ok {
+		return nil, errors.New("not rtp port")
+	}
+	info.Port = int(port)
+	pt, _ := rtp.Uint64("payload_type")
+	info.PayloadType = uint8(pt)
+	return info, nil
+}
+
+//ForwardConfig destination of rtp_forward, mix of room is sent to Host:Port
+type ForwardConfig struct {
+	Host       string
+	Port       int
+	SSRC       uint32 //0, janus-gateway choose
+	PT         uint8  //0, janus-gateway default (100)
+	Codec      string //empty, opus, or pcma, pcmu, g722 (janus-gateway 1.x)
+	Group      string //optional, mix of group only
+	SRTPSuite  int    //optional, 32 or 80
+	SRTPCrypto string //base64 of master key and salt
+	AlwaysOn   bool   //forward silence when nobody talks
+}
+
+func (c *ForwardConfig) fill(msg jwsapi.Message) {
+	msg["host"] = c.Host
+	msg["port"] = c.Port
+	if c.SSRC > 0 {
+		msg["ssrc"] = c.SSRC
+	}
+	if c.PT > 0 {
+		msg["ptype"] = c.PT
+	}
+	if c.Codec != "" {
+		msg["codec"] = c.Codec
+	}
+	if c.Group != "" {
+		msg["group"] = c.Group
+	}
+	if c.SRTPSuite > 0 {
+		msg["srtp_suite"] = c.SRTPSuite
+		msg["srtp_crypto"] = c.SRTPCrypto
+	}
+	if c.AlwaysOn {
+		msg["always_on"] = true
+	}
+}
+
+//WithMessageOptionAdminKey set admin_key, if janus-gateway required it for rtp_forward
+func WithMessageOptionAdminKey(adminKey string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["admin_key"] = adminKey
+	}
+}
+
+type stopForwardRequest struct {
+	Room     uint64 `json:"room"`
+	StreamID uint64 `json:"stream_id"`
+}
+
+func (stopForwardRequest) Request() string { return "stop_rtp_forward" }
+
+//RTPForward forward mix of room to config.Host:config.Port
+//WithMessageOptionSecret, WithMessageOptionAdminKey for authorization
+//return stream id, for StopRTPForward
+func RTPForward(h *jwsapi.Handle, room uint64, config *ForwardConfig, opts ...jwsapi.MessageOption) (uint64, error) {
+	opts = append([]jwsapi.MessageOption{config.fill}, opts...)
+	rsp, err := jplugin.Call[struct {
+		StreamID uint64 `json:"stream_id"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "rtp_forward"}, nil, opts...)
+	if err != nil {
+		return 0, err
+	}
+	if rsp.Data.StreamID == 0 {
+		return 0, errors.New("not stream_id")
+	}
+	return rsp.Data.StreamID, nil
+}
+
+//StopRTPForward stop rtp_forward of stream id
+func StopRTPForward(h *jwsapi.Handle, room uint64, streamID uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, stopForwardRequest{Room: room, StreamID: streamID}, opts...)
+}
+
+//Forwarder rtp forwarder of listforwarders
+type Forwarder struct {
+	jwsapi.Message
+}
+
+//StreamID stream id of forwarder
+func (f *Forwarder) StreamID() uint64 {
+	id, _ := f.Uint64("stream_id")
+	return id
+}
+
+//IP destination ip
+func (f *Forwarder) IP() string {
+	ip, _ := f.String("ip")
+	return ip
+}
+
+//Port destination port
+func (f *Forwarder) Port() int {
+	port, _ := f.Uint64("port")
+	return int(port)
+}
+
+//SSRC ssrc of forwarded rtp
+func (f *Forwarder) SSRC() uint32 {
+	ssrc, _ := f.Uint32("ssrc")
+	return ssrc
+}
+
+//PT payload type of forwarded rtp
+func (f *Forwarder) PT() uint8 {
+	pt, _ := f.Uint64("ptype")
+	return uint8(pt)
+}
+
+//Codec codec of forwarded rtp
+func (f *Forwarder) Codec() string {
+	codec, _ := f.String("codec")
+	return codec
+}
+
+//SRTP forwarded as srtp
+func (f *Forwarder) SRTP() bool {
+	return f.Bool("srtp")
+}
+
+//AlwaysOn forward silence when nobody talks
+func (f *Forwarder) AlwaysOn() bool {
+	return f.Bool("always_on")
+}
+
+//ListForwarders list rtp forwarders of room
+func ListForwarders(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) ([]Forwarder, error) {
+	rsp, err := jplugin.Call[struct {
+		Forwarders []jwsapi.Message `json:"rtp_forwarders"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "listforwarders"}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	forwarders := make([]Forwarder, 0, len(rsp.Data.Forwarders))
+	for _, forwarder := range rsp.Data.Forwarders {
+		if forwarder != nil {
+			forwarders = append(forwarders, Forwarder{forwarder})
+		}
+	}
+	return forwarders, nil
+}
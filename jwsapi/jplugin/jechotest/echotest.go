@@ -0,0 +1,173 @@
+//Package jechotest janus-gateway echotest plugin, media sent is echoed back by janus
+//see https://janus.conf.meetecho.com/docs/echotest.html
+package jechotest
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Plugin janus-gateway echotest plugin name
+const Plugin = "janus.plugin.echotest"
+
+//WithMessageOptionAudio enable or disable audio echo
+func WithMessageOptionAudio(audio bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audio"] = audio
+	}
+}
+
+//WithMessageOptionVideo enable or disable video echo
+func WithMessageOptionVideo(video bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video"] = video
+	}
+}
+
+//WithMessageOptionBitrate cap video bitrate by REMB, bps, 0 is no limit
+func WithMessageOptionBitrate(bitrate uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["bitrate"] = bitrate
+	}
+}
+
+//WithMessageOptionRecord start or stop record, filename is base path of recording, optional
+func WithMessageOptionRecord(record bool, filename string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["record"] = record
+		if filename != "" {
+			msg["filename"] = filename
+		}
+	}
+}
+
+//Descriptor echotest plugin descriptor for jplugin.Call, jplugin.Router
+var Descriptor = jplugin.NewPlugin(Plugin, "echotest")
+
+//EchoTest echotest handle
+type EchoTest struct {
+	handle  *jwsapi.Handle
+	onEvent func(jwsapi.Message)
+}
+
+//EchoTestOption option for EchoTest
+type EchoTestOption func(*EchoTest)
+
+//WithEchoTestEvent callback of asynchronous event, eg: {"echotest":"event","result":"done"}
+func WithEchoTestEvent(callback func(jwsapi.Message)) EchoTestOption {
+	return func(e *EchoTest) {
+		e.onEvent = callback
+	}
+}
+
+//NewEchoTest create echotest, h is handle of janus.plugin.echotest
+func NewEchoTest(ctx context.Context, h *jwsapi.Handle, opts ...EchoTestOption) *EchoTest {
+	e := &EchoTest{
+		handle: h,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(func(event *jplugin.Event) {
+		if e.onEvent != nil {
+			e.onEvent(event.Data)
+		}
+	})
+	go r.Run(ctx, h)
+	return e
+}
+
+//Handle return handle
+func (e *EchoTest) Handle() *jwsapi.Handle {
+	return e.handle
+}
+
+//echotest request has no request name, audio, video and others are set by opts
+type configureRequest struct{}
+
+func (configureRequest) Request() string { return "" }
+
+type startRequest struct {
+	Audio bool `json:"audio"`
+	Video bool `json:"video"`
+}
+
+func (startRequest) Request() string { return "" }
+
+type result struct {
+	Result string `json:"result"`
+}
+
+//Start send offer, return answer
+//audio,video default is true, WithMessageOptionBitrate, WithMessageOptionRecord... for other params
+func (e *EchoTest) Start(offer string, opts ...jwsapi.MessageOption) (string, error) {
+	return e.StartContext(context.Background(), offer, opts...)
+}
+
+//StartContext send offer, ctx using for cancel and trace
+//return answer, error
+func (e *EchoTest) StartContext(ctx context.Context, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[result](ctx, e.handle, Descriptor, startRequest{Audio: true, Video: true}, &jplugin.JSEP{Type: "offer", SDP: offer}, opts...)
+	if err != nil {
+		return "", err
+	}
+	if rsp.Data.Result != "ok" {
+		return "", errors.New("echotest result not ok")
+	}
+	if rsp.JSEP == nil {
+		return "", errors.New("not jsep")
+	}
+	if rsp.JSEP.Type != "answer" {
+		return "", errors.New("jsep type error")
+	}
+	if rsp.JSEP.SDP == "" {
+		return "", errors.New("not sdp")
+	}
+	return rsp.JSEP.SDP, nil
+}
+
+//Configure change audio,video,bitrate,record during echo
+func (e *EchoTest) Configure(opts ...jwsapi.MessageOption) error {
+	return e.ConfigureContext(context.Background(), opts...)
+}
+
+//ConfigureContext change audio,video,bitrate,record during echo, ctx using for cancel and trace
+func (e *EchoTest) ConfigureContext(ctx context.Context, opts ...jwsapi.MessageOption) error {
+	rsp, err := jplugin.Call[result](ctx, e.handle, Descriptor, configureRequest{}, nil, opts...)
+	if err != nil {
+		return err
+	}
+	if rsp.Data.Result != "ok" {
+		return errors.New("echotest result not ok")
+	}
+	return nil
+}
+
+//SetAudio enable or disable audio echo
+func (e *EchoTest) SetAudio(audio bool) error {
+	return e.Configure(WithMessageOptionAudio(audio))
+}
+
+//SetVideo enable or disable video echo
+func (e *EchoTest) SetVideo(video bool) error {
+	return e.Configure(WithMessageOptionVideo(video))
+}
+
+//SetBitrate cap video bitrate, bps
+func (e *EchoTest) SetBitrate(bitrate uint32) error {
+	return e.Configure(WithMessageOptionBitrate(bitrate))
+}
+
+//Record start record to filename
+func (e *EchoTest) Record(filename string) error {
+	return e.Configure(WithMessageOptionRecord(true, filename))
+}
+
+//StopRecord stop record
+func (e *EchoTest) StopRecord() error {
+	return e.Configure(WithMessageOptionRecord(false, ""))
+}
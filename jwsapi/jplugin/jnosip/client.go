@@ -0,0 +1,178 @@
+package jnosip
+
+import (
+	"context"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//Client nosip session of handle, webrtc peer on handle, plain rtp peer negotiated by barebone sdp
+//
+//webrtc peer offer:
+//
+//	c := jnosip.NewClient(ctx, h)
+//	local, err := c.Generate(jnosip.SDPTypeOffer, offer) //barebone offer of janus
+//	peer, err := nosip.Answer("127.0.0.1", local)      //open udp sockets of worker
+//	answer, err := c.Process(peer.SessionDescription(jnosip.SDPTypeAnswer)) //answer of webrtc peer
+//
+//plain rtp peer offer:
+//
+//	peer, err := nosip.Listen("127.0.0.1", media...)
+//	offer, err := c.Process(peer.SessionDescription(jnosip.SDPTypeOffer)) //offer of webrtc peer
+//	local, err := c.Generate(jnosip.SDPTypeAnswer, answer)              //barebone answer of janus
+//	peer.Connect(local)
+type Client struct {
+	ctx    context.Context
+	handle *jwsapi.Handle
+
+	mu     sync.Mutex
+	local  *SessionDescription //barebone sdp of janus
+	remote *SessionDescription //barebone sdp of rtp peer
+	//callback
+	onEvent func(Event)
+}
+
+//ClientOption option for Client
+type ClientOption func(*Client)
+
+//WithClientEvent callback of asynchronous event
+func WithClientEvent(callback func(Event)) ClientOption {
+	return func(c *Client) {
+		c.onEvent = callback
+	}
+}
+
+//NewClient create client, h is handle of janus.plugin.nosip
+func NewClient(ctx context.Context, h *jwsapi.Handle, opts ...ClientOption) *Client {
+	c := &Client{
+		ctx:    ctx,
+		handle: h,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(c.onRouterEvent)
+	go r.Run(ctx, h)
+	return c
+}
+
+//Handle return handle
+func (c *Client) Handle() *jwsapi.Handle {
+	return c.handle
+}
+
+//SetOption set callback, eg: WithClientEvent
+func (c *Client) SetOption(opts ...ClientOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+//Local return barebone sdp of janus, nil before Generate
+func (c *Client) Local() *SessionDescription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.local
+}
+
+//Remote return barebone sdp of rtp peer, nil before Process
+func (c *Client) Remote() *SessionDescription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remote
+}
+
+//Generate send jsep(jsepType) of webrtc peer, return barebone sdp of janus for rtp peer
+//jsepType is SDPTypeOffer or SDPTypeAnswer(answer of offer of Process)
+//WithMessageOptionSRTP, WithMessageOptionInfo... for other params
+func (c *Client) Generate(jsepType string, jsep string, opts ...jwsapi.MessageOption) (*SessionDescription, error) {
+	return c.GenerateContext(c.ctx, jsepType, jsep, opts...)
+}
+
+//GenerateContext generate, ctx using for cancel and trace
+func (c *Client) GenerateContext(ctx context.Context, jsepType string, jsep string, opts ...jwsapi.MessageOption) (*SessionDescription, error) {
+	rsp, err := jplugin.Call[jwsapi.Message](ctx, c.handle, Descriptor, request{"generate"}, &jplugin.JSEP{Type: jsepType, SDP: jsep}, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate")
+	}
+	e := newEvent(*rsp.Message)
+	if e.Type != EventGenerated {
+		return nil, errors.Errorf("generate: unexpected event %q", e.Type)
+	}
+	local, err := ParseSDP(e.SDPType, e.SDP)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate")
+	}
+	c.mu.Lock()
+	c.local = local
+	c.mu.Unlock()
+	return local, nil
+}
+
+//Process send barebone sdp of rtp peer, return jsep for webrtc peer, type of jsep is the same as remote.Type
+//WithMessageOptionSRTP, WithMessageOptionInfo... for other params
+func (c *Client) Process(remote *SessionDescription, opts ...jwsapi.MessageOption) (string, error) {
+	return c.ProcessContext(c.ctx, remote, opts...)
+}
+
+//ProcessContext process, ctx using for cancel and trace
+func (c *Client) ProcessContext(ctx context.Context, remote *SessionDescription, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[jwsapi.Message](ctx, c.handle, Descriptor, processRequest{Type: remote.Type, SDP: remote.Marshal()}, nil, opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "process")
+	}
+	e := newEvent(*rsp.Message)
+	if e.Type != EventProcessed {
+		return "", errors.Errorf("process: unexpected event %q", e.Type)
+	}
+	if e.JSEP == "" {
+		return "", errors.New("process: not jsep")
+	}
+	if e.JSEPType != remote.Type {
+		return "", errors.New("process: jsep type error")
+	}
+	c.mu.Lock()
+	c.remote = remote
+	c.mu.Unlock()
+	return e.JSEP, nil
+}
+
+//Hangup hangup session, PeerConnection is closed by janus-gateway
+func (c *Client) Hangup() error {
+	_, err := jplugin.Call[struct{}](c.ctx, c.handle, Descriptor, request{"hangup"}, nil)
+	c.mu.Lock()
+	c.local, c.remote = nil, nil
+	c.mu.Unlock()
+	return err
+}
+
+//Recording start or stop recording, action is RecordingStart, RecordingStop
+//WithMessageOptionAudio, WithMessageOptionPeerAudio, WithMessageOptionFilename... for other params
+func (c *Client) Recording(action string, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](c.ctx, c.handle, Descriptor, recordingRequest{Action: action}, nil, opts...)
+	return err
+}
+
+func (c *Client) onPluginEvent(e Event) {
+	c.mu.Lock()
+	onEvent := c.onEvent
+	c.mu.Unlock()
+	if onEvent != nil {
+		onEvent(e)
+	}
+}
+
+func (c *Client) onRouterEvent(e *jplugin.Event) {
+	if err := e.Data.PluginDataError(); err != nil {
+		log.Warn("error event", logging.F("handle", c.handle.ID), logging.F("err", err))
+		return
+	}
+	c.onPluginEvent(newEvent(*e.Message))
+}
@@ -0,0 +1,89 @@
+package jnosip_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jnosip"
+	"github.com/pion/webrtc/v2"
+)
+
+//newTestOffer webrtc peer with opus track, offer is set as local description
+func newTestOffer(t *testing.T) *webrtc.PeerConnection {
+	t.Helper()
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	setting := webrtc.SettingEngine{}
+	setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	track, err := pc.NewTrack(webrtc.DefaultPayloadTypeOpus, 1234, "audio", "nosip")
+	if err == nil {
+		_, err = pc.AddTrack(track)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.NoSIPPlugin, janustest.NewNoSIP())
+
+	pc := newTestOffer(t)
+	c := jnosip.NewClient(ctx, janustest.Attach(t, ctx, s, jnosip.Plugin))
+	//error of plugin is *jplugin.Error
+	_, err := c.Generate(jnosip.SDPTypeOffer, pc.LocalDescription().SDP, jnosip.WithMessageOptionSRTP(jnosip.SRTPMandatory))
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.NoSIPErrorInvalidElement {
+		t.Errorf("generate with srtp: err = %v, want %d", err, janustest.NoSIPErrorInvalidElement)
+	}
+
+	local, err := c.Generate(jnosip.SDPTypeOffer, pc.LocalDescription().SDP)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if local.Type != jnosip.SDPTypeOffer || local.MediaOf(jnosip.KindAudio) == nil {
+		t.Fatalf("barebone offer = %+v", local)
+	}
+	//plain rtp peer is package nosip, answer of it is barebone sdp
+	audio := local.MediaOf(jnosip.KindAudio)
+	peer := &jnosip.SessionDescription{Type: jnosip.SDPTypeAnswer, Address: "127.0.0.1", Media: []jnosip.Media{{
+		Kind:      jnosip.KindAudio,
+		Port:      40000,
+		RTCPPort:  40001,
+		Direction: jnosip.ReverseDirection(audio.Direction),
+		Codecs:    audio.Codecs[:1],
+	}}}
+	answer, err := c.Process(peer)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Local() != local || c.Remote() != peer {
+		t.Errorf("local %+v, remote %+v", c.Local(), c.Remote())
+	}
+	if err := c.Hangup(); err != nil {
+		t.Errorf("hangup: %v", err)
+	}
+}
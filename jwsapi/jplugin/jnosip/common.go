@@ -0,0 +1,113 @@
+//Package jnosip janus-gateway nosip plugin, translate webrtc to plain rtp with barebone sdp
+//the other side of plain rtp is negotiated by application, eg: media-processing worker, see package nosip
+//see https://janus.conf.meetecho.com/docs/nosip.html
+package jnosip
+
+import (
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+)
+
+//Plugin janus-gateway nosip plugin name
+const Plugin = "janus.plugin.nosip"
+
+//Descriptor nosip plugin descriptor for jplugin.Call, jplugin.Router
+//all requests of nosip are ack + event
+var Descriptor = jplugin.NewPlugin(Plugin, "nosip")
+
+var log = logging.Named("jnosip")
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type processRequest struct {
+	Type string `json:"type"`
+	SDP  string `json:"sdp"`
+}
+
+func (processRequest) Request() string { return "process" }
+
+type recordingRequest struct {
+	Action string `json:"action"`
+}
+
+func (recordingRequest) Request() string { return "recording" }
+
+//srtp of generate, process
+const (
+	SRTPOptional  = "sdes_optional"
+	SRTPMandatory = "sdes_mandatory"
+)
+
+//action of recording
+const (
+	RecordingStart = "start"
+	RecordingStop  = "stop"
+)
+
+//WithMessageOptionInfo opaque string of application, for generate,process
+func WithMessageOptionInfo(info string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["info"] = info
+	}
+}
+
+//WithMessageOptionSRTP SRTPOptional or SRTPMandatory, barebone sdp with a=crypto, for generate,process
+func WithMessageOptionSRTP(srtp string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["srtp"] = srtp
+	}
+}
+
+//WithMessageOptionSRTPProfile srtp profile, eg: AES_CM_128_HMAC_SHA1_80, for generate
+func WithMessageOptionSRTPProfile(profile string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["srtp_profile"] = profile
+	}
+}
+
+//WithMessageOptionUpdate sdp is renegotiation of session, for generate,process
+func WithMessageOptionUpdate(update bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["update"] = update
+	}
+}
+
+//WithMessageOptionAudio record audio of webrtc peer, for recording
+func WithMessageOptionAudio(audio bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audio"] = audio
+	}
+}
+
+//WithMessageOptionVideo record video of webrtc peer, for recording
+func WithMessageOptionVideo(video bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video"] = video
+	}
+}
+
+//WithMessageOptionPeerAudio record audio of rtp peer, for recording
+func WithMessageOptionPeerAudio(audio bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["peer_audio"] = audio
+	}
+}
+
+//WithMessageOptionPeerVideo record video of rtp peer, for recording
+func WithMessageOptionPeerVideo(video bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["peer_video"] = video
+	}
+}
+
+//WithMessageOptionFilename base path of mjr files, for recording
+func WithMessageOptionFilename(filename string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["filename"] = filename
+	}
+}
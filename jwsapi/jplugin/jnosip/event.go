@@ -0,0 +1,43 @@
+package jnosip
+
+import (
+	"github.com/newzai/janus-go/jwsapi"
+)
+
+//event type of Event, result.event of janus-gateway
+const (
+	EventGenerated        = "generated"
+	EventProcessed        = "processed"
+	EventHangingUp        = "hangingup"
+	EventRecordingUpdated = "recordingupdated"
+)
+
+//Event event of nosip plugin
+type Event struct {
+	Type     string //EventGenerated, EventProcessed, EventHangingUp...
+	SDPType  string //generated: type of barebone sdp, offer or answer
+	SDP      string //generated: barebone sdp
+	SRTP     string //generated, processed: SRTPOptional, SRTPMandatory if srtp
+	Update   bool   //generated, processed: renegotiation
+	JSEPType string //processed: jsep for webrtc peer, offer or answer
+	JSEP     string
+	Result   jwsapi.Message //raw result of janus-gateway
+}
+
+//newEvent event of janus message, Type is empty if not result
+func newEvent(msg jwsapi.Message) Event {
+	pluginData, _ := msg.SubMessage("plugindata")
+	data, _ := pluginData.SubMessage("data")
+	result, _ := data.SubMessage("result")
+	e := Event{Result: result}
+	e.Type, _ = result.String("event")
+	e.SDPType, _ = result.String("type")
+	e.SDP, _ = result.String("sdp")
+	e.SRTP, _ = result.String("srtp")
+	e.Update = result.Bool("update")
+	if jsep, ok := msg.SubMessage("jsep"); ok {
+		e.JSEPType, _ = jsep.String("type")
+		e.JSEP, _ = jsep.String("sdp")
+	}
+	return e
+}
@@ -0,0 +1,261 @@
+package jnosip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pion/sdp/v2"
+	"github.com/pkg/errors"
+)
+
+//type of SessionDescription
+const (
+	SDPTypeOffer  = "offer"
+	SDPTypeAnswer = "answer"
+)
+
+//kind of Media
+const (
+	KindAudio = "audio"
+	KindVideo = "video"
+)
+
+//direction of Media
+const (
+	DirectionSendRecv = "sendrecv"
+	DirectionSendOnly = "sendonly"
+	DirectionRecvOnly = "recvonly"
+	DirectionInactive = "inactive"
+)
+
+//SessionDescription barebone sdp of plain rtp
+type SessionDescription struct {
+	Type    string //SDPTypeOffer, SDPTypeAnswer
+	Address string //c= of session, eg: 192.168.1.10
+	Media   []Media
+}
+
+//Media m= of barebone sdp
+type Media struct {
+	Kind      string //KindAudio, KindVideo
+	Address   string //c= of media, empty is Address of session
+	Port      int    //rtp port, 0 is rejected
+	RTCPPort  int    //rtcp port, a=rtcp or Port+1
+	Direction string //DirectionSendRecv(default) ...
+	Codecs    []Codec
+	Crypto    []string //a=crypto of srtp, eg: 1 AES_CM_128_HMAC_SHA1_80 inline:xxx
+}
+
+//Codec payload type of m=
+type Codec struct {
+	PayloadType uint8
+	Name        string //eg: opus, PCMU, H264
+	ClockRate   uint32
+	Channels    uint16 //0 is 1
+	Fmtp        string //eg: packetization-mode=1
+}
+
+//staticCodecs codecs of static payload type without rtpmap
+var staticCodecs = map[uint8]Codec{
+	0: {PayloadType: 0, Name: "PCMU", ClockRate: 8000},
+	8: {PayloadType: 8, Name: "PCMA", ClockRate: 8000},
+	9: {PayloadType: 9, Name: "G722", ClockRate: 8000},
+}
+
+//ParseSDP parse barebone sdp of sdpType
+func ParseSDP(sdpType string, sdpString string) (*SessionDescription, error) {
+	sd := sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(sdpString)); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	s := &SessionDescription{Type: sdpType}
+	if sd.ConnectionInformation != nil && sd.ConnectionInformation.Address != nil {
+		s.Address = sd.ConnectionInformation.Address.Address
+	}
+	for _, md := range sd.MediaDescriptions {
+		m := Media{
+			Kind:      md.MediaName.Media,
+			Port:      md.MediaName.Port.Value,
+			Direction: DirectionSendRecv,
+		}
+		if md.ConnectionInformation != nil && md.ConnectionInformation.Address != nil {
+			m.Address = md.ConnectionInformation.Address.Address
+		}
+		if m.Port > 0 {
+			m.RTCPPort = m.Port + 1
+		}
+		index := make(map[uint8]int) //index of payload type in Codecs
+		for _, format := range md.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 8)
+			if err != nil {
+				continue
+			}
+			codec := staticCodecs[uint8(pt)]
+			codec.PayloadType = uint8(pt)
+			index[uint8(pt)] = len(m.Codecs)
+			m.Codecs = append(m.Codecs, codec)
+		}
+		for _, attr := range md.Attributes {
+			switch attr.Key {
+			case "rtpmap", "fmtp":
+				fields := strings.SplitN(attr.Value, " ", 2)
+				pt, err := strconv.ParseUint(fields[0], 10, 8)
+				i, ok := index[uint8(pt)]
+				if err != nil || len(fields) != 2 || !ok {
+					continue
+				}
+				if attr.Key == "fmtp" {
+					m.Codecs[i].Fmtp = fields[1]
+					continue
+				}
+				parseRTPMap(&m.Codecs[i], fields[1])
+			case "rtcp":
+				//a=rtcp:port [IN IP4 address]
+				if fields := strings.Fields(attr.Value); len(fields) > 0 {
+					if port, err := strconv.Atoi(fields[0]); err == nil {
+						m.RTCPPort = port
+					}
+				}
+			case DirectionSendRecv, DirectionSendOnly, DirectionRecvOnly, DirectionInactive:
+				m.Direction = attr.Key
+			case "crypto":
+				m.Crypto = append(m.Crypto, attr.Value)
+			}
+		}
+		s.Media = append(s.Media, m)
+	}
+	return s, nil
+}
+
+//parseRTPMap name/clockrate[/channels] of rtpmap
+func parseRTPMap(codec *Codec, rtpmap string) {
+	fields := strings.Split(rtpmap, "/")
+	codec.Name = fields[0]
+	if len(fields) > 1 {
+		if clockRate, err := strconv.ParseUint(fields[1], 10, 32); err == nil {
+			codec.ClockRate = uint32(clockRate)
+		}
+	}
+	if len(fields) > 2 {
+		if channels, err := strconv.ParseUint(fields[2], 10, 16); err == nil {
+			codec.Channels = uint16(channels)
+		}
+	}
+}
+
+//Marshal barebone sdp
+func (s *SessionDescription) Marshal() string {
+	id := time.Now().UnixNano() / int64(time.Millisecond)
+	var b strings.Builder
+	b.WriteString("v=0\r\n")
+	fmt.Fprintf(&b, "o=- %d %d IN %s %s\r\n", id, id, addressType(s.Address), s.Address)
+	b.WriteString("s=janus-go\r\n")
+	fmt.Fprintf(&b, "c=IN %s %s\r\n", addressType(s.Address), s.Address)
+	b.WriteString("t=0 0\r\n")
+	for _, m := range s.Media {
+		proto := "RTP/AVP"
+		if len(m.Crypto) > 0 {
+			proto = "RTP/SAVP"
+		}
+		formats := make([]string, 0, len(m.Codecs))
+		for _, codec := range m.Codecs {
+			formats = append(formats, strconv.Itoa(int(codec.PayloadType)))
+		}
+		fmt.Fprintf(&b, "m=%s %d %s %s\r\n", m.Kind, m.Port, proto, strings.Join(formats, " "))
+		if m.Address != "" && m.Address != s.Address {
+			fmt.Fprintf(&b, "c=IN %s %s\r\n", addressType(m.Address), m.Address)
+		}
+		if m.Port == 0 {
+			continue
+		}
+		for _, codec := range m.Codecs {
+			if codec.Name != "" {
+				fmt.Fprintf(&b, "a=rtpmap:%d %s/%d", codec.PayloadType, codec.Name, codec.ClockRate)
+				if codec.Channels > 1 {
+					fmt.Fprintf(&b, "/%d", codec.Channels)
+				}
+				b.WriteString("\r\n")
+			}
+			if codec.Fmtp != "" {
+				fmt.Fprintf(&b, "a=fmtp:%d %s\r\n", codec.PayloadType, codec.Fmtp)
+			}
+		}
+		if m.RTCPPort != 0 && m.RTCPPort != m.Port+1 {
+			fmt.Fprintf(&b, "a=rtcp:%d\r\n", m.RTCPPort)
+		}
+		for _, crypto := range m.Crypto {
+			fmt.Fprintf(&b, "a=crypto:%s\r\n", crypto)
+		}
+		direction := m.Direction
+		if direction == "" {
+			direction = DirectionSendRecv
+		}
+		fmt.Fprintf(&b, "a=%s\r\n", direction)
+	}
+	return b.String()
+}
+
+//MediaOf first media of kind, nil if not found
+func (s *SessionDescription) MediaOf(kind string) *Media {
+	for i := range s.Media {
+		if s.Media[i].Kind == kind {
+			return &s.Media[i]
+		}
+	}
+	return nil
+}
+
+//RTPAddr remote rtp address of media, s is sdp of media
+func (m *Media) RTPAddr(s *SessionDescription) *net.UDPAddr {
+	return m.udpAddr(s, m.Port)
+}
+
+//RTCPAddr remote rtcp address of media, s is sdp of media
+func (m *Media) RTCPAddr(s *SessionDescription) *net.UDPAddr {
+	return m.udpAddr(s, m.RTCPPort)
+}
+
+func (m *Media) udpAddr(s *SessionDescription, port int) *net.UDPAddr {
+	address := m.Address
+	if address == "" {
+		address = s.Address
+	}
+	ip := net.ParseIP(address)
+	if ip == nil || port == 0 {
+		return nil
+	}
+	return &net.UDPAddr{IP: ip, Port: port}
+}
+
+//Codec codec of name(case insensitive), false if not found
+func (m *Media) Codec(name string) (Codec, bool) {
+	for _, codec := range m.Codecs {
+		if strings.EqualFold(codec.Name, name) {
+			return codec, true
+		}
+	}
+	return Codec{}, false
+}
+
+//ReverseDirection direction of answer to media of direction
+func ReverseDirection(direction string) string {
+	switch direction {
+	case DirectionSendOnly:
+		return DirectionRecvOnly
+	case DirectionRecvOnly:
+		return DirectionSendOnly
+	case DirectionInactive:
+		return DirectionInactive
+	}
+	return DirectionSendRecv
+}
+
+func addressType(address string) string {
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		return "IP6"
+	}
+	return "IP4"
+}
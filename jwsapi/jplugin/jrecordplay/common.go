@@ -0,0 +1,187 @@
+//Package jrecordplay janus-gateway record&play plugin, record webrtc session and replay recordings
+//see https://janus.conf.meetecho.com/docs/recordplay.html
+package jrecordplay
+
+import (
+	"context"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Plugin janus-gateway record&play plugin name
+const Plugin = "janus.plugin.recordplay"
+
+//Descriptor record&play plugin descriptor for jplugin.Call, jplugin.Router
+var Descriptor = jplugin.NewPlugin(Plugin, "recordplay", "list", "update")
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type playRequest struct {
+	ID uint64 `json:"id"`
+}
+
+func (playRequest) Request() string { return "play" }
+
+type recordRequest struct {
+	Name string `json:"name"`
+}
+
+func (recordRequest) Request() string { return "record" }
+
+//status of result event
+const (
+	StatusRecording = "recording"
+	StatusPreparing = "preparing"
+	StatusPlaying   = "playing"
+	StatusStopped   = "stopped"
+	StatusDone      = "done" //playout is finished
+)
+
+//WithMessageOptionID id of recording, random if not set, for record
+func WithMessageOptionID(id uint64) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["id"] = id
+	}
+}
+
+//WithMessageOptionFilename base path of mjr files, for record
+func WithMessageOptionFilename(filename string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["filename"] = filename
+	}
+}
+
+//WithMessageOptionAudioCodec preferred audio codec, eg: opus, pcmu, for record
+func WithMessageOptionAudioCodec(codec string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audiocodec"] = codec
+	}
+}
+
+//WithMessageOptionVideoCodec preferred video codec, eg: vp8, h264, for record
+func WithMessageOptionVideoCodec(codec string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["videocodec"] = codec
+	}
+}
+
+//WithMessageOptionVideoBitrateMax cap bitrate(bps) of recorder by REMB, for record,configure
+func WithMessageOptionVideoBitrateMax(bitrate uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video-bitrate-max"] = bitrate
+	}
+}
+
+//WithMessageOptionVideoKeyframeInterval interval of PLI sent to recorder, for record,configure
+func WithMessageOptionVideoKeyframeInterval(interval time.Duration) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video-keyframe-interval"] = uint32(interval / time.Millisecond)
+	}
+}
+
+//Recording recording info of list
+type Recording struct {
+	jwsapi.Message
+}
+
+//ID recording id
+func (r *Recording) ID() uint64 {
+	id, _ := r.Uint64("id")
+	return id
+}
+
+//Name recording name
+func (r *Recording) Name() string {
+	name, _ := r.String("name")
+	return name
+}
+
+//Date date of recording, eg: 2020-01-01 12:00:00
+func (r *Recording) Date() string {
+	date, _ := r.String("date")
+	return date
+}
+
+//Audio recording has audio
+func (r *Recording) Audio() bool {
+	return r.Bool("audio")
+}
+
+//Video recording has video
+func (r *Recording) Video() bool {
+	return r.Bool("video")
+}
+
+//AudioCodec audio codec of recording, empty if no audio
+func (r *Recording) AudioCodec() string {
+	codec, _ := r.String("audio_codec")
+	return codec
+}
+
+//VideoCodec video codec of recording, empty if no video
+func (r *Recording) VideoCodec() string {
+	codec, _ := r.String("video_codec")
+	return codec
+}
+
+//List list recordings
+func List(h *jwsapi.Handle) ([]Recording, error) {
+	rsp, err := jplugin.Call[struct {
+		List []jwsapi.Message `json:"list"`
+	}](context.Background(), h, Descriptor, request{"list"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	recordings := make([]Recording, 0, len(rsp.Data.List))
+	for _, recording := range rsp.Data.List {
+		if recording != nil {
+			recordings = append(recordings, Recording{recording})
+		}
+	}
+	return recordings, nil
+}
+
+//Update rescan recordings folder of janus-gateway
+func Update(h *jwsapi.Handle) error {
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, request{"update"}, nil)
+	return err
+}
+
+//result of recordplay event, status is empty if not result
+func result(event jwsapi.Message) (status string, id uint64) {
+	if result, ok := event.SubMessage("result"); ok {
+		status, _ = result.String("status")
+		id, _ = result.Uint64("id")
+	}
+	return status, id
+}
+
+//route data of plugin events of h to onPluginEvent until ctx done
+func route(ctx context.Context, h *jwsapi.Handle, onPluginEvent func(jwsapi.Message)) {
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(func(e *jplugin.Event) {
+		onPluginEvent(e.Data)
+	})
+	go r.Run(ctx, h)
+}
+
+//sdpOf sdp of jsep of rsp, jtype is the expected type
+func sdpOf[T any](rsp *jplugin.Response[T], jtype string) (string, error) {
+	if rsp.JSEP == nil {
+		return "", errors.New("not jsep")
+	}
+	if rsp.JSEP.Type != jtype {
+		return "", errors.New("jsep type error")
+	}
+	if rsp.JSEP.SDP == "" {
+		return "", errors.New("not sdp")
+	}
+	return rsp.JSEP.SDP, nil
+}
@@ -0,0 +1,113 @@
+package jrecordplay
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+)
+
+//Player replay recording to handle
+//
+//	p := jrecordplay.NewPlayer(ctx, h, id, jrecordplay.WithPlayerStatus(onStatus))
+//	offer, err := p.Play()
+//	p.Start(answer)
+type Player struct {
+	ctx    context.Context
+	handle *jwsapi.Handle
+	id     uint64
+	//callback
+	onStatus func(string)
+	onEvent  func(jwsapi.Message)
+}
+
+//PlayerOption option for Player
+type PlayerOption func(*Player)
+
+//WithPlayerStatus callback of status event, preparing, playing, stopped, done
+func WithPlayerStatus(callback func(status string)) PlayerOption {
+	return func(p *Player) {
+		p.onStatus = callback
+	}
+}
+
+//WithPlayerEvent callback of other asynchronous event
+func WithPlayerEvent(callback func(jwsapi.Message)) PlayerOption {
+	return func(p *Player) {
+		p.onEvent = callback
+	}
+}
+
+//NewPlayer create player of recording id, h is handle of janus.plugin.recordplay
+func NewPlayer(ctx context.Context, h *jwsapi.Handle, id uint64, opts ...PlayerOption) *Player {
+	p := &Player{
+		ctx:    ctx,
+		handle: h,
+		id:     id,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	route(ctx, h, p.onPluginEvent)
+	return p
+}
+
+//ID return recording id
+func (p *Player) ID() uint64 {
+	return p.id
+}
+
+//Handle return handle
+func (p *Player) Handle() *jwsapi.Handle {
+	return p.handle
+}
+
+//SetOption set callback, eg: WithPlayerStatus
+func (p *Player) SetOption(opts ...PlayerOption) {
+	for _, opt := range opts {
+		opt(p)
+	}
+}
+
+//Play prepare playout of recording, return sdp(offer),nil, or "", err
+func (p *Player) Play(opts ...jwsapi.MessageOption) (string, error) {
+	return p.PlayContext(p.ctx, opts...)
+}
+
+//PlayContext prepare playout, ctx using for cancel and trace
+func (p *Player) PlayContext(ctx context.Context, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[struct{}](ctx, p.handle, Descriptor, playRequest{ID: p.id}, nil, opts...)
+	if err != nil {
+		return "", err
+	}
+	return sdpOf(rsp, "offer")
+}
+
+//Start send answer to janus, playout start
+func (p *Player) Start(answer string) error {
+	return p.StartContext(p.ctx, answer)
+}
+
+//StartContext send answer to janus, ctx using for cancel and trace
+func (p *Player) StartContext(ctx context.Context, answer string) error {
+	_, err := jplugin.Call[struct{}](ctx, p.handle, Descriptor, request{"start"}, &jplugin.JSEP{Type: "answer", SDP: answer})
+	return err
+}
+
+//Stop stop playout, PeerConnection is closed by janus
+func (p *Player) Stop() error {
+	_, err := jplugin.Call[struct{}](p.ctx, p.handle, Descriptor, request{"stop"}, nil)
+	return err
+}
+
+func (p *Player) onPluginEvent(event jwsapi.Message) {
+	if status, _ := result(event); status != "" {
+		if p.onStatus != nil {
+			p.onStatus(status)
+		}
+		return
+	}
+	if p.onEvent != nil {
+		p.onEvent(event)
+	}
+}
@@ -0,0 +1,117 @@
+package jrecordplay
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+)
+
+//Recorder record webrtc session of handle
+//
+//	r := jrecordplay.NewRecorder(ctx, h)
+//	answer, err := r.Record("demo", offer)
+//	...
+//	r.Stop()
+type Recorder struct {
+	ctx    context.Context
+	handle *jwsapi.Handle
+	id     uint64 //atomic, id of recording
+	//callback
+	onStatus func(string)
+	onEvent  func(jwsapi.Message)
+}
+
+//RecorderOption option for Recorder
+type RecorderOption func(*Recorder)
+
+//WithRecorderStatus callback of status event, recording, stopped
+func WithRecorderStatus(callback func(status string)) RecorderOption {
+	return func(r *Recorder) {
+		r.onStatus = callback
+	}
+}
+
+//WithRecorderEvent callback of other asynchronous event
+func WithRecorderEvent(callback func(jwsapi.Message)) RecorderOption {
+	return func(r *Recorder) {
+		r.onEvent = callback
+	}
+}
+
+//NewRecorder create recorder, h is handle of janus.plugin.recordplay
+func NewRecorder(ctx context.Context, h *jwsapi.Handle, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		ctx:    ctx,
+		handle: h,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	route(ctx, h, r.onPluginEvent)
+	return r
+}
+
+//ID return id of recording, 0 before Record
+func (r *Recorder) ID() uint64 {
+	return atomic.LoadUint64(&r.id)
+}
+
+//Handle return handle
+func (r *Recorder) Handle() *jwsapi.Handle {
+	return r.handle
+}
+
+//SetOption set callback, eg: WithRecorderStatus
+func (r *Recorder) SetOption(opts ...RecorderOption) {
+	for _, opt := range opts {
+		opt(r)
+	}
+}
+
+//Record start record with offer, return sdp(answer)
+//WithMessageOptionID, WithMessageOptionFilename, WithMessageOptionVideoCodec... for other params
+func (r *Recorder) Record(name string, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	return r.RecordContext(r.ctx, name, offer, opts...)
+}
+
+//RecordContext start record, ctx using for cancel and trace
+func (r *Recorder) RecordContext(ctx context.Context, name string, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[struct {
+		Result struct {
+			ID uint64 `json:"id"`
+		} `json:"result"`
+	}](ctx, r.handle, Descriptor, recordRequest{Name: name}, &jplugin.JSEP{Type: "offer", SDP: offer}, opts...)
+	if err != nil {
+		return "", err
+	}
+	if id := rsp.Data.Result.ID; id != 0 {
+		atomic.StoreUint64(&r.id, id)
+	}
+	return sdpOf(rsp, "answer")
+}
+
+//Configure configure recording, WithMessageOptionVideoBitrateMax, WithMessageOptionVideoKeyframeInterval
+func (r *Recorder) Configure(opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](r.ctx, r.handle, Descriptor, request{"configure"}, nil, opts...)
+	return err
+}
+
+//Stop stop recording, recording is listed after stopped, PeerConnection is closed by janus
+func (r *Recorder) Stop() error {
+	_, err := jplugin.Call[struct{}](r.ctx, r.handle, Descriptor, request{"stop"}, nil)
+	return err
+}
+
+func (r *Recorder) onPluginEvent(event jwsapi.Message) {
+	if status, _ := result(event); status != "" {
+		if r.onStatus != nil {
+			r.onStatus(status)
+		}
+		return
+	}
+	if r.onEvent != nil {
+		r.onEvent(event)
+	}
+}
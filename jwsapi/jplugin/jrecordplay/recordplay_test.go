@@ -0,0 +1,43 @@
+package jrecordplay_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jrecordplay"
+)
+
+func TestRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.RecordPlayPlugin, janustest.NewRecordPlay())
+
+	h := janustest.Attach(t, ctx, s, jrecordplay.Plugin)
+	if list, err := jrecordplay.List(h); err != nil || len(list) != 0 {
+		t.Errorf("list = %v, %v, want empty", list, err)
+	}
+	if err := jrecordplay.Update(h); err != nil {
+		t.Errorf("update: %v", err)
+	}
+
+	//error of plugin is *jplugin.Error
+	var info *jplugin.Error
+	_, err := jrecordplay.NewPlayer(ctx, h, 1234).Play()
+	if !errors.As(err, &info) || info.Code != janustest.RecordPlayErrorNotFound {
+		t.Errorf("play of unknown recording: err = %v, want %d", err, janustest.RecordPlayErrorNotFound)
+	}
+	_, err = jrecordplay.NewRecorder(ctx, janustest.Attach(t, ctx, s, jrecordplay.Plugin)).Record("demo", "")
+	if !errors.As(err, &info) || info.Code != janustest.RecordPlayErrorInvalidSDP {
+		t.Errorf("record without sdp: err = %v, want %d", err, janustest.RecordPlayErrorInvalidSDP)
+	}
+	err = jrecordplay.NewRecorder(ctx, janustest.Attach(t, ctx, s, jrecordplay.Plugin)).Stop()
+	if !errors.As(err, &info) || info.Code != janustest.RecordPlayErrorInvalidState {
+		t.Errorf("stop without recording: err = %v, want %d", err, janustest.RecordPlayErrorInvalidState)
+	}
+}
@@ -0,0 +1,272 @@
+package jsip
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//Client sip account of handle, register, one call at a time
+//
+//	c := jsip.NewClient(ctx, h, jsip.WithClientEvent(onEvent))
+//	c.Register("sip:alice@example.com", jsip.WithMessageOptionSecret("xx"), jsip.WithMessageOptionProxy("sip:example.com"))
+//	answer, err := c.Call("sip:bob@example.com", offer)
+type Client struct {
+	ctx    context.Context
+	handle *jwsapi.Handle
+
+	mu         sync.Mutex
+	username   string
+	registered bool
+	callID     string
+	waiters    map[chan Event][]string
+	//callback
+	onEvent func(Event)
+}
+
+//ClientOption option for Client
+type ClientOption func(*Client)
+
+//WithClientEvent callback of asynchronous event, eg: registered, incomingcall, hangup, transfer, info
+func WithClientEvent(callback func(Event)) ClientOption {
+	return func(c *Client) {
+		c.onEvent = callback
+	}
+}
+
+//NewClient create client, h is handle of janus.plugin.sip
+func NewClient(ctx context.Context, h *jwsapi.Handle, opts ...ClientOption) *Client {
+	c := &Client{
+		ctx:     ctx,
+		handle:  h,
+		waiters: make(map[chan Event][]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(c.onRouterEvent)
+	go r.Run(ctx, h)
+	return c
+}
+
+//Handle return handle
+func (c *Client) Handle() *jwsapi.Handle {
+	return c.handle
+}
+
+//SetOption set callback, eg: WithClientEvent
+func (c *Client) SetOption(opts ...ClientOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+//Username return username of register, eg: sip:alice@example.com
+func (c *Client) Username() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.username
+}
+
+//Registered account is registered
+func (c *Client) Registered() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.registered
+}
+
+//CallID return Call-ID of current call, empty if not in call
+func (c *Client) CallID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.callID
+}
+
+//Register register username(sip uri) to sip server, return when registered or registration_failed(*Error)
+//WithMessageOptionSecret, WithMessageOptionProxy... for other params
+func (c *Client) Register(username string, opts ...jwsapi.MessageOption) error {
+	return c.RegisterContext(c.ctx, username, opts...)
+}
+
+//RegisterContext register, ctx using for cancel and trace
+func (c *Client) RegisterContext(ctx context.Context, username string, opts ...jwsapi.MessageOption) error {
+	e, err := c.request(ctx, registerRequest{Username: username}, nil, opts, EventRegistered, EventRegistrationFailed)
+	if err != nil {
+		return errors.Wrap(err, "register")
+	}
+	if e.Type == EventRegistrationFailed {
+		return &Error{Code: e.Code, Reason: e.Reason}
+	}
+	c.mu.Lock()
+	c.username = username
+	c.mu.Unlock()
+	return nil
+}
+
+//Unregister unregister account, return when unregistered
+func (c *Client) Unregister() error {
+	_, err := c.request(c.ctx, request{"unregister"}, nil, nil, EventUnregistered)
+	return err
+}
+
+//Call call uri with offer, return sdp(answer) when accepted, *Error if hangup before accepted, eg: 404, 486
+//WithMessageOptionHeaders, WithMessageOptionSRTP... for other params
+func (c *Client) Call(uri string, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	return c.CallContext(c.ctx, uri, offer, opts...)
+}
+
+//CallContext call uri, ctx using for cancel and trace, call is not hangup if ctx is done
+func (c *Client) CallContext(ctx context.Context, uri string, offer string, opts ...jwsapi.MessageOption) (string, error) {
+	e, err := c.request(ctx, callRequest{URI: uri}, &jplugin.JSEP{Type: "offer", SDP: offer}, opts, EventAccepted, EventHangup)
+	if err != nil {
+		return "", errors.Wrap(err, "call")
+	}
+	if e.Type == EventHangup {
+		return "", &Error{Code: e.Code, Reason: e.Reason}
+	}
+	if e.SDPType != "answer" {
+		return "", errors.New("not answer")
+	}
+	return e.SDP, nil
+}
+
+//Accept accept incoming call with answer of offer of incomingcall
+func (c *Client) Accept(answer string, opts ...jwsapi.MessageOption) error {
+	_, err := c.request(c.ctx, request{"accept"}, &jplugin.JSEP{Type: "answer", SDP: answer}, opts)
+	return err
+}
+
+//Decline decline incoming call with sip code, 0 is 486 Busy Here
+func (c *Client) Decline(code int, opts ...jwsapi.MessageOption) error {
+	_, err := c.request(c.ctx, declineRequest{Code: code}, nil, opts)
+	return err
+}
+
+//Hangup hangup current call, PeerConnection is closed by janus-gateway
+func (c *Client) Hangup(opts ...jwsapi.MessageOption) error {
+	_, err := c.request(c.ctx, request{"hangup"}, nil, opts)
+	return err
+}
+
+//Hold put call on hold by re-INVITE, direction is HoldSendOnly(default), HoldRecvOnly, HoldInactive
+func (c *Client) Hold(direction string) error {
+	_, err := c.request(c.ctx, holdRequest{Direction: direction}, nil, nil)
+	return err
+}
+
+//Unhold resume call on hold
+func (c *Client) Unhold() error {
+	_, err := c.request(c.ctx, request{"unhold"}, nil, nil)
+	return err
+}
+
+//DTMF send digit by SIP INFO(application/dtmf-relay), digit is 0-9,*,#,A-D, duration 0 is default of janus-gateway
+func (c *Client) DTMF(digit string, duration time.Duration) error {
+	_, err := c.request(c.ctx, dtmfRequest{Digit: digit, Duration: uint32(duration / time.Millisecond)}, nil, nil)
+	return err
+}
+
+//Transfer transfer call to uri by REFER, replace is Call-ID of other call for attended transfer, empty for blind
+func (c *Client) Transfer(uri string, replace string, opts ...jwsapi.MessageOption) error {
+	_, err := c.request(c.ctx, transferRequest{URI: uri, Replace: replace}, nil, opts)
+	return err
+}
+
+//request send req with jsep if not nil, wait one of final events if response is not
+func (c *Client) request(ctx context.Context, req jplugin.Request, jsep *jplugin.JSEP, opts []jwsapi.MessageOption, final ...string) (Event, error) {
+	var wait chan Event
+	if len(final) > 0 {
+		//async event may arrive before response of request
+		wait = c.wait(final...)
+		defer c.unwait(wait)
+	}
+
+	rsp, err := jplugin.Call[jwsapi.Message](ctx, c.handle, Descriptor, req, jsep, opts...)
+	if err != nil {
+		return Event{}, err
+	}
+	e := newEvent(*rsp.Message)
+	c.update(e)
+	if len(final) == 0 || contains(final, e.Type) {
+		return e, nil
+	}
+	select {
+	case e := <-wait:
+		return e, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+func (c *Client) wait(types ...string) chan Event {
+	ch := make(chan Event, 1)
+	c.mu.Lock()
+	c.waiters[ch] = types
+	c.mu.Unlock()
+	return ch
+}
+
+func (c *Client) unwait(ch chan Event) {
+	c.mu.Lock()
+	delete(c.waiters, ch)
+	c.mu.Unlock()
+}
+
+//update state of client by event
+func (c *Client) update(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch e.Type {
+	case EventRegistered:
+		c.registered = true
+	case EventRegistrationFailed, EventUnregistered:
+		c.registered = false
+	case EventCalling, EventIncomingCall, EventAccepted:
+		if e.CallID != "" {
+			c.callID = e.CallID
+		}
+	case EventHangup:
+		c.callID = ""
+	}
+}
+
+func (c *Client) onPluginEvent(e Event) {
+	c.update(e)
+	c.mu.Lock()
+	for ch, types := range c.waiters {
+		if contains(types, e.Type) {
+			ch <- e
+			delete(c.waiters, ch)
+		}
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+	if onEvent != nil {
+		onEvent(e)
+	}
+}
+
+func (c *Client) onRouterEvent(e *jplugin.Event) {
+	if err := e.Data.PluginDataError(); err != nil {
+		log.Warn("error event", logging.F("handle", c.handle.ID), logging.F("err", err))
+		return
+	}
+	c.onPluginEvent(newEvent(*e.Message))
+}
+
+func contains(types []string, t string) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
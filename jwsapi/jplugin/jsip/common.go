@@ -0,0 +1,190 @@
+//Package jsip janus-gateway sip plugin, register to sip server, call/accept/hangup between webrtc and sip (eg: PSTN)
+//see https://janus.conf.meetecho.com/docs/sip.html
+package jsip
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+)
+
+//Plugin janus-gateway sip plugin name
+const Plugin = "janus.plugin.sip"
+
+//Descriptor sip plugin descriptor for jplugin.Call, jplugin.Router
+//all requests of sip are ack + event
+var Descriptor = jplugin.NewPlugin(Plugin, "sip")
+
+var log = logging.Named("jsip")
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type registerRequest struct {
+	Username string `json:"username"`
+}
+
+func (registerRequest) Request() string { return "register" }
+
+type callRequest struct {
+	URI string `json:"uri"`
+}
+
+func (callRequest) Request() string { return "call" }
+
+type declineRequest struct {
+	Code int `json:"code,omitempty"`
+}
+
+func (declineRequest) Request() string { return "decline" }
+
+type holdRequest struct {
+	Direction string `json:"direction,omitempty"`
+}
+
+func (holdRequest) Request() string { return "hold" }
+
+type dtmfRequest struct {
+	Digit    string `json:"digit"`
+	Duration uint32 `json:"duration,omitempty"` //ms
+}
+
+func (dtmfRequest) Request() string { return "dtmf_info" }
+
+type transferRequest struct {
+	URI     string `json:"uri"`
+	Replace string `json:"replace,omitempty"`
+}
+
+func (transferRequest) Request() string { return "transfer" }
+
+//type of register
+const (
+	RegisterTypeGuest  = "guest"  //not register to sip server, call only
+	RegisterTypeHelper = "helper" //helper of master account, see WithMessageOptionMasterID
+)
+
+//direction of hold
+const (
+	HoldSendOnly = "sendonly"
+	HoldRecvOnly = "recvonly"
+	HoldInactive = "inactive"
+)
+
+//Error sip error of registration_failed, hangup of call, Code is sip code eg: 404, 486
+type Error struct {
+	Code   int
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Reason)
+}
+
+//WithMessageOptionSecret password of account, for register
+func WithMessageOptionSecret(secret string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["secret"] = secret
+	}
+}
+
+//WithMessageOptionAuthUser user for authentication if not the same as username, for register
+func WithMessageOptionAuthUser(authUser string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["authuser"] = authUser
+	}
+}
+
+//WithMessageOptionDisplayName display name of account, for register
+func WithMessageOptionDisplayName(displayName string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["display_name"] = displayName
+	}
+}
+
+//WithMessageOptionProxy sip server, eg: sip:proxy.example.com:5060, for register
+func WithMessageOptionProxy(proxy string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["proxy"] = proxy
+	}
+}
+
+//WithMessageOptionOutboundProxy outbound proxy, for register
+func WithMessageOptionOutboundProxy(proxy string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["outbound_proxy"] = proxy
+	}
+}
+
+//WithMessageOptionType RegisterTypeGuest or RegisterTypeHelper, for register
+func WithMessageOptionType(registerType string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["type"] = registerType
+	}
+}
+
+//WithMessageOptionMasterID handle id of master account, for register of helper
+func WithMessageOptionMasterID(id uint64) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["master_id"] = id
+	}
+}
+
+//WithMessageOptionRefresh refresh registration at janus-gateway, username is kept, for register
+func WithMessageOptionRefresh(refresh bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["refresh"] = refresh
+	}
+}
+
+//WithMessageOptionRegisterTTL expires of REGISTER, for register
+func WithMessageOptionRegisterTTL(ttl time.Duration) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["register_ttl"] = uint32(ttl / time.Second)
+	}
+}
+
+//WithMessageOptionHeaders custom sip headers, eg: X-Conference: 1234, for register,call,accept,decline,hangup,transfer
+func WithMessageOptionHeaders(headers map[string]string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		values := make(map[string]interface{}, len(headers))
+		for key, value := range headers {
+			values[key] = value
+		}
+		msg["headers"] = values
+	}
+}
+
+//WithMessageOptionCallID set Call-ID of INVITE, for call
+func WithMessageOptionCallID(callID string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["call_id"] = callID
+	}
+}
+
+//WithMessageOptionReferID call uri of transfer event, for call
+func WithMessageOptionReferID(referID uint64) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["refer_id"] = referID
+	}
+}
+
+//WithMessageOptionSRTP sdes_optional or sdes_mandatory, for call,accept
+func WithMessageOptionSRTP(srtp string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["srtp"] = srtp
+	}
+}
+
+//WithMessageOptionAutoAcceptReinvites false to get updatingcall event for re-INVITE, for call,accept
+func WithMessageOptionAutoAcceptReinvites(autoAccept bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["autoaccept_reinvites"] = autoAccept
+	}
+}
@@ -0,0 +1,98 @@
+package jsip
+
+import (
+	"strings"
+
+	"github.com/newzai/janus-go/jwsapi"
+)
+
+//event type of Event, result.event of janus-gateway
+const (
+	EventRegistering        = "registering"
+	EventRegistered         = "registered"
+	EventRegistrationFailed = "registration_failed"
+	EventUnregistering      = "unregistering"
+	EventUnregistered       = "unregistered"
+	EventCalling            = "calling"
+	EventRinging            = "ringing"
+	EventProceeding         = "proceeding"
+	EventProgress           = "progress"
+	EventIncomingCall       = "incomingcall"
+	EventMissedCall         = "missed_call"
+	EventAccepting          = "accepting"
+	EventAccepted           = "accepted"
+	EventDeclining          = "declining"
+	EventUpdatingCall       = "updatingcall"
+	EventHolding            = "holding"
+	EventResuming           = "resuming"
+	EventHangingUp          = "hangingup"
+	EventHangup             = "hangup"
+	EventTransferring       = "transferring"
+	EventTransfer           = "transfer"
+	EventInfo               = "info"
+	EventMessage            = "message"
+)
+
+//Event event of sip plugin
+type Event struct {
+	Type        string //EventRegistered, EventIncomingCall, EventHangup...
+	CallID      string
+	Username    string //registered: account, incomingcall: caller, accepted: callee
+	DisplayName string //incomingcall
+	Callee      string //incomingcall
+	Code        int    //registration_failed, declining, hangup: sip code
+	Reason      string //registration_failed, hangup
+	ReferID     uint64 //transfer, using by WithMessageOptionReferID
+	ReferTo     string //transfer
+	ReferredBy  string //transfer
+	Replaces    string //transfer, attended transfer
+	ContentType string //info, message
+	Content     string //info, message
+	Digit       string //info of application/dtmf-relay
+	SDPType     string //offer of incomingcall,updatingcall, answer of accepted,progress
+	SDP         string
+	Result      jwsapi.Message //raw result of janus-gateway
+}
+
+//newEvent event of janus message, Type is empty if not result
+func newEvent(msg jwsapi.Message) Event {
+	pluginData, _ := msg.SubMessage("plugindata")
+	data, _ := pluginData.SubMessage("data")
+	result, _ := data.SubMessage("result")
+	e := Event{Result: result}
+	e.Type, _ = result.String("event")
+	if e.CallID, _ = result.String("call_id"); e.CallID == "" {
+		e.CallID, _ = data.String("call_id")
+	}
+	e.Username, _ = result.String("username")
+	e.DisplayName, _ = result.String("displayname")
+	e.Callee, _ = result.String("callee")
+	code, _ := result.Uint64("code")
+	e.Code = int(code)
+	e.Reason, _ = result.String("reason")
+	e.ReferID, _ = result.Uint64("refer_id")
+	e.ReferTo, _ = result.String("refer_to")
+	e.ReferredBy, _ = result.String("referred_by")
+	e.Replaces, _ = result.String("replaces")
+	e.ContentType, _ = result.String("type")
+	e.Content, _ = result.String("content")
+	if e.Type == EventInfo && strings.EqualFold(e.ContentType, "application/dtmf-relay") {
+		e.Digit = dtmfSignal(e.Content)
+	}
+	if jsep, ok := msg.SubMessage("jsep"); ok {
+		e.SDPType, _ = jsep.String("type")
+		e.SDP, _ = jsep.String("sdp")
+	}
+	return e
+}
+
+//dtmfSignal Signal of dtmf-relay, eg: Signal=1\r\nDuration=160
+func dtmfSignal(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "signal") {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+//Package jstreaming janus-gateway streaming plugin, mountpoint management and viewer
+//see https://janus.conf.meetecho.com/docs/streaming.html
+package jstreaming
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Plugin janus-gateway streaming plugin name
+const Plugin = "janus.plugin.streaming"
+
+//Descriptor streaming plugin descriptor for jplugin.Call, jplugin.Router
+var Descriptor = jplugin.NewPlugin(Plugin, "streaming",
+	"list", "info", "create", "destroy", "recording", "enable", "disable")
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type idRequest struct {
+	ID   uint64 `json:"id"`
+	name string
+}
+
+func (r idRequest) Request() string { return r.name }
+
+//WithMessageOptionSecret set secret of mountpoint, for destroy,enable,disable,recording
+func WithMessageOptionSecret(secret string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["secret"] = secret
+	}
+}
+
+//WithMessageOptionPin set pin of mountpoint, for watch,info
+func WithMessageOptionPin(pin string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["pin"] = pin
+	}
+}
+
+//WithMessageOptionAdminKey set admin_key, if janus-gateway required it for create
+func WithMessageOptionAdminKey(adminKey string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["admin_key"] = adminKey
+	}
+}
+
+//WithMessageOptionPermanent save to config file, for create,destroy
+func WithMessageOptionPermanent(permanent bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["permanent"] = permanent
+	}
+}
+
+//WithMessageOptionAudio enable or disable audio, for watch,configure
+func WithMessageOptionAudio(audio bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audio"] = audio
+	}
+}
+
+//WithMessageOptionVideo enable or disable video, for watch,configure
+func WithMessageOptionVideo(video bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video"] = video
+	}
+}
+
+//WithMessageOptionData enable or disable data, for watch,configure
+func WithMessageOptionData(data bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["data"] = data
+	}
+}
+
+//Mountpoint mountpoint info of list,info,create
+type Mountpoint struct {
+	jwsapi.Message
+}
+
+//ID mountpoint id
+func (m *Mountpoint) ID() uint64 {
+	id, _ := m.Uint64("id")
+	return id
+}
+
+//Type rtp, live, ondemand, rtsp
+func (m *Mountpoint) Type() string {
+	t, _ := m.String("type")
+	return t
+}
+
+//Name mountpoint name
+func (m *Mountpoint) Name() string {
+	name, _ := m.String("name")
+	return name
+}
+
+//Description mountpoint description
+func (m *Mountpoint) Description() string {
+	description, _ := m.String("description")
+	return description
+}
+
+//Enabled mountpoint is enabled, only info of admin
+func (m *Mountpoint) Enabled() bool {
+	return m.Bool("enabled")
+}
+
+//Port local port of rtp mountpoint, kind is audio,video,data
+//read audio_port (janus-gateway 0.x) or ports,media (janus-gateway 1.x), return 0 if not found
+func (m *Mountpoint) Port(kind string) int {
+	return m.port(kind, "port")
+}
+
+//RTCPPort local rtcp port of rtp mountpoint, kind is audio,video
+//janus-gateway send rtcp feedback (PLI,FIR,REMB) to source from this port, return 0 if not found
+func (m *Mountpoint) RTCPPort(kind string) int {
+	return m.port(kind, "rtcp_port")
+}
+
+func (m *Mountpoint) port(kind string, key string) int {
+	if port, ok := m.Uint64(kind + "_" + key); ok {
+		return int(port)
+	}
+	for _, list := range []string{"ports", "media"} {
+		for _, item := range m.Array(list) {
+			media, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			msg := jwsapi.Message(media)
+			if t, _ := msg.String("type"); t != kind {
+				continue
+			}
+			if port, ok := msg.Uint64(key); ok {
+				return int(port)
+			}
+		}
+	}
+	return 0
+}
+
+//List list all public mountpoints
+func List(h *jwsapi.Handle) ([]Mountpoint, error) {
+	rsp, err := jplugin.Call[struct {
+		List []jwsapi.Message `json:"list"`
+	}](context.Background(), h, Descriptor, request{"list"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	mountpoints := make([]Mountpoint, 0, len(rsp.Data.List))
+	for _, mp := range rsp.Data.List {
+		if mp != nil {
+			mountpoints = append(mountpoints, Mountpoint{mp})
+		}
+	}
+	return mountpoints, nil
+}
+
+//Info get mountpoint info, WithMessageOptionSecret for more details
+func Info(h *jwsapi.Handle, id uint64, opts ...jwsapi.MessageOption) (*Mountpoint, error) {
+	rsp, err := jplugin.Call[struct {
+		Info jwsapi.Message `json:"info"`
+	}](context.Background(), h, Descriptor, idRequest{ID: id, name: "info"}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Data.Info == nil {
+		return nil, errors.New("not info")
+	}
+	return &Mountpoint{rsp.Data.Info}, nil
+}
+
+//Create create mountpoint, config is *RTPConfig, *LiveConfig or *OndemandConfig
+//return created mountpoint, id and ports are included
+func Create(h *jwsapi.Handle, config MountpointConfig, opts ...jwsapi.MessageOption) (*Mountpoint, error) {
+	opts = append([]jwsapi.MessageOption{config.fill}, opts...)
+	rsp, err := jplugin.Call[struct {
+		Stream jwsapi.Message `json:"stream"`
+	}](context.Background(), h, Descriptor, request{"create"}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Data.Stream == nil {
+		return nil, errors.New("not stream")
+	}
+	return &Mountpoint{rsp.Data.Stream}, nil
+}
+
+//Destroy destroy mountpoint
+func Destroy(h *jwsapi.Handle, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, idRequest{ID: id, name: "destroy"}, opts...)
+}
+
+//Enable enable mountpoint
+func Enable(h *jwsapi.Handle, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, idRequest{ID: id, name: "enable"}, opts...)
+}
+
+//Disable disable mountpoint, viewers are kicked
+func Disable(h *jwsapi.Handle, id uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, idRequest{ID: id, name: "disable"}, opts...)
+}
+
+type startRecordingRequest struct {
+	ID     uint64 `json:"id"`
+	Action string `json:"action"`
+	Audio  string `json:"audio,omitempty"`
+	Video  string `json:"video,omitempty"`
+	Data   string `json:"data,omitempty"`
+}
+
+func (startRecordingRequest) Request() string { return "recording" }
+
+type stopRecordingRequest struct {
+	ID     uint64 `json:"id"`
+	Action string `json:"action"`
+	Audio  bool   `json:"audio"`
+	Video  bool   `json:"video"`
+	Data   bool   `json:"data"`
+}
+
+func (stopRecordingRequest) Request() string { return "recording" }
+
+//StartRecording start record mountpoint, empty filename is not recorded
+func StartRecording(h *jwsapi.Handle, id uint64, audio string, video string, data string, opts ...jwsapi.MessageOption) error {
+	return call(h, startRecordingRequest{ID: id, Action: "start", Audio: audio, Video: video, Data: data}, opts...)
+}
+
+//StopRecording stop record mountpoint
+func StopRecording(h *jwsapi.Handle, id uint64, audio bool, video bool, data bool, opts ...jwsapi.MessageOption) error {
+	return call(h, stopRecordingRequest{ID: id, Action: "stop", Audio: audio, Video: video, Data: data}, opts...)
+}
+
+//call request without result
+func call(h *jwsapi.Handle, req jplugin.Request, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
@@ -0,0 +1,169 @@
+package jstreaming
+
+import "github.com/newzai/janus-go/jwsapi"
+
+//MountpointConfig config of create request, *RTPConfig, *LiveConfig or *OndemandConfig
+type MountpointConfig interface {
+	fill(msg jwsapi.Message)
+}
+
+//MountpointBase common fields of mountpoint
+type MountpointBase struct {
+	ID          uint64 //0, janus-gateway choose a random id
+	Name        string
+	Description string
+	Metadata    string
+	IsPrivate   bool
+	Secret      string
+	Pin         string
+	Permanent   bool
+}
+
+func (b *MountpointBase) fill(msg jwsapi.Message, mtype string) {
+	msg["type"] = mtype
+	if b.ID > 0 {
+		msg["id"] = b.ID
+	}
+	strs := map[string]string{
+		"name":        b.Name,
+		"description": b.Description,
+		"metadata":    b.Metadata,
+		"secret":      b.Secret,
+		"pin":         b.Pin,
+	}
+	for key, value := range strs {
+		if value != "" {
+			msg[key] = value
+		}
+	}
+	msg["is_private"] = b.IsPrivate
+	msg["permanent"] = b.Permanent
+}
+
+//RTPMedia audio or video of rtp mountpoint
+type RTPMedia struct {
+	Port     int    //local port, 0 janus-gateway choose a random port, see Mountpoint.Port
+	RTCPPort int    //optional, for send rtcp to source
+	Mcast    string //optional, multicast group
+	Iface    string //optional, network interface or ip to bind
+	PT       uint8
+	RTPMap   string //eg: opus/48000/2, H264/90000
+	Fmtp     string //optional, eg: profile-level-id=42e01f;packetization-mode=1
+	Skew     bool   //skew compensation
+	//BufferKeyFrame keep latest key frame, send to new viewer at once, video only
+	BufferKeyFrame bool
+}
+
+func (m *RTPMedia) fill(msg jwsapi.Message, kind string) {
+	msg[kind] = true
+	msg[kind+"port"] = m.Port
+	if m.RTCPPort > 0 {
+		msg[kind+"rtcpport"] = m.RTCPPort
+	}
+	if m.Mcast != "" {
+		msg[kind+"mcast"] = m.Mcast
+	}
+	if m.Iface != "" {
+		msg[kind+"iface"] = m.Iface
+	}
+	msg[kind+"pt"] = m.PT
+	msg[kind+"rtpmap"] = m.RTPMap
+	if m.Fmtp != "" {
+		msg[kind+"fmtp"] = m.Fmtp
+	}
+	if m.Skew {
+		msg[kind+"skew"] = true
+	}
+	if m.BufferKeyFrame && kind == "video" {
+		msg["videobufferkf"] = true
+	}
+}
+
+//RTPData data of rtp mountpoint
+type RTPData struct {
+	Port      int
+	Iface     string
+	Text      bool //datatype text or binary
+	BufferMsg bool //keep latest message, send to new viewer
+}
+
+//RTPConfig rtp mountpoint, janus-gateway listen on port and relay rtp to viewers
+//legacy audio/video/data syntax, supported by janus-gateway 0.x and 1.x
+type RTPConfig struct {
+	MountpointBase
+	Audio *RTPMedia //nil, no audio
+	Video *RTPMedia //nil, no video
+	Data  *RTPData  //nil, no data
+	//SRTPSuite 32 or 80, SRTPCrypto base64 master key and salt, incoming rtp is srtp if set
+	SRTPSuite  int
+	SRTPCrypto string
+	//Collision ssrc collision, ms
+	Collision int
+}
+
+func (c *RTPConfig) fill(msg jwsapi.Message) {
+	c.MountpointBase.fill(msg, "rtp")
+	msg["audio"] = c.Audio != nil
+	msg["video"] = c.Video != nil
+	msg["data"] = c.Data != nil
+	if c.Audio != nil {
+		c.Audio.fill(msg, "audio")
+	}
+	if c.Video != nil {
+		c.Video.fill(msg, "video")
+	}
+	if d := c.Data; d != nil {
+		msg["dataport"] = d.Port
+		if d.Iface != "" {
+			msg["dataiface"] = d.Iface
+		}
+		if d.Text {
+			msg["datatype"] = "text"
+		} else {
+			msg["datatype"] = "binary"
+		}
+		msg["databuffermsg"] = d.BufferMsg
+	}
+	if c.SRTPSuite > 0 && c.SRTPCrypto != "" {
+		msg["srtpsuite"] = c.SRTPSuite
+		msg["srtpcrypto"] = c.SRTPCrypto
+	}
+	if c.Collision > 0 {
+		msg["collision"] = c.Collision
+	}
+}
+
+//FileSource file of live or ondemand mountpoint, janus-gateway support .alaw, .mulaw (audio only)
+type FileSource struct {
+	Filename string
+	Audio    bool
+	Video    bool
+}
+
+func (f *FileSource) fill(msg jwsapi.Message) {
+	msg["filename"] = f.Filename
+	msg["audio"] = f.Audio
+	msg["video"] = f.Video
+}
+
+//LiveConfig live mountpoint, file is streamed in loop, all viewers see the same position
+type LiveConfig struct {
+	MountpointBase
+	FileSource
+}
+
+func (c *LiveConfig) fill(msg jwsapi.Message) {
+	c.MountpointBase.fill(msg, "live")
+	c.FileSource.fill(msg)
+}
+
+//OndemandConfig ondemand mountpoint, file is streamed from start for every viewer
+type OndemandConfig struct {
+	MountpointBase
+	FileSource
+}
+
+func (c *OndemandConfig) fill(msg jwsapi.Message) {
+	c.MountpointBase.fill(msg, "ondemand")
+	c.FileSource.fill(msg)
+}
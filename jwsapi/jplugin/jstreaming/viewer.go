@@ -0,0 +1,171 @@
+package jstreaming
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Viewer viewer of mountpoint
+type Viewer struct {
+	handle *jwsapi.Handle
+	id     uint64 //atomic, changed by Switch
+	//callback
+	onStatus   func(string)
+	onSwitched func(uint64)
+	onEvent    func(jwsapi.Message)
+}
+
+//ViewerOption option for Viewer
+type ViewerOption func(*Viewer)
+
+//WithViewerStatus callback of status event, preparing, starting, started, pausing, stopping, stopped, updating
+func WithViewerStatus(callback func(status string)) ViewerOption {
+	return func(v *Viewer) {
+		v.onStatus = callback
+	}
+}
+
+//WithViewerSwitched callback when switched to other mountpoint
+func WithViewerSwitched(callback func(id uint64)) ViewerOption {
+	return func(v *Viewer) {
+		v.onSwitched = callback
+	}
+}
+
+//WithViewerEvent callback of other asynchronous event
+func WithViewerEvent(callback func(jwsapi.Message)) ViewerOption {
+	return func(v *Viewer) {
+		v.onEvent = callback
+	}
+}
+
+//NewViewer create viewer, h is handle of janus.plugin.streaming
+func NewViewer(ctx context.Context, h *jwsapi.Handle, id uint64, opts ...ViewerOption) *Viewer {
+	v := &Viewer{
+		handle: h,
+		id:     id,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(func(e *jplugin.Event) {
+		v.onPluginEvent(e.Data)
+	})
+	go r.Run(ctx, h)
+	return v
+}
+
+//ID return mountpoint id
+func (v *Viewer) ID() uint64 {
+	return atomic.LoadUint64(&v.id)
+}
+
+//Handle return handle
+func (v *Viewer) Handle() *jwsapi.Handle {
+	return v.handle
+}
+
+//SetOption set callback, eg: WithViewerStatus
+func (v *Viewer) SetOption(opts ...ViewerOption) {
+	for _, opt := range opts {
+		opt(v)
+	}
+}
+
+//Watch watch mountpoint
+//WithMessageOptionPin, WithMessageOptionAudio... for other params
+//return sdp(offer),nil, or "", err
+func (v *Viewer) Watch(opts ...jwsapi.MessageOption) (string, error) {
+	return v.WatchContext(context.Background(), opts...)
+}
+
+//WatchContext watch mountpoint, ctx using for cancel and trace
+//return sdp(offer),nil, or "", err
+func (v *Viewer) WatchContext(ctx context.Context, opts ...jwsapi.MessageOption) (string, error) {
+	rsp, err := jplugin.Call[struct{}](ctx, v.handle, Descriptor, idRequest{ID: v.ID(), name: "watch"}, nil, opts...)
+	if err != nil {
+		return "", err
+	}
+	if rsp.JSEP == nil {
+		return "", errors.New("not jsep")
+	}
+	if rsp.JSEP.Type != "offer" {
+		return "", errors.New("jsep type error")
+	}
+	if rsp.JSEP.SDP == "" {
+		return "", errors.New("not sdp")
+	}
+	return rsp.JSEP.SDP, nil
+}
+
+//Start send answer to janus
+func (v *Viewer) Start(answer string, trickle bool) error {
+	return v.StartContext(context.Background(), answer, trickle)
+}
+
+//StartContext send answer to janus, ctx using for cancel and trace
+func (v *Viewer) StartContext(ctx context.Context, answer string, trickle bool) error {
+	_, err := jplugin.Call[struct{}](ctx, v.handle, Descriptor, request{"start"}, &jplugin.JSEP{Type: "answer", SDP: answer, Trickle: &trickle})
+	return err
+}
+
+//Pause pause stream
+func (v *Viewer) Pause() error {
+	return v.message(request{"pause"})
+}
+
+//Play after call Pause to start recv stream
+func (v *Viewer) Play() error {
+	return v.message(request{"start"})
+}
+
+//Stop stop stream, PeerConnection is closed by janus
+func (v *Viewer) Stop() error {
+	return v.message(request{"stop"})
+}
+
+//Switch switch to other mountpoint without renegotiation, codecs must be same
+func (v *Viewer) Switch(id uint64) error {
+	err := v.message(idRequest{ID: id, name: "switch"})
+	if err == nil {
+		atomic.StoreUint64(&v.id, id)
+	}
+	return err
+}
+
+//Configure enable or disable audio,video,data
+//WithMessageOptionAudio, WithMessageOptionVideo, WithMessageOptionData
+//jwsapi.WithMessageOption("substream",1) for simulcast
+func (v *Viewer) Configure(opts ...jwsapi.MessageOption) error {
+	return v.message(request{"configure"}, opts...)
+}
+
+func (v *Viewer) message(req jplugin.Request, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](context.Background(), v.handle, Descriptor, req, nil, opts...)
+	return err
+}
+
+func (v *Viewer) onPluginEvent(event jwsapi.Message) {
+	if result, ok := event.SubMessage("result"); ok {
+		if status, ok := result.String("status"); ok {
+			if v.onStatus != nil {
+				v.onStatus(status)
+			}
+			return
+		}
+	}
+	if switched, ok := event.String("switched"); ok && switched == "ok" {
+		if id, ok := event.Uint64("id"); ok && v.onSwitched != nil {
+			v.onSwitched(id)
+		}
+		return
+	}
+	if v.onEvent != nil {
+		v.onEvent(event)
+	}
+}
@@ -0,0 +1,209 @@
+//Package jtextroom janus-gateway textroom plugin, chat over data channel and room management
+//see https://janus.conf.meetecho.com/docs/textroom.html
+package jtextroom
+
+import (
+	"context"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/pkg/errors"
+)
+
+//Plugin janus-gateway textroom plugin name
+const Plugin = "janus.plugin.textroom"
+
+//Descriptor textroom plugin descriptor for jplugin.Call, jplugin.Router
+//setup and ack are ack + event, join, message, leave are sent over data channel, see package textroom
+var Descriptor = jplugin.NewPlugin(Plugin, "textroom",
+	"create", "edit", "destroy", "exists", "list", "listparticipants", "allowed", "kick", "announcement")
+
+//WithMessageOptionSecret set secret of room, for edit,destroy,allowed,kick,announcement
+func WithMessageOptionSecret(secret string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["secret"] = secret
+	}
+}
+
+//WithMessageOptionPin set pin of room, for create,join
+func WithMessageOptionPin(pin string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["pin"] = pin
+	}
+}
+
+//WithMessageOptionPermanent save to config file, for create,edit,destroy
+func WithMessageOptionPermanent(permanent bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["permanent"] = permanent
+	}
+}
+
+//WithMessageOptionDescription set description of room, for create
+func WithMessageOptionDescription(description string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["description"] = description
+	}
+}
+
+//WithMessageOptionHistory number of messages to keep and send to new participant, for create
+func WithMessageOptionHistory(history uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["history"] = history
+	}
+}
+
+//WithMessageOptionPost http backend to forward messages of room to, for create
+func WithMessageOptionPost(url string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["post"] = url
+	}
+}
+
+//WithMessageOptionAdminKey set admin_key, if janus-gateway required it for create
+func WithMessageOptionAdminKey(adminKey string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["admin_key"] = adminKey
+	}
+}
+
+//WithMessageOptionTo whisper to username(s) of room, for message
+func WithMessageOptionTo(usernames ...string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		if len(usernames) == 1 {
+			msg["to"] = usernames[0]
+			return
+		}
+		tos := make([]interface{}, 0, len(usernames))
+		for _, username := range usernames {
+			tos = append(tos, username)
+		}
+		msg["tos"] = tos
+	}
+}
+
+//WithMessageOptionToken token of participant, for join if room is allowed by tokens
+func WithMessageOptionToken(token string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["token"] = token
+	}
+}
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+//SetupRequest and AckRequest negotiate PeerConnection of data channel by jplugin.Call
+//setup is answered with jsep offer, ack is sent with jsep answer
+var (
+	SetupRequest jplugin.Request = request{"setup"}
+	AckRequest   jplugin.Request = request{"ack"}
+)
+
+type roomRequest struct {
+	Room uint64 `json:"room"`
+	name string
+}
+
+func (r roomRequest) Request() string { return r.name }
+
+type createRequest struct {
+	Room uint64 `json:"room,omitempty"`
+}
+
+func (createRequest) Request() string { return "create" }
+
+//CreateRoom create room, room is 0 janus-gateway choose a random id
+//return id of room
+func CreateRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) (uint64, error) {
+	rsp, err := jplugin.Call[struct {
+		Room uint64 `json:"room"`
+	}](context.Background(), h, Descriptor, createRequest{Room: room}, nil, opts...)
+	if err != nil {
+		return 0, err
+	}
+	if rsp.Data.Room == 0 {
+		return 0, errors.New("not room")
+	}
+	return rsp.Data.Room, nil
+}
+
+//EditRoom edit room, jwsapi.WithMessageOption("new_description","xx") for new_description, new_secret, new_pin, new_is_private, new_post
+func EditRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "edit"}, opts...)
+}
+
+//DestroyRoom destroy room, participants are notified
+func DestroyRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "destroy"}, opts...)
+}
+
+//Exists room exists
+func Exists(h *jwsapi.Handle, room uint64) (bool, error) {
+	rsp, err := jplugin.Call[struct {
+		Exists bool `json:"exists"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "exists"}, nil)
+	if err != nil {
+		return false, err
+	}
+	return rsp.Data.Exists, nil
+}
+
+//List list all public rooms
+func List(h *jwsapi.Handle) ([]Room, error) {
+	rsp, err := jplugin.Call[struct {
+		List []jwsapi.Message `json:"list"`
+	}](context.Background(), h, Descriptor, request{"list"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make([]Room, 0, len(rsp.Data.List))
+	for _, room := range rsp.Data.List {
+		if room != nil {
+			rooms = append(rooms, Room{room})
+		}
+	}
+	return rooms, nil
+}
+
+//ListParticipants list participants of room
+func ListParticipants(h *jwsapi.Handle, room uint64) ([]Participant, error) {
+	rsp, err := jplugin.Call[struct {
+		Participants []jwsapi.Message `json:"participants"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "listparticipants"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return participants(rsp.Data.Participants), nil
+}
+
+//Allowed enable, disable, add or remove tokens of room
+//action is enable, disable, add, remove, tokens is for add, remove
+func Allowed(h *jwsapi.Handle, room uint64, action string, tokens []string, opts ...jwsapi.MessageOption) error {
+	allowed := make([]interface{}, 0, len(tokens))
+	for _, token := range tokens {
+		allowed = append(allowed, token)
+	}
+	return call(h, roomRequest{Room: room, name: "allowed"}, append([]jwsapi.MessageOption{
+		jwsapi.WithMessageOption("action", action),
+		jwsapi.WithMessageOption("allowed", allowed),
+	}, opts...)...)
+}
+
+//Kick kick username out of room
+func Kick(h *jwsapi.Handle, room uint64, username string, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "kick"}, append([]jwsapi.MessageOption{jwsapi.WithMessageOption("username", username)}, opts...)...)
+}
+
+//Announcement send announcement to all participants of room, without joining
+func Announcement(h *jwsapi.Handle, room uint64, text string, opts ...jwsapi.MessageOption) error {
+	return call(h, roomRequest{Room: room, name: "announcement"}, append([]jwsapi.MessageOption{jwsapi.WithMessageOption("text", text)}, opts...)...)
+}
+
+//call request without result
+func call(h *jwsapi.Handle, req jplugin.Request, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
@@ -0,0 +1,58 @@
+package jtextroom
+
+import "github.com/newzai/janus-go/jwsapi"
+
+//Room room info of list
+type Room struct {
+	jwsapi.Message
+}
+
+//ID room id
+func (r *Room) ID() uint64 {
+	room, _ := r.Uint64("room")
+	return room
+}
+
+//Description room description
+func (r *Room) Description() string {
+	description, _ := r.String("description")
+	return description
+}
+
+//PinRequired pin is required to join
+func (r *Room) PinRequired() bool {
+	return r.Bool("pin_required")
+}
+
+//NumParticipants count of participants
+func (r *Room) NumParticipants() int {
+	num, _ := r.Uint64("num_participants")
+	return int(num)
+}
+
+//Participant participant info of listparticipants and join
+type Participant struct {
+	jwsapi.Message
+}
+
+//Username unique username of participant in room
+func (p *Participant) Username() string {
+	username, _ := p.String("username")
+	return username
+}
+
+//Display display of participant
+func (p *Participant) Display() string {
+	display, _ := p.String("display")
+	return display
+}
+
+func participants(list []jwsapi.Message) []Participant {
+	parts := make([]Participant, 0, len(list))
+	for _, part := range list {
+		if part != nil {
+			parts = append(parts, Participant{part})
+		}
+	}
+	return parts
+}
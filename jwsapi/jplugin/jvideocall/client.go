@@ -0,0 +1,246 @@
+package jvideocall
+
+import (
+	"context"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//Client registered username of handle, one call at a time
+//
+//	c := jvideocall.NewClient(ctx, h, jvideocall.WithClientEvent(onEvent))
+//	c.Register("alice")
+//	answer, err := c.Call("bob", offer)
+type Client struct {
+	ctx    context.Context
+	handle *jwsapi.Handle
+
+	mu       sync.Mutex
+	username string
+	peer     string
+	waiters  map[chan Event][]string
+	//callback
+	onEvent func(Event)
+}
+
+//ClientOption option for Client
+type ClientOption func(*Client)
+
+//WithClientEvent callback of asynchronous event, eg: incomingcall, accepted, hangup, update
+func WithClientEvent(callback func(Event)) ClientOption {
+	return func(c *Client) {
+		c.onEvent = callback
+	}
+}
+
+//NewClient create client, h is handle of janus.plugin.videocall
+func NewClient(ctx context.Context, h *jwsapi.Handle, opts ...ClientOption) *Client {
+	c := &Client{
+		ctx:     ctx,
+		handle:  h,
+		waiters: make(map[chan Event][]string),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(c.onRouterEvent)
+	go r.Run(ctx, h)
+	return c
+}
+
+//Handle return handle
+func (c *Client) Handle() *jwsapi.Handle {
+	return c.handle
+}
+
+//SetOption set callback, eg: WithClientEvent
+func (c *Client) SetOption(opts ...ClientOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+//Username return registered username
+func (c *Client) Username() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.username
+}
+
+//Peer return username of peer in call, empty if not in call
+func (c *Client) Peer() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peer
+}
+
+//Register register unique username of plugin
+func (c *Client) Register(username string) error {
+	return c.RegisterContext(c.ctx, username)
+}
+
+//RegisterContext register, ctx using for cancel and trace
+func (c *Client) RegisterContext(ctx context.Context, username string) error {
+	e, err := c.request(ctx, usernameRequest{Username: username, name: "register"}, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "register")
+	}
+	if e.Type != EventRegistered {
+		return errors.Errorf("unexpected %s", e.Type)
+	}
+	return nil
+}
+
+//List list registered usernames
+func (c *Client) List() ([]string, error) {
+	e, err := c.request(c.ctx, request{"list"}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	list := e.Result.Array("list")
+	usernames := make([]string, 0, len(list))
+	for _, item := range list {
+		if username, ok := item.(string); ok {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames, nil
+}
+
+//Call call username with offer, return sdp(answer) when accepted, error with reason if hangup, eg: User busy
+func (c *Client) Call(username string, offer string) (string, error) {
+	return c.CallContext(c.ctx, username, offer)
+}
+
+//CallContext call username, ctx using for cancel and trace, call is not hangup if ctx is done
+func (c *Client) CallContext(ctx context.Context, username string, offer string) (string, error) {
+	e, err := c.request(ctx, usernameRequest{Username: username, name: "call"}, &jplugin.JSEP{Type: "offer", SDP: offer}, nil, EventAccepted, EventHangup)
+	if err != nil {
+		return "", errors.Wrap(err, "call")
+	}
+	if e.Type == EventHangup {
+		return "", errors.Errorf("hangup: %s", e.Reason)
+	}
+	if e.SDPType != "answer" {
+		return "", errors.New("not answer")
+	}
+	return e.SDP, nil
+}
+
+//Accept accept incoming call with answer of offer of incomingcall
+func (c *Client) Accept(answer string) error {
+	_, err := c.request(c.ctx, request{"accept"}, &jplugin.JSEP{Type: "answer", SDP: answer}, nil)
+	return err
+}
+
+//Set configure call, WithMessageOptionAudio, WithMessageOptionVideo, WithMessageOptionBitrate, WithMessageOptionRecord...
+func (c *Client) Set(opts ...jwsapi.MessageOption) error {
+	_, err := c.request(c.ctx, request{"set"}, nil, opts)
+	return err
+}
+
+//Hangup hangup or decline current call, PeerConnection is closed by janus-gateway
+func (c *Client) Hangup() error {
+	_, err := c.request(c.ctx, request{"hangup"}, nil, nil)
+	return err
+}
+
+//request send req with jsep if not nil, wait one of final events if response is not
+func (c *Client) request(ctx context.Context, req jplugin.Request, jsep *jplugin.JSEP, opts []jwsapi.MessageOption, final ...string) (Event, error) {
+	var wait chan Event
+	if len(final) > 0 {
+		//async event may arrive before response of request
+		wait = c.wait(final...)
+		defer c.unwait(wait)
+	}
+
+	rsp, err := jplugin.Call[jwsapi.Message](ctx, c.handle, Descriptor, req, jsep, opts...)
+	if err != nil {
+		return Event{}, err
+	}
+	e := newEvent(*rsp.Message)
+	c.update(e, req)
+	if len(final) == 0 || contains(final, e.Type) {
+		return e, nil
+	}
+	select {
+	case e := <-wait:
+		return e, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+func (c *Client) wait(types ...string) chan Event {
+	ch := make(chan Event, 1)
+	c.mu.Lock()
+	c.waiters[ch] = types
+	c.mu.Unlock()
+	return ch
+}
+
+func (c *Client) unwait(ch chan Event) {
+	c.mu.Lock()
+	delete(c.waiters, ch)
+	c.mu.Unlock()
+}
+
+//update state of client by event, req is request of response, nil for asynchronous event
+func (c *Client) update(e Event, req jplugin.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch e.Type {
+	case EventRegistered:
+		c.username = e.Username
+	case EventCalling:
+		if r, ok := req.(usernameRequest); ok {
+			c.peer = r.Username
+		}
+	case EventIncomingCall, EventAccepted:
+		if e.Username != "" {
+			c.peer = e.Username
+		}
+	case EventHangup:
+		c.peer = ""
+	}
+}
+
+func (c *Client) onPluginEvent(e Event) {
+	c.update(e, nil)
+	c.mu.Lock()
+	for ch, types := range c.waiters {
+		if contains(types, e.Type) {
+			ch <- e
+			delete(c.waiters, ch)
+		}
+	}
+	onEvent := c.onEvent
+	c.mu.Unlock()
+	if onEvent != nil {
+		onEvent(e)
+	}
+}
+
+func (c *Client) onRouterEvent(e *jplugin.Event) {
+	if err := e.Data.PluginDataError(); err != nil {
+		log.Warn("error event", logging.F("handle", c.handle.ID), logging.F("err", err))
+		return
+	}
+	c.onPluginEvent(newEvent(*e.Message))
+}
+
+func contains(types []string, t string) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
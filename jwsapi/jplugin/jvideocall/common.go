@@ -0,0 +1,76 @@
+//Package jvideocall janus-gateway videocall plugin, 1:1 call between registered usernames
+//see https://janus.conf.meetecho.com/docs/videocall.html
+package jvideocall
+
+import (
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+)
+
+//Plugin janus-gateway videocall plugin name
+const Plugin = "janus.plugin.videocall"
+
+//Descriptor videocall plugin descriptor for jplugin.Call, jplugin.Router
+//all requests of videocall are ack + event
+var Descriptor = jplugin.NewPlugin(Plugin, "videocall")
+
+var log = logging.Named("jvideocall")
+
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type usernameRequest struct {
+	Username string `json:"username"`
+	name     string
+}
+
+func (r usernameRequest) Request() string { return r.name }
+
+//WithMessageOptionAudio send audio or not, for set
+func WithMessageOptionAudio(audio bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["audio"] = audio
+	}
+}
+
+//WithMessageOptionVideo send video or not, for set
+func WithMessageOptionVideo(video bool) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["video"] = video
+	}
+}
+
+//WithMessageOptionBitrate cap bitrate(bps) of peer by REMB, 0 is no cap, for set
+func WithMessageOptionBitrate(bitrate uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["bitrate"] = bitrate
+	}
+}
+
+//WithMessageOptionRecord record call, filename is base path of recording, empty for default, for set
+func WithMessageOptionRecord(record bool, filename string) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["record"] = record
+		if filename != "" {
+			msg["filename"] = filename
+		}
+	}
+}
+
+//WithMessageOptionSubstream simulcast substream(0~2) to receive, for set
+func WithMessageOptionSubstream(substream uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["substream"] = substream
+	}
+}
+
+//WithMessageOptionTemporal simulcast temporal layer(0~2) to receive, for set
+func WithMessageOptionTemporal(temporal uint32) jwsapi.MessageOption {
+	return func(msg jwsapi.Message) {
+		msg["temporal"] = temporal
+	}
+}
@@ -0,0 +1,41 @@
+package jvideocall
+
+import "github.com/newzai/janus-go/jwsapi"
+
+//event type of Event, result.event of janus-gateway
+const (
+	EventRegistered   = "registered"
+	EventCalling      = "calling"
+	EventIncomingCall = "incomingcall"
+	EventAccepted     = "accepted"
+	EventUpdate       = "update"
+	EventSet          = "set"
+	EventSimulcast    = "simulcast"
+	EventHangup       = "hangup"
+)
+
+//Event event of videocall plugin
+type Event struct {
+	Type     string //EventIncomingCall, EventAccepted, EventHangup...
+	Username string //registered: itself, incomingcall: caller, accepted: callee, hangup: peer
+	Reason   string //hangup, eg: User busy, Remote hangup
+	SDPType  string //offer of incomingcall,update, answer of accepted,update
+	SDP      string
+	Result   jwsapi.Message //raw result of janus-gateway
+}
+
+//newEvent event of janus message, Type is empty if not result.event
+func newEvent(msg jwsapi.Message) Event {
+	pluginData, _ := msg.SubMessage("plugindata")
+	data, _ := pluginData.SubMessage("data")
+	result, _ := data.SubMessage("result")
+	e := Event{Result: result}
+	e.Type, _ = result.String("event")
+	e.Username, _ = result.String("username")
+	e.Reason, _ = result.String("reason")
+	if jsep, ok := msg.SubMessage("jsep"); ok {
+		e.SDPType, _ = jsep.String("type")
+		e.SDP, _ = jsep.String("sdp")
+	}
+	return e
+}
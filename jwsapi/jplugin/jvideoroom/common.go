@@ -1,9 +1,19 @@
 package jvideoroom
 
 import (
+	"context"
+
 	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
 )
 
+var log = logging.Named("jvideoroom")
+
+//Plugin janus-gateway videoroom plugin name
+const Plugin = "janus.plugin.videoroom"
+
 //UserType user type , publisher, subscriber
 type UserType int
 
@@ -53,110 +63,125 @@ func WithMessageOptionSecret(secret string) jwsapi.MessageOption {
 	}
 }
 
+//Descriptor videoroom plugin descriptor for jplugin.Call, jplugin.Router
+var Descriptor = jplugin.NewPlugin(Plugin, "videoroom",
+	"create", "edit", "destroy", "exists", "list", "listparticipants", "allowed", "kick", "moderate",
+	"enable_recording", "rtp_forward", "stop_rtp_forward", "listforwarders",
+	"add_remote_publisher", "update_remote_publisher", "remove_remote_publisher",
+	"publish_remotely", "unpublish_remotely", "list_remotes")
+
+//request request without parameters, eg: leave, unpublish
+type request struct {
+	name string
+}
+
+func (r request) Request() string { return r.name }
+
+type joinRequest struct {
+	Ptype   string `json:"ptype"`
+	Room    uint64 `json:"room"`
+	ID      uint64 `json:"id,omitempty"`      //publisher
+	Display string `json:"display,omitempty"` //publisher
+	Feed    uint64 `json:"feed,omitempty"`    //subscriber
+	Audio   *bool  `json:"audio,omitempty"`
+	Video   *bool  `json:"video,omitempty"`
+	Data    *bool  `json:"data,omitempty"`
+}
+
+func (joinRequest) Request() string { return "join" }
+
+type configureRequest struct {
+	Audio *bool `json:"audio,omitempty"`
+	Video *bool `json:"video,omitempty"`
+	Data  *bool `json:"data,omitempty"`
+}
+
+func (configureRequest) Request() string { return "configure" }
+
+//sdpOf sdp of jsep, jsep must be typ, offer or answer
+func sdpOf(jsep *jplugin.JSEP, typ string) (string, error) {
+	if jsep == nil {
+		return "", errors.New("not jsep")
+	}
+	if jsep.Type != typ {
+		return "", errors.New("jsep type error")
+	}
+	if jsep.SDP == "" {
+		return "", errors.New("not sdp")
+	}
+	return jsep.SDP, nil
+}
+
+type createRequest struct{}
+
+func (createRequest) Request() string { return "create" }
+
+type roomRequest struct {
+	Room uint64 `json:"room"`
+	name string
+}
+
+func (r roomRequest) Request() string { return r.name }
+
 //CreateRoom create room from janus-gateway videoroom plugin
 //jwsapi.WithMessageOption("publishers",10) to set publishers parm
 //see https://janus.conf.meetecho.com/docs/videoroom.html create room param
 func CreateRoom(h *jwsapi.Handle, opts ...jwsapi.MessageOption) (uint64, error) {
-	msg := jwsapi.Message{
-		jwsapi.AttrRequest: "create",
-	}
-
-	for _, opt := range opts {
-		opt(msg)
-	}
-	rsp, err := h.Request(msg)
+	rsp, err := jplugin.Call[struct {
+		Room uint64 `json:"room"`
+	}](context.Background(), h, Descriptor, createRequest{}, nil, opts...)
 	if err != nil {
 		return 0, err
 	}
-	pluginData := rsp.PluginData()
-	data := pluginData.Data()
-	room, ok := data.Uint64("room")
-	if !ok {
-		return 0, data.PluginDataError()
+	if rsp.Data.Room == 0 {
+		return 0, errors.New("not room")
 	}
-	return room, nil
+	return rsp.Data.Room, nil
 }
 
 //DestroyRoom destroy room
 func DestroyRoom(h *jwsapi.Handle, room uint64, opts ...jwsapi.MessageOption) error {
-
-	msg := jwsapi.Message{
-		jwsapi.AttrRequest: "destroy",
-		"room":             room,
-	}
-	for _, opt := range opts {
-		opt(msg)
-	}
-	rsp, err := h.Request(msg)
-	if err != nil {
-		return err
-	}
-
-	return rsp.PluginDataError()
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "destroy"}, nil, opts...)
+	return err
 }
 
 //Exists check room is exists
 func Exists(h *jwsapi.Handle, room uint64) (bool, error) {
-	msg := jwsapi.Message{
-		jwsapi.AttrRequest: "exists",
-		"room":             room,
-	}
-
-	rsp, err := h.Request(msg)
+	rsp, err := jplugin.Call[struct {
+		Exists bool `json:"exists"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "exists"}, nil)
 	if err != nil {
 		return false, err
 	}
-	plugindata := rsp.PluginData()
-	data := plugindata.Data()
-
-	return data.Bool("exists"), nil
+	return rsp.Data.Exists, nil
 }
 
-//List list all rooms in janus-gateway videoroom
-func List(h *jwsapi.Handle) ([]Room, error) {
+type listRequest struct{}
 
-	msg := jwsapi.Message{
-		jwsapi.AttrRequest: "list",
-	}
+func (listRequest) Request() string { return "list" }
 
-	rsp, err := h.Request(msg)
+//List list all rooms in janus-gateway videoroom
+func List(h *jwsapi.Handle) ([]Room, error) {
+	rsp, err := jplugin.Call[struct {
+		List  []Room `json:"list"`
+		Rooms []Room `json:"rooms"` //some gateways
+	}](context.Background(), h, Descriptor, listRequest{}, nil)
 	if err != nil {
 		return nil, err
 	}
-	plugindata := rsp.PluginData()
-	data := plugindata.Data()
-	rooms := data.Array("list")
-	if rooms == nil {
-		rooms = data.Array("rooms")
-	}
-	jrooms := make([]Room, 0, len(rooms))
-	for _, r := range rooms {
-		rr := jwsapi.Message(r.(map[string]interface{}))
-		jrooms = append(jrooms, Room{rr})
+	if rsp.Data.List == nil {
+		return rsp.Data.Rooms, nil
 	}
-	return jrooms, nil
+	return rsp.Data.List, nil
 }
 
 //Listparticipants get all room publishers
 func Listparticipants(h *jwsapi.Handle, room uint64) ([]Participant, error) {
-	msg := jwsapi.Message{
-		jwsapi.AttrRequest: "listparticipants",
-		"room":             room,
-	}
-
-	rsp, err := h.Request(msg)
+	rsp, err := jplugin.Call[struct {
+		Participants []Participant `json:"participants"`
+	}](context.Background(), h, Descriptor, roomRequest{Room: room, name: "listparticipants"}, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	pluginData := rsp.PluginData()
-	data := pluginData.Data()
-
-	arParts := data.Array("participants")
-	parts := make([]Participant, 0, len(arParts))
-	for _, part := range arParts {
-		parts = append(parts, Participant{jwsapi.Message(part.(map[string]interface{}))})
-	}
-
-	return parts, nil
+	return rsp.Data.Participants, nil
 }
@@ -0,0 +1,308 @@
+package jvideoroom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//ErrRoomNotFound room not found at any healthy gateway of pool
+var ErrRoomNotFound = errors.New("room not found")
+
+//errorNoSuchRoom error_code of videoroom when room is not exists
+const errorNoSuchRoom = 426
+
+type dirEntry struct {
+	gateway *jwsapi.Gateway
+	room    Room
+	expire  time.Time
+}
+
+//Directory find gateway hosting the room across jwsapi.Pool, by List of every gateway
+//result is cached for ttl, Attach and Join are routed to gateway of the room
+type Directory struct {
+	pool *jwsapi.Pool
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	sessions map[*jwsapi.Gateway]*jwsapi.Session
+	handles  map[*jwsapi.Gateway]*jwsapi.Handle //handle for list
+	rooms    map[uint64]dirEntry
+}
+
+//DirectoryOption option for Directory
+type DirectoryOption func(*Directory)
+
+//WithDirectoryTTL cache time of room location, default is 30s
+func WithDirectoryTTL(ttl time.Duration) DirectoryOption {
+	return func(d *Directory) {
+		d.ttl = ttl
+	}
+}
+
+//NewDirectory create room directory over pool
+func NewDirectory(pool *jwsapi.Pool, opts ...DirectoryOption) *Directory {
+	d := &Directory{
+		pool:     pool,
+		ttl:      30 * time.Second,
+		sessions: make(map[*jwsapi.Gateway]*jwsapi.Session),
+		handles:  make(map[*jwsapi.Gateway]*jwsapi.Handle),
+		rooms:    make(map[uint64]dirEntry),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+//Session session of directory at gateway, created by pool if not exist or destroyed
+func (d *Directory) Session(g *jwsapi.Gateway) (*jwsapi.Session, error) {
+	d.mu.Lock()
+	s, ok := d.sessions[g]
+	d.mu.Unlock()
+	if ok && !s.IsDestroy() {
+		return s, nil
+	}
+	s, err := d.pool.CreateAt(g)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	//created by concurrent call, keep the first one
+	if other, ok := d.sessions[g]; ok && !other.IsDestroy() {
+		d.mu.Unlock()
+		s.Destroy()
+		return other, nil
+	}
+	d.sessions[g] = s
+	delete(d.handles, g)
+	d.mu.Unlock()
+	return s, nil
+}
+
+func (d *Directory) listHandle(g *jwsapi.Gateway) (*jwsapi.Handle, error) {
+	d.mu.Lock()
+	h, ok := d.handles[g]
+	d.mu.Unlock()
+	if ok && !h.IsDestroy() {
+		return h, nil
+	}
+	s, err := d.Session(g)
+	if err != nil {
+		return nil, err
+	}
+	h, err = s.Attach(Plugin)
+	if err != nil {
+		return nil, errors.Wrapf(err, "attach at %s", g.URL)
+	}
+	d.mu.Lock()
+	if other, ok := d.handles[g]; ok && !other.IsDestroy() {
+		d.mu.Unlock()
+		h.Detach()
+		return other, nil
+	}
+	d.handles[g] = h
+	d.mu.Unlock()
+	return h, nil
+}
+
+//Refresh list rooms of all healthy gateways, replace cached rooms of listed gateways,
+//rooms of gateways failed to list are kept until expired
+func (d *Directory) Refresh() error {
+	type result struct {
+		gateway *jwsapi.Gateway
+		rooms   []Room
+		err     error
+	}
+	var gateways []*jwsapi.Gateway
+	for _, g := range d.pool.Gateways() {
+		if g.Healthy() {
+			gateways = append(gateways, g)
+		}
+	}
+	if len(gateways) == 0 {
+		return jwsapi.ErrNoGateway
+	}
+
+	results := make([]result, len(gateways))
+	var wg sync.WaitGroup
+	for i, g := range gateways {
+		wg.Add(1)
+		go func(i int, g *jwsapi.Gateway) {
+			defer wg.Done()
+			results[i].gateway = g
+			h, err := d.listHandle(g)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].rooms, results[i].err = List(h)
+		}(i, g)
+	}
+	wg.Wait()
+
+	expire := time.Now().Add(d.ttl)
+	listed := make(map[*jwsapi.Gateway]bool)
+	var err error
+	for _, r := range results {
+		if r.err != nil {
+			log.Warn("list rooms failed", logging.F("url", r.gateway.URL), logging.F("err", r.err))
+			err = r.err
+			continue
+		}
+		listed[r.gateway] = true
+	}
+	if len(listed) == 0 {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id, entry := range d.rooms {
+		if listed[entry.gateway] {
+			delete(d.rooms, id)
+		}
+	}
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		for _, room := range r.rooms {
+			d.rooms[room.ID] = dirEntry{gateway: r.gateway, room: room, expire: expire}
+		}
+	}
+	return nil
+}
+
+//Lookup find gateway of room, List all gateways if not cached or expired,
+//private room is not listed, Exists at every healthy gateway if room is not found by List
+func (d *Directory) Lookup(room uint64) (*jwsapi.Gateway, error) {
+	if g, ok := d.cached(room); ok {
+		return g, nil
+	}
+	if err := d.Refresh(); err != nil {
+		return nil, err
+	}
+	if g, ok := d.cached(room); ok {
+		return g, nil
+	}
+	for _, g := range d.pool.Gateways() {
+		if !g.Healthy() {
+			continue
+		}
+		h, err := d.listHandle(g)
+		if err != nil {
+			continue
+		}
+		exists, err := Exists(h, room)
+		if err != nil {
+			log.Warn("exists room failed", logging.F("url", g.URL), logging.F("room", room), logging.F("err", err))
+			continue
+		}
+		if exists {
+			d.Add(room, g)
+			return g, nil
+		}
+	}
+	return nil, ErrRoomNotFound
+}
+
+func (d *Directory) cached(room uint64) (*jwsapi.Gateway, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.rooms[room]
+	if !ok || time.Now().After(entry.expire) || !entry.gateway.Healthy() {
+		return nil, false
+	}
+	return entry.gateway, true
+}
+
+//Room get cached room info
+func (d *Directory) Room(room uint64) (Room, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, ok := d.rooms[room]
+	return entry.room, ok
+}
+
+//Add add room location to cache, eg: room created by CreateRoom
+func (d *Directory) Add(room uint64, g *jwsapi.Gateway) {
+	d.mu.Lock()
+	d.rooms[room] = dirEntry{
+		gateway: g,
+		room:    Room{ID: room},
+		expire:  time.Now().Add(d.ttl),
+	}
+	d.mu.Unlock()
+}
+
+//Invalidate remove room from cache
+func (d *Directory) Invalidate(room uint64) {
+	d.mu.Lock()
+	delete(d.rooms, room)
+	d.mu.Unlock()
+}
+
+//Attach attach videoroom handle at gateway of room
+func (d *Directory) Attach(room uint64) (*jwsapi.Handle, error) {
+	g, err := d.Lookup(room)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.Session(g)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.Attach(Plugin)
+	if err != nil {
+		return nil, errors.Wrapf(err, "attach at %s", g.URL)
+	}
+	return h, nil
+}
+
+//CreateRoom create room at gateway picked by pool, room location is cached
+func (d *Directory) CreateRoom(opts ...jwsapi.MessageOption) (uint64, *jwsapi.Gateway, error) {
+	g, err := d.pool.Pick()
+	if err != nil {
+		return 0, nil, err
+	}
+	h, err := d.listHandle(g)
+	if err != nil {
+		return 0, nil, err
+	}
+	room, err := CreateRoom(h, opts...)
+	if err != nil {
+		return 0, nil, err
+	}
+	d.Add(room, g)
+	return room, g, nil
+}
+
+//Join attach at gateway of room and join as publisher
+//if room is not exists at the gateway, room location is invalidated and retry once, room may be moved to other gateway
+//other errors are returned without retry
+func (d *Directory) Join(ctx context.Context, room uint64, popts []PublisherOption, opts ...jwsapi.MessageOption) (*Publisher, error) {
+	var err error
+	for i := 0; i < 2; i++ {
+		var h *jwsapi.Handle
+		h, err = d.Attach(room)
+		if err != nil {
+			return nil, err
+		}
+		p := NewPublisher(ctx, h, room, popts...)
+		if err = p.JoinContext(ctx, opts...); err == nil {
+			return p, nil
+		}
+		h.Detach()
+		var perr *jplugin.Error
+		if !errors.As(err, &perr) || perr.Code != errorNoSuchRoom {
+			return nil, err
+		}
+		d.Invalidate(room)
+	}
+	return nil, err
+}
@@ -0,0 +1,218 @@
+package jvideoroom_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+)
+
+//newPool pool of n fake gateways with videoroom
+func newPool(t *testing.T, n int) (*jwsapi.Pool, []*janustest.Server) {
+	t.Helper()
+	var servers []*janustest.Server
+	var urls []string
+	for i := 0; i < n; i++ {
+		s := janustest.NewServer()
+		t.Cleanup(s.Close)
+		s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+		servers = append(servers, s)
+		urls = append(urls, s.URL)
+	}
+	pool := jwsapi.NewPool(context.Background(), urls)
+	t.Cleanup(pool.Close)
+	for _, g := range pool.Gateways() {
+		for i := 0; i < 100 && !g.Conn.Connected(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return pool, servers
+}
+
+func createRoom(t *testing.T, d *jvideoroom.Directory, g *jwsapi.Gateway, room uint64, opts ...jwsapi.MessageOption) {
+	t.Helper()
+	s, err := d.Session(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.Attach(jvideoroom.Plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Detach()
+	opts = append(opts, jvideoroom.WithMessageOptionRoom(room))
+	if _, err := jvideoroom.CreateRoom(h, opts...); err != nil {
+		t.Fatalf("create room %d: %v", room, err)
+	}
+}
+
+func count(s *janustest.Server, janus string) int {
+	n := 0
+	for _, msg := range s.Received() {
+		if msg.Type() == janus {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDirectoryPrivateRoom(t *testing.T) {
+	pool, _ := newPool(t, 2)
+	d := jvideoroom.NewDirectory(pool)
+	gateways := pool.Gateways()
+	createRoom(t, d, gateways[1], 5678, jwsapi.WithMessageOption("is_private", true))
+
+	g, err := d.Lookup(5678)
+	if err != nil {
+		t.Fatalf("lookup private room: %v", err)
+	}
+	if g != gateways[1] {
+		t.Errorf("private room at %s, want %s", g.URL, gateways[1].URL)
+	}
+	if _, err := d.Lookup(9999); err != jvideoroom.ErrRoomNotFound {
+		t.Errorf("lookup err = %v, want ErrRoomNotFound", err)
+	}
+}
+
+func TestDirectoryRefreshFailed(t *testing.T) {
+	pool, servers := newPool(t, 2)
+	d := jvideoroom.NewDirectory(pool)
+	gateways := pool.Gateways()
+	createRoom(t, d, gateways[0], 1111)
+	createRoom(t, d, gateways[1], 2222)
+	if err := d.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	//list of second gateway timeout, its rooms are kept
+	servers[1].DropNext(janustest.MatchRequest("list"), 1)
+	if err := d.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	for room, g := range map[uint64]*jwsapi.Gateway{1111: gateways[0], 2222: gateways[1]} {
+		got, err := d.Lookup(room)
+		if err != nil {
+			t.Fatalf("lookup %d: %v", room, err)
+		}
+		if got != g {
+			t.Errorf("room %d at %s, want %s", room, got.URL, g.URL)
+		}
+	}
+}
+
+func TestDirectoryConcurrentSession(t *testing.T) {
+	pool, servers := newPool(t, 1)
+	d := jvideoroom.NewDirectory(pool)
+	g := pool.Gateways()[0]
+
+	const n = 10
+	sessions := make([]*jwsapi.Session, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := d.Session(g)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sessions[i] = s
+		}(i)
+	}
+	wg.Wait()
+	for _, s := range sessions {
+		if s != sessions[0] {
+			t.Fatal("concurrent Session return different sessions")
+		}
+	}
+	//sessions created by losers are destroyed
+	if created, destroyed := count(servers[0], "create"), count(servers[0], "destroy"); created-destroyed != 1 {
+		t.Errorf("created %d, destroyed %d sessions, %d leaked", created, destroyed, created-destroyed-1)
+	}
+
+	//list handles attached by losers are detached
+	d = jvideoroom.NewDirectory(pool)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.Refresh(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if attached, detached := count(servers[0], "attach"), count(servers[0], "detach"); attached-detached != 1 {
+		t.Errorf("attached %d, detached %d handles, %d leaked", attached, detached, attached-detached-1)
+	}
+}
+
+func countRequest(s *janustest.Server, request string) int {
+	n := 0
+	for _, msg := range s.Received() {
+		if janustest.MatchRequest(request)(msg) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDirectoryJoin(t *testing.T) {
+	pool, servers := newPool(t, 2)
+	d := jvideoroom.NewDirectory(pool)
+	gateways := pool.Gateways()
+	createRoom(t, d, gateways[0], 1111)
+	if g, err := d.Lookup(1111); err != nil || g != gateways[0] {
+		t.Fatalf("lookup = %v, %v", g, err)
+	}
+
+	//room moved to second gateway, cached location is stale
+	s, err := d.Session(gateways[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.Attach(jvideoroom.Plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jvideoroom.DestroyRoom(h, 1111); err != nil {
+		t.Fatal(err)
+	}
+	h.Detach()
+	createRoom(t, d, gateways[1], 1111)
+	d.Add(1111, gateways[0])
+
+	ctx := context.Background()
+	p, err := d.Join(ctx, 1111, []jvideoroom.PublisherOption{jvideoroom.WithPublisherOptionID(7)})
+	if err != nil {
+		t.Fatalf("join moved room: %v", err)
+	}
+	defer p.Leave()
+	if g, _ := d.Lookup(1111); g != gateways[1] {
+		t.Errorf("room at %s after join, want %s", g.URL, gateways[1].URL)
+	}
+	if n := countRequest(servers[0], "join") + countRequest(servers[1], "join"); n != 2 {
+		t.Errorf("join requests = %d, want 2", n)
+	}
+
+	//other errors are not retried, location is kept
+	lists := countRequest(servers[0], "list")
+	_, err = d.Join(ctx, 1111, []jvideoroom.PublisherOption{jvideoroom.WithPublisherOptionID(7)})
+	var perr *jplugin.Error
+	if !errors.As(err, &perr) || perr.Code != janustest.VideoRoomErrorIDExists {
+		t.Fatalf("join with existing id: err = %v, want %d", err, janustest.VideoRoomErrorIDExists)
+	}
+	if n := countRequest(servers[1], "join"); n != 2 {
+		t.Errorf("join requests at %s = %d, want 2", gateways[1].URL, n)
+	}
+	if n := countRequest(servers[0], "list"); n != lists {
+		t.Errorf("list requests = %d, want %d, location is invalidated", n, lists)
+	}
+}
@@ -2,10 +2,11 @@ package jvideoroom
 
 import (
 	"context"
+	"sync"
 
 	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
 	"github.com/newzai/janus-go/logging"
-	"github.com/pkg/errors"
 )
 
 //PublisherState publisher state
@@ -22,13 +23,13 @@ const (
 
 //Publisher publisher
 type Publisher struct {
-	ctx     context.Context
-	handle  *jwsapi.Handle
-	room    uint64
+	handle *jwsapi.Handle
+	room   uint64
+
+	mu      sync.Mutex
 	id      uint64
 	display string
 	parts   map[uint64]Participant
-	tasks   chan func(*Publisher)
 	state   PublisherState
 	//callback
 	onNewPublisher func(Participant)
@@ -75,15 +76,13 @@ func WithPublisherOptionLeaved(callback func(uint64)) PublisherOption {
 	}
 }
 
-//NewPublisher create new publisher
+//NewPublisher create new publisher, events of h are routed until ctx done
 func NewPublisher(ctx context.Context, h *jwsapi.Handle, room uint64, opts ...PublisherOption) *Publisher {
 
 	p := &Publisher{
-		ctx:    ctx,
 		handle: h,
 		room:   room,
 		parts:  make(map[uint64]Participant),
-		tasks:  make(chan func(*Publisher), 128),
 		state:  PublisherStateUnjoin,
 	}
 
@@ -91,18 +90,29 @@ func NewPublisher(ctx context.Context, h *jwsapi.Handle, room uint64, opts ...Pu
 		opt(p)
 	}
 
-	go p.execLoop()
+	r := jplugin.NewRouter(Descriptor)
+	r.Fallback(func(e *jplugin.Event) {
+		p.onPluginEvent(e.Data)
+	})
+	go func() {
+		r.Run(ctx, h)
+		log.Info("Publisher End", logging.F("room", p.room), logging.F("id", p.ID()))
+	}()
 
 	return p
 }
 
-//ID get id
+//ID get id, assigned by janus-gateway after join if not set
 func (p *Publisher) ID() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.id
 }
 
 //Display get display
 func (p *Publisher) Display() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.display
 }
 
@@ -118,6 +128,8 @@ func (p *Publisher) Handle() *jwsapi.Handle {
 
 //SetOption set param, callback, eg: WithPublisherOptionNewPublisher
 func (p *Publisher) SetOption(opts ...PublisherOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -125,34 +137,28 @@ func (p *Publisher) SetOption(opts ...PublisherOption) {
 
 //Join join to janus
 func (p *Publisher) Join(opts ...jwsapi.MessageOption) error {
+	return p.JoinContext(context.Background(), opts...)
+}
 
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "join",
-		"ptype":            UserTypePublisher.String(),
-		"room":             p.room,
-	}
-	if p.id > 0 {
-		body["id"] = p.id
-	}
-	if p.display != "" {
-		body["display"] = p.display
-	}
-	for _, opt := range opts {
-		opt(body)
-	}
+//JoinContext join to janus, ctx using for cancel and trace
+func (p *Publisher) JoinContext(ctx context.Context, opts ...jwsapi.MessageOption) error {
 
-	rsp, err := p.handle.Message(body)
+	p.mu.Lock()
+	req := joinRequest{Ptype: UserTypePublisher.String(), Room: p.room, ID: p.id, Display: p.display}
+	p.mu.Unlock()
+	rsp, err := jplugin.Call[struct {
+		ID         uint64        `json:"id"`
+		Publishers []interface{} `json:"publishers"`
+	}](ctx, p.handle, Descriptor, req, nil, opts...)
 	if err != nil {
 		return err
 	}
-	pluginData := rsp.PluginData()
-	data := pluginData.Data()
-	p.id, _ = data.Uint64("id")
-
-	publishers := data.Array("publishers")
-	p.onPublishers(publishers)
 
+	p.mu.Lock()
+	p.id = rsp.Data.ID
 	p.state = PublisherStateJoined
+	p.mu.Unlock()
+	p.onPublishers(rsp.Data.Publishers)
 
 	return nil
 }
@@ -160,72 +166,55 @@ func (p *Publisher) Join(opts ...jwsapi.MessageOption) error {
 //Publish start offer
 //return answer, error
 func (p *Publisher) Publish(audio bool, video bool, data bool, offer string, trickle bool, opts ...jwsapi.MessageOption) (string, error) {
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "configure",
-		"audio":            audio,
-		"video":            video,
-		"data":             data,
-	}
-	for _, opt := range opts {
-		opt(body)
-	}
-	jsep := jwsapi.Message{
-		"type":    "offer",
-		"sdp":     offer,
-		"trickle": trickle,
-	}
+	return p.PublishContext(context.Background(), audio, video, data, offer, trickle, opts...)
+}
 
-	rsp, err := p.handle.JsepMessage(body, jsep)
+//PublishContext start offer, ctx using for cancel and trace
+//return answer, error
+func (p *Publisher) PublishContext(ctx context.Context, audio bool, video bool, data bool, offer string, trickle bool, opts ...jwsapi.MessageOption) (string, error) {
+	req := configureRequest{Audio: &audio, Video: &video, Data: &data}
+	rsp, err := jplugin.Call[struct{}](ctx, p.handle, Descriptor, req, &jplugin.JSEP{Type: "offer", SDP: offer, Trickle: &trickle}, opts...)
 	if err != nil {
 		return "", err
 	}
-	jsep, ok := rsp.SubMessage("jsep")
-	if !ok {
-		return "", errors.New("not jsep")
-	}
-	if jtype, ok := jsep.String("type"); !ok || jtype != "answer" {
-		return "", errors.New("jsep type error")
-	}
-	sdp, ok := jsep.String("sdp")
-	if !ok {
-		return "", errors.New("not sdp")
-	}
-	return sdp, nil
-
+	return sdpOf(rsp.JSEP, "answer")
 }
 
 //Unpublish tell janus-gateway close PeerConnection
 func (p *Publisher) Unpublish() error {
-
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "unpublish",
-	}
-
-	_, err := p.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), p.handle, Descriptor, request{"unpublish"}, nil)
 	return err
 }
 
 //Leave leave the room
 func (p *Publisher) Leave() error {
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "leave",
-	}
-
-	_, err := p.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), p.handle, Descriptor, request{"leave"}, nil)
 	return err
 }
 
+//onPublishers decode publishers, entries are not object are skipped
 func (p *Publisher) onPublishers(publishers []interface{}) {
 	for _, pub := range publishers {
-		part := Participant{jwsapi.Message(pub.(map[string]interface{}))}
-		id := part.ID()
-		if id > 0 {
-			p.parts[id] = part
-			if p.onNewPublisher != nil {
-				p.onNewPublisher(part)
-			}
+		obj, ok := pub.(map[string]interface{})
+		if !ok {
+			log.Warn("unexpected publisher", logging.F("room", p.room), logging.F("publisher", pub))
+			continue
+		}
+		var part Participant
+		if err := jplugin.Decode(jwsapi.Message(obj), &part); err != nil {
+			log.Warn("unexpected publisher", logging.F("room", p.room), logging.F("publisher", pub), logging.F("err", err))
+			continue
+		}
+		if part.ID == 0 {
+			continue
+		}
+		p.mu.Lock()
+		p.parts[part.ID] = part
+		onNewPublisher := p.onNewPublisher
+		p.mu.Unlock()
+		if onNewPublisher != nil {
+			onNewPublisher(part)
 		}
-
 	}
 }
 
@@ -233,63 +222,34 @@ func (p *Publisher) onPluginEvent(event jwsapi.Message) {
 
 	if publishers := event.Array("publishers"); publishers != nil {
 		p.onPublishers(publishers)
-	} else if unpublished, ok := event.Uint64("unpublished"); ok {
+		return
+	}
+
+	p.mu.Lock()
+	onUnpublished, onLeaved, onStateChanged := p.onUnpublished, p.onLeaved, p.onStateChanged
+	if unpublished, ok := event.Uint64("unpublished"); ok {
 		delete(p.parts, unpublished)
-		if p.onUnpublished != nil {
-			p.onUnpublished(unpublished)
+		p.mu.Unlock()
+		if onUnpublished != nil {
+			onUnpublished(unpublished)
 		}
-
 	} else if leaving, ok := event.Uint64("leaving"); ok {
 		delete(p.parts, leaving)
-		if p.onUnpublished != nil {
-			p.onUnpublished(leaving)
+		p.mu.Unlock()
+		if onUnpublished != nil {
+			onUnpublished(leaving)
 		}
-		if p.onLeaved != nil {
-			p.onLeaved(leaving)
+		if onLeaved != nil {
+			onLeaved(leaving)
 		}
 	} else if leaving, ok := event.String("leaving"); ok && leaving == "ok" {
 		//itself leave,use call Leave()
 		p.state = PublisherStateUnjoin
-		if p.onStateChanged != nil {
-			p.onStateChanged(p.state)
-		}
-	}
-}
-
-func (p *Publisher) onEvent(event *jwsapi.Message) {
-
-	logging.Infof("[%d] recv event %v", p.id, event)
-	switch event.Type() {
-	case "event":
-		pluginData := event.PluginData()
-		data := pluginData.Data()
-		p.onPluginEvent(data)
-		//被动挂断..
-	default:
-		logging.Warnf("[%d] unknown msg %s", p.id, event.Type())
-	}
-}
-func (p *Publisher) execLoop() {
-
-	defer func() {
-		logging.Infof("[%d] Publisher End", p.id)
-		close(p.tasks)
-	}()
-	for {
-		select {
-		case <-p.ctx.Done():
-			return
-		case event, ok := <-p.handle.Events:
-			if !ok {
-				return
-			}
-
-			p.onEvent(event)
-		case t, ok := <-p.tasks:
-			if !ok {
-				return
-			}
-			t(p)
+		p.mu.Unlock()
+		if onStateChanged != nil {
+			onStateChanged(PublisherStateUnjoin)
 		}
+	} else {
+		p.mu.Unlock()
 	}
 }
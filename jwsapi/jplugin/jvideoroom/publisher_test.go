@@ -0,0 +1,104 @@
+package jvideoroom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+)
+
+func TestPublishersMalformed(t *testing.T) {
+	pool, servers := newPool(t, 1)
+	d := jvideoroom.NewDirectory(pool)
+	s, err := d.Session(pool.Gateways()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.Attach(jvideoroom.Plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSess, _ := servers[0].Session(s.ID)
+	serverHandle, ok := serverSess.Handle(h.ID)
+	if !ok {
+		t.Fatal("no handle at server")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	parts := make(chan jvideoroom.Participant, 4)
+	jvideoroom.NewPublisher(ctx, h, 1234, jvideoroom.WithPublisherOptionNewPublisher(func(part jvideoroom.Participant) {
+		parts <- part
+	}))
+
+	//entries of unexpected type are skipped
+	serverHandle.Event(jwsapi.Message{
+		"videoroom":  "event",
+		"room":       1234,
+		"publishers": []interface{}{1, "x", nil, jwsapi.Message{"id": 7, "display": "seven"}},
+	}, nil)
+	select {
+	case part := <-parts:
+		if part.ID != 7 {
+			t.Errorf("publisher id = %d, want 7", part.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("valid publisher is not reported")
+	}
+	select {
+	case part := <-parts:
+		t.Errorf("unexpected publisher %+v", part)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestListTyped(t *testing.T) {
+	pool, _ := newPool(t, 1)
+	d := jvideoroom.NewDirectory(pool)
+	g := pool.Gateways()[0]
+	createRoom(t, d, g, 4321, jvideoroom.WithMessageOptionDescription("demo"))
+	s, err := d.Session(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.Attach(jvideoroom.Plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := jvideoroom.NewPublisher(ctx, h, 4321, jvideoroom.WithPublisherOptionID(7), jvideoroom.WithPublisherOptionDisplay("seven"))
+	if err := p.Join(); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID() != 7 {
+		t.Errorf("id = %d, want 7", p.ID())
+	}
+
+	rooms, err := jvideoroom.List(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := jvideoroom.Room{ID: 4321, Description: "demo", MaxPublishers: 100, NumParticipants: 1}
+	found := false
+	for _, room := range rooms {
+		if room.ID == want.ID {
+			found = true
+			if room != want {
+				t.Errorf("room = %+v, want %+v", room, want)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("room %d is not listed: %+v", want.ID, rooms)
+	}
+	parts, err := jvideoroom.Listparticipants(h, 4321)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || parts[0].ID != 7 || parts[0].Display != "seven" || parts[0].Publisher {
+		t.Errorf("participants = %+v", parts)
+	}
+}
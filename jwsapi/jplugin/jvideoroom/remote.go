@@ -0,0 +1,297 @@
+package jvideoroom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//remoteStreams streams for add_remote_publisher and update_remote_publisher, description is not allowed
+func remoteStreams(streams []Stream) []Stream {
+	ar := make([]Stream, 0, len(streams))
+	for _, s := range streams {
+		s.Description = ""
+		ar = append(ar, s)
+	}
+	return ar
+}
+
+//RemotePublisherInfo response of add_remote_publisher
+//rtp of the remote publisher should be sent to IP:Port, rtcp is from RTCPPort
+type RemotePublisherInfo struct {
+	Room     uint64 `json:"room"`
+	ID       uint64 `json:"id"`
+	IP       string `json:"ip"`
+	Port     uint16 `json:"port"`
+	RTCPPort uint16 `json:"rtcp_port"`
+}
+
+type remotePublisherRequest struct {
+	Room    uint64   `json:"room"`
+	ID      uint64   `json:"id,omitempty"`
+	Streams []Stream `json:"streams,omitempty"`
+	name    string
+}
+
+func (r remotePublisherRequest) Request() string { return r.name }
+
+type remoteRequest struct {
+	Room        uint64 `json:"room"`
+	PublisherID uint64 `json:"publisher_id"`
+	RemoteID    string `json:"remote_id,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Port        uint16 `json:"port,omitempty"`
+	RTCPPort    uint16 `json:"rtcp_port,omitempty"`
+	name        string
+}
+
+func (r remoteRequest) Request() string { return r.name }
+
+//AddRemotePublisher add remote publisher to room, janus-gateway 1.x
+//streams are from Participant.Streams of the publisher at other janus-gateway
+//jwsapi.WithMessageOption("display", "xx") to set display, WithMessageOptionSecret to set secret
+func AddRemotePublisher(h *jwsapi.Handle, room uint64, streams []Stream, opts ...jwsapi.MessageOption) (*RemotePublisherInfo, error) {
+	req := remotePublisherRequest{Room: room, Streams: remoteStreams(streams), name: "add_remote_publisher"}
+	rsp, err := jplugin.Call[RemotePublisherInfo](context.Background(), h, Descriptor, req, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Data.ID == 0 {
+		return nil, errors.New("add_remote_publisher response without id")
+	}
+	info := rsp.Data
+	info.Room = room
+	return &info, nil
+}
+
+//UpdateRemotePublisher update streams of remote publisher, janus-gateway 1.x
+func UpdateRemotePublisher(h *jwsapi.Handle, room uint64, id uint64, streams []Stream, opts ...jwsapi.MessageOption) error {
+	req := remotePublisherRequest{Room: room, ID: id, Streams: remoteStreams(streams), name: "update_remote_publisher"}
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
+
+//RemoveRemotePublisher remove remote publisher from room, janus-gateway 1.x
+func RemoveRemotePublisher(h *jwsapi.Handle, room uint64, id uint64, opts ...jwsapi.MessageOption) error {
+	req := remotePublisherRequest{Room: room, ID: id, name: "remove_remote_publisher"}
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
+
+//PublishRemotely forward publisher to remote publisher at host:port, janus-gateway 1.x
+//remoteID is unique id of this forward for the publisher
+//jwsapi.WithMessageOption("srtp_suite", 32) and ("srtp_crypto", "xx") for srtp
+func PublishRemotely(h *jwsapi.Handle, room uint64, publisherID uint64, remoteID string, host string, port uint16, rtcpPort uint16, opts ...jwsapi.MessageOption) error {
+	req := remoteRequest{
+		Room:        room,
+		PublisherID: publisherID,
+		RemoteID:    remoteID,
+		Host:        host,
+		Port:        port,
+		RTCPPort:    rtcpPort,
+		name:        "publish_remotely",
+	}
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
+
+//UnpublishRemotely stop forward publisher to remote, janus-gateway 1.x
+func UnpublishRemotely(h *jwsapi.Handle, room uint64, publisherID uint64, remoteID string, opts ...jwsapi.MessageOption) error {
+	req := remoteRequest{Room: room, PublisherID: publisherID, RemoteID: remoteID, name: "unpublish_remotely"}
+	_, err := jplugin.Call[struct{}](context.Background(), h, Descriptor, req, nil, opts...)
+	return err
+}
+
+//Remote a remote of publisher, by publish_remotely
+type Remote struct {
+	RemoteID string `json:"remote_id"`
+	Host     string `json:"host"`
+	Port     uint16 `json:"port"`
+	RTCPPort uint16 `json:"rtcp_port"`
+}
+
+//ListRemotes list remotes of publisher, janus-gateway 1.x
+func ListRemotes(h *jwsapi.Handle, room uint64, publisherID uint64, opts ...jwsapi.MessageOption) ([]Remote, error) {
+	req := remoteRequest{Room: room, PublisherID: publisherID, name: "list_remotes"}
+	rsp, err := jplugin.Call[struct {
+		List []Remote `json:"list"`
+	}](context.Background(), h, Descriptor, req, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Data.List, nil
+}
+
+//RemoteFeed publisher of source room, forwarded to remote publisher of destination room
+type RemoteFeed struct {
+	Feed     uint64 //publisher id at source room
+	ID       uint64 //remote publisher id at destination room
+	RemoteID string //remote_id of publish_remotely
+	Host     string
+	Port     uint16
+	RTCPPort uint16
+}
+
+//RemoteCoordinator share publishers of source room to destination room by publish_remotely and add_remote_publisher
+//src and dst are videoroom handles, may be at different janus-gateway, janus-gateway 1.x only
+//media is forwarded by janus-gateway over rtp, not decoded by go
+type RemoteCoordinator struct {
+	src       *jwsapi.Handle
+	srcRoom   uint64
+	dst       *jwsapi.Handle
+	dstRoom   uint64
+	host      string
+	srcSecret string
+	dstSecret string
+
+	mu    sync.Mutex
+	feeds map[uint64]*RemoteFeed
+}
+
+//RemoteCoordinatorOption option for RemoteCoordinator
+type RemoteCoordinatorOption func(*RemoteCoordinator)
+
+//WithRemoteHost host for publish_remotely, default is ip of add_remote_publisher response
+//eg: public address of destination janus-gateway
+func WithRemoteHost(host string) RemoteCoordinatorOption {
+	return func(c *RemoteCoordinator) {
+		c.host = host
+	}
+}
+
+//WithRemoteSecret secret of source room and destination room
+func WithRemoteSecret(srcSecret string, dstSecret string) RemoteCoordinatorOption {
+	return func(c *RemoteCoordinator) {
+		c.srcSecret = srcSecret
+		c.dstSecret = dstSecret
+	}
+}
+
+//NewRemoteCoordinator create coordinator
+//feed Participant of source room by Add, eg: WithPublisherOptionNewPublisher of a publisher at source room
+func NewRemoteCoordinator(src *jwsapi.Handle, srcRoom uint64, dst *jwsapi.Handle, dstRoom uint64, opts ...RemoteCoordinatorOption) *RemoteCoordinator {
+	c := &RemoteCoordinator{
+		src:     src,
+		srcRoom: srcRoom,
+		dst:     dst,
+		dstRoom: dstRoom,
+		feeds:   make(map[uint64]*RemoteFeed),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *RemoteCoordinator) srcOpts() []jwsapi.MessageOption {
+	if c.srcSecret == "" {
+		return nil
+	}
+	return []jwsapi.MessageOption{WithMessageOptionSecret(c.srcSecret)}
+}
+
+func (c *RemoteCoordinator) dstOpts(opts ...jwsapi.MessageOption) []jwsapi.MessageOption {
+	if c.dstSecret != "" {
+		opts = append(opts, WithMessageOptionSecret(c.dstSecret))
+	}
+	return opts
+}
+
+//Add add remote publisher of part to destination room and publish remotely, update streams if added
+func (c *RemoteCoordinator) Add(part Participant) (*RemoteFeed, error) {
+	feed := part.ID
+	streams := part.Streams
+	display := jwsapi.WithMessageOption("display", part.Display)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rf, ok := c.feeds[feed]; ok {
+		if err := UpdateRemotePublisher(c.dst, c.dstRoom, rf.ID, streams, c.dstOpts(display)...); err != nil {
+			return nil, errors.Wrap(err, "update_remote_publisher")
+		}
+		return rf, nil
+	}
+	if len(streams) == 0 {
+		return nil, errors.Errorf("publisher %d has no streams", feed)
+	}
+
+	info, err := AddRemotePublisher(c.dst, c.dstRoom, streams, c.dstOpts(display)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "add_remote_publisher")
+	}
+	rf := &RemoteFeed{
+		Feed:     feed,
+		ID:       info.ID,
+		RemoteID: fmt.Sprintf("%d-%d", c.dstRoom, info.ID),
+		Host:     info.IP,
+		Port:     info.Port,
+		RTCPPort: info.RTCPPort,
+	}
+	if c.host != "" {
+		rf.Host = c.host
+	}
+	err = PublishRemotely(c.src, c.srcRoom, feed, rf.RemoteID, rf.Host, rf.Port, rf.RTCPPort, c.srcOpts()...)
+	if err != nil {
+		RemoveRemotePublisher(c.dst, c.dstRoom, rf.ID, c.dstOpts()...)
+		return nil, errors.Wrap(err, "publish_remotely")
+	}
+	c.feeds[feed] = rf
+	log.Info("remote publisher added", logging.F("room", c.srcRoom), logging.F("feed", feed), logging.F("remote_room", c.dstRoom), logging.F("remote", rf.ID))
+	return rf, nil
+}
+
+//Remove stop publish remotely and remove remote publisher of feed
+func (c *RemoteCoordinator) Remove(feed uint64) error {
+	c.mu.Lock()
+	rf, ok := c.feeds[feed]
+	delete(c.feeds, feed)
+	c.mu.Unlock()
+	if !ok {
+		return errors.Errorf("feed %d not added", feed)
+	}
+	return c.remove(rf)
+}
+
+func (c *RemoteCoordinator) remove(rf *RemoteFeed) error {
+	//publisher may be gone at source room, remove remote publisher anyway
+	err := UnpublishRemotely(c.src, c.srcRoom, rf.Feed, rf.RemoteID, c.srcOpts()...)
+	if rerr := RemoveRemotePublisher(c.dst, c.dstRoom, rf.ID, c.dstOpts()...); rerr != nil {
+		return errors.Wrap(rerr, "remove_remote_publisher")
+	}
+	log.Info("remote publisher removed", logging.F("room", c.srcRoom), logging.F("feed", rf.Feed), logging.F("remote_room", c.dstRoom), logging.F("remote", rf.ID))
+	if err != nil {
+		log.Warn("unpublish_remotely failed", logging.F("room", c.srcRoom), logging.F("feed", rf.Feed), logging.F("err", err))
+	}
+	return nil
+}
+
+//Feeds all remote feeds
+func (c *RemoteCoordinator) Feeds() []RemoteFeed {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	feeds := make([]RemoteFeed, 0, len(c.feeds))
+	for _, rf := range c.feeds {
+		feeds = append(feeds, *rf)
+	}
+	return feeds
+}
+
+//Close remove all remote feeds
+func (c *RemoteCoordinator) Close() error {
+	c.mu.Lock()
+	feeds := c.feeds
+	c.feeds = make(map[uint64]*RemoteFeed)
+	c.mu.Unlock()
+	var err error
+	for _, rf := range feeds {
+		if rerr := c.remove(rf); rerr != nil {
+			err = rerr
+		}
+	}
+	return err
+}
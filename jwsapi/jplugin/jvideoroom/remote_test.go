@@ -0,0 +1,131 @@
+package jvideoroom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+	"github.com/newzai/janus-go/videoroom"
+)
+
+//watch join room as publisher without media, report publishers and leaving
+func watch(t *testing.T, ctx context.Context, s *janustest.Server, room uint64) (<-chan jvideoroom.Participant, <-chan uint64) {
+	t.Helper()
+	parts := make(chan jvideoroom.Participant, 8)
+	leaving := make(chan uint64, 8)
+	w := jvideoroom.NewPublisher(ctx, janustest.Attach(t, ctx, s, jvideoroom.Plugin), room,
+		jvideoroom.WithPublisherOptionDisplay("watcher"),
+		jvideoroom.WithPublisherOptionNewPublisher(func(part jvideoroom.Participant) { parts <- part }),
+		jvideoroom.WithPublisherOptionLeaved(func(id uint64) { leaving <- id }))
+	if err := w.Join(); err != nil {
+		t.Fatalf("join watcher: %v", err)
+	}
+	return parts, leaving
+}
+
+func nextPart(t *testing.T, parts <-chan jvideoroom.Participant) jvideoroom.Participant {
+	t.Helper()
+	select {
+	case part := <-parts:
+		return part
+	case <-time.After(10 * time.Second):
+		t.Fatal("publisher is not notified")
+	}
+	return jvideoroom.Participant{}
+}
+
+func TestRemoteCoordinator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src, dst := janustest.NewServer(), janustest.NewServer()
+	defer src.Close()
+	defer dst.Close()
+	src.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+	dstRoom := janustest.NewVideoRoom()
+	dst.RegisterPlugin(janustest.VideoRoomPlugin, dstRoom)
+
+	srcParts, _ := watch(t, ctx, src, 1234)
+	dstParts, dstLeaving := watch(t, ctx, dst, 1234)
+
+	pub := videoroom.NewPublisher(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, src, jvideoroom.Plugin), 1234)
+	pub.Object().SetOption(jvideoroom.WithPublisherOptionDisplay("alice"))
+	if err := pub.Join(); err != nil {
+		t.Fatalf("join publisher: %v", err)
+	}
+	if err := pub.Publish(true, true); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	defer pub.Unpublish()
+	part := nextPart(t, srcParts)
+	if part.ID != pub.Object().ID() || len(part.Streams) < 2 || part.Streams[0].Type != "audio" || part.Streams[1].Type != "video" {
+		t.Fatalf("publisher = %+v, want %d with audio and video", part, pub.Object().ID())
+	}
+
+	srcHandle := janustest.Attach(t, ctx, src, jvideoroom.Plugin)
+	c := jvideoroom.NewRemoteCoordinator(srcHandle, 1234, janustest.Attach(t, ctx, dst, jvideoroom.Plugin), 1234,
+		jvideoroom.WithRemoteHost("192.0.2.1"))
+	rf, err := c.Add(part)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if rf.Feed != part.ID || rf.ID == 0 || rf.Host != "192.0.2.1" || rf.Port == 0 || rf.RTCPPort != rf.Port+1 {
+		t.Errorf("remote feed = %+v", rf)
+	}
+
+	//remote publisher is a publisher of destination room, with streams of source
+	remote := nextPart(t, dstParts)
+	if remote.ID != rf.ID || remote.Display != "alice" || len(remote.Streams) != len(part.Streams) {
+		t.Fatalf("remote publisher = %+v, want %d alice", remote, rf.ID)
+	}
+	for i, stream := range remote.Streams {
+		if stream.Type != part.Streams[i].Type || stream.Codec != part.Streams[i].Codec || stream.MIndex != part.Streams[i].MIndex {
+			t.Errorf("stream %d = %+v, want %+v", i, stream, part.Streams[i])
+		}
+	}
+
+	remotes, err := jvideoroom.ListRemotes(srcHandle, 1234, part.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := jvideoroom.Remote{RemoteID: rf.RemoteID, Host: rf.Host, Port: rf.Port, RTCPPort: rf.RTCPPort}
+	if len(remotes) != 1 || remotes[0] != want {
+		t.Errorf("remotes = %+v, want %+v", remotes, want)
+	}
+
+	//add again updates remote publisher
+	part.Display = "alice2"
+	again, err := c.Add(part)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if again.ID != rf.ID || len(c.Feeds()) != 1 {
+		t.Errorf("update added feed %+v, feeds %d", again, len(c.Feeds()))
+	}
+	if updated := nextPart(t, dstParts); updated.ID != rf.ID || updated.Display != "alice2" {
+		t.Errorf("updated remote publisher = %+v", updated)
+	}
+
+	if err := c.Remove(part.ID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	select {
+	case id := <-dstLeaving:
+		if id != rf.ID {
+			t.Errorf("leaving = %d, want %d", id, rf.ID)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("remote publisher is not removed")
+	}
+	if remotes, err := jvideoroom.ListRemotes(srcHandle, 1234, part.ID); err != nil || len(remotes) != 0 {
+		t.Errorf("remotes after remove = %+v, %v", remotes, err)
+	}
+	if ids := dstRoom.Publishers(1234); len(ids) != 0 {
+		t.Errorf("publishers of destination = %v", ids)
+	}
+	if err := c.Remove(part.ID); err == nil {
+		t.Error("remove twice is not failed")
+	}
+}
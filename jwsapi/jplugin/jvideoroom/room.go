@@ -1,48 +1,51 @@
 package jvideoroom
 
-import "github.com/newzai/janus-go/jwsapi"
-
-//Room room info
+//Room room info of list
 type Room struct {
-	jwsapi.Message
-}
-
-//ID room id
-func (r *Room) ID() uint64 {
-	room, _ := r.Uint64("room")
-	return room
-}
-
-//Participant part
+	ID              uint64 `json:"room"`
+	Description     string `json:"description"`
+	PinRequired     bool   `json:"pin_required"`
+	IsPrivate       bool   `json:"is_private"`
+	MaxPublishers   int    `json:"max_publishers"`
+	Bitrate         uint64 `json:"bitrate"`
+	FirFreq         int    `json:"fir_freq"`
+	RequirePvtID    bool   `json:"require_pvtid"`
+	NotifyJoining   bool   `json:"notify_joining"`
+	AudioCodec      string `json:"audiocodec"` //eg: opus,g722
+	VideoCodec      string `json:"videocodec"` //eg: vp8,h264
+	Record          bool   `json:"record"`
+	RecDir          string `json:"rec_dir"`
+	NumParticipants int    `json:"num_participants"`
+}
+
+//Participant publisher of publishers event, or participant of listparticipants
 type Participant struct {
-	jwsapi.Message
-}
-
-//ID return publisher id
-func (r *Participant) ID() uint64 {
-	id, _ := r.Uint64("id")
-	return id
-}
-
-//Display get display
-func (r *Participant) Display() string {
-	display, _ := r.String("display")
-	return display
-}
-
-//AudioCodec audio codec for this publisher
-func (r *Participant) AudioCodec() string {
-	codec, _ := r.String("audio_codec")
-	return codec
-}
-
-//VideoCodec video codec for this publisher
-func (r *Participant) VideoCodec() string {
-	codec, _ := r.String("video_codec")
-	return codec
-}
-
-//Simulcast only for VP8 and H.264
-func (r *Participant) Simulcast() bool {
-	return r.Bool("simulcast")
+	ID         uint64   `json:"id"`
+	Display    string   `json:"display"`
+	Publisher  bool     `json:"publisher"` //listparticipants only
+	Talking    bool     `json:"talking"`
+	AudioCodec string   `json:"audio_codec"`
+	VideoCodec string   `json:"video_codec"`
+	Simulcast  bool     `json:"simulcast"` //only for VP8 and H.264
+	Streams    []Stream `json:"streams"`   //janus-gateway 1.x only
+}
+
+//Stream stream of publisher, janus-gateway 1.x
+type Stream struct {
+	Type               string `json:"type"` //audio, video or data
+	MIndex             uint64 `json:"mindex"`
+	Mid                string `json:"mid"`
+	Disabled           bool   `json:"disabled,omitempty"`
+	Codec              string `json:"codec,omitempty"`
+	Description        string `json:"description,omitempty"`
+	Fmtp               string `json:"fmtp,omitempty"`
+	H264Profile        string `json:"h264_profile,omitempty"`
+	VP9Profile         string `json:"vp9_profile,omitempty"`
+	Simulcast          bool   `json:"simulcast,omitempty"`
+	SVC                bool   `json:"svc,omitempty"`
+	AudioLevelExt      bool   `json:"audiolevel_ext,omitempty"`
+	AudioLevelEvent    bool   `json:"audiolevel_event,omitempty"`
+	VideoOrientExt     bool   `json:"videoorient_ext,omitempty"`
+	PlayoutDelayExt    bool   `json:"playoutdelay_ext,omitempty"`
+	TransportWideCCExt bool   `json:"transport_wide_cc_ext,omitempty"`
 }
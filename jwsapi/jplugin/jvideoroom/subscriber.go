@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"github.com/newzai/janus-go/jwsapi"
-	"github.com/pkg/errors"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
 )
 
 //Subscriber a subscriber
@@ -56,73 +56,42 @@ func (s *Subscriber) Handle() *jwsapi.Handle {
 //jwsapi.WithMessageOption("video",false) to ignore video stream
 //return sdp(offer),nil, or "", err
 func (s *Subscriber) Join(opts ...jwsapi.MessageOption) (string, error) {
+	return s.JoinContext(context.Background(), opts...)
+}
 
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "join",
-		"ptype":            UserTypeSubscriber.String(),
-		"room":             s.room,
-		"feed":             s.feed,
-		"audio":            true,
-		"video":            true,
-		"data":             false,
-	}
-
-	for _, opt := range opts {
-		opt(body)
-	}
+//JoinContext join the janus, ctx using for cancel and trace
+//return sdp(offer),nil, or "", err
+func (s *Subscriber) JoinContext(ctx context.Context, opts ...jwsapi.MessageOption) (string, error) {
 
-	rsp, err := s.handle.Message(body)
+	audio, video, data := true, true, false
+	req := joinRequest{Ptype: UserTypeSubscriber.String(), Room: s.room, Feed: s.feed, Audio: &audio, Video: &video, Data: &data}
+	rsp, err := jplugin.Call[struct{}](ctx, s.handle, Descriptor, req, nil, opts...)
 	if err != nil {
 		return "", err
 	}
-
-	jsep, ok := rsp.SubMessage("jsep")
-	if !ok {
-		return "", errors.New("not jsep")
-	}
-	if jtype, ok := jsep.String("type"); !ok || jtype != "offer" {
-		return "", errors.New("jsep type error")
-	}
-	sdp, ok := jsep.String("sdp")
-	if !ok {
-		return "", errors.New("not sdp")
-	}
-	return sdp, nil
+	return sdpOf(rsp.JSEP, "offer")
 }
 
 //Start send answer to janus
 func (s *Subscriber) Start(answer string, trickle bool) error {
+	return s.StartContext(context.Background(), answer, trickle)
+}
 
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "start",
-	}
-	jsep := jwsapi.Message{
-		"type":    "answer",
-		"sdp":     answer,
-		"trickle": trickle,
-	}
-
-	_, err := s.handle.JsepMessage(body, jsep)
+//StartContext send answer to janus, ctx using for cancel and trace
+func (s *Subscriber) StartContext(ctx context.Context, answer string, trickle bool) error {
+	_, err := jplugin.Call[struct{}](ctx, s.handle, Descriptor, request{"start"}, &jplugin.JSEP{Type: "answer", SDP: answer, Trickle: &trickle})
 	return err
 }
 
 //Pause stop recv audio,video stream from janus video
 func (s *Subscriber) Pause() error {
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "pause",
-	}
-
-	_, err := s.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), s.handle, Descriptor, request{"pause"}, nil)
 	return err
 }
 
 //Play after call Pause to start recv audio,video stream
 func (s *Subscriber) Play() error {
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "start",
-	}
-
-	_, err := s.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), s.handle, Descriptor, request{"start"}, nil)
 	return err
 }
 
@@ -131,42 +100,27 @@ func (s *Subscriber) Play() error {
 //jwsapi.WithMessageOption("video",true) to recv video from janus
 //see https://jwsapi.conf.meetecho.com/docs/videoroom.html configure
 func (s *Subscriber) Configure(opts ...jwsapi.MessageOption) error {
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "configure",
-	}
-	for _, opt := range opts {
-		opt(body)
-	}
-	_, err := s.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), s.handle, Descriptor, configureRequest{}, nil, opts...)
 	return err
 }
 
-//Switch switch stream from feed to new feed
-func (s *Subscriber) Switch(newFeed uint64, opts ...jwsapi.MessageOption) error {
-
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "switch",
-		"feed":             newFeed,
-	}
+type switchRequest struct {
+	Feed uint64 `json:"feed"`
+}
 
-	for _, opt := range opts {
-		opt(body)
-	}
+func (switchRequest) Request() string { return "switch" }
 
-	_, err := s.handle.Message(body)
+//Switch switch stream from feed to new feed
+func (s *Subscriber) Switch(newFeed uint64, opts ...jwsapi.MessageOption) error {
+	_, err := jplugin.Call[struct{}](context.Background(), s.handle, Descriptor, switchRequest{Feed: newFeed}, nil, opts...)
 	if err == nil {
 		s.feed = newFeed
 	}
-
 	return err
 }
 
 //Leave leave to subscriber
 func (s *Subscriber) Leave() error {
-
-	body := jwsapi.Message{
-		jwsapi.AttrRequest: "leave",
-	}
-	_, err := s.handle.Message(body)
+	_, err := jplugin.Call[struct{}](context.Background(), s.handle, Descriptor, request{"leave"}, nil)
 	return err
 }
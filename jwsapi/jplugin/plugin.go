@@ -0,0 +1,147 @@
+//Package jplugin typed framework of janus-gateway plugin clients
+//Plugin describe name, event key and synchronous requests of plugin,
+//Call send typed request (struct with json tags) and decode typed response,
+//Router route plugin events of handle to typed handlers by name
+//
+//	var descriptor = jplugin.NewPlugin("janus.plugin.videoroom", "videoroom", "create", "exists", "list")
+//
+//	type existsRequest struct {
+//		Room uint64 `json:"room"`
+//	}
+//
+//	func (existsRequest) Request() string { return "exists" }
+//
+//	rsp, err := jplugin.Call[struct {
+//		Exists bool `json:"exists"`
+//	}](ctx, h, descriptor, existsRequest{Room: 1234}, nil)
+package jplugin
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Named("jplugin")
+
+//Plugin descriptor of janus-gateway plugin
+type Plugin struct {
+	Name  string //eg: janus.plugin.videoroom
+	Event string //key of event type in plugindata.data, eg: videoroom
+	sync  map[string]bool
+}
+
+//NewPlugin create descriptor, sync is requests with success response, others are ack + event
+func NewPlugin(name string, event string, sync ...string) *Plugin {
+	p := &Plugin{
+		Name:  name,
+		Event: event,
+		sync:  make(map[string]bool, len(sync)),
+	}
+	for _, request := range sync {
+		p.sync[request] = true
+	}
+	return p
+}
+
+//IsSync request has success response
+func (p *Plugin) IsSync(request string) bool {
+	return p.sync[request]
+}
+
+//Attach attach handle of plugin
+func (p *Plugin) Attach(s *jwsapi.Session) (*jwsapi.Handle, error) {
+	return s.Attach(p.Name)
+}
+
+//EventName name of plugin event, in order of:
+//data[Event] if not "event", eg: joined of videoroom;
+//result.event, eg: incomingcall of sip;
+//result.status, eg: playing of recordplay;
+//data[Event], empty if not found
+func (p *Plugin) EventName(data jwsapi.Message) string {
+	name, _ := data.String(p.Event)
+	if name != "" && name != "event" {
+		return name
+	}
+	if result, ok := data.SubMessage("result"); ok {
+		if event, ok := result.String("event"); ok {
+			return event
+		}
+		if status, ok := result.String("status"); ok {
+			return status
+		}
+	}
+	return name
+}
+
+//Request typed request, json of struct is body of message
+type Request interface {
+	//Request name of request, eg: create, join
+	Request() string
+}
+
+//JSEP offer or answer of message
+type JSEP = jwsapi.JSEP
+
+//Error error of janus-gateway, error_code and error of plugindata, or code and reason of janus error
+type Error = jwsapi.ErrorInfo
+
+//Encode json of v to Message, numbers are json.Number like received Message
+func Encode(v interface{}) (jwsapi.Message, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode")
+	}
+	msg := jwsapi.Message{}
+	if err := decodeJSON(data, &msg); err != nil {
+		return nil, errors.Wrap(err, "encode")
+	}
+	return msg, nil
+}
+
+//Decode Message to v, v is pointer of typed struct, jwsapi.Message fields of v keep json.Number
+func Decode(msg jwsapi.Message, v interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "decode")
+	}
+	if err := decodeJSON(data, v); err != nil {
+		return errors.Wrap(err, "decode")
+	}
+	return nil
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+//pluginData data of plugindata of message, nil if not
+func pluginData(msg *jwsapi.Message) jwsapi.Message {
+	if msg == nil {
+		return nil
+	}
+	pd, _ := msg.SubMessage("plugindata")
+	data, _ := pd.SubMessage("data")
+	return data
+}
+
+//jsep of message, nil if not
+func jsep(msg *jwsapi.Message) *JSEP {
+	if msg == nil {
+		return nil
+	}
+	sub, ok := msg.SubMessage("jsep")
+	if !ok {
+		return nil
+	}
+	j := &JSEP{}
+	j.Type, _ = sub.String("type")
+	j.SDP, _ = sub.String("sdp")
+	return j
+}
@@ -0,0 +1,119 @@
+package jplugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/logging/redact"
+)
+
+//Event plugin event of handle
+type Event struct {
+	Name    string         //see Plugin.EventName
+	Data    jwsapi.Message //plugindata.data
+	JSEP    *JSEP          //nil if not
+	Message *jwsapi.Message
+}
+
+//Decode decode Data to v, v is pointer of typed struct
+func (e *Event) Decode(v interface{}) error {
+	return Decode(e.Data, v)
+}
+
+//Router route plugin events of handle to handlers by name of event
+//
+//	r := jplugin.NewRouter(descriptor)
+//	jplugin.On(r, "joined", func(e joinedEvent, raw *jplugin.Event) {...})
+//	r.Fallback(func(e *jplugin.Event) {...})
+//	go r.Run(ctx, h)
+type Router struct {
+	plugin *Plugin
+
+	mu       sync.Mutex
+	handlers map[string]func(*Event)
+	fallback func(*Event)
+}
+
+//NewRouter create router of plugin p
+func NewRouter(p *Plugin) *Router {
+	return &Router{
+		plugin:   p,
+		handlers: make(map[string]func(*Event)),
+	}
+}
+
+//Handle set handler of event name, replace old handler
+func (r *Router) Handle(name string, handler func(*Event)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = handler
+}
+
+//Fallback set handler of events without handler, and error events
+func (r *Router) Fallback(handler func(*Event)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = handler
+}
+
+//On set typed handler of event name, Data is decoded to T, event is dropped if decode failed
+func On[T any](r *Router, name string, handler func(T, *Event)) {
+	r.Handle(name, func(e *Event) {
+		var v T
+		if err := e.Decode(&v); err != nil {
+			log.Warn("decode event", logging.F("plugin", r.plugin.Name), logging.F("event", name), logging.F("err", err))
+			return
+		}
+		handler(v, e)
+	})
+}
+
+//Dispatch route event message to handler
+func (r *Router) Dispatch(msg *jwsapi.Message) {
+	data := pluginData(msg)
+	if data == nil {
+		return
+	}
+	e := &Event{
+		Name:    r.plugin.EventName(data),
+		Data:    data,
+		JSEP:    jsep(msg),
+		Message: msg,
+	}
+	r.mu.Lock()
+	handler, ok := r.handlers[e.Name]
+	if !ok || data.PluginDataError() != nil {
+		handler = r.fallback
+	}
+	r.mu.Unlock()
+	if handler != nil {
+		handler(e)
+	}
+}
+
+//Run dispatch events of h until ctx done or handle is detached
+func (r *Router) Run(ctx context.Context, h *jwsapi.Handle) {
+	defer func() {
+		log.Info("Router End", logging.F("plugin", r.plugin.Name), logging.F("handle", h.ID))
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-h.Events:
+			if !ok {
+				return
+			}
+			if log.Enabled(logging.LevelDebug) {
+				log.Debug("recv event", logging.F("handle", h.ID), logging.F("event", redact.Default().Map(*event)))
+			}
+			if event.Type() != "event" {
+				log.Warn("unknown msg", logging.F("handle", h.ID), logging.F("janus", event.Type()))
+				continue
+			}
+			r.Dispatch(event)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package jprometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//Metrics prometheus implementation of jwsapi.Metrics
+//
+//	m := jprometheus.NewMetrics("myapp")
+//	prometheus.MustRegister(m)
+//	conn := jwsapi.NewConnection(ctx, url, 1, jwsapi.WithConnectionMetrics(m))
+type Metrics struct {
+	requests   *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	timeouts   *prometheus.CounterVec
+	reconnects *prometheus.CounterVec
+	dropped    *prometheus.CounterVec
+	sessions   *prometheus.GaugeVec
+	handles    *prometheus.GaugeVec
+}
+
+var _ jwsapi.Metrics = (*Metrics)(nil)
+var _ prometheus.Collector = (*Metrics)(nil)
+
+//NewMetrics create metrics, namespace is optional
+func NewMetrics(namespace string) *Metrics {
+	const subsystem = "janus_client"
+	requestLabels := []string{"janus", "request", "plugin"}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Janus requests and messages, by result(ok,error,timeout).",
+		}, append(requestLabels, "result")),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Janus request and message latency.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, requestLabels),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_timeouts_total",
+			Help:      "Janus requests and messages timeout.",
+		}, requestLabels),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "reconnects_total",
+			Help:      "Websocket re connections.",
+		}, []string{"url"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dropped_messages_total",
+			Help:      "Received messages dropped for receive queue is full.",
+		}, []string{"url"}),
+		sessions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "sessions",
+			Help:      "Open janus sessions.",
+		}, []string{"url"}),
+		handles: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "handles",
+			Help:      "Attached janus plugin handles.",
+		}, []string{"url", "plugin"}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requests, m.durations, m.timeouts, m.reconnects, m.dropped, m.sessions, m.handles}
+}
+
+//Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+//Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+//RequestDone implements jwsapi.Metrics
+//jwsapi.ErrTimeout and context.DeadlineExceeded, wrapped or not, are timeout
+func (m *Metrics) RequestDone(info jwsapi.RequestInfo, duration time.Duration, err error) {
+	result := "ok"
+	if errors.Is(err, jwsapi.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		result = "timeout"
+		m.timeouts.WithLabelValues(info.Janus, info.Request, info.Plugin).Inc()
+	} else if err != nil {
+		result = "error"
+	}
+	m.requests.WithLabelValues(info.Janus, info.Request, info.Plugin, result).Inc()
+	m.durations.WithLabelValues(info.Janus, info.Request, info.Plugin).Observe(duration.Seconds())
+}
+
+//Reconnected implements jwsapi.Metrics
+func (m *Metrics) Reconnected(url string) {
+	m.reconnects.WithLabelValues(url).Inc()
+}
+
+//MessageDropped implements jwsapi.Metrics
+func (m *Metrics) MessageDropped(url string) {
+	m.dropped.WithLabelValues(url).Inc()
+}
+
+//SessionCreated implements jwsapi.Metrics
+func (m *Metrics) SessionCreated(url string) {
+	m.sessions.WithLabelValues(url).Inc()
+}
+
+//SessionDestroyed implements jwsapi.Metrics
+func (m *Metrics) SessionDestroyed(url string) {
+	m.sessions.WithLabelValues(url).Dec()
+}
+
+//HandleAttached implements jwsapi.Metrics
+func (m *Metrics) HandleAttached(url string, plugin string) {
+	m.handles.WithLabelValues(url, plugin).Inc()
+}
+
+//HandleDetached implements jwsapi.Metrics
+func (m *Metrics) HandleDetached(url string, plugin string) {
+	m.handles.WithLabelValues(url, plugin).Dec()
+}
@@ -0,0 +1,160 @@
+package jprometheus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestConnection(t *testing.T, ctx context.Context, s *janustest.Server, m *Metrics) *jwsapi.Connection {
+	t.Helper()
+	conn := jwsapi.NewConnection(ctx, s.URL, 1, jwsapi.WithConnectionMetrics(m))
+	for i := 0; i < 100 && !conn.Connected(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !conn.Connected() {
+		t.Fatal("not connected")
+	}
+	return conn
+}
+
+//sample count of request_duration_seconds of janus, all requests and plugins
+func durationCount(t *testing.T, m *Metrics, janus string) uint64 {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(m)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	var count uint64
+	for _, family := range families {
+		if family.GetName() != "test_janus_client_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "janus" && label.GetValue() == janus {
+					count += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return count
+}
+
+//gauge is updated by loop of connection, wait for value
+func waitGauge(g prometheus.Gauge, value float64) float64 {
+	got := testutil.ToFloat64(g)
+	for i := 0; i < 100 && got != value; i++ {
+		time.Sleep(10 * time.Millisecond)
+		got = testutil.ToFloat64(g)
+	}
+	return got
+}
+
+func TestMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.EchoTestPlugin, janustest.NewEchoTest())
+
+	m := NewMetrics("test")
+	conn := newTestConnection(t, ctx, s, m)
+	url := s.URL
+
+	if _, err := conn.Info(); err != nil {
+		t.Fatalf("info: %v", err)
+	}
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := sess.Attach(janustest.EchoTestPlugin); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if _, err := sess.Attach("janus.plugin.none"); err == nil {
+		t.Fatal("attach of unknown plugin: no error")
+	}
+
+	counters := []struct {
+		name   string
+		got    float64
+		expect float64
+	}{
+		{"info ok", testutil.ToFloat64(m.requests.WithLabelValues("info", "", "", "ok")), 1},
+		{"create ok", testutil.ToFloat64(m.requests.WithLabelValues("create", "", "", "ok")), 1},
+		{"attach ok", testutil.ToFloat64(m.requests.WithLabelValues("attach", "", janustest.EchoTestPlugin, "ok")), 1},
+		{"attach error", testutil.ToFloat64(m.requests.WithLabelValues("attach", "", "janus.plugin.none", "error")), 1},
+		{"sessions", waitGauge(m.sessions.WithLabelValues(url), 1), 1},
+		{"handles", waitGauge(m.handles.WithLabelValues(url, janustest.EchoTestPlugin), 1), 1},
+	}
+	for _, c := range counters {
+		if c.got != c.expect {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.expect)
+		}
+	}
+	if n := durationCount(t, m, "attach"); n != 2 {
+		t.Errorf("duration samples of attach = %d, want 2", n)
+	}
+	if n := durationCount(t, m, "info"); n != 1 {
+		t.Errorf("duration samples of info = %d, want 1", n)
+	}
+
+	if err := sess.Destroy(); err != nil {
+		t.Fatalf("destroy: %v", err)
+	}
+	if got := waitGauge(m.sessions.WithLabelValues(url), 0); got != 0 {
+		t.Errorf("sessions after destroy = %v, want 0", got)
+	}
+	if got := waitGauge(m.handles.WithLabelValues(url, janustest.EchoTestPlugin), 0); got != 0 {
+		t.Errorf("handles after destroy = %v, want 0", got)
+	}
+}
+
+func TestMetricsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer(janustest.WithServerDelay(500 * time.Millisecond))
+	defer s.Close()
+
+	m := NewMetrics("test")
+	conn := newTestConnection(t, ctx, s, m)
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer reqCancel()
+	if _, err := conn.InfoContext(reqCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("info: err = %v, want deadline exceeded", err)
+	}
+	//wrapped timeout of plugin clients
+	m.RequestDone(jwsapi.RequestInfo{URL: s.URL, Janus: "message", Request: "join", Plugin: janustest.VideoRoomPlugin}, time.Second, errors.Wrap(jwsapi.ErrTimeout, "join"))
+
+	counters := []struct {
+		name   string
+		got    float64
+		expect float64
+	}{
+		{"info timeout", testutil.ToFloat64(m.requests.WithLabelValues("info", "", "", "timeout")), 1},
+		{"info error", testutil.ToFloat64(m.requests.WithLabelValues("info", "", "", "error")), 0},
+		{"info timeouts", testutil.ToFloat64(m.timeouts.WithLabelValues("info", "", "")), 1},
+		{"join timeout", testutil.ToFloat64(m.requests.WithLabelValues("message", "join", janustest.VideoRoomPlugin, "timeout")), 1},
+		{"join timeouts", testutil.ToFloat64(m.timeouts.WithLabelValues("message", "join", janustest.VideoRoomPlugin)), 1},
+	}
+	for _, c := range counters {
+		if c.got != c.expect {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.expect)
+		}
+	}
+	if n := durationCount(t, m, "info"); n != 1 {
+		t.Errorf("duration samples of info = %d, want 1", n)
+	}
+}
@@ -0,0 +1,174 @@
+//Package jrecord record ws frames of jwsapi.Connection to jsonl file, and load it for janustest replay
+//
+//	rec, err := jrecord.Create("janus.jsonl")
+//	conn := jwsapi.NewConnection(ctx, url, 1, jwsapi.WithConnectionTap(rec))
+//	...
+//	rec.Close()
+package jrecord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/logging/redact"
+	"github.com/pkg/errors"
+)
+
+//Record a ws frame, one line of jsonl file
+type Record struct {
+	Time      time.Time        `json:"ts"`
+	Conn      string           `json:"conn"`
+	Direction jwsapi.Direction `json:"dir"`
+	//Frame json frame, nil if frame is not valid json
+	Frame json.RawMessage `json:"frame,omitempty"`
+	//Raw frame is not valid json, eg: malformed message
+	Raw string `json:"raw,omitempty"`
+}
+
+//Data return frame data
+func (r *Record) Data() []byte {
+	if r.Frame != nil {
+		return r.Frame
+	}
+	return []byte(r.Raw)
+}
+
+//Message decode frame as jwsapi.Message, number is json.Number like jwsapi
+func (r *Record) Message() (jwsapi.Message, error) {
+	if r.Frame == nil {
+		return nil, errors.New("not json frame")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(r.Frame))
+	decoder.UseNumber()
+	msg := make(jwsapi.Message)
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//Recorder implements jwsapi.Tap, write frames to jsonl
+type Recorder struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	redactor *redact.Redactor
+	now      func() time.Time
+	err      error
+}
+
+var _ jwsapi.Tap = (*Recorder)(nil)
+
+//RecorderOption option for Recorder
+type RecorderOption func(*Recorder)
+
+//WithRecorderRedactor set redactor for frames, default is redact.Default(), nil to record secrets
+func WithRecorderRedactor(redactor *redact.Redactor) RecorderOption {
+	return func(r *Recorder) {
+		r.redactor = redactor
+	}
+}
+
+//NewRecorder create recorder write to w
+func NewRecorder(w io.Writer, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		w:        w,
+		redactor: redact.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+//Create create jsonl file and recorder, Close to close file
+func Create(path string, opts ...RecorderOption) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "create record file")
+	}
+	r := NewRecorder(f, opts...)
+	r.closer = f
+	return r, nil
+}
+
+//OnFrame implements jwsapi.Tap
+func (r *Recorder) OnFrame(conn string, direction jwsapi.Direction, data []byte) {
+	record := Record{
+		Time:      r.now(),
+		Conn:      conn,
+		Direction: direction,
+	}
+	if json.Valid(data) {
+		record.Frame = json.RawMessage(r.redactor.JSON(data))
+	} else {
+		record.Raw = r.redactor.String(data)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		_, r.err = r.w.Write(line)
+	}
+}
+
+//Err return first write error
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+//Close close file created by Create
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return r.err
+}
+
+//Read read all records from jsonl
+func Read(reader io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read records")
+	}
+	return records, nil
+}
+
+//Load read all records from jsonl file
+func Load(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open record file")
+	}
+	defer f.Close()
+	return Read(f)
+}
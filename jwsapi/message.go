@@ -27,18 +27,13 @@ type Message map[string]interface{}
 
 //Type return janus message type
 func (m *Message) Type() string {
-
-	return (*m)[attrType].(string)
+	t, _ := m.String(attrType)
+	return t
 }
 
-//Transaction get transaction from message
+//Transaction get transaction from message, false if not or not string
 func (m *Message) Transaction() (string, bool) {
-
-	t, ok := (*m)[attrTransaction]
-	if ok {
-		return t.(string), true
-	}
-	return "", false
+	return m.String(attrTransaction)
 }
 
 //SubMessage get sub Message
@@ -159,9 +154,8 @@ func (m *Message) IsError() bool {
 //Error IsError() is true ,call this
 func (m *Message) Error() error {
 	if m.IsError() {
-		errInfo := (*m)["error"].(map[string]interface{})
-		reason := errInfo["reason"].(string)
-		return errors.New(reason)
+		errInfo, _ := m.SubMessage("error")
+		return errors.WithStack(errInfo.errorInfo())
 	} else if pluginData, ok := m.SubMessage(attrPluginData); ok {
 		return pluginData.PluginDataError()
 	}
@@ -173,13 +167,13 @@ func (m *Message) PluginDataError() error {
 	if err, ok := (*m)["error"]; ok {
 		switch err.(type) {
 		case string:
-			return errors.New(err.(string))
+			code, _ := m.Uint64("error_code")
+			return errors.WithStack(&ErrorInfo{Code: int(code), Reason: err.(string)})
 		case map[string]interface{}:
-			errInfo := (*m)["error"].(map[string]interface{})
-			reason := errInfo["reason"].(string)
-			return errors.New(reason)
+			errInfo, _ := m.SubMessage("error")
+			return errors.WithStack(errInfo.errorInfo())
 		default:
-			return errors.New("unkwon error")
+			return errors.New("unknown error")
 		}
 
 	} else if data, ok := m.SubMessage("data"); ok {
@@ -188,24 +182,38 @@ func (m *Message) PluginDataError() error {
 	return nil
 }
 
-//Data return data
+//errorInfo code and reason of error object
+func (m *Message) errorInfo() *ErrorInfo {
+	code, _ := m.Uint64("code")
+	reason, _ := m.String("reason")
+	if reason == "" {
+		reason = "unknown error"
+	}
+	return &ErrorInfo{Code: int(code), Reason: reason}
+}
+
+//Data return data, nil if not
 func (m *Message) Data() Message {
-	return Message((*m)["data"].(map[string]interface{}))
+	data, _ := m.SubMessage("data")
+	return data
 }
 
 //Plugin get plugin...
 func (m *Message) Plugin() string {
-	return (*m)[attrPlugin].(string)
+	plugin, _ := m.String(attrPlugin)
+	return plugin
 }
 
-//PluginData get plugindata
+//PluginData get plugindata, nil if not
 func (m *Message) PluginData() Message {
-	return Message((*m)[attrPluginData].(map[string]interface{}))
+	pluginData, _ := m.SubMessage(attrPluginData)
+	return pluginData
 }
 
 //VideoRoom get videoroom event type
 func (m *Message) VideoRoom() string {
-	return (*m)[attrVideoRoom].(string)
+	event, _ := m.String(attrVideoRoom)
+	return event
 }
 
 //Set set key value
@@ -0,0 +1,57 @@
+package jwsapi
+
+import "time"
+
+//RequestInfo labels of a request or message
+type RequestInfo struct {
+	URL     string //gateway url
+	Janus   string //janus type: create,attach,message,trickle...
+	Request string //plugin request: join,configure... empty if not plugin message
+	Plugin  string //plugin name, empty if not handle request
+}
+
+//Metrics metrics hook for Connection, set by WithConnectionMetrics
+//all method must be safe for concurrent use
+type Metrics interface {
+	//RequestDone a Request or Message is done, err is ErrTimeout when timeout
+	RequestDone(info RequestInfo, duration time.Duration, err error)
+	//Reconnected ws connection re connected
+	Reconnected(url string)
+	//MessageDropped recv message dropped for recv queue or queue of handle is full, or session of message is done
+	MessageDropped(url string)
+	//SessionCreated new session
+	SessionCreated(url string)
+	//SessionDestroyed session destroy or released
+	SessionDestroyed(url string)
+	//HandleAttached new plugin handle
+	HandleAttached(url string, plugin string)
+	//HandleDetached handle detach or released
+	HandleDetached(url string, plugin string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RequestDone(RequestInfo, time.Duration, error) {}
+func (nopMetrics) Reconnected(string)                            {}
+func (nopMetrics) MessageDropped(string)                         {}
+func (nopMetrics) SessionCreated(string)                         {}
+func (nopMetrics) SessionDestroyed(string)                       {}
+func (nopMetrics) HandleAttached(string, string)                 {}
+func (nopMetrics) HandleDetached(string, string)                 {}
+
+func newRequestInfo(url string, msg Message, plugin string) RequestInfo {
+	info := RequestInfo{
+		URL:    url,
+		Plugin: plugin,
+	}
+	info.Janus, _ = msg.String(attrType)
+	var body Message
+	switch value := msg[attrBody].(type) {
+	case Message:
+		body = value
+	case map[string]interface{}:
+		body = Message(value)
+	}
+	info.Request, _ = body.String(AttrRequest)
+	return info
+}
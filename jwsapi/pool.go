@@ -0,0 +1,407 @@
+package jwsapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/logging"
+	"github.com/pkg/errors"
+)
+
+//ErrNoGateway no healthy gateway in pool
+var ErrNoGateway = errors.New("no healthy gateway")
+
+//Policy policy to pick gateway for new session
+type Policy int
+
+const (
+	//PolicyRoundRobin pick healthy gateway in turn
+	PolicyRoundRobin Policy = iota
+	//PolicyLeastSessions pick healthy gateway has least sessions created by pool
+	PolicyLeastSessions
+	//PolicyWeight smooth weighted round-robin, weight from info response, see WithPoolWeight
+	PolicyWeight
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyRoundRobin:
+		return "round-robin"
+	case PolicyLeastSessions:
+		return "least-sessions"
+	case PolicyWeight:
+		return "weight"
+	default:
+		return "n/a"
+	}
+}
+
+//Gateway a janus gateway of pool
+type Gateway struct {
+	URL  string
+	Conn *Connection
+
+	mu       sync.Mutex
+	healthy  bool
+	failures int
+	weight   int
+	info     Message
+	sessions map[uint64]*Session
+	current  int //current weight of smooth weighted round-robin, lock by Pool.mu
+}
+
+//Healthy gateway is alive, last health check is ok
+func (g *Gateway) Healthy() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.healthy
+}
+
+//Weight weight of last info response
+func (g *Gateway) Weight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.weight
+}
+
+//Info last info(server_info) response, nil if never success
+func (g *Gateway) Info() Message {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.info
+}
+
+//Sessions alive sessions created by pool at this gateway
+func (g *Gateway) Sessions() []*Session {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sessions := make([]*Session, 0, len(g.sessions))
+	for _, s := range g.sessions {
+		if !s.IsDestroy() {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions
+}
+
+//sessionCount lock by caller
+func (g *Gateway) sessionCount() int {
+	n := 0
+	for _, s := range g.sessions {
+		if !s.IsDestroy() {
+			n++
+		}
+	}
+	return n
+}
+
+//Pool connections to multiple janus gateways
+//new session is created at gateway picked by policy, gateway is health checked by info request
+//when gateway dead, sessions created at it are released and reported by WithPoolSessionLost
+type Pool struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	gateways []*Gateway
+	policy   Policy
+	interval time.Duration
+	failures int
+	weightFn func(info Message) int
+	onLost   func(g *Gateway, sessions []*Session)
+	onState  func(g *Gateway, healthy bool)
+	connOpts []ConnectionOption
+	log      *logging.Logger
+
+	mu   sync.Mutex
+	next int
+}
+
+//PoolOption option for Pool
+type PoolOption func(*Pool)
+
+//WithPoolPolicy set policy to pick gateway, default is PolicyRoundRobin
+func WithPoolPolicy(policy Policy) PoolOption {
+	return func(p *Pool) {
+		p.policy = policy
+	}
+}
+
+//WithPoolHealthCheck health check every interval, gateway is dead after failures times in a row
+//default is 5s, 3 times
+func WithPoolHealthCheck(interval time.Duration, failures int) PoolOption {
+	return func(p *Pool) {
+		p.interval = interval
+		if failures > 0 {
+			p.failures = failures
+		}
+	}
+}
+
+//WithPoolWeight get weight from info(server_info) response for PolicyWeight
+//default is "weight" field of info, 1 if not exist, gateway of weight 0 is not picked
+func WithPoolWeight(weight func(info Message) int) PoolOption {
+	return func(p *Pool) {
+		p.weightFn = weight
+	}
+}
+
+//WithPoolSessionLost callback when gateway dead, sessions are released, app should create them at other gateway
+func WithPoolSessionLost(callback func(g *Gateway, sessions []*Session)) PoolOption {
+	return func(p *Pool) {
+		p.onLost = callback
+	}
+}
+
+//WithPoolGatewayState callback when gateway healthy state changed
+func WithPoolGatewayState(callback func(g *Gateway, healthy bool)) PoolOption {
+	return func(p *Pool) {
+		p.onState = callback
+	}
+}
+
+//WithPoolConnectionOptions options for every Connection of pool
+func WithPoolConnectionOptions(opts ...ConnectionOption) PoolOption {
+	return func(p *Pool) {
+		p.connOpts = append(p.connOpts, opts...)
+	}
+}
+
+func defaultWeight(info Message) int {
+	if weight, ok := info.Uint64("weight"); ok {
+		return int(weight)
+	}
+	return 1
+}
+
+//NewPool create connections to urls, gateway is healthy before first health check
+func NewPool(ctx context.Context, urls []string, opts ...PoolOption) *Pool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		ctx:      ctx,
+		cancel:   cancel,
+		policy:   PolicyRoundRobin,
+		interval: 5 * time.Second,
+		failures: 3,
+		weightFn: defaultWeight,
+		log:      logging.Named("jwsapi.pool"),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for i, url := range urls {
+		g := &Gateway{
+			URL:      url,
+			Conn:     NewConnection(ctx, url, i+1, p.connOpts...),
+			healthy:  true,
+			weight:   1,
+			sessions: make(map[uint64]*Session),
+		}
+		p.gateways = append(p.gateways, g)
+		go p.healthLoop(g)
+	}
+	return p
+}
+
+//Close close all connections
+func (p *Pool) Close() {
+	p.cancel()
+}
+
+//Gateways all gateways
+func (p *Pool) Gateways() []*Gateway {
+	return append([]*Gateway(nil), p.gateways...)
+}
+
+//Gateway get gateway by url
+func (p *Pool) Gateway(url string) (*Gateway, bool) {
+	for _, g := range p.gateways {
+		if g.URL == url {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+//Pick pick a healthy gateway by policy
+func (p *Pool) Pick() (*Gateway, error) {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return nil, ErrNoGateway
+	}
+	return candidates[0], nil
+}
+
+//candidates healthy gateways, first is picked by policy, others for failover
+func (p *Pool) candidates() []*Gateway {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy []*Gateway
+	for _, g := range p.gateways {
+		if g.Healthy() {
+			healthy = append(healthy, g)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	switch p.policy {
+	case PolicyLeastSessions:
+		counts := make(map[*Gateway]int, len(healthy))
+		for _, g := range healthy {
+			g.mu.Lock()
+			counts[g] = g.sessionCount()
+			g.mu.Unlock()
+		}
+		for i := 1; i < len(healthy); i++ {
+			for j := i; j > 0 && counts[healthy[j]] < counts[healthy[j-1]]; j-- {
+				healthy[j], healthy[j-1] = healthy[j-1], healthy[j]
+			}
+		}
+		return healthy
+	case PolicyWeight:
+		var best *Gateway
+		total := 0
+		var weighted []*Gateway
+		for _, g := range healthy {
+			weight := g.Weight()
+			if weight <= 0 {
+				continue
+			}
+			weighted = append(weighted, g)
+			g.current += weight
+			total += weight
+			if best == nil || g.current > best.current {
+				best = g
+			}
+		}
+		if best == nil {
+			return nil
+		}
+		best.current -= total
+		candidates := []*Gateway{best}
+		for _, g := range weighted {
+			if g != best {
+				candidates = append(candidates, g)
+			}
+		}
+		return candidates
+	default:
+		start := p.next % len(healthy)
+		p.next++
+		candidates := make([]*Gateway, 0, len(healthy))
+		candidates = append(candidates, healthy[start:]...)
+		return append(candidates, healthy[:start]...)
+	}
+}
+
+//Create create session at gateway picked by policy, try next healthy gateway if failed
+func (p *Pool) Create() (*Session, error) {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return nil, ErrNoGateway
+	}
+	var err error
+	for _, g := range candidates {
+		var s *Session
+		s, err = p.CreateAt(g)
+		if err == nil {
+			return s, nil
+		}
+		p.log.Warn("create session failed", logging.F("url", g.URL), logging.F("err", err))
+	}
+	return nil, err
+}
+
+//CreateAt create session at gateway, session is tracked by pool
+func (p *Pool) CreateAt(g *Gateway) (*Session, error) {
+	s, err := g.Conn.Create()
+	if err != nil {
+		return nil, errors.Wrapf(err, "create session at %s", g.URL)
+	}
+	g.mu.Lock()
+	g.sessions[s.ID] = s
+	g.mu.Unlock()
+	return s, nil
+}
+
+//GatewayOf get gateway of session created by pool
+func (p *Pool) GatewayOf(s *Session) (*Gateway, bool) {
+	for _, g := range p.gateways {
+		if g.Conn == s.conn {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Pool) healthLoop(g *Gateway) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.check(g)
+		}
+	}
+}
+
+func (p *Pool) check(g *Gateway) {
+	var info *Message
+	err := errors.New("not connected")
+	if g.Conn.IsDestroy() {
+		err = errors.New("conn is destroy")
+	} else if g.Conn.Connected() {
+		info, err = g.Conn.InfoContext(p.ctx)
+	}
+
+	g.mu.Lock()
+	if err == nil {
+		wasHealthy := g.healthy
+		g.healthy = true
+		g.failures = 0
+		g.info = *info
+		g.weight = p.weightFn(*info)
+		for sid, s := range g.sessions {
+			if s.IsDestroy() {
+				delete(g.sessions, sid)
+			}
+		}
+		g.mu.Unlock()
+		if !wasHealthy {
+			p.log.Info("gateway up", logging.F("url", g.URL))
+			if p.onState != nil {
+				p.onState(g, true)
+			}
+		}
+		return
+	}
+
+	g.failures++
+	if !g.healthy || g.failures < p.failures {
+		g.mu.Unlock()
+		return
+	}
+	g.healthy = false
+	lost := make([]*Session, 0, len(g.sessions))
+	for _, s := range g.sessions {
+		lost = append(lost, s)
+	}
+	g.sessions = make(map[uint64]*Session)
+	g.mu.Unlock()
+
+	p.log.Warn("gateway down", logging.F("url", g.URL), logging.F("err", err), logging.F("lost", len(lost)))
+	for _, s := range lost {
+		//release session, not claim when gateway is back
+		g.Conn.delSession(s.ID)
+	}
+	if p.onState != nil {
+		p.onState(g, false)
+	}
+	if p.onLost != nil && len(lost) > 0 {
+		p.onLost(g, lost)
+	}
+}
@@ -0,0 +1,280 @@
+package jwsapi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+)
+
+//newTestPool pool of servers, wait until all gateways connected
+func newTestPool(t *testing.T, servers []*janustest.Server, opts ...jwsapi.PoolOption) *jwsapi.Pool {
+	t.Helper()
+	var urls []string
+	for _, s := range servers {
+		urls = append(urls, s.URL)
+	}
+	pool := jwsapi.NewPool(context.Background(), urls, opts...)
+	t.Cleanup(pool.Close)
+	for _, g := range pool.Gateways() {
+		waitFor(t, "gateway connected", g.Conn.Connected)
+	}
+	return pool
+}
+
+func newTestServers(t *testing.T, n int) []*janustest.Server {
+	t.Helper()
+	var servers []*janustest.Server
+	for i := 0; i < n; i++ {
+		s := janustest.NewServer()
+		t.Cleanup(s.Close)
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+//waitFor poll cond until true, fatal after 10s
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//create n sessions by pool, return sessions count of every gateway url
+func createSessions(t *testing.T, pool *jwsapi.Pool, n int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		s, err := pool.Create()
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, ok := pool.GatewayOf(s)
+		if !ok {
+			t.Fatalf("no gateway of session %d", s.ID)
+		}
+		counts[g.URL]++
+	}
+	return counts
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	servers := newTestServers(t, 3)
+	pool := newTestPool(t, servers)
+
+	var last *jwsapi.Gateway
+	for i := 0; i < 6; i++ {
+		g, err := pool.Pick()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g == last {
+			t.Fatalf("pick %d: %s is picked twice in turn", i, g.URL)
+		}
+		last = g
+	}
+
+	counts := createSessions(t, pool, 6)
+	for _, s := range servers {
+		if counts[s.URL] != 2 {
+			t.Errorf("sessions at %s = %d, want 2", s.URL, counts[s.URL])
+		}
+		if n := len(s.Sessions()); n != 2 {
+			t.Errorf("sessions of server %s = %d, want 2", s.URL, n)
+		}
+	}
+}
+
+func TestPoolLeastSessions(t *testing.T) {
+	servers := newTestServers(t, 3)
+	pool := newTestPool(t, servers, jwsapi.WithPoolPolicy(jwsapi.PolicyLeastSessions))
+
+	first := pool.Gateways()[0]
+	var sessions []*jwsapi.Session
+	for i := 0; i < 2; i++ {
+		s, err := pool.CreateAt(first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, s)
+	}
+
+	//gateways without session are picked before first
+	counts := createSessions(t, pool, 4)
+	if counts[first.URL] != 0 {
+		t.Errorf("sessions created at %s = %d, want 0", first.URL, counts[first.URL])
+	}
+	for _, g := range pool.Gateways() {
+		if n := len(g.Sessions()); n != 2 {
+			t.Errorf("sessions of %s = %d, want 2", g.URL, n)
+		}
+	}
+
+	//destroyed session is not counted
+	for _, s := range sessions {
+		s.Destroy()
+	}
+	waitFor(t, "sessions destroyed", func() bool { return len(first.Sessions()) == 0 })
+	counts = createSessions(t, pool, 2)
+	if counts[first.URL] != 2 {
+		t.Errorf("sessions created at %s = %d, want 2", first.URL, counts[first.URL])
+	}
+}
+
+func TestPoolWeight(t *testing.T) {
+	weights := []int{1, 2, 3, 0}
+	var servers []*janustest.Server
+	for _, w := range weights {
+		s := janustest.NewServer(janustest.WithServerInfo(jwsapi.Message{"capacity": w}))
+		t.Cleanup(s.Close)
+		servers = append(servers, s)
+	}
+	pool := newTestPool(t, servers,
+		jwsapi.WithPoolPolicy(jwsapi.PolicyWeight),
+		jwsapi.WithPoolHealthCheck(20*time.Millisecond, 1),
+		jwsapi.WithPoolWeight(func(info jwsapi.Message) int {
+			capacity, _ := info.Uint64("capacity")
+			return int(capacity)
+		}))
+	for i, g := range pool.Gateways() {
+		g := g
+		w := weights[i]
+		waitFor(t, "weight of info", func() bool { return g.Weight() == w && g.Info() != nil })
+	}
+
+	//every 6 picks has 1, 2, 3 of gateways, gateway of weight 0 is never picked
+	for round := 0; round < 5; round++ {
+		counts := make(map[string]int)
+		var last string
+		for i := 0; i < 6; i++ {
+			g, err := pool.Pick()
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[g.URL]++
+			if g.URL == last && g.URL != servers[2].URL {
+				t.Errorf("round %d: %s is picked twice in turn", round, g.URL)
+			}
+			last = g.URL
+		}
+		for i, s := range servers {
+			if counts[s.URL] != weights[i] {
+				t.Errorf("round %d: picks of weight %d = %d", round, weights[i], counts[s.URL])
+			}
+		}
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	servers := newTestServers(t, 2)
+	type state struct {
+		url     string
+		healthy bool
+	}
+	states := make(chan state, 8)
+	lost := make(chan []*jwsapi.Session, 8)
+	pool := newTestPool(t, servers,
+		jwsapi.WithPoolHealthCheck(20*time.Millisecond, 2),
+		jwsapi.WithPoolGatewayState(func(g *jwsapi.Gateway, healthy bool) {
+			states <- state{g.URL, healthy}
+		}),
+		jwsapi.WithPoolSessionLost(func(g *jwsapi.Gateway, sessions []*jwsapi.Session) {
+			if g.URL == servers[0].URL {
+				lost <- sessions
+			}
+		}))
+	down, up := pool.Gateways()[0], pool.Gateways()[1]
+
+	var atDown []*jwsapi.Session
+	for i := 0; i < 2; i++ {
+		s, err := pool.CreateAt(down)
+		if err != nil {
+			t.Fatal(err)
+		}
+		atDown = append(atDown, s)
+	}
+	if _, err := pool.CreateAt(up); err != nil {
+		t.Fatal(err)
+	}
+
+	//server refuses every request until faults cleared,
+	//info in flight when disconnected fails by timeout, so down and up may take seconds
+	servers[0].DisconnectOn(janustest.MatchAny(), 0)
+	servers[0].Disconnect()
+
+	select {
+	case st := <-states:
+		if st.url != down.URL || st.healthy {
+			t.Fatalf("state = %+v, want %s down", st, down.URL)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("gateway is not down")
+	}
+	select {
+	case sessions := <-lost:
+		if len(sessions) != len(atDown) {
+			t.Fatalf("lost sessions = %d, want %d", len(sessions), len(atDown))
+		}
+		ids := map[uint64]bool{atDown[0].ID: true, atDown[1].ID: true}
+		for _, s := range sessions {
+			if !ids[s.ID] {
+				t.Errorf("lost session %d is not created at %s", s.ID, down.URL)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lost sessions are not reported")
+	}
+	if down.Healthy() || len(down.Sessions()) != 0 {
+		t.Errorf("down gateway: healthy %v, sessions %d", down.Healthy(), len(down.Sessions()))
+	}
+	for _, s := range atDown {
+		waitFor(t, "lost session released", s.IsDestroy)
+	}
+
+	//all sessions go to healthy gateway
+	counts := createSessions(t, pool, 3)
+	if counts[up.URL] != 3 {
+		t.Errorf("sessions created at %s = %d, want 3", up.URL, counts[up.URL])
+	}
+
+	servers[0].ClearFaults()
+	select {
+	case st := <-states:
+		if st.url != down.URL || !st.healthy {
+			t.Fatalf("state = %+v, want %s up", st, down.URL)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("gateway is not up")
+	}
+	if !down.Healthy() {
+		t.Error("gateway is not healthy after up")
+	}
+	counts = createSessions(t, pool, 2)
+	if counts[down.URL] != 1 || counts[up.URL] != 1 {
+		t.Errorf("sessions after up = %v, want 1 of each", counts)
+	}
+}
+
+func TestPoolNoGateway(t *testing.T) {
+	servers := newTestServers(t, 1)
+	pool := newTestPool(t, servers, jwsapi.WithPoolHealthCheck(20*time.Millisecond, 1))
+
+	servers[0].DisconnectOn(janustest.MatchAny(), 0)
+	servers[0].Disconnect()
+	g := pool.Gateways()[0]
+	waitFor(t, "gateway down", func() bool { return !g.Healthy() })
+
+	if _, err := pool.Pick(); err != jwsapi.ErrNoGateway {
+		t.Errorf("Pick() error = %v, want ErrNoGateway", err)
+	}
+	if _, err := pool.Create(); err != jwsapi.ErrNoGateway {
+		t.Errorf("Create() error = %v, want ErrNoGateway", err)
+	}
+}
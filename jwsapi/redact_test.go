@@ -0,0 +1,86 @@
+package jwsapi_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/logging/redact"
+)
+
+type captureBackend struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (b *captureBackend) Log(level logging.Level, subsystem string, msg string, fields []logging.Field) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, f := range fields {
+		if s, ok := f.Value.(string); ok {
+			b.lines = append(b.lines, s)
+		}
+	}
+}
+
+func (b *captureBackend) contains(s string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, line := range b.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnectionRedactor(t *testing.T) {
+	capture := &captureBackend{}
+	prevLevel, hasLevel := logging.LookupLevel("jwsapi.wire")
+	logging.SetBackend(capture)
+	logging.SetLevel("jwsapi.wire", logging.LevelTrace)
+	defer func() {
+		logging.SetBackend(logging.Discard)
+		if hasLevel {
+			logging.SetLevel("jwsapi.wire", prevLevel)
+		} else {
+			logging.UnsetLevel("jwsapi.wire")
+		}
+	}()
+
+	s := janustest.NewServer()
+	defer s.Close()
+
+	cases := []struct {
+		name   string
+		opts   []jwsapi.ConnectionOption
+		secret bool
+	}{
+		{"default", nil, false},
+		{"custom", []jwsapi.ConnectionOption{jwsapi.WithConnectionRedactor(redact.New(redact.WithKeys("secret")))}, false},
+		{"disabled", []jwsapi.ConnectionOption{jwsapi.WithConnectionRedactor(nil)}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			conn := jwsapi.NewConnection(ctx, s.URL, 1, c.opts...)
+			for i := 0; i < 100 && !conn.Connected(); i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			secret := "s3cr3t-" + c.name
+			s.SendRaw([]byte(`{"janus":"event","case":"` + c.name + `","secret":"` + secret + `"}`))
+			for i := 0; i < 100 && !capture.contains(`"case":"`+c.name+`"`); i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := capture.contains(secret); got != c.secret {
+				t.Errorf("secret in wire log: %v, want %v", got, c.secret)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package jwsapi
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -15,9 +16,10 @@ type Session struct {
 	ID            uint64
 	isDestroy     int32
 	conn          *Connection
-	handles       map[uint64]*Handle
+	mu            sync.RWMutex
+	handles       map[uint64]*Handle //guarded by mu, looked up by execLoop of connection
 	handlesCancel map[uint64]context.CancelFunc
-	tasks         chan func(*Session)
+	log           *logging.Logger
 }
 
 //NewSession new session
@@ -29,7 +31,7 @@ func NewSession(ctx context.Context, id uint64, conn *Connection) *Session {
 		conn:          conn,
 		handles:       make(map[uint64]*Handle),
 		handlesCancel: make(map[uint64]context.CancelFunc),
-		tasks:         make(chan func(*Session), 128),
+		log:           conn.log.With(logging.F("session", id)),
 	}
 
 	go s.execLoop()
@@ -45,58 +47,84 @@ func (s *Session) IsDestroy() bool {
 }
 
 func (s *Session) addHandle(h *Handle, cancel context.CancelFunc) {
-	s.run(func(ss *Session) {
-		s.handles[h.ID] = h
-		s.handlesCancel[h.ID] = cancel
-	})
-
-}
-func (s *Session) delHandle(hid uint64) {
-	s.run(func(ss *Session) {
-		if cancel, ok := s.handlesCancel[hid]; ok {
-			cancel()
-			delete(s.handles, hid)
-			delete(s.handlesCancel, hid)
-		}
-	})
+	s.mu.Lock()
+	s.handles[h.ID] = h
+	s.handlesCancel[h.ID] = cancel
+	s.mu.Unlock()
+	s.conn.metrics.HandleAttached(s.conn.url, h.plugin)
 }
 
-func (s *Session) run(f func(*Session)) {
-	if s.IsDestroy() {
-		return
+func (s *Session) delHandle(hid uint64) {
+	s.mu.Lock()
+	cancel, ok := s.handlesCancel[hid]
+	var plugin string
+	if ok {
+		cancel()
+		plugin = s.handles[hid].plugin
+		delete(s.handles, hid)
+		delete(s.handlesCancel, hid)
 	}
-
-	select {
-	case s.tasks <- f:
-	default:
+	s.mu.Unlock()
+	if ok {
+		s.conn.metrics.HandleDetached(s.conn.url, plugin)
 	}
 }
 
+func (s *Session) handle(hid uint64) (*Handle, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.handles[hid]
+	return h, ok
+}
+
+//onMessage called by execLoop of conn, queue msg to handle, never wait
 func (s *Session) onMessage(conn *Connection, msg *Message) {
 
-	if hid, ok := msg.HandleID(); ok {
-		if h, ok := s.handles[hid]; ok {
-			h.onMessage(msg)
-		} else {
-			logging.Warnf("Session[%d] can't Handle[%d]", s.ID, hid)
-		}
+	hid, ok := msg.HandleID()
+	if !ok {
+		s.log.Warn("can't find handle_id at event msg", logging.F("janus", msg.Type()))
+		return
+	}
+	if s.IsDestroy() {
+		s.log.Warn("message dropped, session is done", logging.F("handle", hid), logging.F("janus", msg.Type()))
+		conn.metrics.MessageDropped(conn.url)
+		return
+	}
+	if h, ok := s.handle(hid); ok {
+		h.onMessage(msg)
 	} else {
-		logging.Warnf("Session[%s] can't find handle_id at event msg", s.ID)
+		s.log.Warn("can't find handle", logging.F("handle", hid))
 	}
-
 }
 
 //Request send request, has success response
 func (s *Session) Request(msg Message) (*Message, error) {
+	return s.request(context.Background(), msg, "")
+}
+
+//RequestContext send request, has success response
+func (s *Session) RequestContext(ctx context.Context, msg Message) (*Message, error) {
+	return s.request(ctx, msg, "")
+}
 
+func (s *Session) request(ctx context.Context, msg Message, plugin string) (*Message, error) {
 	msg[attrSessionID] = s.ID
-	return s.conn.Request(msg)
+	return s.conn.request(ctx, msg, plugin)
 }
 
 //Message send message to janus, has ack ,event resposne
 func (s *Session) Message(msg Message) (*Message, error) {
+	return s.message(context.Background(), msg, "")
+}
+
+//MessageContext send message to janus, has ack ,event resposne
+func (s *Session) MessageContext(ctx context.Context, msg Message) (*Message, error) {
+	return s.message(ctx, msg, "")
+}
+
+func (s *Session) message(ctx context.Context, msg Message, plugin string) (*Message, error) {
 	msg[attrSessionID] = s.ID
-	return s.conn.Message(msg)
+	return s.conn.message(ctx, msg, plugin)
 }
 
 //Attach new handle from gateway
@@ -107,7 +135,7 @@ func (s *Session) Attach(pluginName string) (*Handle, error) {
 		"plugin":      pluginName,
 		attrSessionID: s.ID,
 	}
-	rsp, err := s.conn.Request(msg)
+	rsp, err := s.conn.request(context.Background(), msg, pluginName)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +143,8 @@ func (s *Session) Attach(pluginName string) (*Handle, error) {
 	if id, ok := data.Uint64("id"); ok {
 		ctx, cancel := context.WithCancel(s.ctx)
 		newH := NewHandle(ctx, id, s)
+		newH.plugin = pluginName
+		newH.cancel = cancel
 		s.addHandle(newH, cancel)
 		return newH, nil
 	}
@@ -145,12 +175,12 @@ func (s *Session) claim() {
 
 	_, err := s.conn.Request(msg)
 	if err != nil {
-		logging.Errorf("[%s] claim err:%v", s.ID, err)
+		s.log.Error("claim err", logging.F("err", err))
 		s.conn.delSession(s.ID)
 		return
 	}
 
-	logging.Infof("[%d] claim OK", s.ID)
+	s.log.Info("claim OK")
 }
 
 func (s *Session) keepalive() error {
@@ -167,11 +197,17 @@ func (s *Session) execLoop() {
 	ticker := time.NewTicker(10 * time.Second)
 	defer func() {
 
-		logging.Infof("[%d] Session End", s.ID)
+		s.log.Info("Session End")
 		atomic.StoreInt32(&s.isDestroy, 1)
+		s.mu.Lock()
+		for hid, h := range s.handles {
+			s.conn.metrics.HandleDetached(s.conn.url, h.plugin)
+			delete(s.handles, hid)
+			delete(s.handlesCancel, hid)
+		}
+		s.mu.Unlock()
 		s.conn.delSession(s.ID)
 		ticker.Stop()
-		close(s.tasks)
 	}()
 
 	for {
@@ -179,13 +215,8 @@ func (s *Session) execLoop() {
 		case <-s.ctx.Done():
 			return
 		case <-ticker.C:
-			//send keepalive...
-			s.keepalive()
-		case t, ok := <-s.tasks:
-			if !ok {
-				return
-			}
-			t(s)
+			//send keepalive..., not wait response in execLoop
+			go s.keepalive()
 		}
 	}
 }
@@ -0,0 +1,188 @@
+package jwsapi_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+)
+
+const testPlugin = "janus.plugin.test"
+
+//attachHandles create session with n handles of testPlugin, return handles of client and server
+func attachHandles(t *testing.T, s *janustest.Server, n int, opts ...jwsapi.ConnectionOption) ([]*jwsapi.Handle, []*janustest.Handle) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	conn := jwsapi.NewConnection(ctx, s.URL, 1, opts...)
+	for i := 0; i < 100 && !conn.Connected(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sess, err := conn.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSess, ok := s.Session(sess.ID)
+	if !ok {
+		t.Fatal("no session at server")
+	}
+	var handles []*jwsapi.Handle
+	var serverHandles []*janustest.Handle
+	for i := 0; i < n; i++ {
+		h, err := sess.Attach(testPlugin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverHandle, ok := serverSess.Handle(h.ID)
+		if !ok {
+			t.Fatal("no handle at server")
+		}
+		handles = append(handles, h)
+		serverHandles = append(serverHandles, serverHandle)
+	}
+	return handles, serverHandles
+}
+
+func newTestServer(t *testing.T) *janustest.Server {
+	t.Helper()
+	s := janustest.NewServer()
+	t.Cleanup(s.Close)
+	s.RegisterPlugin(testPlugin, janustest.PluginFunc(func(req *janustest.Request) {
+		req.Success(jwsapi.Message{"test": "success"})
+	}))
+	return s
+}
+
+func TestEventBurst(t *testing.T) {
+	s := newTestServer(t)
+	handles, serverHandles := attachHandles(t, s, 1)
+
+	const count = 300
+	for i := 0; i < count; i++ {
+		serverHandles[0].Event(jwsapi.Message{"seq": i}, nil)
+	}
+	//slow reader, events are queued, not dropped
+	time.Sleep(200 * time.Millisecond)
+	for i := 0; i < count; i++ {
+		select {
+		case event := <-handles[0].Events:
+			pluginData := event.PluginData()
+			data := pluginData.Data()
+			if seq, _ := data.Uint64("seq"); seq != uint64(i) {
+				t.Fatalf("event seq = %d, want %d", seq, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d is lost", i)
+		}
+	}
+}
+
+func TestUnreadHandle(t *testing.T) {
+	s := newTestServer(t)
+	handles, serverHandles := attachHandles(t, s, 2)
+
+	//nobody read Events of handles[0]
+	for i := 0; i < 100; i++ {
+		serverHandles[0].Event(jwsapi.Message{"seq": i}, nil)
+	}
+	serverHandles[1].Event(jwsapi.Message{"seq": 0}, nil)
+	select {
+	case <-handles[1].Events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is stalled by unread handle")
+	}
+	if _, err := handles[1].Request(jwsapi.Message{"request": "test"}); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	//more unread events than Events can buffer, detach still get response
+	for i := 0; i < 300; i++ {
+		serverHandles[0].Event(jwsapi.Message{"seq": i}, nil)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := handles[0].Detach(); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	serverHandles[1].Event(jwsapi.Message{"seq": 1}, nil)
+	select {
+	case <-handles[1].Events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is stalled by detached handle")
+	}
+}
+
+//dropMetrics count dropped messages
+type dropMetrics struct {
+	dropped int64
+}
+
+func (m *dropMetrics) RequestDone(jwsapi.RequestInfo, time.Duration, error) {}
+func (m *dropMetrics) Reconnected(string)                                   {}
+func (m *dropMetrics) MessageDropped(string)                                { atomic.AddInt64(&m.dropped, 1) }
+func (m *dropMetrics) SessionCreated(string)                                {}
+func (m *dropMetrics) SessionDestroyed(string)                              {}
+func (m *dropMetrics) HandleAttached(string, string)                        {}
+func (m *dropMetrics) HandleDetached(string, string)                        {}
+
+func TestHandleQueueFull(t *testing.T) {
+	s := newTestServer(t)
+	m := &dropMetrics{}
+	handles, serverHandles := attachHandles(t, s, 2, jwsapi.WithConnectionMetrics(m))
+
+	//nobody read Events of handles[0], queue of handle is bounded, the rest is dropped
+	//in batches, so recv queue of connection is not full
+	const count = 3000
+	for i := 0; i < count; i++ {
+		serverHandles[0].Event(jwsapi.Message{"seq": i}, nil)
+		if i%100 == 99 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	//messages of connection are in order, all events of handles[0] are queued or dropped before this
+	serverHandles[1].Event(jwsapi.Message{"seq": 0}, nil)
+	select {
+	case <-handles[1].Events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session is stalled by full handle")
+	}
+	if _, err := handles[1].Request(jwsapi.Message{"request": "test"}); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	dropped := atomic.LoadInt64(&m.dropped)
+	if dropped == 0 || dropped >= count {
+		t.Fatalf("dropped = %d, want (0, %d)", dropped, count)
+	}
+
+	//queued events are kept in order, dropped events are skipped
+	var received int64
+	next := uint64(0)
+	for ; ; received++ {
+		select {
+		case event := <-handles[0].Events:
+			pluginData := event.PluginData()
+			data := pluginData.Data()
+			seq, _ := data.Uint64("seq")
+			if seq < next {
+				t.Fatalf("event seq = %d, want >= %d", seq, next)
+			}
+			next = seq + 1
+			continue
+		case <-time.After(200 * time.Millisecond):
+		}
+		break
+	}
+	if received+dropped != count {
+		t.Errorf("received %d + dropped %d != %d", received, dropped, count)
+	}
+
+	//handle is usable after queue is drained
+	serverHandles[0].Event(jwsapi.Message{"seq": "next"}, nil)
+	select {
+	case <-handles[0].Events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event after drain is lost")
+	}
+}
@@ -0,0 +1,33 @@
+package jwsapi
+
+import "fmt"
+
+//Direction direction of ws frame
+type Direction string
+
+const (
+	//DirectionSend frame send to janus-gateway
+	DirectionSend Direction = "send"
+	//DirectionRecv frame recv from janus-gateway
+	DirectionRecv Direction = "recv"
+)
+
+//Tap observe every ws frame of Connection, set by WithConnectionTap, see jrecord.Recorder
+//conn is url@id of Connection, data must not be modified or retained
+//all method must be safe for concurrent use
+type Tap interface {
+	OnFrame(conn string, direction Direction, data []byte)
+}
+
+//WithConnectionTap set tap for ws frames
+func WithConnectionTap(tap Tap) ConnectionOption {
+	return func(c *Connection) {
+		c.tap = tap
+	}
+}
+
+func (c *Connection) onFrame(direction Direction, data []byte) {
+	if c.tap != nil {
+		c.tap.OnFrame(fmt.Sprintf("%s@%d", c.url, c.id), direction, data)
+	}
+}
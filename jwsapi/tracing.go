@@ -0,0 +1,70 @@
+package jwsapi
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/newzai/janus-go/jwsapi"
+
+//span attributes
+const (
+	AttrKeyTransaction = attribute.Key("janus.transaction")
+	AttrKeySessionID   = attribute.Key("janus.session_id")
+	AttrKeyHandleID    = attribute.Key("janus.handle_id")
+	AttrKeyJanus       = attribute.Key("janus.type")
+	AttrKeyRequest     = attribute.Key("janus.request")
+	AttrKeyPlugin      = attribute.Key("janus.plugin")
+)
+
+//WithConnectionTracerProvider set opentelemetry tracer provider, default is otel.GetTracerProvider()
+func WithConnectionTracerProvider(provider trace.TracerProvider) ConnectionOption {
+	return func(c *Connection) {
+		c.tracer = provider.Tracer(tracerName)
+	}
+}
+
+//Tracer return tracer of this connection
+func (c *Connection) Tracer() trace.Tracer {
+	return c.tracer
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+func (c *Connection) startSpan(ctx context.Context, info RequestInfo) (context.Context, trace.Span) {
+	name := "janus." + info.Janus
+	if info.Request != "" {
+		name += "." + info.Request
+	}
+	attrs := []attribute.KeyValue{AttrKeyJanus.String(info.Janus)}
+	if info.Request != "" {
+		attrs = append(attrs, AttrKeyRequest.String(info.Request))
+	}
+	if info.Plugin != "" {
+		attrs = append(attrs, AttrKeyPlugin.String(info.Plugin))
+	}
+	return c.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, msg Message, err error) {
+	if tid, ok := msg.String(attrTransaction); ok {
+		span.SetAttributes(AttrKeyTransaction.String(tid))
+	}
+	if sid, ok := msg[attrSessionID].(uint64); ok {
+		span.SetAttributes(AttrKeySessionID.Int64(int64(sid)))
+	}
+	if hid, ok := msg[attrHandleID].(uint64); ok {
+		span.SetAttributes(AttrKeyHandleID.Int64(int64(hid)))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
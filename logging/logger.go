@@ -0,0 +1,211 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//Level log level
+type Level int
+
+const (
+	//LevelTrace trace, wire payload
+	LevelTrace Level = iota
+	//LevelDebug debug
+	LevelDebug
+	//LevelInfo info
+	LevelInfo
+	//LevelWarn warn
+	LevelWarn
+	//LevelError error
+	LevelError
+	//LevelOff disable log
+	LevelOff
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "trace"
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelOff:
+		return "off"
+	default:
+		return "n/a"
+	}
+}
+
+//ParseLevel parse level from string, trace,debug,info,warn,error,off
+func ParseLevel(level string) (Level, error) {
+	for l := LevelTrace; l <= LevelOff; l++ {
+		if strings.EqualFold(level, l.String()) {
+			return l, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown level %q", level)
+}
+
+//Field structured log field
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+//F create field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+//Backend structured log backend, see NewSlogBackend, zaplog.New, logruslog.New
+//all method must be safe for concurrent use
+type Backend interface {
+	Log(level Level, subsystem string, msg string, fields []Field)
+}
+
+type backendHolder struct {
+	backend Backend
+}
+
+var (
+	backend      atomic.Value //backendHolder
+	levelsMu     sync.RWMutex
+	levels       = make(map[string]Level)
+	defaultLevel = LevelInfo
+)
+
+//SetBackend set structured log backend
+func SetBackend(b Backend) {
+	backend.Store(backendHolder{b})
+}
+
+func currentBackend() Backend {
+	return backend.Load().(backendHolder).backend
+}
+
+//SetDefaultLevel set level for subsystem without SetLevel
+func SetDefaultLevel(level Level) {
+	levelsMu.Lock()
+	defaultLevel = level
+	levelsMu.Unlock()
+}
+
+//SetLevel set level for subsystem, eg: "jwsapi", "jwsapi.wire", "videoroom"
+//subsystem "jwsapi.wire" inherits level of "jwsapi" when not set
+func SetLevel(subsystem string, level Level) {
+	levelsMu.Lock()
+	levels[subsystem] = level
+	levelsMu.Unlock()
+}
+
+//UnsetLevel remove level of subsystem set by SetLevel, subsystem inherits level of parent again
+func UnsetLevel(subsystem string) {
+	levelsMu.Lock()
+	delete(levels, subsystem)
+	levelsMu.Unlock()
+}
+
+//LookupLevel get level set by SetLevel for subsystem, false if not set, without inheritance
+func LookupLevel(subsystem string) (Level, bool) {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	level, ok := levels[subsystem]
+	return level, ok
+}
+
+//GetLevel get level of subsystem
+func GetLevel(subsystem string) Level {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	for name := subsystem; ; {
+		if level, ok := levels[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return defaultLevel
+		}
+		name = name[:i]
+	}
+}
+
+//Logger structured logger of a subsystem
+type Logger struct {
+	subsystem string
+	fields    []Field
+}
+
+//Named get logger for subsystem
+func Named(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+//With return logger with context fields
+func (l *Logger) With(fields ...Field) *Logger {
+	all := make([]Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+	return &Logger{subsystem: l.subsystem, fields: all}
+}
+
+//Enabled check level is enabled, false for all levels if backend is Discard
+func (l *Logger) Enabled(level Level) bool {
+	if _, ok := currentBackend().(discardBackend); ok {
+		return false
+	}
+	return level >= GetLevel(l.subsystem)
+}
+
+//Log write log
+func (l *Logger) Log(level Level, msg string, fields ...Field) {
+	if level >= LevelOff || !l.Enabled(level) {
+		return
+	}
+	all := fields
+	if len(l.fields) > 0 {
+		all = make([]Field, 0, len(l.fields)+len(fields))
+		all = append(all, l.fields...)
+		all = append(all, fields...)
+	}
+	currentBackend().Log(level, l.subsystem, msg, all)
+}
+
+//Trace write log with level = Trace
+func (l *Logger) Trace(msg string, fields ...Field) {
+	l.Log(LevelTrace, msg, fields...)
+}
+
+//Debug write log with level = Debug
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.Log(LevelDebug, msg, fields...)
+}
+
+//Info write log with level = Info
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.Log(LevelInfo, msg, fields...)
+}
+
+//Warn write log with level = Warn
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.Log(LevelWarn, msg, fields...)
+}
+
+//Error write log with level = Error
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.Log(LevelError, msg, fields...)
+}
+
+type discardBackend struct{}
+
+func (discardBackend) Log(Level, string, string, []Field) {}
+
+//Discard backend discards everything, it is the default backend, set other backend by SetBackend
+var Discard Backend = discardBackend{}
@@ -0,0 +1,208 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/newzai/janus-go/logging"
+)
+
+type entry struct {
+	level     logging.Level
+	subsystem string
+	msg       string
+	fields    []logging.Field
+}
+
+type recorder struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+func (r *recorder) Log(level logging.Level, subsystem string, msg string, fields []logging.Field) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry{level, subsystem, msg, fields})
+	r.mu.Unlock()
+}
+
+//use backend for the test, restore Discard and levels after
+func useBackend(t *testing.T, b logging.Backend, subsystems ...string) {
+	t.Helper()
+	logging.SetBackend(b)
+	type saved struct {
+		level logging.Level
+		ok    bool
+	}
+	prev := make(map[string]saved, len(subsystems))
+	for _, name := range subsystems {
+		level, ok := logging.LookupLevel(name)
+		prev[name] = saved{level, ok}
+	}
+	t.Cleanup(func() {
+		logging.SetBackend(logging.Discard)
+		for name, s := range prev {
+			if s.ok {
+				logging.SetLevel(name, s.level)
+			} else {
+				logging.UnsetLevel(name)
+			}
+		}
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		in    string
+		level logging.Level
+		err   bool
+	}{
+		{"trace", logging.LevelTrace, false},
+		{"debug", logging.LevelDebug, false},
+		{"INFO", logging.LevelInfo, false},
+		{"Warn", logging.LevelWarn, false},
+		{"error", logging.LevelError, false},
+		{"off", logging.LevelOff, false},
+		{"verbose", logging.LevelInfo, true},
+		{"", logging.LevelInfo, true},
+	}
+	for _, c := range cases {
+		level, err := logging.ParseLevel(c.in)
+		if level != c.level || (err != nil) != c.err {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v, error %v", c.in, level, err, c.level, c.err)
+		}
+		if err == nil && !c.err {
+			if back, _ := logging.ParseLevel(level.String()); back != level {
+				t.Errorf("ParseLevel(%v.String()) = %v", level, back)
+			}
+		}
+	}
+}
+
+func TestGetLevel(t *testing.T) {
+	useBackend(t, logging.Discard, "lt", "lt.a", "lt.a.b")
+
+	logging.SetLevel("lt", logging.LevelWarn)
+	logging.SetLevel("lt.a.b", logging.LevelTrace)
+	cases := []struct {
+		subsystem string
+		level     logging.Level
+	}{
+		{"lt", logging.LevelWarn},
+		{"lt.a", logging.LevelWarn},      //inherits lt
+		{"lt.a.b", logging.LevelTrace},   //own level
+		{"lt.a.b.c", logging.LevelTrace}, //inherits lt.a.b
+		{"lt.x.y", logging.LevelWarn},    //inherits lt
+		{"ltx", logging.GetLevel("")},    //not a child of lt
+	}
+	for _, c := range cases {
+		if level := logging.GetLevel(c.subsystem); level != c.level {
+			t.Errorf("GetLevel(%q) = %v, want %v", c.subsystem, level, c.level)
+		}
+	}
+
+	logging.SetLevel("lt.a", logging.LevelError)
+	if level := logging.GetLevel("lt.a.x"); level != logging.LevelError {
+		t.Errorf("GetLevel(lt.a.x) after SetLevel(lt.a) = %v, want error", level)
+	}
+	if level, ok := logging.LookupLevel("lt.a.x"); ok {
+		t.Errorf("LookupLevel(lt.a.x) = %v, want not set", level)
+	}
+	logging.UnsetLevel("lt.a")
+	if level := logging.GetLevel("lt.a.x"); level != logging.LevelWarn {
+		t.Errorf("GetLevel(lt.a.x) after UnsetLevel(lt.a) = %v, want warn", level)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	r := &recorder{}
+	useBackend(t, r, "lg")
+	logging.SetLevel("lg", logging.LevelInfo)
+
+	base := logging.Named("lg").With(logging.F("session", 1))
+	child := base.With(logging.F("handle", 2))
+	child.Debug("dropped")
+	child.Info("attached", logging.F("plugin", "videoroom"))
+	base.Warn("keepalive")
+	logging.Named("lg").Error("plain")
+	logging.Named("lg").Log(logging.LevelOff, "off")
+
+	want := []entry{
+		{logging.LevelInfo, "lg", "attached", []logging.Field{logging.F("session", 1), logging.F("handle", 2), logging.F("plugin", "videoroom")}},
+		{logging.LevelWarn, "lg", "keepalive", []logging.Field{logging.F("session", 1)}},
+		{logging.LevelError, "lg", "plain", nil},
+	}
+	if !reflect.DeepEqual(r.entries, want) {
+		t.Errorf("entries = %+v, want %+v", r.entries, want)
+	}
+
+	//With does not change fields of parent
+	r.entries = nil
+	base.With(logging.F("a", 1))
+	base.With(logging.F("b", 2)).Info("b")
+	if got := r.entries[0].fields; !reflect.DeepEqual(got, []logging.Field{logging.F("session", 1), logging.F("b", 2)}) {
+		t.Errorf("fields = %+v", got)
+	}
+
+	//level change takes effect for existing logger
+	logging.SetLevel("lg", logging.LevelTrace)
+	if !child.Enabled(logging.LevelTrace) {
+		t.Error("trace is not enabled after SetLevel")
+	}
+
+	//Discard disables all levels
+	logging.SetBackend(logging.Discard)
+	if child.Enabled(logging.LevelError) {
+		t.Error("error is enabled with Discard backend")
+	}
+}
+
+func TestSlogBackend(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: logging.LevelSlogTrace})
+	useBackend(t, logging.NewSlogBackend(slog.New(handler)), "sl")
+	logging.SetLevel("sl", logging.LevelTrace)
+
+	log := logging.Named("sl").With(logging.F("room", 1234))
+	log.Trace("wire", logging.F("size", 10))
+	log.Debug("debug")
+	log.Info("info")
+	log.Warn("warn")
+	log.Error("error")
+
+	want := []struct {
+		level string
+		msg   string
+	}{
+		{"DEBUG-4", "wire"}, {"DEBUG", "debug"}, {"INFO", "info"}, {"WARN", "warn"}, {"ERROR", "error"},
+	}
+	decoder := json.NewDecoder(&buf)
+	for i, w := range want {
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if record["level"] != w.level || record["msg"] != w.msg {
+			t.Errorf("record %d = %s %s, want %s %s", i, record["level"], record["msg"], w.level, w.msg)
+		}
+		if record["subsystem"] != "sl" || record["room"] != float64(1234) {
+			t.Errorf("record %d: subsystem = %v, room = %v", i, record["subsystem"], record["room"])
+		}
+		if i == 0 && record["size"] != float64(10) {
+			t.Errorf("record %d: size = %v", i, record["size"])
+		}
+	}
+
+	//disabled levels of slog handler are skipped
+	buf.Reset()
+	quiet := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	logging.SetBackend(logging.NewSlogBackend(slog.New(quiet)))
+	log.Info("info")
+	log.Warn("warn")
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 1 {
+		t.Errorf("records = %d, want 1: %s", n, buf.String())
+	}
+}
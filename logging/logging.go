@@ -1,6 +1,11 @@
 package logging
 
-//LoggerInterface logger 
+import (
+	"fmt"
+	"strings"
+)
+
+//LoggerInterface logger
 type LoggerInterface interface {
 
 	// Tracef formats message according to format specifier
@@ -23,8 +28,6 @@ type LoggerInterface interface {
 	// and writes to log with level = Error.
 	Errorf(format string, params ...interface{}) error
 
-	
-
 	// Trace formats message using the default formats for its operands
 	// and writes to log with level = Trace
 	Trace(v ...interface{})
@@ -46,141 +49,129 @@ type LoggerInterface interface {
 	Error(v ...interface{}) error
 }
 
+//printfBackend adapt LoggerInterface(seelog) to Backend
+//fields are appended to message as key=value
+type printfBackend struct {
+	logger LoggerInterface
+}
+
+func (b *printfBackend) Log(level Level, subsystem string, msg string, fields []Field) {
+	var sb strings.Builder
+	if subsystem != "" {
+		sb.WriteString("[")
+		sb.WriteString(subsystem)
+		sb.WriteString("] ")
+	}
+	sb.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&sb, " %s=%v", f.Key, f.Value)
+	}
+	line := sb.String()
+	switch level {
+	case LevelTrace:
+		b.logger.Trace(line)
+	case LevelDebug:
+		b.logger.Debug(line)
+	case LevelInfo:
+		b.logger.Info(line)
+	case LevelWarn:
+		b.logger.Warn(line)
+	default:
+		b.logger.Error(line)
+	}
+}
+
+var std = Named("")
 
-type defaultLogger struct {
-
+func init() {
+	SetBackend(Discard)
 }
 
-	// Tracef formats message according to format specifier
-	// and writes to log with level = Trace.
-func (d *defaultLogger)	Tracef(format string, params ...interface{}){
+//SetLogger set printf logger, eg: seelog
+func SetLogger(logger LoggerInterface) {
+	SetBackend(&printfBackend{logger: logger})
+}
 
+// Tracef formats message according to format specifier
+// and writes to log with level = Trace.
+func Tracef(format string, params ...interface{}) {
+	if std.Enabled(LevelTrace) {
+		std.Trace(fmt.Sprintf(format, params...))
+	}
 }
 
 // Debugf formats message according to format specifier
 // and writes to log with level = Debug.
-func (d *defaultLogger)Debugf(format string, params ...interface{}){
-	
+func Debugf(format string, params ...interface{}) {
+	if std.Enabled(LevelDebug) {
+		std.Debug(fmt.Sprintf(format, params...))
+	}
 }
 
 // Infof formats message according to format specifier
 // and writes to log with level = Info.
-func (d *defaultLogger)Infof(format string, params ...interface{}){
-
+func Infof(format string, params ...interface{}) {
+	if std.Enabled(LevelInfo) {
+		std.Info(fmt.Sprintf(format, params...))
+	}
 }
 
 // Warnf formats message according to format specifier
 // and writes to log with level = Warn.
-func (d *defaultLogger)Warnf(format string, params ...interface{}) error{
+func Warnf(format string, params ...interface{}) error {
+	if std.Enabled(LevelWarn) {
+		std.Warn(fmt.Sprintf(format, params...))
+	}
 	return nil
 }
 
 // Errorf formats message according to format specifier
 // and writes to log with level = Error.
-func (d *defaultLogger)Errorf(format string, params ...interface{}) error{
+func Errorf(format string, params ...interface{}) error {
+	if std.Enabled(LevelError) {
+		std.Error(fmt.Sprintf(format, params...))
+	}
 	return nil
 }
 
-
 // Trace formats message using the default formats for its operands
 // and writes to log with level = Trace
-func (d *defaultLogger)Trace(v ...interface{}){
-
+func Trace(v ...interface{}) {
+	if std.Enabled(LevelTrace) {
+		std.Trace(fmt.Sprint(v...))
+	}
 }
 
 // Debug formats message using the default formats for its operands
 // and writes to log with level = Debug
-func (d *defaultLogger)Debug(v ...interface{}){
-
+func Debug(v ...interface{}) {
+	if std.Enabled(LevelDebug) {
+		std.Debug(fmt.Sprint(v...))
+	}
 }
 
 // Info formats message using the default formats for its operands
 // and writes to log with level = Info
-func (d *defaultLogger)Info(v ...interface{}){
-
+func Info(v ...interface{}) {
+	if std.Enabled(LevelInfo) {
+		std.Info(fmt.Sprint(v...))
+	}
 }
 
 // Warn formats message using the default formats for its operands
 // and writes to log with level = Warn
-func (d *defaultLogger)Warn(v ...interface{}) error{
+func Warn(v ...interface{}) error {
+	if std.Enabled(LevelWarn) {
+		std.Warn(fmt.Sprint(v...))
+	}
 	return nil
 }
 
 // Error formats message using the default formats for its operands
 // and writes to log with level = Error
-func (d *defaultLogger)Error(v ...interface{}) error{
+func Error(v ...interface{}) error {
+	if std.Enabled(LevelError) {
+		std.Error(fmt.Sprint(v...))
+	}
 	return nil
 }
-
-var current LoggerInterface 
-
-func init() {
-	current = &defaultLogger{}
-}
-//SetLogger set logger 
-func SetLogger( logger LoggerInterface){
-	current = logger
-}
-
-
-// Tracef formats message according to format specifier
-// and writes to log with level = Trace.
-func Tracef(format string, params ...interface{}){
-	current.Tracef(format,params...)
-}
-
-// Debugf formats message according to format specifier
-// and writes to log with level = Debug.
-func	Debugf(format string, params ...interface{}){
-	current.Debugf(format,params...)
-}
-
-// Infof formats message according to format specifier
-// and writes to log with level = Info.
-func	Infof(format string, params ...interface{}){
-	current.Infof(format,params...)
-}
-
-// Warnf formats message according to format specifier
-// and writes to log with level = Warn.
-func	Warnf(format string, params ...interface{}) error{
-	return current.Warnf(format,params...)
-}
-
-// Errorf formats message according to format specifier
-// and writes to log with level = Error.
-func	Errorf(format string, params ...interface{}) error{
-	return current.Errorf(format,params...)
-}
-
-
-
-// Trace formats message using the default formats for its operands
-// and writes to log with level = Trace
-func Trace(v ...interface{}){
-	current.Trace(v...)
-}
-
-// Debug formats message using the default formats for its operands
-// and writes to log with level = Debug
-func Debug(v ...interface{}){
-	current.Debug(v...)
-}
-
-// Info formats message using the default formats for its operands
-// and writes to log with level = Info
-func Info(v ...interface{}){
-	current.Info(v...)
-}
-
-// Warn formats message using the default formats for its operands
-// and writes to log with level = Warn
-func Warn(v ...interface{}) error{
-	return current.Warn(v...)
-}
-
-// Error formats message using the default formats for its operands
-// and writes to log with level = Error
-func Error(v ...interface{}) error{
-	return current.Error(v...)
-}
\ No newline at end of file
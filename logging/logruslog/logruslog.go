@@ -0,0 +1,38 @@
+package logruslog
+
+import (
+	"github.com/newzai/janus-go/logging"
+	"github.com/sirupsen/logrus"
+)
+
+type backend struct {
+	logger logrus.Ext1FieldLogger
+}
+
+//New logrus backend for logging.SetBackend, logger is *logrus.Logger or *logrus.Entry
+func New(logger logrus.Ext1FieldLogger) logging.Backend {
+	return &backend{logger: logger}
+}
+
+func (b *backend) Log(level logging.Level, subsystem string, msg string, fields []logging.Field) {
+	lfields := make(logrus.Fields, len(fields)+1)
+	if subsystem != "" {
+		lfields["subsystem"] = subsystem
+	}
+	for _, f := range fields {
+		lfields[f.Key] = f.Value
+	}
+	entry := b.logger.WithFields(lfields)
+	switch level {
+	case logging.LevelTrace:
+		entry.Trace(msg)
+	case logging.LevelDebug:
+		entry.Debug(msg)
+	case logging.LevelInfo:
+		entry.Info(msg)
+	case logging.LevelWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+}
@@ -0,0 +1,234 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+//Replacement default replacement of secret value
+const Replacement = "[REDACTED]"
+
+//DefaultKeys json keys redacted by default, case insensitive
+var DefaultKeys = []string{
+	"secret", "pin", "apisecret", "token", "admin_key", "admin_secret",
+	"new_secret", "new_pin", "password", "ha1_secret", "srtp_key",
+}
+
+//DefaultSDPAttributes sdp attributes scrubbed by default
+var DefaultSDPAttributes = []string{"ice-ufrag", "ice-pwd", "fingerprint", "crypto"}
+
+//Redactor redact secrets of janus json message and sdp
+//a nil *Redactor do nothing
+type Redactor struct {
+	keys        map[string]struct{}
+	sdpAttrs    []string
+	replacement string
+	jsonPattern *regexp.Regexp
+	sdpPattern  *regexp.Regexp
+	sdpEscaped  *regexp.Regexp //sdp line in json string, end with \r\n
+}
+
+//Option option for Redactor
+type Option func(*Redactor)
+
+//WithKeys set json keys to redact, replace DefaultKeys
+func WithKeys(keys ...string) Option {
+	return func(r *Redactor) {
+		r.keys = make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			r.keys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+//WithExtraKeys add json keys to redact
+func WithExtraKeys(keys ...string) Option {
+	return func(r *Redactor) {
+		for _, key := range keys {
+			r.keys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+//WithSDPAttributes set sdp attributes to scrub, replace DefaultSDPAttributes
+func WithSDPAttributes(attrs ...string) Option {
+	return func(r *Redactor) {
+		r.sdpAttrs = attrs
+	}
+}
+
+//WithReplacement set replacement, default is Replacement
+func WithReplacement(replacement string) Option {
+	return func(r *Redactor) {
+		r.replacement = replacement
+	}
+}
+
+//New create Redactor
+func New(opts ...Option) *Redactor {
+	r := &Redactor{
+		replacement: Replacement,
+	}
+	WithKeys(DefaultKeys...)(r)
+	WithSDPAttributes(DefaultSDPAttributes...)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	keys := make([]string, 0, len(r.keys))
+	for key := range r.keys {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	if len(keys) > 0 {
+		//fallback for invalid json: "key" : "value" or "key" : value
+		r.jsonPattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(keys, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[^,}\]\s]+)`)
+	}
+	attrs := make([]string, 0, len(r.sdpAttrs))
+	for _, attr := range r.sdpAttrs {
+		attrs = append(attrs, regexp.QuoteMeta(attr))
+	}
+	if len(attrs) > 0 {
+		r.sdpPattern = regexp.MustCompile(`(?im)^(a=(?:` + strings.Join(attrs, "|") + `):)([^\r\n]*)`)
+		r.sdpEscaped = regexp.MustCompile(`(?i)(a=(?:` + strings.Join(attrs, "|") + `):)((?:[^\\"]|\\[^rn])*)`)
+	}
+	return r
+}
+
+var current atomic.Value //holder
+
+type holder struct {
+	r *Redactor
+}
+
+func init() {
+	current.Store(holder{New()})
+}
+
+//Default return default redactor, used by jwsapi, jvideoroom logs
+func Default() *Redactor {
+	return current.Load().(holder).r
+}
+
+//SetDefault set default redactor, nil to disable redaction
+func SetDefault(r *Redactor) {
+	current.Store(holder{r})
+}
+
+func (r *Redactor) isKey(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+//SDP scrub sdp attributes, eg: a=ice-pwd:[REDACTED]
+func (r *Redactor) SDP(sdp string) string {
+	if r == nil || r.sdpPattern == nil {
+		return sdp
+	}
+	return r.sdpPattern.ReplaceAllString(sdp, "${1}"+r.replacement)
+}
+
+//Value redact json value, map and array are copied, value of "sdp" key is scrubbed
+//map with string key and slice of any named type are redacted too, eg: jwsapi.Message
+func (r *Redactor) Value(value interface{}) interface{} {
+	if r == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.Map(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.Value(item)
+		}
+		return out
+	case string, []byte, nil:
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return r.Map(m)
+	case reflect.Slice:
+		if rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = r.Value(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Ptr:
+		if !rv.IsNil() && rv.Elem().Kind() == reflect.Map {
+			return r.Value(rv.Elem().Interface())
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+//Map return redacted copy of json object, jwsapi.Message can convert to map[string]interface{}
+func (r *Redactor) Map(m map[string]interface{}) map[string]interface{} {
+	if r == nil || m == nil {
+		return m
+	}
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		switch {
+		case r.isKey(key):
+			out[key] = r.replacement
+		case key == "sdp":
+			if sdp, ok := value.(string); ok {
+				out[key] = r.SDP(sdp)
+			} else {
+				out[key] = r.Value(value)
+			}
+		default:
+			out[key] = r.Value(value)
+		}
+	}
+	return out
+}
+
+//JSON return redacted json, invalid json is redacted by pattern
+func (r *Redactor) JSON(data []byte) []byte {
+	if r == nil {
+		return data
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err == nil {
+		if out, err := json.Marshal(r.Value(value)); err == nil {
+			return out
+		}
+	}
+
+	out := data
+	if r.jsonPattern != nil {
+		out = r.jsonPattern.ReplaceAll(out, []byte(`${1}"`+r.replacement+`"`))
+	}
+	if r.sdpEscaped != nil {
+		out = r.sdpEscaped.ReplaceAll(out, []byte("${1}"+r.replacement))
+	}
+	return out
+}
+
+//String same as JSON, for logging
+func (r *Redactor) String(data []byte) string {
+	return string(r.JSON(data))
+}
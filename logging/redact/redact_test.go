@@ -0,0 +1,52 @@
+package redact_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/logging/redact"
+)
+
+func TestMapNestedMessage(t *testing.T) {
+	msg := jwsapi.Message{
+		"janus": "message",
+		"body": jwsapi.Message{
+			"request": "join",
+			"secret":  "s3cr3t",
+			"rtp":     map[string]string{"srtp_key": "k3y"},
+		},
+		"jsep": jwsapi.Message{
+			"type": "offer",
+			"sdp":  "v=0\r\na=ice-pwd:p4ss\r\n",
+		},
+		"list": []jwsapi.Message{{"pin": "1234"}},
+	}
+	out := redact.New().Map(msg)
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cr3t", "k3y", "p4ss", "1234"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s is not redacted: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"request":"join"`) {
+		t.Errorf("request is lost: %s", data)
+	}
+	body := msg["body"].(jwsapi.Message)
+	if body["secret"] != "s3cr3t" {
+		t.Error("source message is modified")
+	}
+}
+
+func TestNilRedactor(t *testing.T) {
+	var r *redact.Redactor
+	msg := jwsapi.Message{"body": jwsapi.Message{"secret": "s3cr3t"}}
+	out := r.Value(msg)
+	if out.(jwsapi.Message)["body"].(jwsapi.Message)["secret"] != "s3cr3t" {
+		t.Error("nil redactor redacted")
+	}
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+//LevelSlogTrace slog level for LevelTrace
+const LevelSlogTrace = slog.LevelDebug - 4
+
+type slogBackend struct {
+	logger *slog.Logger
+}
+
+//NewSlogBackend log/slog backend, eg: logging.SetBackend(logging.NewSlogBackend(slog.Default()))
+func NewSlogBackend(logger *slog.Logger) Backend {
+	return &slogBackend{logger: logger}
+}
+
+func (b *slogBackend) Log(level Level, subsystem string, msg string, fields []Field) {
+	var lvl slog.Level
+	switch level {
+	case LevelTrace:
+		lvl = LevelSlogTrace
+	case LevelDebug:
+		lvl = slog.LevelDebug
+	case LevelInfo:
+		lvl = slog.LevelInfo
+	case LevelWarn:
+		lvl = slog.LevelWarn
+	default:
+		lvl = slog.LevelError
+	}
+	ctx := context.Background()
+	if !b.logger.Enabled(ctx, lvl) {
+		return
+	}
+	attrs := make([]slog.Attr, 0, len(fields)+1)
+	if subsystem != "" {
+		attrs = append(attrs, slog.String("subsystem", subsystem))
+	}
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	b.logger.LogAttrs(ctx, lvl, msg, attrs...)
+}
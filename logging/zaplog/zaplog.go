@@ -0,0 +1,36 @@
+package zaplog
+
+import (
+	"github.com/newzai/janus-go/logging"
+	"go.uber.org/zap"
+)
+
+type backend struct {
+	logger *zap.Logger
+}
+
+//New zap backend for logging.SetBackend
+//Trace and Debug are both written with zap Debug level
+func New(logger *zap.Logger) logging.Backend {
+	return &backend{logger: logger.WithOptions(zap.AddCallerSkip(3))}
+}
+
+func (b *backend) Log(level logging.Level, subsystem string, msg string, fields []logging.Field) {
+	zfields := make([]zap.Field, 0, len(fields)+1)
+	if subsystem != "" {
+		zfields = append(zfields, zap.String("subsystem", subsystem))
+	}
+	for _, f := range fields {
+		zfields = append(zfields, zap.Any(f.Key, f.Value))
+	}
+	switch level {
+	case logging.LevelTrace, logging.LevelDebug:
+		b.logger.Debug(msg, zfields...)
+	case logging.LevelInfo:
+		b.logger.Info(msg, zfields...)
+	case logging.LevelWarn:
+		b.logger.Warn(msg, zfields...)
+	default:
+		b.logger.Error(msg, zfields...)
+	}
+}
@@ -0,0 +1,284 @@
+//Package nosip plain rtp peer of janus nosip plugin, the other side of barebone sdp, see jwsapi/jplugin/jnosip
+//eg: media-processing worker without webrtc stack
+package nosip
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/newzai/janus-go/jwsapi/jplugin/jnosip"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Named("nosip")
+
+//Peer plain rtp peer, local udp sockets(rtp, rtcp) of every media, no webrtc stack
+//
+//	peer, err := nosip.Answer("127.0.0.1", local)
+//	defer peer.Close()
+//	audio := peer.Endpoint(jnosip.KindAudio)
+//	packet, err := audio.ReadRTP()
+type Peer struct {
+	address   string
+	endpoints []*Endpoint
+}
+
+//Endpoint local udp sockets of one media of Peer
+type Endpoint struct {
+	media    jnosip.Media //local media, port of sockets
+	rtpConn  *net.UDPConn
+	rtcpConn *net.UDPConn
+
+	mu         sync.RWMutex
+	codec      jnosip.Codec //negotiated codec
+	remote     *net.UDPAddr //rtp of janus
+	remoteRTCP *net.UDPAddr //rtcp of janus
+}
+
+//Listen open udp sockets for media, address is local ip to bind and in sdp, eg: 127.0.0.1
+//Port, RTCPPort of media are ignored, media with port 0 is rejected
+func Listen(address string, media ...jnosip.Media) (*Peer, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, errors.Errorf("invalid address %s", address)
+	}
+	p := &Peer{address: address}
+	for _, m := range media {
+		e := &Endpoint{media: m}
+		p.endpoints = append(p.endpoints, e)
+		if m.Port == 0 || len(m.Codecs) == 0 {
+			e.media.Port, e.media.RTCPPort = 0, 0
+			continue
+		}
+		var err error
+		if e.rtpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: ip}); err == nil {
+			e.rtcpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+		}
+		if err != nil {
+			p.Close()
+			return nil, errors.Wrap(err, m.Kind)
+		}
+		e.media.Address = ""
+		e.media.Port = e.rtpConn.LocalAddr().(*net.UDPAddr).Port
+		e.media.RTCPPort = e.rtcpConn.LocalAddr().(*net.UDPAddr).Port
+		e.codec = m.Codecs[0]
+	}
+	return p, nil
+}
+
+//Answer open udp sockets for media of offer, first codec of every media is accepted, connected to offer
+func Answer(address string, offer *jnosip.SessionDescription) (*Peer, error) {
+	media := make([]jnosip.Media, 0, len(offer.Media))
+	for _, m := range offer.Media {
+		answer := jnosip.Media{
+			Kind:      m.Kind,
+			Port:      m.Port,
+			Direction: jnosip.ReverseDirection(m.Direction),
+		}
+		if len(m.Codecs) > 0 {
+			answer.Codecs = m.Codecs[:1]
+		}
+		media = append(media, answer)
+	}
+	p, err := Listen(address, media...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Connect(offer); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+//SessionDescription barebone sdp of local sockets, sdpType is jnosip.SDPTypeOffer or jnosip.SDPTypeAnswer
+func (p *Peer) SessionDescription(sdpType string) *jnosip.SessionDescription {
+	s := &jnosip.SessionDescription{Type: sdpType, Address: p.address}
+	for _, e := range p.endpoints {
+		m := e.media
+		if sdpType == jnosip.SDPTypeAnswer && m.Port != 0 {
+			m.Codecs = []jnosip.Codec{e.Codec()}
+		}
+		s.Media = append(s.Media, m)
+	}
+	return s
+}
+
+//Connect set remote address of media by remote(sdp of janus), matched by order of kind
+//codec of endpoint is the first codec of remote media with the same name
+func (p *Peer) Connect(remote *jnosip.SessionDescription) error {
+	used := make(map[int]bool)
+	for _, e := range p.endpoints {
+		if e.rtpConn == nil {
+			continue
+		}
+		var media *jnosip.Media
+		for i := range remote.Media {
+			if !used[i] && remote.Media[i].Kind == e.media.Kind {
+				used[i] = true
+				media = &remote.Media[i]
+				break
+			}
+		}
+		if media == nil || media.Port == 0 {
+			continue
+		}
+		codec, ok := jnosip.Codec{}, false
+		for _, c := range media.Codecs {
+			if _, ok = e.media.Codec(c.Name); ok {
+				codec = c
+				break
+			}
+		}
+		if !ok {
+			return errors.Errorf("%s: no codec of %s", e.media.Kind, codecNames(e.media.Codecs))
+		}
+		e.mu.Lock()
+		e.codec = codec
+		e.remote = media.RTPAddr(remote)
+		e.remoteRTCP = media.RTCPAddr(remote)
+		e.mu.Unlock()
+	}
+	return nil
+}
+
+//Endpoint first endpoint of kind with sockets, nil if not found
+func (p *Peer) Endpoint(kind string) *Endpoint {
+	for _, e := range p.endpoints {
+		if e.media.Kind == kind && e.rtpConn != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+//Endpoints endpoints in order of media, endpoint of rejected media has no sockets
+func (p *Peer) Endpoints() []*Endpoint {
+	return p.endpoints
+}
+
+//Close close sockets, ReadRTP, ReadRTCP return error
+func (p *Peer) Close() error {
+	for _, e := range p.endpoints {
+		e.close()
+	}
+	return nil
+}
+
+//Kind audio or video
+func (e *Endpoint) Kind() string {
+	return e.media.Kind
+}
+
+//Codec negotiated codec, payload type of WriteRTP
+func (e *Endpoint) Codec() jnosip.Codec {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.codec
+}
+
+//LocalAddr local rtp address, nil if media is rejected
+func (e *Endpoint) LocalAddr() *net.UDPAddr {
+	if e.rtpConn == nil {
+		return nil
+	}
+	return e.rtpConn.LocalAddr().(*net.UDPAddr)
+}
+
+//RemoteAddr rtp address of janus, nil before Connect
+func (e *Endpoint) RemoteAddr() *net.UDPAddr {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.remote
+}
+
+//ReadRTP read rtp from janus
+func (e *Endpoint) ReadRTP() (*rtp.Packet, error) {
+	if e.rtpConn == nil {
+		return nil, errors.New("media is rejected")
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := e.rtpConn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, err
+		}
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(append([]byte(nil), buf[:n]...)); err != nil {
+			log.Debug("invalid rtp")
+			continue
+		}
+		return packet, nil
+	}
+}
+
+//WriteRTP write rtp to janus, PayloadType is set by caller, see jnosip.Codec
+func (e *Endpoint) WriteRTP(packet *rtp.Packet) error {
+	remote := e.RemoteAddr()
+	if e.rtpConn == nil || remote == nil {
+		return errors.New("not connected")
+	}
+	data, err := packet.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = e.rtpConn.WriteToUDP(data, remote)
+	return err
+}
+
+//ReadRTCP read rtcp from janus, eg: PLI of webrtc peer
+func (e *Endpoint) ReadRTCP() ([]rtcp.Packet, error) {
+	if e.rtcpConn == nil {
+		return nil, errors.New("media is rejected")
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := e.rtcpConn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, err
+		}
+		packets, err := rtcp.Unmarshal(buf[:n])
+		if err != nil {
+			log.Debug("invalid rtcp")
+			continue
+		}
+		return packets, nil
+	}
+}
+
+//WriteRTCP write rtcp to janus, eg: PLI to webrtc peer
+func (e *Endpoint) WriteRTCP(packets []rtcp.Packet) error {
+	e.mu.RLock()
+	remote := e.remoteRTCP
+	e.mu.RUnlock()
+	if e.rtcpConn == nil || remote == nil {
+		return errors.New("not connected")
+	}
+	data, err := rtcp.Marshal(packets)
+	if err != nil {
+		return err
+	}
+	_, err = e.rtcpConn.WriteToUDP(data, remote)
+	return err
+}
+
+func (e *Endpoint) close() {
+	if e.rtpConn != nil {
+		e.rtpConn.Close()
+	}
+	if e.rtcpConn != nil {
+		e.rtcpConn.Close()
+	}
+}
+
+func codecNames(codecs []jnosip.Codec) string {
+	names := make([]string, 0, len(codecs))
+	for _, codec := range codecs {
+		names = append(names, codec.Name)
+	}
+	return strings.Join(names, ",")
+}
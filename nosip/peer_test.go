@@ -0,0 +1,136 @@
+package nosip_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jnosip"
+	"github.com/newzai/janus-go/nosip"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+//newTestOffer webrtc peer with opus track, offer is set as local description
+func newTestOffer(t *testing.T) (*webrtc.PeerConnection, *webrtc.Track) {
+	t.Helper()
+	m := webrtc.MediaEngine{}
+	m.RegisterDefaultCodecs()
+	setting := webrtc.SettingEngine{}
+	setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithSettingEngine(setting)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	track, err := pc.NewTrack(webrtc.DefaultPayloadTypeOpus, 1234, "audio", "nosip")
+	if err == nil {
+		_, err = pc.AddTrack(track)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc, track
+}
+
+func TestPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.NoSIPPlugin, janustest.NewNoSIP())
+
+	pc, track := newTestOffer(t)
+	c := jnosip.NewClient(ctx, janustest.Attach(t, ctx, s, jnosip.Plugin))
+	local, err := c.Generate(jnosip.SDPTypeOffer, pc.LocalDescription().SDP)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if local.Type != jnosip.SDPTypeOffer || local.MediaOf(jnosip.KindAudio) == nil {
+		t.Fatalf("barebone offer = %+v", local)
+	}
+	peer, err := nosip.Answer("127.0.0.1", local)
+	if err != nil {
+		t.Fatalf("answer: %v", err)
+	}
+	defer peer.Close()
+	answer, err := c.Process(peer.SessionDescription(jnosip.SDPTypeAnswer))
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}); err != nil {
+		t.Fatal(err)
+	}
+
+	//rtp of webrtc peer is relayed to plain rtp peer
+	packets := make(chan *rtp.Packet, 16)
+	go func() {
+		for {
+			packet, err := peer.Endpoint(jnosip.KindAudio).ReadRTP()
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}()
+	deadline := time.After(10 * time.Second)
+	for seq := uint16(1); ; seq++ {
+		err := track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), SequenceNumber: seq, Timestamp: uint32(seq) * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case packet := <-packets:
+			if string(packet.Payload) != "\xf8\xff\xfe" {
+				t.Errorf("payload = %x", packet.Payload)
+			}
+			if err := c.Hangup(); err != nil {
+				t.Errorf("hangup: %v", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("rtp of webrtc peer is not relayed")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	opus := jnosip.Codec{PayloadType: 111, Name: "opus", ClockRate: 48000, Channels: 2}
+	peer, err := nosip.Listen("127.0.0.1",
+		jnosip.Media{Kind: jnosip.KindAudio, Port: 1, Codecs: []jnosip.Codec{opus}},
+		jnosip.Media{Kind: jnosip.KindVideo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	if peer.Endpoint(jnosip.KindVideo) != nil {
+		t.Error("endpoint of rejected media")
+	}
+	audio := peer.Endpoint(jnosip.KindAudio)
+	if audio == nil || audio.Codec() != opus || audio.RemoteAddr() != nil {
+		t.Fatalf("audio endpoint = %+v", audio)
+	}
+	offer := peer.SessionDescription(jnosip.SDPTypeOffer)
+	if len(offer.Media) != 2 || offer.Media[0].Port != audio.LocalAddr().Port || offer.Media[0].RTCPPort == 0 || offer.Media[1].Port != 0 {
+		t.Errorf("offer = %+v", offer)
+	}
+	if err := audio.WriteRTP(&rtp.Packet{}); err == nil {
+		t.Error("write before connect")
+	}
+	if _, err := nosip.Listen("localhost"); err == nil {
+		t.Error("listen of host name")
+	}
+}
@@ -0,0 +1,437 @@
+//Package recordplay pion clients of janus record&play plugin, see jwsapi/jplugin/jrecordplay
+package recordplay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jrecordplay"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("recordplay")
+
+//span attributes
+const attrKeyRecording = attribute.Key("janus.recording")
+
+//Track track
+type Track = rtcsession.Track
+
+//Stats statistics snapshot of a session
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//RecordPlayRecorder record opus and h264 to janus-gateway record&play plugin, flow of Publisher
+//
+//	r := recordplay.NewRecordPlayRecorder(ctx, api, h)
+//	r.Record("demo")
+//	r.GetTrack(webrtc.RTPCodecTypeVideo).WriteRTP(packet)
+//	r.Stop()
+type RecordPlayRecorder struct {
+	rtc       *rtcsession.Session
+	jRecorder *jrecordplay.Recorder
+	tracks    []*Track
+}
+
+//RecordPlayRecorderOption option for RecordPlayRecorder
+type RecordPlayRecorderOption func(*RecordPlayRecorder)
+
+//WithRecordPlayRecorderConfigure set webrtc configure
+func WithRecordPlayRecorderConfigure(configure webrtc.Configuration) RecordPlayRecorderOption {
+	return func(r *RecordPlayRecorder) {
+		r.rtc.Configure = configure
+	}
+}
+
+//WithRecordPlayRecorderStats report stats every interval
+func WithRecordPlayRecorderStats(interval time.Duration, callback func(Stats)) RecordPlayRecorderOption {
+	return func(r *RecordPlayRecorder) {
+		r.rtc.StatsInterval = interval
+		r.rtc.OnStats = callback
+	}
+}
+
+//NewRecordPlayRecorder new recorder, h is handle of janus.plugin.recordplay
+func NewRecordPlayRecorder(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, opts ...jrecordplay.RecorderOption) *RecordPlayRecorder {
+	r := &RecordPlayRecorder{
+		jRecorder: jrecordplay.NewRecorder(ctx, h, opts...),
+	}
+	r.rtc = rtcsession.New(ctx, api, h, r.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(r.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(r.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(r.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(r.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(r.rtc.OnSlowLink))
+
+	return r
+}
+
+//Object return jrecordplay.Recorder
+func (r *RecordPlayRecorder) Object() *jrecordplay.Recorder {
+	return r.jRecorder
+}
+
+//ID return id info
+func (r *RecordPlayRecorder) ID() string {
+	return fmt.Sprintf("[Recording.%d]", r.jRecorder.ID())
+}
+
+//Stats return statistics snapshot
+func (r *RecordPlayRecorder) Stats() Stats {
+	return r.rtc.Stats()
+}
+
+//SetOption set option
+func (r *RecordPlayRecorder) SetOption(opts ...RecordPlayRecorderOption) *RecordPlayRecorder {
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+//Record start record with name, api should only have opus and h264 like Publisher
+//jrecordplay.WithMessageOptionID, jrecordplay.WithMessageOptionFilename... for other params
+func (r *RecordPlayRecorder) Record(name string, opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := r.rtc.StartSpan(r.rtc.Ctx, "recordplay.RecordPlayRecorder.Record")
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	r.rtc.SetTraceLink(ctx)
+
+	var pc *webrtc.PeerConnection
+	err = r.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = r.rtc.API.NewPeerConnection(r.rtc.Configure)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "NewPeerConnection")
+	}
+	r.rtc.PC = pc
+
+	pc.OnConnectionStateChange(r.onPeerConnectionState)
+	pc.OnICECandidate(r.rtc.OnICECandidate)
+
+	tracks, senders, err := r.rtc.AddSendTracks(pc, "record")
+	if err != nil {
+		pc.Close()
+		return err
+	}
+	r.tracks = tracks
+
+	var offer webrtc.SessionDescription
+	err = r.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateOffer")
+	}
+	err = r.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	answer, err := r.jRecorder.RecordContext(ctx, name, offer.SDP, opts...)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "record")
+	}
+	span.SetAttributes(attrKeyRecording.Int64(int64(r.jRecorder.ID())))
+	err = r.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+
+	go r.rtc.DoRemoteCandidate(r.rtc.RemoteCandidates)
+	for _, sender := range senders {
+		go r.startSender(sender)
+	}
+	go r.rtc.DoStats(pc)
+	return nil
+}
+
+//Stop stop recording, close PeerConnection, recording is listed after stopped
+func (r *RecordPlayRecorder) Stop() error {
+	if r.rtc.PC != nil {
+		r.rtc.PC.Close()
+	}
+	return r.jRecorder.Stop()
+}
+
+//GetTrack return track by kind, nil before Record
+func (r *RecordPlayRecorder) GetTrack(kind webrtc.RTPCodecType) *Track {
+	if len(r.tracks) != 2 {
+		return nil
+	}
+	switch kind {
+	case webrtc.RTPCodecTypeAudio:
+		return r.tracks[0]
+	case webrtc.RTPCodecTypeVideo:
+		return r.tracks[1]
+	default:
+		return nil
+	}
+}
+
+func (r *RecordPlayRecorder) onHangup(msg jwsapi.Message) {
+	if r.rtc.PC != nil {
+		r.rtc.PC.Close()
+	}
+}
+
+func (r *RecordPlayRecorder) onWebrtcup(msg jwsapi.Message) {
+	log.Info("webrtcup", logging.F("recorder", r.ID()))
+}
+
+func (r *RecordPlayRecorder) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("recorder", r.ID()), logging.F("state", state.String()))
+}
+
+func (r *RecordPlayRecorder) startSender(sender *webrtc.RTPSender) {
+	for r.rtc.Ctx.Err() == nil {
+		packets, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		r.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
+
+//RecordPlayPlayer replay recording of janus-gateway record&play plugin, flow of StreamingViewer
+//
+//	p := recordplay.NewRecordPlayPlayer(ctx, api, h, id, jrecordplay.WithPlayerStatus(onStatus))
+//	p.SetOption(recordplay.WithRecordPlayPlayerVideoTrack(onVideo))
+//	p.Start()
+type RecordPlayPlayer struct {
+	rtc     *rtcsession.Session
+	jPlayer *jrecordplay.Player
+
+	onAudioTrack func(context.Context, *webrtc.Track)
+	onVideoTrack func(context.Context, *webrtc.Track)
+}
+
+//RecordPlayPlayerOption option for RecordPlayPlayer
+type RecordPlayPlayerOption func(*RecordPlayPlayer)
+
+//WithRecordPlayPlayerAudioTrack using to setting audio track callback
+func WithRecordPlayPlayerAudioTrack(callback func(context.Context, *webrtc.Track)) RecordPlayPlayerOption {
+	return func(p *RecordPlayPlayer) {
+		p.onAudioTrack = callback
+	}
+}
+
+//WithRecordPlayPlayerVideoTrack using to setting video track callback
+func WithRecordPlayPlayerVideoTrack(callback func(context.Context, *webrtc.Track)) RecordPlayPlayerOption {
+	return func(p *RecordPlayPlayer) {
+		p.onVideoTrack = callback
+	}
+}
+
+//WithRecordPlayPlayerConfigure set webrtc configure
+func WithRecordPlayPlayerConfigure(configure webrtc.Configuration) RecordPlayPlayerOption {
+	return func(p *RecordPlayPlayer) {
+		p.rtc.Configure = configure
+	}
+}
+
+//WithRecordPlayPlayerStats report stats every interval
+func WithRecordPlayPlayerStats(interval time.Duration, callback func(Stats)) RecordPlayPlayerOption {
+	return func(p *RecordPlayPlayer) {
+		p.rtc.StatsInterval = interval
+		p.rtc.OnStats = callback
+	}
+}
+
+//NewRecordPlayPlayer new player of recording id, h is handle of janus.plugin.recordplay
+//jrecordplay.WithPlayerStatus for done of playout
+func NewRecordPlayPlayer(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, id uint64, opts ...jrecordplay.PlayerOption) *RecordPlayPlayer {
+	p := &RecordPlayPlayer{
+		jPlayer: jrecordplay.NewPlayer(ctx, h, id, opts...),
+	}
+	p.rtc = rtcsession.New(ctx, api, h, p.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(p.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(p.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(p.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(p.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(p.rtc.OnSlowLink))
+
+	return p
+}
+
+//Object return jrecordplay.Player
+func (p *RecordPlayPlayer) Object() *jrecordplay.Player {
+	return p.jPlayer
+}
+
+//ID return id info
+func (p *RecordPlayPlayer) ID() string {
+	return fmt.Sprintf("[Playout.%d]", p.jPlayer.ID())
+}
+
+//Stats return statistics snapshot
+func (p *RecordPlayPlayer) Stats() Stats {
+	return p.rtc.Stats()
+}
+
+//SetOption set option, for callback
+func (p *RecordPlayPlayer) SetOption(opts ...RecordPlayPlayerOption) *RecordPlayPlayer {
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+//Start play recording, answer offer of janus
+func (p *RecordPlayPlayer) Start(opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := p.rtc.StartSpan(p.rtc.Ctx, "recordplay.RecordPlayPlayer.Start", attrKeyRecording.Int64(int64(p.jPlayer.ID())))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	p.rtc.SetTraceLink(ctx)
+
+	offer, err := p.jPlayer.PlayContext(ctx, opts...)
+	if err != nil {
+		return errors.Wrap(err, "play")
+	}
+
+	answer, err := p.rtc.RecvAnswer(ctx, offer, p.onTrack, p.onPeerConnectionState)
+	if err != nil {
+		return err
+	}
+	pc := p.rtc.PC
+
+	err = p.jPlayer.StartContext(ctx, answer)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "jrecordplay.Player.Start")
+	}
+
+	go p.rtc.DoRemoteCandidate(p.rtc.RemoteCandidates)
+	go p.rtc.DoStats(pc)
+	return nil
+}
+
+//Stop stop playout, close PeerConnection
+func (p *RecordPlayPlayer) Stop() error {
+	if p.rtc.PC != nil {
+		p.rtc.PC.Close()
+	}
+	return p.jPlayer.Stop()
+}
+
+//ReadRTP read rtp from track and update stats
+//audio/video track callback should using this instead of track.ReadRTP
+func (p *RecordPlayPlayer) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	p.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+//RequestKeyFrame send PLI to janus for video track
+func (p *RecordPlayPlayer) RequestKeyFrame() error {
+	if p.rtc.PC == nil {
+		return errors.New("not started")
+	}
+	ssrc := p.rtc.Collector.Snapshot().Video.SSRC
+	if ssrc == 0 {
+		return errors.New("video not received")
+	}
+	packets := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}}
+	err := p.rtc.PC.WriteRTCP(packets)
+	if err == nil {
+		p.rtc.Collector.OnSendRTCP(packets)
+	}
+	return err
+}
+
+func (p *RecordPlayPlayer) onHangup(msg jwsapi.Message) {
+	if p.rtc.PC != nil {
+		p.rtc.PC.Close()
+	}
+}
+
+func (p *RecordPlayPlayer) onWebrtcup(msg jwsapi.Message) {
+	if p.rtc.PC == nil {
+		return
+	}
+	for _, tr := range p.rtc.PC.GetTransceivers() {
+		if sender := tr.Sender(); sender != nil {
+			go rtcsession.DrainSender(p.rtc.Ctx, sender)
+		}
+	}
+}
+
+func (p *RecordPlayPlayer) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("player", p.ID()), logging.F("state", state.String()))
+}
+
+func (p *RecordPlayPlayer) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+
+	log.Info("onTrack", logging.F("player", p.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+
+	go p.startReceiver(receiver)
+
+	if codec := track.Codec(); codec != nil {
+		p.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
+	switch track.Kind() {
+	case webrtc.RTPCodecTypeAudio:
+		if p.onAudioTrack != nil {
+			p.onAudioTrack(p.rtc.Ctx, track)
+			return
+		}
+	case webrtc.RTPCodecTypeVideo:
+		if p.onVideoTrack != nil {
+			p.onVideoTrack(p.rtc.Ctx, track)
+			return
+		}
+	}
+
+	//no callback for user
+	for p.rtc.Ctx.Err() == nil {
+		if _, err := p.ReadRTP(track); err != nil {
+			return
+		}
+	}
+}
+
+func (p *RecordPlayPlayer) startReceiver(receiver *webrtc.RTPReceiver) {
+	for p.rtc.Ctx.Err() == nil {
+		packets, err := receiver.ReadRTCP()
+		if err != nil {
+			return
+		}
+		p.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
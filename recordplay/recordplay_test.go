@@ -0,0 +1,99 @@
+package recordplay_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jrecordplay"
+	"github.com/newzai/janus-go/recordplay"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestRecordPlay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	rp := janustest.NewRecordPlay()
+	s.RegisterPlugin(janustest.RecordPlayPlugin, rp)
+
+	//record audio until the fake keeps packets
+	recorder := recordplay.NewRecordPlayRecorder(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jrecordplay.Plugin))
+	if err := recorder.Record("demo", jrecordplay.WithMessageOptionID(1234)); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if id := recorder.Object().ID(); id != 1234 {
+		t.Errorf("id of recording = %d, want 1234", id)
+	}
+	deadline := time.After(10 * time.Second)
+	track := recorder.GetTrack(webrtc.RTPCodecTypeAudio)
+	for seq := uint32(1); rp.Packets(1234) < 10; seq++ {
+		err := track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), Timestamp: seq * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case <-deadline:
+			t.Fatal("rtp of recorder is not recorded")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	//recording is listed when stopped
+	list, err := jrecordplay.List(janustest.Attach(t, ctx, s, jrecordplay.Plugin))
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list = %v, %v, want 1 recording", list, err)
+	}
+	if list[0].ID() != 1234 || list[0].Name() != "demo" || !list[0].Audio() || list[0].AudioCodec() != "opus" {
+		t.Errorf("recording = %v", list[0].Message)
+	}
+
+	packets := make(chan *rtp.Packet, 64)
+	status := make(chan string, 16)
+	player := recordplay.NewRecordPlayPlayer(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jrecordplay.Plugin), 1234, jrecordplay.WithPlayerStatus(func(st string) {
+		status <- st
+	}))
+	player.SetOption(recordplay.WithRecordPlayPlayerAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := player.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := player.Start(); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	defer player.Stop()
+	select {
+	case packet := <-packets:
+		if string(packet.Payload) != "\xf8\xff\xfe" {
+			t.Errorf("payload = %x", packet.Payload)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("rtp of recording is not played")
+	}
+	//done without transaction is routed to status callback
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case st := <-status:
+			if st == jrecordplay.StatusDone {
+				return
+			}
+		case <-timeout:
+			t.Fatal("done is not received")
+		}
+	}
+}
@@ -0,0 +1,366 @@
+//Package sip pion clients of janus sip plugin, see jwsapi/jplugin/jsip
+package sip
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jsip"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("sip")
+
+//span attributes
+const attrKeySIPURI = attribute.Key("janus.sip.uri")
+
+//Track track
+type Track = rtcsession.Track
+
+//Stats statistics snapshot of a session
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//sipCodecs audio codecs of incoming call accepted by SIPCall
+var sipCodecs = []string{"opus", "pcmu", "pcma", "g722"}
+
+//SIPCall audio call of janus-gateway sip plugin, send audio to phone, recv audio of phone
+//
+//	c := sip.NewSIPCall(ctx, api, h)
+//	c.Register("sip:alice@example.com", jsip.WithMessageOptionSecret("xx"))
+//	c.Call("sip:+8613800000000@example.com")
+//	c.GetTrack().WriteRTP(packet)
+type SIPCall struct {
+	rtc     *rtcsession.Session
+	jClient *jsip.Client
+	track   *Track
+	pt      uint8
+
+	onAudioTrack func(context.Context, *webrtc.Track)
+}
+
+//SIPCallOption option for SIPCall
+type SIPCallOption func(*SIPCall)
+
+//WithSIPCallAudioTrack using to setting audio track of phone callback
+func WithSIPCallAudioTrack(callback func(context.Context, *webrtc.Track)) SIPCallOption {
+	return func(c *SIPCall) {
+		c.onAudioTrack = callback
+	}
+}
+
+//WithSIPCallPayloadType payload type of audio for Call, api must support it, default opus(111)
+//eg: webrtc.DefaultPayloadTypePCMU with api only PCMU for PSTN
+func WithSIPCallPayloadType(pt uint8) SIPCallOption {
+	return func(c *SIPCall) {
+		c.pt = pt
+	}
+}
+
+//WithSIPCallConfigure set webrtc configure
+func WithSIPCallConfigure(configure webrtc.Configuration) SIPCallOption {
+	return func(c *SIPCall) {
+		c.rtc.Configure = configure
+	}
+}
+
+//WithSIPCallStats report stats every interval
+func WithSIPCallStats(interval time.Duration, callback func(Stats)) SIPCallOption {
+	return func(c *SIPCall) {
+		c.rtc.StatsInterval = interval
+		c.rtc.OnStats = callback
+	}
+}
+
+//NewSIPCall new sip call, h is handle of janus.plugin.sip
+//jsip.WithClientEvent for incomingcall, hangup... then Accept offer of incomingcall
+func NewSIPCall(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, opts ...jsip.ClientOption) *SIPCall {
+	c := &SIPCall{
+		jClient: jsip.NewClient(ctx, h, opts...),
+		pt:      webrtc.DefaultPayloadTypeOpus,
+	}
+	c.rtc = rtcsession.New(ctx, api, h, c.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(c.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(c.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(c.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(c.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(c.rtc.OnSlowLink))
+
+	//PeerConnection is changed by every call
+	go c.rtc.DoRemoteCandidate(c.rtc.RemoteCandidates)
+	return c
+}
+
+//Object return jsip.Client
+func (c *SIPCall) Object() *jsip.Client {
+	return c.jClient
+}
+
+//ID return id info
+func (c *SIPCall) ID() string {
+	return fmt.Sprintf("[SIP.%s]", c.jClient.Username())
+}
+
+//Stats return statistics snapshot
+func (c *SIPCall) Stats() Stats {
+	return c.rtc.Stats()
+}
+
+//SetOption set option
+func (c *SIPCall) SetOption(opts ...SIPCallOption) *SIPCall {
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+//Register register username(sip uri), jsip.WithMessageOptionSecret, jsip.WithMessageOptionProxy... for other params
+func (c *SIPCall) Register(username string, opts ...jwsapi.MessageOption) error {
+	return c.jClient.Register(username, opts...)
+}
+
+//Call call uri with audio PeerConnection, return when accepted, *jsip.Error if rejected, eg: 404, 486
+func (c *SIPCall) Call(uri string, opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := c.rtc.StartSpan(c.rtc.Ctx, "sip.SIPCall.Call", attrKeySIPURI.String(uri))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	c.rtc.SetTraceLink(ctx)
+
+	pc, sender, err := c.newPeerConnection(ctx, c.rtc.API, c.pt)
+	if err != nil {
+		return err
+	}
+
+	var offer webrtc.SessionDescription
+	err = c.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateOffer")
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	answer, err := c.jClient.CallContext(ctx, uri, offer.SDP, opts...)
+	if err != nil {
+		pc.Close()
+		return err
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+
+	go c.startSender(sender)
+	go c.rtc.DoStats(pc)
+	return nil
+}
+
+//Accept accept incoming call, offer is sdp of jsip.EventIncomingCall
+//audio codec is the first of offer in opus, pcmu, pcma, g722
+func (c *SIPCall) Accept(offer string, opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := c.rtc.StartSpan(c.rtc.Ctx, "sip.SIPCall.Accept")
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	c.rtc.SetTraceLink(ctx)
+
+	pt, ok := rtcsession.OfferPayloadType(offer, webrtc.RTPCodecTypeAudio, sipCodecs...)
+	if !ok {
+		return errors.New("no audio codec of offer")
+	}
+	//answer janus-gateway, explicit dtls client(setup:active) match the role when janus-gateway is ice-lite
+	setting := rtcsession.DefaultSettingEngine()
+	setting.SetAnsweringDTLSRole(webrtc.DTLSRoleClient)
+	api := rtcsession.NewAPI(offer, setting, sipCodecs...)
+
+	pc, sender, err := c.newPeerConnection(ctx, api, pt)
+	if err != nil {
+		return err
+	}
+
+	err = c.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  offer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+	var answer webrtc.SessionDescription
+	err = c.rtc.Trace(ctx, "webrtc.CreateAnswer", func() (err error) {
+		answer, err = pc.CreateAnswer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateAnswer")
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(answer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	if err = c.jClient.Accept(answer.SDP, opts...); err != nil {
+		pc.Close()
+		return errors.Wrap(err, "accept")
+	}
+
+	go c.startSender(sender)
+	go c.rtc.DoStats(pc)
+	return nil
+}
+
+//Hangup hangup current call, close PeerConnection
+func (c *SIPCall) Hangup() error {
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+	return c.jClient.Hangup()
+}
+
+//DTMF send digit by SIP INFO, duration 0 is default of janus-gateway
+func (c *SIPCall) DTMF(digit string, duration time.Duration) error {
+	return c.jClient.DTMF(digit, duration)
+}
+
+//GetTrack return audio track to phone, nil before Call or Accept
+func (c *SIPCall) GetTrack() *Track {
+	return c.track
+}
+
+//ReadRTP read rtp from track of phone and update stats
+//audio track callback should using this instead of track.ReadRTP
+func (c *SIPCall) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	c.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+//newPeerConnection PeerConnection with audio track of payload type
+func (c *SIPCall) newPeerConnection(ctx context.Context, api *webrtc.API, pt uint8) (*webrtc.PeerConnection, *webrtc.RTPSender, error) {
+	var pc *webrtc.PeerConnection
+	err := c.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = api.NewPeerConnection(c.rtc.Configure)
+		return err
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewPeerConnection")
+	}
+
+	pc.OnTrack(c.onTrack)
+	pc.OnICECandidate(c.rtc.OnICECandidate)
+	pc.OnConnectionStateChange(c.onPeerConnectionState)
+
+	track, err := pc.NewTrack(pt, rand.Uint32(), "audio", "sipA0")
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "NewTrack(Audio)")
+	}
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "AddTrack(Audio)")
+	}
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+	c.rtc.PC = pc
+	c.track = rtcsession.NewTrack(track, c.rtc.Collector)
+	return pc, sender, nil
+}
+
+func (c *SIPCall) onHangup(msg jwsapi.Message) {
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+}
+
+func (c *SIPCall) onWebrtcup(msg jwsapi.Message) {
+	log.Info("webrtcup", logging.F("sip", c.ID()))
+}
+
+func (c *SIPCall) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("sip", c.ID()), logging.F("state", state.String()))
+}
+
+func (c *SIPCall) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+
+	log.Info("onTrack", logging.F("sip", c.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+
+	go c.startReceiver(receiver)
+
+	if codec := track.Codec(); codec != nil {
+		c.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
+	if c.onAudioTrack != nil {
+		c.onAudioTrack(c.rtc.Ctx, track)
+		return
+	}
+
+	//no callback for user
+	for c.rtc.Ctx.Err() == nil {
+		if _, err := c.ReadRTP(track); err != nil {
+			return
+		}
+	}
+}
+
+func (c *SIPCall) startSender(sender *webrtc.RTPSender) {
+	for c.rtc.Ctx.Err() == nil {
+		packets, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		c.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
+
+func (c *SIPCall) startReceiver(receiver *webrtc.RTPReceiver) {
+	for c.rtc.Ctx.Err() == nil {
+		packets, err := receiver.ReadRTCP()
+		if err != nil {
+			return
+		}
+		c.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
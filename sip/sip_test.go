@@ -0,0 +1,113 @@
+package sip_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jsip"
+	"github.com/newzai/janus-go/sip"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestSIPCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.SIPPlugin, janustest.NewSIP(
+		janustest.WithSIPAccount("sip:alice@example.com", "secret"),
+		janustest.WithSIPAccount("sip:bob@example.com", "secret"),
+	))
+
+	//registration_failed is *jsip.Error with sip code
+	alice := sip.NewSIPCall(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jsip.Plugin))
+	err := alice.Register("sip:alice@example.com", jsip.WithMessageOptionSecret("wrong"))
+	var sipErr *jsip.Error
+	if !errors.As(err, &sipErr) || sipErr.Code != 403 {
+		t.Errorf("register with wrong secret: err = %v, want 403", err)
+	}
+	if err := alice.Register("sip:alice@example.com", jsip.WithMessageOptionSecret("secret")); err != nil {
+		t.Fatalf("register alice: %v", err)
+	}
+	//error of plugin is *jplugin.Error
+	err = alice.Register("sip:alice@example.com", jsip.WithMessageOptionSecret("secret"))
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.SIPErrorAlreadyRegistered {
+		t.Errorf("register again: err = %v, want %d", err, janustest.SIPErrorAlreadyRegistered)
+	}
+	err = alice.Call("sip:nobody@example.com")
+	if !errors.As(err, &sipErr) || sipErr.Code != 404 {
+		t.Errorf("call of unregistered uri: err = %v, want 404", err)
+	}
+
+	packets := make(chan *rtp.Packet, 16)
+	incoming := make(chan jsip.Event, 1)
+	bob := sip.NewSIPCall(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jsip.Plugin), jsip.WithClientEvent(func(e jsip.Event) {
+		if e.Type == jsip.EventIncomingCall {
+			incoming <- e
+		}
+	}))
+	bob.SetOption(sip.WithSIPCallAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := bob.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := bob.Register("sip:bob@example.com", jsip.WithMessageOptionSecret("secret")); err != nil {
+		t.Fatalf("register bob: %v", err)
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		select {
+		case e := <-incoming:
+			if e.Username != "sip:alice@example.com" || e.SDPType != "offer" {
+				t.Errorf("incomingcall = %+v", e)
+			}
+			accepted <- bob.Accept(e.SDP)
+		case <-time.After(5 * time.Second):
+			accepted <- errors.New("incomingcall is not received")
+		}
+	}()
+	if err := alice.Call("sip:bob@example.com"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if err := <-accepted; err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer alice.Hangup()
+	if alice.Object().CallID() == "" {
+		t.Error("Call-ID of accepted call is empty")
+	}
+
+	deadline := time.After(10 * time.Second)
+	track := alice.GetTrack()
+	for seq := uint32(1); ; seq++ {
+		err := track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), Timestamp: seq * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case <-packets:
+			if stats := bob.Stats(); stats.Audio.Packets == 0 {
+				t.Errorf("stats of audio = %+v", stats.Audio)
+			}
+			return
+		case <-deadline:
+			t.Fatal("audio of call is not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,533 @@
+package streaming
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jstreaming"
+	"github.com/newzai/janus-go/logging"
+	"github.com/newzai/janus-go/videoroom"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pion/webrtc/v2/pkg/media/rtpdump"
+	"github.com/pkg/errors"
+)
+
+//senderReportInterval interval of rtcp sender report to mountpoint
+const senderReportInterval = 5 * time.Second
+
+//RTPStream destination of audio or video, port of rtp mountpoint
+type RTPStream struct {
+	Port      int
+	RTCPPort  int    //0, no rtcp
+	PT        uint8  //payload type of mountpoint, audiopt or videopt
+	ClockRate uint32 //0, 48000 for audio, 90000 for video
+	SSRC      uint32 //0, random
+}
+
+//MountpointStream stream of kind(audio,video) for created rtp mountpoint, nil if media is nil or port not found
+//media is the config of create, mp is returned by jstreaming.Create
+func MountpointStream(mp *jstreaming.Mountpoint, kind string, media *jstreaming.RTPMedia) *RTPStream {
+	if media == nil {
+		return nil
+	}
+	port := mp.Port(kind)
+	if port == 0 {
+		port = media.Port
+	}
+	if port == 0 {
+		return nil
+	}
+	stream := &RTPStream{
+		Port:     port,
+		RTCPPort: mp.RTCPPort(kind),
+		PT:       media.PT,
+	}
+	if stream.RTCPPort == 0 {
+		stream.RTCPPort = media.RTCPPort
+	}
+	//opus/48000/2
+	if parts := strings.Split(media.RTPMap, "/"); len(parts) > 1 {
+		if clockRate, err := strconv.ParseUint(parts[1], 10, 32); err == nil {
+			stream.ClockRate = uint32(clockRate)
+		}
+	}
+	return stream
+}
+
+//RTPSource push rtp of file or track to rtp mountpoint of janus-gateway streaming plugin
+//ssrc and payload type are rewritten to RTPStream, sequence and timestamp are continuous when input ssrc changed
+//
+//	mp, _ := jstreaming.Create(h, config)
+//	src, _ := streaming.NewRTPSource(ctx, host, streaming.MountpointStream(mp, "audio", config.Audio), streaming.MountpointStream(mp, "video", config.Video))
+//	src.ForwardSubscriber(subscriber)
+type RTPSource struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	host   string
+
+	srtpSuite  int
+	srtpCrypto string
+
+	mu         sync.Mutex
+	onKeyFrame func()
+
+	senders map[webrtc.RTPCodecType]*rtpSender
+}
+
+//RTPSourceOption option for RTPSource
+type RTPSourceOption func(*RTPSource)
+
+//WithRTPSourceSRTP send srtp, same as srtpsuite and srtpcrypto of mountpoint
+//only suite 80 (AES_CM_128_HMAC_SHA1_80) is supported
+func WithRTPSourceSRTP(suite int, crypto string) RTPSourceOption {
+	return func(s *RTPSource) {
+		s.srtpSuite = suite
+		s.srtpCrypto = crypto
+	}
+}
+
+//WithRTPSourceKeyFrame callback when janus-gateway request key frame (PLI,FIR) by rtcp port of video
+func WithRTPSourceKeyFrame(callback func()) RTPSourceOption {
+	return func(s *RTPSource) {
+		s.onKeyFrame = callback
+	}
+}
+
+//NewRTPSource create source for mountpoint at host, audio or video is nil if not streamed
+func NewRTPSource(ctx context.Context, host string, audio *RTPStream, video *RTPStream, opts ...RTPSourceOption) (*RTPSource, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &RTPSource{
+		ctx:     ctx,
+		cancel:  cancel,
+		host:    host,
+		senders: make(map[webrtc.RTPCodecType]*rtpSender),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var key []byte
+	if s.srtpSuite > 0 || s.srtpCrypto != "" {
+		var err error
+		if key, err = rtcsession.SRTPMasterKey(s.srtpSuite, s.srtpCrypto); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	streams := map[webrtc.RTPCodecType]*RTPStream{
+		webrtc.RTPCodecTypeAudio: audio,
+		webrtc.RTPCodecTypeVideo: video,
+	}
+	for kind, stream := range streams {
+		if stream == nil {
+			continue
+		}
+		sender, err := newRTPSender(host, kind, *stream, key)
+		if err != nil {
+			s.Close()
+			return nil, errors.Wrapf(err, "%s", kind)
+		}
+		s.senders[kind] = sender
+	}
+	if len(s.senders) == 0 {
+		cancel()
+		return nil, errors.New("no audio or video")
+	}
+
+	for _, sender := range s.senders {
+		if sender.rtcpConn != nil {
+			go s.doRTCP(sender)
+		}
+	}
+	return s, nil
+}
+
+//SetOption set option, for callback
+func (s *RTPSource) SetOption(opts ...RTPSourceOption) *RTPSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+//WriteRTP write packet of kind to mountpoint, packet is not modified
+func (s *RTPSource) WriteRTP(kind webrtc.RTPCodecType, packet *rtp.Packet) error {
+	sender, ok := s.senders[kind]
+	if !ok {
+		return errors.Errorf("no %s stream", kind)
+	}
+	return sender.writeRTP(packet)
+}
+
+//Forward read rtp by read and write to mountpoint, until read return error or source closed
+func (s *RTPSource) Forward(kind webrtc.RTPCodecType, read func() (*rtp.Packet, error)) error {
+	if _, ok := s.senders[kind]; !ok {
+		return errors.Errorf("no %s stream", kind)
+	}
+	for s.ctx.Err() == nil {
+		packet, err := read()
+		if err != nil {
+			return err
+		}
+		if err := s.WriteRTP(kind, packet); err != nil {
+			return err
+		}
+	}
+	return s.ctx.Err()
+}
+
+//ForwardTrack forward remote track of pion to mountpoint
+func (s *RTPSource) ForwardTrack(track *webrtc.Track) error {
+	return s.Forward(track.Kind(), track.ReadRTP)
+}
+
+//ForwardSubscriber forward audio/video track of subscriber to mountpoint, call before sub.Start
+//key frame request of janus-gateway is sent to publisher by sub.RequestKeyFrame if WithRTPSourceKeyFrame is not set
+func (s *RTPSource) ForwardSubscriber(sub *videoroom.Subscriber) {
+	forward := func(ctx context.Context, track *webrtc.Track) {
+		err := s.Forward(track.Kind(), func() (*rtp.Packet, error) {
+			return sub.ReadRTP(track)
+		})
+		log.Info("forward end", logging.F("subscriber", sub.ID()), logging.F("kind", track.Kind().String()), logging.F("err", err))
+	}
+	opts := []videoroom.SubscriberOption{}
+	if _, ok := s.senders[webrtc.RTPCodecTypeAudio]; ok {
+		opts = append(opts, videoroom.WithSubscriberAudioTrack(forward))
+	}
+	if _, ok := s.senders[webrtc.RTPCodecTypeVideo]; ok {
+		opts = append(opts, videoroom.WithSubscriberVideoTrack(forward))
+	}
+	sub.SetOption(opts...)
+
+	s.mu.Lock()
+	if s.onKeyFrame == nil {
+		s.onKeyFrame = func() {
+			if err := sub.RequestKeyFrame(); err != nil {
+				log.Debug("RequestKeyFrame", logging.F("subscriber", sub.ID()), logging.F("err", err))
+			}
+		}
+	}
+	s.mu.Unlock()
+}
+
+//PlayFile send rtp of rtpdump file (rtpplay format) to mountpoint, paced by offset of packet
+//packet with audioPT is sent to audio, videoPT to video, others are dropped
+//loop is true, play from start again at the end of file, until source closed
+func (s *RTPSource) PlayFile(filename string, audioPT uint8, videoPT uint8, loop bool) error {
+	for {
+		err := s.playFile(filename, audioPT, videoPT)
+		if err != nil || !loop {
+			return err
+		}
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		//timestamp and sequence of file start again
+		for _, sender := range s.senders {
+			sender.resync()
+		}
+	}
+}
+
+func (s *RTPSource) playFile(filename string, audioPT uint8, videoPT uint8) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, _, err := rtpdump.NewReader(f)
+	if err != nil {
+		return errors.Wrap(err, "rtpdump")
+	}
+
+	start := time.Now()
+	for {
+		packet, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "rtpdump")
+		}
+		if packet.IsRTCP {
+			continue
+		}
+		if wait := time.Until(start.Add(packet.Offset)); wait > 0 {
+			select {
+			case <-s.ctx.Done():
+				return s.ctx.Err()
+			case <-time.After(wait):
+			}
+		} else if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+
+		p := &rtp.Packet{}
+		if err := p.Unmarshal(packet.Payload); err != nil {
+			continue
+		}
+		var kind webrtc.RTPCodecType
+		switch p.PayloadType {
+		case audioPT:
+			kind = webrtc.RTPCodecTypeAudio
+		case videoPT:
+			kind = webrtc.RTPCodecTypeVideo
+		default:
+			continue
+		}
+		if _, ok := s.senders[kind]; !ok {
+			continue
+		}
+		if err := s.WriteRTP(kind, p); err != nil {
+			return err
+		}
+	}
+}
+
+//Close stop forward, close udp
+func (s *RTPSource) Close() error {
+	s.cancel()
+	for _, sender := range s.senders {
+		sender.close()
+	}
+	return nil
+}
+
+func (s *RTPSource) doRTCP(sender *rtpSender) {
+	go func() {
+		ticker := time.NewTicker(senderReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := sender.writeSenderReport(); err != nil {
+					log.Debug("sender report", logging.F("kind", sender.kind.String()), logging.F("err", err))
+				}
+			}
+		}
+	}()
+
+	for s.ctx.Err() == nil {
+		packets, err := sender.readRTCP()
+		if err != nil {
+			return
+		}
+		for _, packet := range packets {
+			if !isKeyFrameRequest(packet) {
+				continue
+			}
+			s.mu.Lock()
+			callback := s.onKeyFrame
+			s.mu.Unlock()
+			if callback != nil {
+				callback()
+			}
+		}
+	}
+}
+
+//isKeyFrameRequest PLI or FIR, FIR is raw packet of pion/rtcp (PSFB, FMT=4)
+func isKeyFrameRequest(packet rtcp.Packet) bool {
+	switch p := packet.(type) {
+	case *rtcp.PictureLossIndication:
+		return true
+	case *rtcp.RawPacket:
+		header := p.Header()
+		return header.Type == rtcp.TypePayloadSpecificFeedback && header.Count == 4
+	}
+	return false
+}
+
+//rtpSender rewrite and send rtp of one kind
+type rtpSender struct {
+	kind     webrtc.RTPCodecType
+	stream   RTPStream
+	conn     *net.UDPConn
+	rtcpConn *net.UDPConn
+	encrypt  *srtp.Context
+	decrypt  *srtp.Context
+
+	mu        sync.Mutex
+	started   bool
+	rebase    bool //rebase sequence and timestamp at next packet
+	inSSRC    uint32
+	seqOffset uint16
+	tsOffset  uint32
+	lastSeq   uint16
+	lastTS    uint32
+	lastTime  time.Time
+	packets   uint32
+	octets    uint32
+}
+
+func newRTPSender(host string, kind webrtc.RTPCodecType, stream RTPStream, key []byte) (sender *rtpSender, err error) {
+	if stream.SSRC == 0 {
+		stream.SSRC = rand.Uint32()
+	}
+	if stream.ClockRate == 0 {
+		stream.ClockRate = 90000
+		if kind == webrtc.RTPCodecTypeAudio {
+			stream.ClockRate = 48000
+		}
+	}
+	sender = &rtpSender{
+		kind:   kind,
+		stream: stream,
+	}
+	defer func() {
+		if err != nil {
+			sender.close()
+		}
+	}()
+
+	if sender.conn, err = dialUDP(host, stream.Port); err != nil {
+		return nil, err
+	}
+	if stream.RTCPPort > 0 {
+		if sender.rtcpConn, err = dialUDP(host, stream.RTCPPort); err != nil {
+			return nil, err
+		}
+	}
+	if key != nil {
+		//srtp context is one way, encrypt for rtp and rtcp, decrypt for rtcp feedback
+		if sender.encrypt, err = srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+			return nil, errors.Wrap(err, "srtp")
+		}
+		if sender.decrypt, err = srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80); err != nil {
+			return nil, errors.Wrap(err, "srtp")
+		}
+	}
+	return sender, nil
+}
+
+func dialUDP(host string, port int) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	return net.DialUDP("udp", nil, addr)
+}
+
+//resync sequence and timestamp are continuous for next packet
+func (r *rtpSender) resync() {
+	r.mu.Lock()
+	r.rebase = true
+	r.mu.Unlock()
+}
+
+func (r *rtpSender) writeRTP(packet *rtp.Packet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.started || r.rebase || packet.SSRC != r.inSSRC {
+		if r.started {
+			//continue from last packet, timestamp advance by elapsed time
+			elapsed := uint32(now.Sub(r.lastTime).Seconds() * float64(r.stream.ClockRate))
+			r.seqOffset = r.lastSeq + 1 - packet.SequenceNumber
+			r.tsOffset = r.lastTS + elapsed - packet.Timestamp
+		}
+		r.started = true
+		r.rebase = false
+		r.inSSRC = packet.SSRC
+	}
+
+	out := *packet
+	out.SSRC = r.stream.SSRC
+	out.PayloadType = r.stream.PT
+	out.SequenceNumber = packet.SequenceNumber + r.seqOffset
+	out.Timestamp = packet.Timestamp + r.tsOffset
+
+	data, err := out.Marshal()
+	if err != nil {
+		return err
+	}
+	if r.encrypt != nil {
+		if data, err = r.encrypt.EncryptRTP(nil, data, nil); err != nil {
+			return errors.Wrap(err, "srtp")
+		}
+	}
+	if _, err := r.conn.Write(data); err != nil {
+		return err
+	}
+
+	r.lastSeq = out.SequenceNumber
+	r.lastTS = out.Timestamp
+	r.lastTime = now
+	r.packets++
+	r.octets += uint32(len(out.Payload))
+	return nil
+}
+
+func (r *rtpSender) writeSenderReport() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		return nil
+	}
+	now := time.Now()
+	data, err := rtcp.Marshal([]rtcp.Packet{&rtcp.SenderReport{
+		SSRC:        r.stream.SSRC,
+		NTPTime:     rtcsession.ToNTP(now),
+		RTPTime:     r.lastTS + uint32(now.Sub(r.lastTime).Seconds()*float64(r.stream.ClockRate)),
+		PacketCount: r.packets,
+		OctetCount:  r.octets,
+	}})
+	if err != nil {
+		return err
+	}
+	if r.encrypt != nil {
+		if data, err = r.encrypt.EncryptRTCP(nil, data, nil); err != nil {
+			return errors.Wrap(err, "srtcp")
+		}
+	}
+	_, err = r.rtcpConn.Write(data)
+	return err
+}
+
+//readRTCP read rtcp feedback of janus-gateway, only one goroutine
+func (r *rtpSender) readRTCP() ([]rtcp.Packet, error) {
+	buf := make([]byte, 1500)
+	for {
+		n, err := r.rtcpConn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		data := buf[:n]
+		if r.decrypt != nil {
+			if data, err = r.decrypt.DecryptRTCP(nil, data, nil); err != nil {
+				continue
+			}
+		}
+		packets, err := rtcp.Unmarshal(data)
+		if err != nil {
+			continue
+		}
+		return packets, nil
+	}
+}
+
+func (r *rtpSender) close() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	if r.rtcpConn != nil {
+		r.rtcpConn.Close()
+	}
+}
@@ -0,0 +1,81 @@
+package streaming_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jstreaming"
+	"github.com/newzai/janus-go/streaming"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestRTPSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.StreamingPlugin, janustest.NewStreaming())
+
+	config := &jstreaming.RTPConfig{
+		MountpointBase: jstreaming.MountpointBase{Name: "source"},
+		Audio:          &jstreaming.RTPMedia{PT: 111, RTPMap: "opus/48000/2"},
+	}
+	mp, err := jstreaming.Create(janustest.Attach(t, ctx, s, jstreaming.Plugin), config)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	stream := streaming.MountpointStream(mp, "audio", config.Audio)
+	if stream == nil || stream.PT != 111 || stream.ClockRate != 48000 {
+		t.Fatalf("stream = %+v", stream)
+	}
+	if streaming.MountpointStream(mp, "video", config.Video) != nil {
+		t.Error("stream of video without config")
+	}
+	src, err := streaming.NewRTPSource(ctx, "127.0.0.1", stream, nil)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	defer src.Close()
+
+	packets := make(chan *rtp.Packet, 16)
+	viewer := streaming.NewStreamingViewer(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jstreaming.Plugin), mp.ID())
+	viewer.SetOption(streaming.WithStreamingViewerAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := viewer.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := viewer.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer viewer.Stop()
+
+	deadline := time.After(10 * time.Second)
+	for seq := uint16(1); ; seq++ {
+		//payload type and ssrc are rewritten by source
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq) * 960, SSRC: 4321},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		}
+		if err := src.WriteRTP(webrtc.RTPCodecTypeAudio, packet); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case packet := <-packets:
+			if string(packet.Payload) != "\xf8\xff\xfe" {
+				t.Errorf("payload = %x", packet.Payload)
+			}
+			return
+		case <-deadline:
+			t.Fatal("rtp of source is not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
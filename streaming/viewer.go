@@ -0,0 +1,243 @@
+//Package streaming pion clients of janus streaming plugin, see jwsapi/jplugin/jstreaming
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jstreaming"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("streaming")
+
+//span attributes
+const attrKeyMountpoint = attribute.Key("janus.mountpoint")
+
+//Stats statistics snapshot of a session
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//StreamingViewer viewer of janus-gateway streaming mountpoint
+type StreamingViewer struct {
+	rtc     *rtcsession.Session
+	jViewer *jstreaming.Viewer
+
+	onAudioTrack func(context.Context, *webrtc.Track)
+	onVideoTrack func(context.Context, *webrtc.Track)
+}
+
+//StreamingViewerOption option for StreamingViewer
+type StreamingViewerOption func(*StreamingViewer)
+
+//WithStreamingViewerAudioTrack using to setting audio track callback
+func WithStreamingViewerAudioTrack(callback func(context.Context, *webrtc.Track)) StreamingViewerOption {
+	return func(v *StreamingViewer) {
+		v.onAudioTrack = callback
+	}
+}
+
+//WithStreamingViewerVideoTrack using to setting video track callback
+func WithStreamingViewerVideoTrack(callback func(context.Context, *webrtc.Track)) StreamingViewerOption {
+	return func(v *StreamingViewer) {
+		v.onVideoTrack = callback
+	}
+}
+
+//WithStreamingViewerConfigure set webrtc configure
+func WithStreamingViewerConfigure(configure webrtc.Configuration) StreamingViewerOption {
+	return func(v *StreamingViewer) {
+		v.rtc.Configure = configure
+	}
+}
+
+//WithStreamingViewerStats report stats every interval
+func WithStreamingViewerStats(interval time.Duration, callback func(Stats)) StreamingViewerOption {
+	return func(v *StreamingViewer) {
+		v.rtc.StatsInterval = interval
+		v.rtc.OnStats = callback
+	}
+}
+
+//NewStreamingViewer new viewer of mountpoint id, h is handle of janus.plugin.streaming
+func NewStreamingViewer(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, id uint64, opts ...jstreaming.ViewerOption) *StreamingViewer {
+	v := &StreamingViewer{
+		jViewer: jstreaming.NewViewer(ctx, h, id, opts...),
+	}
+	v.rtc = rtcsession.New(ctx, api, h, v.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(v.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(v.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(v.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(v.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(v.rtc.OnSlowLink))
+
+	return v
+}
+
+//Object return jstreaming.Viewer
+func (v *StreamingViewer) Object() *jstreaming.Viewer {
+	return v.jViewer
+}
+
+//ID return id for this viewer
+func (v *StreamingViewer) ID() string {
+	return fmt.Sprintf("[Mountpoint.%d]", v.jViewer.ID())
+}
+
+//Stats return statistics snapshot
+func (v *StreamingViewer) Stats() Stats {
+	return v.rtc.Stats()
+}
+
+//SetOption set option, for callback
+func (v *StreamingViewer) SetOption(opts ...StreamingViewerOption) *StreamingViewer {
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+//Start watch mountpoint, answer offer of janus
+//jstreaming.WithMessageOptionPin, jstreaming.WithMessageOptionVideo(false)... for other params
+func (v *StreamingViewer) Start(opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := v.rtc.StartSpan(v.rtc.Ctx, "streaming.StreamingViewer.Start", attrKeyMountpoint.Int64(int64(v.jViewer.ID())))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	v.rtc.SetTraceLink(ctx)
+
+	offer, err := v.jViewer.WatchContext(ctx, opts...)
+
+	if err != nil {
+		return errors.Wrap(err, "watch")
+	}
+
+	answer, err := v.rtc.RecvAnswer(ctx, offer, v.onTrack, v.onPeerConnectionState)
+	if err != nil {
+		return err
+	}
+	pc := v.rtc.PC
+
+	err = v.jViewer.StartContext(ctx, answer, true)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "jstreaming.Viewer.Start")
+	}
+
+	go v.rtc.DoRemoteCandidate(v.rtc.RemoteCandidates)
+	go v.rtc.DoStats(pc)
+	return nil
+}
+
+//Stop stop watch, close PeerConnection
+func (v *StreamingViewer) Stop() error {
+	if v.rtc.PC != nil {
+		v.rtc.PC.Close()
+	}
+	return v.jViewer.Stop()
+}
+
+//ReadRTP read rtp from track and update stats
+//audio/video track callback should using this instead of track.ReadRTP
+func (v *StreamingViewer) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	v.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+//RequestKeyFrame send PLI to janus for video track
+func (v *StreamingViewer) RequestKeyFrame() error {
+	if v.rtc.PC == nil {
+		return errors.New("not started")
+	}
+	ssrc := v.rtc.Collector.Snapshot().Video.SSRC
+	if ssrc == 0 {
+		return errors.New("video not received")
+	}
+	packets := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}}
+	err := v.rtc.PC.WriteRTCP(packets)
+	if err == nil {
+		v.rtc.Collector.OnSendRTCP(packets)
+	}
+	return err
+}
+
+func (v *StreamingViewer) onHangup(msg jwsapi.Message) {
+	if v.rtc.PC != nil {
+		v.rtc.PC.Close()
+	}
+}
+
+func (v *StreamingViewer) onWebrtcup(msg jwsapi.Message) {
+	if v.rtc.PC == nil {
+		return
+	}
+	for _, tr := range v.rtc.PC.GetTransceivers() {
+		if sender := tr.Sender(); sender != nil {
+			go rtcsession.DrainSender(v.rtc.Ctx, sender)
+		}
+	}
+}
+
+func (v *StreamingViewer) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("viewer", v.ID()), logging.F("state", state.String()))
+}
+
+func (v *StreamingViewer) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+
+	log.Info("onTrack", logging.F("viewer", v.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+
+	go v.startReceiver(receiver)
+
+	if codec := track.Codec(); codec != nil {
+		v.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
+	switch track.Kind() {
+	case webrtc.RTPCodecTypeAudio:
+		if v.onAudioTrack != nil {
+			v.onAudioTrack(v.rtc.Ctx, track)
+			return
+		}
+	case webrtc.RTPCodecTypeVideo:
+		if v.onVideoTrack != nil {
+			v.onVideoTrack(v.rtc.Ctx, track)
+			return
+		}
+	}
+
+	//no callback for user
+	for v.rtc.Ctx.Err() == nil {
+		if _, err := v.ReadRTP(track); err != nil {
+			return
+		}
+	}
+}
+
+func (v *StreamingViewer) startReceiver(receiver *webrtc.RTPReceiver) {
+	for v.rtc.Ctx.Err() == nil {
+		packets, err := receiver.ReadRTCP()
+		if err != nil {
+			return
+		}
+		v.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
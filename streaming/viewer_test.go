@@ -0,0 +1,98 @@
+package streaming_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jstreaming"
+	"github.com/newzai/janus-go/streaming"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestViewer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.StreamingPlugin, janustest.NewStreaming())
+
+	admin := janustest.Attach(t, ctx, s, jstreaming.Plugin)
+	mp, err := jstreaming.Create(admin, &jstreaming.RTPConfig{
+		MountpointBase: jstreaming.MountpointBase{Name: "test"},
+		Audio:          &jstreaming.RTPMedia{PT: 111, RTPMap: "opus/48000/2"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	list, err := jstreaming.List(admin)
+	if err != nil || len(list) != 1 || list[0].ID() != mp.ID() {
+		t.Fatalf("list = %v, %v, want mountpoint %d", list, err, mp.ID())
+	}
+	_, err = jstreaming.Info(admin, mp.ID()+1)
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.StreamingErrorNoSuchMountpoint {
+		t.Errorf("info of unknown mountpoint: err = %v, want %d", err, janustest.StreamingErrorNoSuchMountpoint)
+	}
+
+	packets := make(chan *rtp.Packet, 16)
+	status := make(chan string, 16)
+	h := janustest.Attach(t, ctx, s, jstreaming.Plugin)
+	viewer := streaming.NewStreamingViewer(ctx, janustest.NewAPI(), h, mp.ID(), jstreaming.WithViewerStatus(func(st string) {
+		status <- st
+	}))
+	viewer.SetOption(streaming.WithStreamingViewerAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := viewer.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := viewer.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer viewer.Stop()
+	//status event without transaction is routed to callback of jstreaming.Viewer
+	select {
+	case st := <-status:
+		if st != "started" {
+			t.Errorf("status = %s, want started", st)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("status event is not received")
+	}
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", mp.Port("audio")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	deadline := time.After(10 * time.Second)
+	for seq := uint16(1); ; seq++ {
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 111, SequenceNumber: seq, Timestamp: uint32(seq) * 960, SSRC: 1234},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		}
+		data, _ := packet.Marshal()
+		conn.Write(data)
+		select {
+		case <-packets:
+			if stats := viewer.Stats(); stats.Audio.Packets == 0 {
+				t.Errorf("stats of audio = %+v", stats.Audio)
+			}
+			return
+		case <-deadline:
+			t.Fatal("rtp of mountpoint is not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,469 @@
+//Package textroom pion client of janus textroom plugin, chat over data channel, see jwsapi/jplugin/jtextroom
+package textroom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jtextroom"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Named("textroom")
+
+//event type of Event
+const (
+	EventMessage      = "message"
+	EventAnnouncement = "announcement"
+	EventJoin         = "join"
+	EventLeave        = "leave"
+	EventKicked       = "kicked"
+	EventDestroyed    = "destroyed"
+)
+
+//Event incoming event of data channel
+type Event struct {
+	Type     string //EventMessage, EventAnnouncement, EventJoin...
+	Room     uint64
+	From     string //username of sender, message
+	Username string //join, leave, kicked
+	Display  string //join
+	Text     string //message, announcement
+	Date     string //message, announcement, eg: 2021-01-01T12:00:00+0800
+	Whisper  bool   //message is sent to me only
+}
+
+func newEvent(msg jwsapi.Message) Event {
+	e := Event{Whisper: msg.Bool("whisper")}
+	e.Type, _ = msg.String("textroom")
+	e.Room, _ = msg.Uint64("room")
+	e.From, _ = msg.String("from")
+	e.Username, _ = msg.String("username")
+	e.Display, _ = msg.String("display")
+	e.Text, _ = msg.String("text")
+	e.Date, _ = msg.String("date")
+	return e
+}
+
+//TextRoom textroom participant over pion data channel
+//setup/ack negotiate PeerConnection with data channel, then join, message, announcement, leave are sent over it
+//
+//	c := textroom.NewTextRoom(ctx, h)
+//	c.Setup()
+//	c.Join(room, "alice", "Alice")
+//	c.Send(room, "hello")
+//	for e := range c.Events() {
+//	}
+type TextRoom struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	handle    *jwsapi.Handle
+	setting   webrtc.SettingEngine
+	configure webrtc.Configuration
+	timeout   time.Duration
+
+	remoteCandidates chan webrtc.ICECandidateInit
+	opened           chan struct{}
+
+	mu           sync.Mutex
+	pc           *webrtc.PeerConnection
+	dc           *webrtc.DataChannel
+	transactions map[string]chan jwsapi.Message
+	events       chan Event
+	closed       bool
+}
+
+//TextRoomOption option for TextRoom
+type TextRoomOption func(*TextRoom)
+
+//WithTextRoomSettingEngine set pion setting engine, eg: port range, network types
+func WithTextRoomSettingEngine(setting webrtc.SettingEngine) TextRoomOption {
+	return func(c *TextRoom) {
+		c.setting = setting
+	}
+}
+
+//WithTextRoomConfigure set webrtc configure, eg: ice servers
+func WithTextRoomConfigure(configure webrtc.Configuration) TextRoomOption {
+	return func(c *TextRoom) {
+		c.configure = configure
+	}
+}
+
+//WithTextRoomTimeout timeout of data channel open and request, default 10s
+func WithTextRoomTimeout(timeout time.Duration) TextRoomOption {
+	return func(c *TextRoom) {
+		c.timeout = timeout
+	}
+}
+
+//WithTextRoomEventBuffer size of Events channel, default 64, event is dropped and logged if channel is full
+func WithTextRoomEventBuffer(size int) TextRoomOption {
+	return func(c *TextRoom) {
+		c.events = make(chan Event, size)
+	}
+}
+
+//NewTextRoom create client, h is handle of janus.plugin.textroom
+func NewTextRoom(ctx context.Context, h *jwsapi.Handle, opts ...TextRoomOption) *TextRoom {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &TextRoom{
+		ctx:              ctx,
+		cancel:           cancel,
+		handle:           h,
+		timeout:          10 * time.Second,
+		remoteCandidates: make(chan webrtc.ICECandidateInit, 8),
+		opened:           make(chan struct{}),
+		transactions:     make(map[string]chan jwsapi.Message),
+		events:           make(chan Event, 64),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	h.SetCallback(jwsapi.WithHandleTrickle(c.onTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(c.onHangup))
+	return c
+}
+
+//Handle return handle
+func (c *TextRoom) Handle() *jwsapi.Handle {
+	return c.handle
+}
+
+//Events incoming message, announcement, join, leave, kicked, destroyed of joined rooms
+//channel is closed by Close
+func (c *TextRoom) Events() <-chan Event {
+	return c.events
+}
+
+//Setup negotiate PeerConnection by setup and ack, return when data channel is open
+func (c *TextRoom) Setup() error {
+	return c.SetupContext(c.ctx)
+}
+
+//SetupContext negotiate PeerConnection, ctx using for cancel and trace
+//PeerConnection is closed if failed
+func (c *TextRoom) SetupContext(ctx context.Context) error {
+	rsp, err := jplugin.Call[struct{}](ctx, c.handle, jtextroom.Descriptor, jtextroom.SetupRequest, nil)
+	if err != nil {
+		return errors.Wrap(err, "setup")
+	}
+	if rsp.JSEP == nil {
+		return errors.New("not jsep")
+	}
+	if rsp.JSEP.Type != "offer" {
+		return errors.Errorf("jsep type %s", rsp.JSEP.Type)
+	}
+	offer := rsp.JSEP.SDP
+
+	//janus-gateway always offer, explicit dtls client(setup:active) also match ice-lite janus-gateway
+	setting := c.setting
+	setting.SetAnsweringDTLSRole(webrtc.DTLSRoleClient)
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(setting))
+	pc, err := api.NewPeerConnection(c.configure)
+	if err != nil {
+		return errors.Wrap(err, "NewPeerConnection")
+	}
+	c.mu.Lock()
+	c.pc = pc
+	c.mu.Unlock()
+	pc.OnDataChannel(c.onDataChannel)
+	pc.OnICECandidate(c.onICECandidate)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		log.Info("PeerConnectionState", logging.F("handle", c.handle.ID), logging.F("state", state.String()))
+	})
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateAnswer")
+	}
+	if err := pc.SetLocalDescription(answer); err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	//stop adding remote candidates and close pc if not open
+	stop := make(chan struct{})
+	abort := func() {
+		close(stop)
+		pc.Close()
+	}
+	go c.doRemoteCandidate(pc, stop)
+
+	_, err = jplugin.Call[struct{}](ctx, c.handle, jtextroom.Descriptor, jtextroom.AckRequest, &jplugin.JSEP{Type: "answer", SDP: answer.SDP})
+	if err != nil {
+		abort()
+		return errors.Wrap(err, "ack")
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case <-c.opened:
+		return nil
+	case <-timer.C:
+		abort()
+		return errors.New("data channel open timeout")
+	case <-ctx.Done():
+		abort()
+		return ctx.Err()
+	}
+}
+
+//Join join room with unique username, display is optional
+//jtextroom.WithMessageOptionPin, WithMessageOptionToken for other params
+//return participants of room
+func (c *TextRoom) Join(room uint64, username string, display string, opts ...jwsapi.MessageOption) ([]jtextroom.Participant, error) {
+	msg := jwsapi.Message{
+		"textroom": "join",
+		"room":     room,
+		"username": username,
+	}
+	if display != "" {
+		msg["display"] = display
+	}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	rsp, err := c.Request(msg)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Participants []jwsapi.Message `json:"participants"`
+	}
+	if err := jplugin.Decode(rsp, &result); err != nil {
+		return nil, err
+	}
+	parts := make([]jtextroom.Participant, 0, len(result.Participants))
+	for _, part := range result.Participants {
+		if part != nil {
+			parts = append(parts, jtextroom.Participant{Message: part})
+		}
+	}
+	return parts, nil
+}
+
+//Send send text to room, jtextroom.WithMessageOptionTo whisper to username(s)
+func (c *TextRoom) Send(room uint64, text string, opts ...jwsapi.MessageOption) error {
+	msg := jwsapi.Message{
+		"textroom": "message",
+		"room":     room,
+		"text":     text,
+	}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	_, err := c.Request(msg)
+	return err
+}
+
+//Announcement send announcement to room, jtextroom.WithMessageOptionSecret is required if room has secret
+func (c *TextRoom) Announcement(room uint64, text string, opts ...jwsapi.MessageOption) error {
+	msg := jwsapi.Message{
+		"textroom": "announcement",
+		"room":     room,
+		"text":     text,
+	}
+	for _, opt := range opts {
+		opt(msg)
+	}
+	_, err := c.Request(msg)
+	return err
+}
+
+//Leave leave room
+func (c *TextRoom) Leave(room uint64) error {
+	_, err := c.Request(jwsapi.Message{
+		"textroom": "leave",
+		"room":     room,
+	})
+	return err
+}
+
+//Request send request over data channel with transaction, wait response
+//textroom of msg is the request, eg: join, message, other requests of janus-gateway
+//error response is *jplugin.Error
+func (c *TextRoom) Request(msg jwsapi.Message) (jwsapi.Message, error) {
+	c.mu.Lock()
+	dc := c.dc
+	c.mu.Unlock()
+	if dc == nil {
+		return nil, errors.New("data channel not open")
+	}
+
+	tid := uuid.New().String()
+	msg["transaction"] = tid
+	rsp := make(chan jwsapi.Message, 1)
+	c.mu.Lock()
+	c.transactions[tid] = rsp
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.transactions, tid)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := dc.SendText(string(data)); err != nil {
+		return nil, errors.Wrap(err, "SendText")
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case result := <-rsp:
+		if name, _ := result.String("textroom"); name == "error" {
+			code, _ := result.Uint64("error_code")
+			reason, _ := result.String("error")
+			return nil, &jplugin.Error{Code: int(code), Reason: reason}
+		}
+		return result, nil
+	case <-timer.C:
+		return nil, errors.Errorf("%s timeout", msg["textroom"])
+	case <-c.ctx.Done():
+		return nil, c.ctx.Err()
+	}
+}
+
+//Close close PeerConnection and Events
+func (c *TextRoom) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	pc := c.pc
+	closed := c.closed
+	if !closed {
+		c.closed = true
+		close(c.events)
+	}
+	c.mu.Unlock()
+	if pc != nil {
+		pc.Close()
+	}
+	return nil
+}
+
+func (c *TextRoom) onDataChannel(dc *webrtc.DataChannel) {
+	log.Info("DataChannel", logging.F("handle", c.handle.ID), logging.F("label", dc.Label()))
+	dc.OnOpen(func() {
+		c.mu.Lock()
+		opened := c.dc != nil
+		c.dc = dc
+		c.mu.Unlock()
+		if !opened {
+			close(c.opened)
+		}
+	})
+	dc.OnMessage(c.onMessage)
+}
+
+func (c *TextRoom) onMessage(dcMsg webrtc.DataChannelMessage) {
+	msg := jwsapi.Message{}
+	decoder := json.NewDecoder(bytes.NewReader(dcMsg.Data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&msg); err != nil {
+		log.Warn("invalid message", logging.F("handle", c.handle.ID), logging.F("err", err))
+		return
+	}
+	if tid, ok := msg.String("transaction"); ok {
+		c.mu.Lock()
+		rsp, ok := c.transactions[tid]
+		c.mu.Unlock()
+		if ok {
+			rsp <- msg
+		}
+		return
+	}
+
+	//events is closed by Close, send under lock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.events <- newEvent(msg):
+	default:
+		log.Warn("event dropped", logging.F("handle", c.handle.ID), logging.F("textroom", msg["textroom"]))
+	}
+}
+
+func (c *TextRoom) onICECandidate(candidate *webrtc.ICECandidate) {
+	if candidate == nil {
+		c.handle.TrickleContext(c.ctx, jwsapi.Message{
+			"completed": true,
+		})
+		return
+	}
+	ci := candidate.ToJSON()
+	msg := jwsapi.Message{
+		"candidate": ci.Candidate,
+	}
+	if ci.SDPMLineIndex != nil {
+		msg["sdpMLineIndex"] = *ci.SDPMLineIndex
+	}
+	if ci.SDPMid != nil {
+		msg["sdpMid"] = *ci.SDPMid
+	}
+	c.handle.TrickleContext(c.ctx, msg)
+}
+
+func (c *TextRoom) onTrickle(msg jwsapi.Message) {
+	candidate, ok := msg.SubMessage("candidate")
+	if !ok || candidate.Bool("completed") {
+		return
+	}
+	value, ok := candidate.String("candidate")
+	if !ok {
+		return
+	}
+	sdpMLineIndex, _ := candidate.Uint16("sdpMLineIndex")
+	sdpMid, _ := candidate.String("sdpMid")
+	select {
+	case c.remoteCandidates <- webrtc.ICECandidateInit{Candidate: value, SDPMLineIndex: &sdpMLineIndex, SDPMid: &sdpMid}:
+	default:
+		//setup failed or not started, must not block callback of handle
+		log.Warn("remote candidate dropped", logging.F("handle", c.handle.ID))
+	}
+}
+
+//doRemoteCandidate add remote candidates to pc until ctx done or stop
+func (c *TextRoom) doRemoteCandidate(pc *webrtc.PeerConnection, stop <-chan struct{}) {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-stop:
+			return
+		case candidate := <-c.remoteCandidates:
+			if err := pc.AddICECandidate(candidate); err != nil {
+				log.Warn("AddICECandidate", logging.F("handle", c.handle.ID), logging.F("err", err))
+			}
+		}
+	}
+}
+
+func (c *TextRoom) onHangup(msg jwsapi.Message) {
+	c.mu.Lock()
+	pc := c.pc
+	c.mu.Unlock()
+	if pc != nil {
+		pc.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package textroom_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jtextroom"
+	"github.com/newzai/janus-go/textroom"
+	"github.com/pion/webrtc/v2"
+)
+
+func newTestSetting() webrtc.SettingEngine {
+	setting := webrtc.SettingEngine{}
+	setting.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	return setting
+}
+
+func newTestClient(t *testing.T, ctx context.Context, s *janustest.Server) *textroom.TextRoom {
+	t.Helper()
+	c := textroom.NewTextRoom(ctx, janustest.Attach(t, ctx, s, jtextroom.Plugin), textroom.WithTextRoomSettingEngine(newTestSetting()))
+	if err := c.Setup(); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.TextRoomPlugin, janustest.NewTextRoom(janustest.WithTextRoomSettingEngine(newTestSetting())))
+
+	admin := janustest.Attach(t, ctx, s, jtextroom.Plugin)
+	room, err := jtextroom.CreateRoom(admin, 0, jtextroom.WithMessageOptionDescription("chat"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if exists, err := jtextroom.Exists(admin, room); err != nil || !exists {
+		t.Fatalf("exists = %v, %v", exists, err)
+	}
+	err = jtextroom.Kick(admin, room+1, "nobody")
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.TextRoomErrorNoSuchRoom {
+		t.Errorf("kick of unknown room: err = %v, want %d", err, janustest.TextRoomErrorNoSuchRoom)
+	}
+
+	alice := newTestClient(t, ctx, s)
+	bob := newTestClient(t, ctx, s)
+	if _, err := alice.Join(room, "alice", "Alice"); err != nil {
+		t.Fatalf("join alice: %v", err)
+	}
+	participants, err := bob.Join(room, "bob", "")
+	if err != nil {
+		t.Fatalf("join bob: %v", err)
+	}
+	if len(participants) != 1 || participants[0].Username() != "alice" || participants[0].Display() != "Alice" {
+		t.Errorf("participants = %v, want alice", participants)
+	}
+	_, err = alice.Join(room, "bob", "")
+	if !errors.As(err, &info) || info.Code != janustest.TextRoomErrorAlreadyInRoom {
+		t.Errorf("join again: err = %v, want %d", err, janustest.TextRoomErrorAlreadyInRoom)
+	}
+	if list, err := jtextroom.ListParticipants(admin, room); err != nil || len(list) != 2 {
+		t.Errorf("listparticipants = %v, %v, want 2", list, err)
+	}
+
+	if err := alice.Send(room, "hello"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-bob.Events():
+			if e.Type != textroom.EventMessage {
+				continue
+			}
+			if e.Room != room || e.From != "alice" || e.Text != "hello" {
+				t.Errorf("event = %+v", e)
+			}
+			return
+		case <-timeout:
+			t.Fatal("message is not received")
+		}
+	}
+}
+
+func TestTextRoomClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.TextRoomPlugin, janustest.NewTextRoom(janustest.WithTextRoomSettingEngine(newTestSetting())))
+
+	//data channel can not be open in 1ns, pc is closed by setup
+	c := textroom.NewTextRoom(ctx, janustest.Attach(t, ctx, s, jtextroom.Plugin),
+		textroom.WithTextRoomSettingEngine(newTestSetting()), textroom.WithTextRoomTimeout(time.Nanosecond))
+	if err := c.Setup(); err == nil {
+		t.Fatal("setup is not timeout")
+	}
+	if _, err := c.Join(1234, "alice", ""); err == nil {
+		t.Error("join without data channel")
+	}
+
+	c.Close()
+	c.Close()
+	select {
+	case e, ok := <-c.Events():
+		if ok {
+			t.Errorf("event after close %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Error("events is not closed")
+	}
+}
@@ -0,0 +1,429 @@
+//Package videocall pion clients of janus videocall plugin, see jwsapi/jplugin/jvideocall
+package videocall
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideocall"
+	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("videocall")
+
+//span attributes
+const attrKeyVideoCallPeer = attribute.Key("janus.videocall.peer")
+
+//Track track
+type Track = rtcsession.Track
+
+//Stats statistics snapshot of a session
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//codecs of incoming call accepted by VideoCall
+var (
+	videoCallAudioCodecs = []string{"opus"}
+	videoCallVideoCodecs = []string{"h264", "vp8"}
+)
+
+//VideoCall 1:1 call of janus-gateway videocall plugin, send audio and video to peer, recv audio and video of peer
+//
+//	c := videocall.NewVideoCall(ctx, api, h, jvideocall.WithClientEvent(func(e jvideocall.Event) {
+//		if e.Type == jvideocall.EventIncomingCall {
+//			go c.Accept(e.SDP)
+//		}
+//	}))
+//	c.Register("agent")
+type VideoCall struct {
+	rtc     *rtcsession.Session
+	jClient *jvideocall.Client
+	tracks  [2]*Track
+
+	onAudioTrack func(context.Context, *webrtc.Track)
+	onVideoTrack func(context.Context, *webrtc.Track)
+}
+
+//VideoCallOption option for VideoCall
+type VideoCallOption func(*VideoCall)
+
+//WithVideoCallAudioTrack using to setting audio track of peer callback
+func WithVideoCallAudioTrack(callback func(context.Context, *webrtc.Track)) VideoCallOption {
+	return func(c *VideoCall) {
+		c.onAudioTrack = callback
+	}
+}
+
+//WithVideoCallVideoTrack using to setting video track of peer callback
+func WithVideoCallVideoTrack(callback func(context.Context, *webrtc.Track)) VideoCallOption {
+	return func(c *VideoCall) {
+		c.onVideoTrack = callback
+	}
+}
+
+//WithVideoCallConfigure set webrtc configure
+func WithVideoCallConfigure(configure webrtc.Configuration) VideoCallOption {
+	return func(c *VideoCall) {
+		c.rtc.Configure = configure
+	}
+}
+
+//WithVideoCallStats report stats every interval
+func WithVideoCallStats(interval time.Duration, callback func(Stats)) VideoCallOption {
+	return func(c *VideoCall) {
+		c.rtc.StatsInterval = interval
+		c.rtc.OnStats = callback
+	}
+}
+
+//NewVideoCall new video call, h is handle of janus.plugin.videocall
+//jvideocall.WithClientEvent for incomingcall, hangup... then Accept offer of incomingcall
+func NewVideoCall(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, opts ...jvideocall.ClientOption) *VideoCall {
+	c := &VideoCall{
+		jClient: jvideocall.NewClient(ctx, h, opts...),
+	}
+	c.rtc = rtcsession.New(ctx, api, h, c.ID)
+
+	h.SetCallback(jwsapi.WithHandleTrickle(c.rtc.OnTrickle))
+	h.SetCallback(jwsapi.WithHandleHangup(c.onHangup))
+	h.SetCallback(jwsapi.WithHandleWebrtcup(c.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(c.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(c.rtc.OnSlowLink))
+
+	//PeerConnection is changed by every call
+	go c.rtc.DoRemoteCandidate(c.rtc.RemoteCandidates)
+	return c
+}
+
+//Object return jvideocall.Client
+func (c *VideoCall) Object() *jvideocall.Client {
+	return c.jClient
+}
+
+//ID return id info
+func (c *VideoCall) ID() string {
+	return fmt.Sprintf("[VideoCall.%s]", c.jClient.Username())
+}
+
+//Stats return statistics snapshot
+func (c *VideoCall) Stats() Stats {
+	return c.rtc.Stats()
+}
+
+//SetOption set option
+func (c *VideoCall) SetOption(opts ...VideoCallOption) *VideoCall {
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+//Register register unique username
+func (c *VideoCall) Register(username string) error {
+	return c.jClient.Register(username)
+}
+
+//Call call username with opus and h264 PeerConnection, return when accepted, error with reason if hangup, eg: User busy
+//api should only have opus and h264 like Publisher, peer answer the first codec of offer
+func (c *VideoCall) Call(username string) (err error) {
+
+	ctx, span := c.rtc.StartSpan(c.rtc.Ctx, "videocall.VideoCall.Call", attrKeyVideoCallPeer.String(username))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	c.rtc.SetTraceLink(ctx)
+
+	pc, senders, err := c.newPeerConnection(ctx, c.rtc.API, webrtc.DefaultPayloadTypeOpus, webrtc.DefaultPayloadTypeH264)
+	if err != nil {
+		return err
+	}
+
+	var offer webrtc.SessionDescription
+	err = c.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateOffer")
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	answer, err := c.jClient.CallContext(ctx, username, offer.SDP)
+	if err != nil {
+		pc.Close()
+		return err
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+
+	for _, sender := range senders {
+		go c.startSender(sender)
+	}
+	go c.rtc.DoStats(pc)
+	return nil
+}
+
+//Accept accept incoming call, offer is sdp of jvideocall.EventIncomingCall
+//audio is opus, video is the first of offer in h264, vp8, no video track if offer has not
+func (c *VideoCall) Accept(offer string) (err error) {
+
+	ctx, span := c.rtc.StartSpan(c.rtc.Ctx, "videocall.VideoCall.Accept", attrKeyVideoCallPeer.String(c.jClient.Peer()))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	c.rtc.SetTraceLink(ctx)
+
+	audioPT, ok := rtcsession.OfferPayloadType(offer, webrtc.RTPCodecTypeAudio, videoCallAudioCodecs...)
+	if !ok {
+		return errors.New("no audio codec of offer")
+	}
+	videoPT, _ := rtcsession.OfferPayloadType(offer, webrtc.RTPCodecTypeVideo, videoCallVideoCodecs...)
+	//answer janus-gateway, explicit dtls client(setup:active) match the role when janus-gateway is ice-lite
+	setting := rtcsession.DefaultSettingEngine()
+	setting.SetAnsweringDTLSRole(webrtc.DTLSRoleClient)
+	api := rtcsession.NewAPI(offer, setting, append(videoCallAudioCodecs, videoCallVideoCodecs...)...)
+
+	pc, senders, err := c.newPeerConnection(ctx, api, audioPT, videoPT)
+	if err != nil {
+		return err
+	}
+
+	err = c.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeOffer,
+			SDP:  offer,
+		})
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetRemoteDescription")
+	}
+	var answer webrtc.SessionDescription
+	err = c.rtc.Trace(ctx, "webrtc.CreateAnswer", func() (err error) {
+		answer, err = pc.CreateAnswer(nil)
+		return err
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "CreateAnswer")
+	}
+	err = c.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(answer)
+	})
+	if err != nil {
+		pc.Close()
+		return errors.Wrap(err, "SetLocalDescription")
+	}
+
+	if err = c.jClient.Accept(answer.SDP); err != nil {
+		pc.Close()
+		return errors.Wrap(err, "accept")
+	}
+
+	for _, sender := range senders {
+		go c.startSender(sender)
+	}
+	go c.rtc.DoStats(pc)
+	return nil
+}
+
+//Set configure call, jvideocall.WithMessageOptionAudio, jvideocall.WithMessageOptionBitrate...
+func (c *VideoCall) Set(opts ...jwsapi.MessageOption) error {
+	return c.jClient.Set(opts...)
+}
+
+//Hangup hangup current call, close PeerConnection
+func (c *VideoCall) Hangup() error {
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+	return c.jClient.Hangup()
+}
+
+//GetTrack return track to peer by kind, nil before Call or Accept, video is nil if not negotiated
+func (c *VideoCall) GetTrack(kind webrtc.RTPCodecType) *Track {
+
+	switch kind {
+	case webrtc.RTPCodecTypeAudio:
+		return c.tracks[0]
+	case webrtc.RTPCodecTypeVideo:
+		return c.tracks[1]
+	default:
+		return nil
+	}
+}
+
+//ReadRTP read rtp from track of peer and update stats
+//track callback should using this instead of track.ReadRTP
+func (c *VideoCall) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	c.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+//RequestKeyFrame send PLI to janus for video of peer
+func (c *VideoCall) RequestKeyFrame() error {
+	if c.rtc.PC == nil {
+		return errors.New("not in call")
+	}
+	ssrc := c.rtc.Collector.Snapshot().Video.SSRC
+	if ssrc == 0 {
+		return errors.New("video not received")
+	}
+	packets := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}}
+	err := c.rtc.PC.WriteRTCP(packets)
+	if err == nil {
+		c.rtc.Collector.OnSendRTCP(packets)
+	}
+	return err
+}
+
+//newPeerConnection PeerConnection with audio and video track of payload type, video is not added if videoPT is 0
+func (c *VideoCall) newPeerConnection(ctx context.Context, api *webrtc.API, audioPT uint8, videoPT uint8) (*webrtc.PeerConnection, []*webrtc.RTPSender, error) {
+	var pc *webrtc.PeerConnection
+	err := c.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = api.NewPeerConnection(c.rtc.Configure)
+		return err
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "NewPeerConnection")
+	}
+
+	pc.OnTrack(c.onTrack)
+	pc.OnICECandidate(c.rtc.OnICECandidate)
+	pc.OnConnectionStateChange(c.onPeerConnectionState)
+
+	var tracks [2]*Track
+	var senders []*webrtc.RTPSender
+	audioTrack, err := pc.NewTrack(audioPT, rand.Uint32(), "audio", "callA0")
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "NewTrack(Audio)")
+	}
+	sender, err := pc.AddTrack(audioTrack)
+	if err != nil {
+		pc.Close()
+		return nil, nil, errors.Wrap(err, "AddTrack(Audio)")
+	}
+	tracks[0] = rtcsession.NewTrack(audioTrack, c.rtc.Collector)
+	senders = append(senders, sender)
+
+	if videoPT != 0 {
+		videoTrack, err := pc.NewTrack(videoPT, rand.Uint32(), "video", "callV0")
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "NewTrack(Video)")
+		}
+		sender, err := pc.AddTrack(videoTrack)
+		if err != nil {
+			pc.Close()
+			return nil, nil, errors.Wrap(err, "AddTrack(Video)")
+		}
+		tracks[1] = rtcsession.NewTrack(videoTrack, c.rtc.Collector)
+		senders = append(senders, sender)
+	}
+
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+	c.rtc.PC = pc
+	c.tracks = tracks
+	return pc, senders, nil
+}
+
+func (c *VideoCall) onHangup(msg jwsapi.Message) {
+	if c.rtc.PC != nil {
+		c.rtc.PC.Close()
+	}
+}
+
+func (c *VideoCall) onWebrtcup(msg jwsapi.Message) {
+	log.Info("webrtcup", logging.F("videocall", c.ID()))
+}
+
+func (c *VideoCall) onPeerConnectionState(state webrtc.PeerConnectionState) {
+	log.Info("PeerConnectionState", logging.F("videocall", c.ID()), logging.F("state", state.String()))
+}
+
+func (c *VideoCall) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
+
+	log.Info("onTrack", logging.F("videocall", c.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
+
+	go c.startReceiver(receiver)
+
+	if codec := track.Codec(); codec != nil {
+		c.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
+	switch track.Kind() {
+	case webrtc.RTPCodecTypeAudio:
+		if c.onAudioTrack != nil {
+			c.onAudioTrack(c.rtc.Ctx, track)
+			return
+		}
+	case webrtc.RTPCodecTypeVideo:
+		if c.onVideoTrack != nil {
+			c.onVideoTrack(c.rtc.Ctx, track)
+			return
+		}
+	}
+
+	//no callback for user
+	for c.rtc.Ctx.Err() == nil {
+		if _, err := c.ReadRTP(track); err != nil {
+			return
+		}
+	}
+}
+
+func (c *VideoCall) startSender(sender *webrtc.RTPSender) {
+	for c.rtc.Ctx.Err() == nil {
+		packets, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		c.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
+
+func (c *VideoCall) startReceiver(receiver *webrtc.RTPReceiver) {
+	for c.rtc.Ctx.Err() == nil {
+		packets, err := receiver.ReadRTCP()
+		if err != nil {
+			return
+		}
+		c.rtc.Collector.OnRecvRTCP(packets)
+	}
+}
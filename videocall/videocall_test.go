@@ -0,0 +1,107 @@
+package videocall_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideocall"
+	"github.com/newzai/janus-go/videocall"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestVideoCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.VideoCallPlugin, janustest.NewVideoCall())
+
+	alice := videocall.NewVideoCall(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideocall.Plugin))
+	if err := alice.Register("alice"); err != nil {
+		t.Fatalf("register alice: %v", err)
+	}
+	//error of plugin is *jplugin.Error
+	err := videocall.NewVideoCall(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideocall.Plugin)).Register("alice")
+	var info *jplugin.Error
+	if !errors.As(err, &info) || info.Code != janustest.VideoCallErrorUsernameTaken {
+		t.Errorf("register taken username: err = %v, want %d", err, janustest.VideoCallErrorUsernameTaken)
+	}
+	err = alice.Call("nobody")
+	if !errors.As(err, &info) || info.Code != janustest.VideoCallErrorNoSuchUsername {
+		t.Errorf("call of unknown username: err = %v, want %d", err, janustest.VideoCallErrorNoSuchUsername)
+	}
+
+	packets := make(chan *rtp.Packet, 16)
+	incoming := make(chan jvideocall.Event, 1)
+	bob := videocall.NewVideoCall(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideocall.Plugin), jvideocall.WithClientEvent(func(e jvideocall.Event) {
+		if e.Type == jvideocall.EventIncomingCall {
+			incoming <- e
+		}
+	}))
+	bob.SetOption(videocall.WithVideoCallAudioTrack(func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := bob.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			packets <- packet
+		}
+	}))
+	if err := bob.Register("bob"); err != nil {
+		t.Fatalf("register bob: %v", err)
+	}
+	if list, err := alice.Object().List(); err != nil || len(list) != 2 {
+		t.Errorf("list = %v, %v, want alice and bob", list, err)
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		select {
+		case e := <-incoming:
+			if e.Username != "alice" || e.SDPType != "offer" {
+				t.Errorf("incomingcall = %+v", e)
+			}
+			accepted <- bob.Accept(e.SDP)
+		case <-time.After(5 * time.Second):
+			accepted <- errors.New("incomingcall is not received")
+		}
+	}()
+	if err := alice.Call("bob"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if err := <-accepted; err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	defer alice.Hangup()
+	if peer := alice.Object().Peer(); peer != "bob" {
+		t.Errorf("peer = %s, want bob", peer)
+	}
+
+	deadline := time.After(10 * time.Second)
+	track := alice.GetTrack(webrtc.RTPCodecTypeAudio)
+	for seq := uint32(1); ; seq++ {
+		err := track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), Timestamp: seq * 960, SSRC: track.SSRC()},
+			Payload: []byte{0xf8, 0xff, 0xfe},
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		select {
+		case <-packets:
+			if stats := bob.Stats(); stats.Audio.Packets == 0 {
+				t.Errorf("stats of audio = %+v", stats.Audio)
+			}
+			return
+		case <-deadline:
+			t.Fatal("audio of call is not received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -3,40 +3,28 @@ package videoroom
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"sync"
+	"time"
 
+	"github.com/newzai/janus-go/internal/rtcsession"
 	"github.com/newzai/janus-go/jwsapi"
 	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
 	"github.com/newzai/janus-go/logging"
-	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pkg/errors"
 )
 
-//Track track
-type Track struct {
-	track *webrtc.Track
-	seqNo uint16
-}
-
-//WriteRTP write rtp
-func (t *Track) WriteRTP(packet *rtp.Packet) error {
-	t.seqNo++
-	packet.SequenceNumber = t.seqNo
-	return t.track.WriteRTP(packet)
-}
-
-//SSRC return ssrc
-func (t *Track) SSRC() uint32 {
-	return t.track.SSRC()
-}
-
 //Publisher a publisher user,
 type Publisher struct {
 	BaseSession
 	jPub    *jvideoroom.Publisher
 	tracks  []*Track
 	senders []*webrtc.RTPSender
+
+	mu         sync.Mutex
+	dc         *webrtc.DataChannel //lock by mu, closed by Unpublish while SendData
+	dataLabel  string
+	onDataOpen func()
 }
 
 //PublisherOption option
@@ -45,24 +33,36 @@ type PublisherOption func(*Publisher)
 //WithPublisherConfigure set webrtc configure
 func WithPublisherConfigure(configure webrtc.Configuration) PublisherOption {
 	return func(p *Publisher) {
-		p.configure = configure
+		p.rtc.Configure = configure
+	}
+}
+
+//WithPublisherStats report stats every interval
+func WithPublisherStats(interval time.Duration, callback func(Stats)) PublisherOption {
+	return func(p *Publisher) {
+		p.rtc.StatsInterval = interval
+		p.rtc.OnStats = callback
+	}
+}
+
+//WithPublisherDataChannel publish with data channel, eg: captions, metadata to subscribers
+//label default is JanusDataChannel, onOpen is called when data channel is open, can be nil
+func WithPublisherDataChannel(label string, onOpen func()) PublisherOption {
+	return func(p *Publisher) {
+		if label == "" {
+			label = rtcsession.DefaultDataChannelLabel
+		}
+		p.dataLabel = label
+		p.onDataOpen = onOpen
 	}
 }
 
 //NewPublisher new publihser
 func NewPublisher(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, room uint64, opts ...jvideoroom.PublisherOption) *Publisher {
 	p := &Publisher{
-		BaseSession: BaseSession{
-			ctx:    ctx,
-			api:    api,
-			handle: h,
-			configure: webrtc.Configuration{
-				SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback,
-			},
-			remoteCandidates: make(chan jwsapi.Message, 8),
-		},
 		jPub: jvideoroom.NewPublisher(ctx, h, room, opts...),
 	}
+	p.rtc = rtcsession.New(ctx, api, h, p.ID)
 	return p
 }
 
@@ -86,97 +86,150 @@ func (p *Publisher) SetOption(opts ...PublisherOption) *Publisher {
 
 //Join join to the
 func (p *Publisher) Join(opts ...jwsapi.MessageOption) error {
-	err := p.jPub.Join(opts...)
+	err := p.jPub.JoinContext(p.rtc.Ctx, opts...)
 	return err
 }
 
 //Publish start send stream
-func (p *Publisher) Publish(audio bool, video bool, opts ...jwsapi.MessageOption) error {
-
-	pc, err := p.api.NewPeerConnection(p.configure)
+func (p *Publisher) Publish(audio bool, video bool, opts ...jwsapi.MessageOption) (err error) {
+
+	ctx, span := p.rtc.StartSpan(p.rtc.Ctx, "videoroom.Publisher.Publish", attrKeyRoom.Int64(int64(p.jPub.Room())), attrKeyFeed.Int64(int64(p.jPub.ID())))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	p.rtc.SetTraceLink(ctx)
+
+	var pc *webrtc.PeerConnection
+	err = p.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = p.rtc.API.NewPeerConnection(p.rtc.Configure)
+		return err
+	})
 	if err != nil {
 		return errors.Wrap(err, "NewPeerConnection")
 	}
-	p.pc = pc
+	p.rtc.PC = pc
 
-	p.handle.SetCallback(jwsapi.WithHandleHangup(p.onHangup))
-	p.handle.SetCallback(jwsapi.WithHandleWebrtcup(p.onWebrtcup))
-	p.handle.SetCallback(jwsapi.WithHandleMedia(p.onMedia))
-	p.handle.SetCallback(jwsapi.WithHandleSlowLink(p.onSlowlink))
+	p.rtc.Handle.SetCallback(jwsapi.WithHandleHangup(p.onHangup))
+	p.rtc.Handle.SetCallback(jwsapi.WithHandleWebrtcup(p.onWebrtcup))
+	p.rtc.Handle.SetCallback(jwsapi.WithHandleMedia(p.rtc.OnMedia))
+	p.rtc.Handle.SetCallback(jwsapi.WithHandleSlowLink(p.rtc.OnSlowLink))
 
 	pc.OnConnectionStateChange(p.onPeerConnectionState)
-	pc.OnICECandidate(p.onICECandidate)
+	pc.OnICECandidate(p.rtc.OnICECandidate)
 	pc.OnICEConnectionStateChange(p.onICEConnectionStateChange)
 
-	audioTrack, err := pc.NewTrack(webrtc.DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pionA0")
+	tracks, senders, err := p.rtc.AddSendTracks(pc, "pion")
 	if err != nil {
 		pc.Close()
-		return errors.Wrap(err, "NewTrack(Audio)")
-
+		return err
 	}
-	audioSender, err := pc.AddTrack(audioTrack)
-	if err != nil {
-		pc.Close()
-		return errors.Wrap(err, "AddTrack(Audio)")
-
-	}
-	videoTrack, err := pc.NewTrack(webrtc.DefaultPayloadTypeH264, rand.Uint32(), "video", "pionV0")
-	if err != nil {
-		pc.Close()
-		return errors.Wrap(err, "NewTrack(Video)")
-
+	p.tracks, p.senders = tracks, senders
+
+	var dc *webrtc.DataChannel
+	if p.dataLabel != "" {
+		dc, err = pc.CreateDataChannel(p.dataLabel, nil)
+		if err != nil {
+			pc.Close()
+			return errors.Wrap(err, "CreateDataChannel")
+		}
+		dc.OnOpen(func() {
+			log.Info("DataChannel open", logging.F("publisher", p.ID()), logging.F("label", dc.Label()))
+			if p.onDataOpen != nil {
+				p.onDataOpen()
+			}
+		})
+		p.mu.Lock()
+		p.dc = dc
+		p.mu.Unlock()
 	}
 
-	videoSender, err := pc.AddTrack(videoTrack)
-	if err != nil {
-		pc.Close()
-		return errors.Wrap(err, "AddTrack(Video)")
-
-	}
-
-	p.tracks = append(p.tracks, &Track{audioTrack, 0}, &Track{videoTrack, 0})
-	p.senders = append(p.senders, audioSender, videoSender)
-
-	offer, err := pc.CreateOffer(nil)
+	var offer webrtc.SessionDescription
+	err = p.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		offer, err = pc.CreateOffer(nil)
+		return err
+	})
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "CreateOffer(Video)")
 
 	}
 
-	err = pc.SetLocalDescription(offer)
+	err = p.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(offer)
+	})
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "SetLocalDescription(Video)")
 	}
 
-	answer, err := p.jPub.Publish(audio, video, false, offer.SDP, true, opts...)
+	answer, err := p.jPub.PublishContext(ctx, audio, video, dc != nil, offer.SDP, true, opts...)
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "publish")
 	}
-	err = pc.SetRemoteDescription(webrtc.SessionDescription{
-		Type: webrtc.SDPTypeAnswer,
-		SDP:  answer,
+	err = p.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  answer,
+		})
 	})
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "SetRemoteDescription")
 	}
+
+	for _, sender := range p.senders {
+		go p.startSender(sender)
+	}
+	go p.rtc.DoStats(pc)
 	return nil
 }
 
 //Unpublish cancel publish
 func (p *Publisher) Unpublish() error {
 
-	if p.pc != nil {
-		p.pc.Close()
-		p.pc = nil
+	if p.rtc.PC != nil {
+		p.rtc.PC.Close()
+		p.rtc.PC = nil
 
 	}
+	p.mu.Lock()
+	p.dc = nil
+	p.mu.Unlock()
 	return p.jPub.Unpublish()
 }
 
+//SendData send binary over data channel, WithPublisherDataChannel is required
+func (p *Publisher) SendData(data []byte) error {
+	dc, err := p.dataChannel()
+	if err != nil {
+		return err
+	}
+	return dc.Send(data)
+}
+
+//SendText send text over data channel, WithPublisherDataChannel is required
+func (p *Publisher) SendText(text string) error {
+	dc, err := p.dataChannel()
+	if err != nil {
+		return err
+	}
+	return dc.SendText(text)
+}
+
+func (p *Publisher) dataChannel() (*webrtc.DataChannel, error) {
+	p.mu.Lock()
+	dc := p.dc
+	p.mu.Unlock()
+	if dc == nil {
+		return nil, errors.New("data channel not published")
+	}
+	if dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return nil, errors.New("data channel not open")
+	}
+	return dc, nil
+}
+
 //GetTrack return track by kind
 func (p *Publisher) GetTrack(kind webrtc.RTPCodecType) *Track {
 
@@ -191,25 +244,34 @@ func (p *Publisher) GetTrack(kind webrtc.RTPCodecType) *Track {
 }
 
 func (p *Publisher) onHangup(msg jwsapi.Message) {
-	if p.pc != nil {
-		p.pc.Close()
-		p.pc = nil
+	if p.rtc.PC != nil {
+		p.rtc.PC.Close()
+		p.rtc.PC = nil
 	}
 }
 func (p *Publisher) onWebrtcup(msg jwsapi.Message) {
-	logging.Infof("%s webrtcup ", p.ID())
-}
-
-func (p *Publisher) onMedia(msg jwsapi.Message) {
-
+	log.Info("webrtcup", logging.F("publisher", p.ID()))
 }
-func (p *Publisher) onSlowlink(msg jwsapi.Message) {
 
+func (p *Publisher) startSender(sender *webrtc.RTPSender) {
+
+	for {
+		select {
+		case <-p.rtc.Ctx.Done():
+			return
+		default:
+			packets, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			p.rtc.Collector.OnRecvRTCP(packets)
+		}
+	}
 }
 
 func (p *Publisher) onICEConnectionStateChange(state webrtc.ICEConnectionState) {
-	logging.Infof("%s ICEConnectionState %s", p.ID(), state.String())
+	log.Info("ICEConnectionState", logging.F("publisher", p.ID()), logging.F("state", state.String()))
 }
 func (p *Publisher) onPeerConnectionState(state webrtc.PeerConnectionState) {
-	logging.Infof("%s PeerConnectionState %s", p.ID(), state.String())
+	log.Info("PeerConnectionState", logging.F("publisher", p.ID()), logging.F("state", state.String()))
 }
@@ -3,10 +3,15 @@ package videoroom
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/newzai/janus-go/internal/rtcsession"
 	"github.com/newzai/janus-go/jwsapi"
 	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
 	"github.com/newzai/janus-go/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pkg/errors"
 )
@@ -19,6 +24,7 @@ type Subscriber struct {
 
 	onAudioTrack func(context.Context, *webrtc.Track)
 	onVideoTrack func(context.Context, *webrtc.Track)
+	onData       func(webrtc.DataChannelMessage)
 }
 
 //SubscriberOption option for Subscriber
@@ -38,31 +44,41 @@ func WithSubscriberVideoTrack(callback func(context.Context, *webrtc.Track)) Sub
 	}
 }
 
+//WithSubscriberData receive data of publisher, subscribe with data=true
+//data channel is negotiated only if publisher has published data
+func WithSubscriberData(callback func(webrtc.DataChannelMessage)) SubscriberOption {
+	return func(s *Subscriber) {
+		s.onData = callback
+	}
+}
+
 //WithSubscriberConfigure set webrtc configure
 func WithSubscriberConfigure(configure webrtc.Configuration) SubscriberOption {
 	return func(s *Subscriber) {
-		s.configure = configure
+		s.rtc.Configure = configure
+	}
+}
+
+//WithSubscriberStats report stats every interval
+func WithSubscriberStats(interval time.Duration, callback func(Stats)) SubscriberOption {
+	return func(s *Subscriber) {
+		s.rtc.StatsInterval = interval
+		s.rtc.OnStats = callback
 	}
 }
 
 //NewSubscriber new subscriber
 func NewSubscriber(ctx context.Context, api *webrtc.API, h *jwsapi.Handle, room uint64, feed uint64) *Subscriber {
 	s := &Subscriber{
-		BaseSession: BaseSession{
-			ctx:    ctx,
-			api:    api,
-			handle: h,
-			configure: webrtc.Configuration{
-				SDPSemantics: webrtc.SDPSemanticsUnifiedPlanWithFallback,
-			},
-			remoteCandidates: make(chan jwsapi.Message, 8),
-		},
 		jSub: jvideoroom.NewSubscriber(ctx, h, room, feed),
 	}
+	s.rtc = rtcsession.New(ctx, api, h, s.ID)
 
-	h.SetCallback(jwsapi.WithHandleTrickle(s.onTrickle))
+	h.SetCallback(jwsapi.WithHandleTrickle(s.rtc.OnTrickle))
 	h.SetCallback(jwsapi.WithHandleHangup(s.onHangup))
 	h.SetCallback(jwsapi.WithHandleWebrtcup(s.onWebrtcup))
+	h.SetCallback(jwsapi.WithHandleMedia(s.rtc.OnMedia))
+	h.SetCallback(jwsapi.WithHandleSlowLink(s.rtc.OnSlowLink))
 
 	return s
 }
@@ -74,7 +90,7 @@ func (s *Subscriber) Object() *jvideoroom.Subscriber {
 
 //ID return id for this subscriber
 func (s *Subscriber) ID() string {
-	return fmt.Sprintf("[%d.Feed.%d", s.jSub.Room(), s.jSub.Feed())
+	return fmt.Sprintf("[%d.Feed.%d]", s.jSub.Room(), s.jSub.Feed())
 }
 
 //SetOption set option, for callback
@@ -86,32 +102,45 @@ func (s *Subscriber) SetOption(opts ...SubscriberOption) *Subscriber {
 }
 
 //Start start pull stream from janus
-//audio,video default is true, data default is false
+//audio,video default is true, data default is false, true if WithSubscriberData
 //optional or default param can use jwsapi.WithMessageOption to setting
 //other params see  https://jwsapi.conf.meetecho.com/docs/videoroom.html VideoRoom Subscribers join
 //jwsapi.WithMessageOption("video",false) to ignore video stream
 //janus-gateway must open ice-lite=true
-func (s *Subscriber) Start(opts ...jwsapi.MessageOption) error {
+func (s *Subscriber) Start(opts ...jwsapi.MessageOption) (err error) {
 
-	offer, err := s.jSub.Join(opts...)
+	ctx, span := s.rtc.StartSpan(s.rtc.Ctx, "videoroom.Subscriber.Start", attrKeyRoom.Int64(int64(s.jSub.Room())), attrKeyFeed.Int64(int64(s.jSub.Feed())))
+	defer func() {
+		rtcsession.EndSpan(span, err)
+	}()
+	s.rtc.SetTraceLink(ctx)
+
+	if s.onData != nil {
+		opts = append([]jwsapi.MessageOption{jwsapi.WithMessageOption("data", true)}, opts...)
+	}
+	offer, err := s.jSub.JoinContext(ctx, opts...)
 	if err != nil {
 		return errors.Wrap(err, "join")
 	}
 
-	api := initAPI(offer)
+	api := rtcsession.InitAPI(offer)
 	if api != nil {
-		s.api = api
+		s.rtc.API = api
 	}
 
-	pc, err := s.api.NewPeerConnection(s.configure)
+	var pc *webrtc.PeerConnection
+	err = s.rtc.Trace(ctx, "webrtc.NewPeerConnection", func() (err error) {
+		pc, err = s.rtc.API.NewPeerConnection(s.rtc.Configure)
+		return err
+	})
 	if err != nil {
 		return errors.Wrap(err, "NewPeerConnection")
 	}
-	s.pc = pc
+	s.rtc.PC = pc
 
 	pc.OnTrack(s.onTrack)
 	pc.OnConnectionStateChange(s.onPeerConnectionState)
-	pc.OnICECandidate(s.onICECandidate)
+	pc.OnICECandidate(s.rtc.OnICECandidate)
 	pc.OnICEConnectionStateChange(s.onICEConnectionStateChange)
 
 	recvOnly := webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}
@@ -130,43 +159,63 @@ func (s *Subscriber) Start(opts ...jwsapi.MessageOption) error {
 	}
 	s.transceivers = append(s.transceivers, vt)
 
-	answer, err := pc.CreateOffer(nil)
+	//local offer is sent as answer, so m=application of janus offer need a local data channel
+	if s.onData != nil && strings.Contains(offer, "m=application") {
+		pc.OnDataChannel(s.onDataChannel)
+		dc, err := pc.CreateDataChannel(rtcsession.DefaultDataChannelLabel, nil)
+		if err != nil {
+			pc.Close()
+			return errors.Wrap(err, "CreateDataChannel")
+		}
+		s.onDataChannel(dc)
+	}
+
+	var answer webrtc.SessionDescription
+	err = s.rtc.Trace(ctx, "webrtc.CreateOffer", func() (err error) {
+		answer, err = pc.CreateOffer(nil)
+		return err
+	})
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "pc.CreateOffer")
 	}
-	pc.SetLocalDescription(answer)
+	s.rtc.Trace(ctx, "webrtc.SetLocalDescription", func() error {
+		return pc.SetLocalDescription(answer)
+	})
 
-	err = s.jSub.Start(answer.SDP, true)
+	err = s.jSub.StartContext(ctx, answer.SDP, true)
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "jvideoroom.Subscriber.Start")
 	}
 
-	err = pc.SetRemoteDescription(webrtc.SessionDescription{
-		Type: webrtc.SDPTypeAnswer,
-		SDP:  offer,
+	err = s.rtc.Trace(ctx, "webrtc.SetRemoteDescription", func() error {
+		return pc.SetRemoteDescription(webrtc.SessionDescription{
+			Type: webrtc.SDPTypeAnswer,
+			SDP:  offer,
+		})
 	})
 	if err != nil {
 		pc.Close()
 		return errors.Wrap(err, "pc.SetRemoteDescription")
 	}
 
-	go s.doRemoteCandidate(s.remoteCandidates)
+	go s.rtc.DoRemoteCandidate(s.rtc.RemoteCandidates)
+	go s.rtc.DoStats(pc)
 	return nil
 }
 
 //Leave leave pull stream
 func (s *Subscriber) Leave() error {
-	if s.pc != nil {
-		s.pc.Close()
+	if s.rtc.PC != nil {
+		s.rtc.PC.Close()
 	}
 	return s.jSub.Leave()
 
 }
 
 func (s *Subscriber) onHangup(msg jwsapi.Message) {
-	s.pc.Close()
+	s.rtc.PC.Close()
 }
 func (s *Subscriber) onWebrtcup(msg jwsapi.Message) {
 
@@ -176,26 +225,30 @@ func (s *Subscriber) onWebrtcup(msg jwsapi.Message) {
 }
 
 func (s *Subscriber) onICEConnectionStateChange(state webrtc.ICEConnectionState) {
-	logging.Infof("ICEConnectionState %s", state.String())
+	log.Info("ICEConnectionState", logging.F("subscriber", s.ID()), logging.F("state", state.String()))
 }
 func (s *Subscriber) onPeerConnectionState(state webrtc.PeerConnectionState) {
-	logging.Infof("PeerConnectionState %s", state.String())
+	log.Info("PeerConnectionState", logging.F("subscriber", s.ID()), logging.F("state", state.String()))
 }
 func (s *Subscriber) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
 
-	logging.Infof("%s onTrack %s SSRC %d PT %d", s.ID(), track.Kind().String(), track.SSRC(), track.PayloadType())
+	log.Info("onTrack", logging.F("subscriber", s.ID()), logging.F("kind", track.Kind().String()), logging.F("ssrc", track.SSRC()), logging.F("pt", track.PayloadType()))
 
 	go s.startReceiver(receiver)
 
+	if codec := track.Codec(); codec != nil {
+		s.rtc.Collector.SetClockRate(track.Kind(), codec.ClockRate)
+	}
+
 	switch track.Kind() {
 	case webrtc.RTPCodecTypeAudio:
 		if s.onAudioTrack != nil {
-			s.onAudioTrack(s.ctx, track)
+			s.onAudioTrack(s.rtc.Ctx, track)
 			return
 		}
 	case webrtc.RTPCodecTypeVideo:
 		if s.onVideoTrack != nil {
-			s.onVideoTrack(s.ctx, track)
+			s.onVideoTrack(s.rtc.Ctx, track)
 			return
 		}
 	}
@@ -203,11 +256,11 @@ func (s *Subscriber) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver)
 	//no callback for user
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-s.rtc.Ctx.Done():
 			return
 		default:
 			//read rtp form track...
-			_, err := track.ReadRTP()
+			_, err := s.ReadRTP(track)
 			if err != nil {
 				return
 			}
@@ -215,11 +268,44 @@ func (s *Subscriber) onTrack(track *webrtc.Track, receiver *webrtc.RTPReceiver)
 	}
 }
 
+func (s *Subscriber) onDataChannel(dc *webrtc.DataChannel) {
+	log.Info("DataChannel", logging.F("subscriber", s.ID()), logging.F("label", dc.Label()))
+	dc.OnMessage(s.onData)
+}
+
+//ReadRTP read rtp from track and update stats
+//audio/video track callback should using this instead of track.ReadRTP
+func (s *Subscriber) ReadRTP(track *webrtc.Track) (*rtp.Packet, error) {
+	packet, err := track.ReadRTP()
+	if err != nil {
+		return nil, err
+	}
+	s.rtc.Collector.OnRecvRTP(track.Kind(), packet)
+	return packet, nil
+}
+
+//RequestKeyFrame send PLI to janus for video track
+func (s *Subscriber) RequestKeyFrame() error {
+	if s.rtc.PC == nil {
+		return errors.New("not started")
+	}
+	ssrc := s.rtc.Collector.Snapshot().Video.SSRC
+	if ssrc == 0 {
+		return errors.New("video not received")
+	}
+	packets := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}}
+	err := s.rtc.PC.WriteRTCP(packets)
+	if err == nil {
+		s.rtc.Collector.OnSendRTCP(packets)
+	}
+	return err
+}
+
 func (s *Subscriber) startRTPTransceiver(tr *webrtc.RTPTransceiver) {
 
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-s.rtc.Ctx.Done():
 			return
 		default:
 			if sender := tr.Sender(); sender != nil {
@@ -236,13 +322,14 @@ func (s *Subscriber) startReceiver(receiver *webrtc.RTPReceiver) {
 
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-s.rtc.Ctx.Done():
 			return
 		default:
-			_, err := receiver.ReadRTCP()
+			packets, err := receiver.ReadRTCP()
 			if err != nil {
 				return
 			}
+			s.rtc.Collector.OnRecvRTCP(packets)
 		}
 	}
 }
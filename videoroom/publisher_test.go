@@ -0,0 +1,134 @@
+package videoroom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+	"github.com/newzai/janus-go/videoroom"
+	"github.com/pion/webrtc/v2"
+)
+
+func TestSendDataUnpublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+
+	h := janustest.Attach(t, ctx, s, janustest.VideoRoomPlugin)
+	open := make(chan struct{})
+	pub := videoroom.NewPublisher(ctx, janustest.NewAPI(), h, 1234)
+	pub.SetOption(videoroom.WithPublisherDataChannel("", func() { close(open) }))
+	if err := pub.Join(); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if err := pub.Publish(true, true); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	select {
+	case <-open:
+	case <-time.After(10 * time.Second):
+		t.Fatal("data channel is not open")
+	}
+
+	//SendText from other goroutine while Unpublish, checked by -race
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				pub.SendText("hello")
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pub.Unpublish()
+	close(stop)
+	<-done
+	if err := pub.SendText("hello"); err == nil {
+		t.Error("SendText after Unpublish is ok")
+	}
+}
+
+func TestSendDataSubscriber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+
+	open := make(chan struct{})
+	pub := publish(t, ctx, s, 1234, videoroom.WithPublisherDataChannel("", func() { close(open) }))
+	defer pub.Unpublish()
+	select {
+	case <-open:
+	case <-time.After(10 * time.Second):
+		t.Fatal("data channel of publisher is not open")
+	}
+
+	messages := make(chan webrtc.DataChannelMessage, 64)
+	sub := videoroom.NewSubscriber(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideoroom.Plugin), 1234, pub.Object().ID())
+	sub.SetOption(videoroom.WithSubscriberData(func(msg webrtc.DataChannelMessage) {
+		messages <- msg
+	}))
+	if err := sub.Start(); err != nil {
+		t.Fatalf("start subscriber: %v", err)
+	}
+	defer sub.Leave()
+
+	cases := []struct {
+		name string
+		send func() error
+		want webrtc.DataChannelMessage
+	}{
+		{"text", func() error { return pub.SendText("hello") }, webrtc.DataChannelMessage{IsString: true, Data: []byte("hello")}},
+		{"binary", func() error { return pub.SendData([]byte{0, 1, 2, 0xff}) }, webrtc.DataChannelMessage{IsString: false, Data: []byte{0, 1, 2, 0xff}}},
+	}
+	var earlier []webrtc.DataChannelMessage
+	for _, c := range cases {
+		//data channel of subscriber may be not open yet, resend until received
+		deadline := time.After(10 * time.Second)
+	recv:
+		for {
+			if err := c.send(); err != nil {
+				t.Fatalf("%s: send: %v", c.name, err)
+			}
+			select {
+			case msg := <-messages:
+				if sameMessage(msg, c.want) {
+					break recv
+				}
+				if !containsMessage(earlier, msg) {
+					t.Fatalf("%s: received %v %x, want %v %x", c.name, msg.IsString, msg.Data, c.want.IsString, c.want.Data)
+				}
+			case <-deadline:
+				t.Fatalf("%s: data of publisher is not received", c.name)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		//resent copies of c may be received later
+		earlier = append(earlier, c.want)
+	}
+}
+
+func sameMessage(a, b webrtc.DataChannelMessage) bool {
+	return a.IsString == b.IsString && string(a.Data) == string(b.Data)
+}
+
+func containsMessage(messages []webrtc.DataChannelMessage, msg webrtc.DataChannelMessage) bool {
+	for _, m := range messages {
+		if sameMessage(m, msg) {
+			return true
+		}
+	}
+	return false
+}
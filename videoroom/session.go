@@ -0,0 +1,37 @@
+package videoroom
+
+import (
+	"github.com/newzai/janus-go/internal/rtcsession"
+	"github.com/newzai/janus-go/logging"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var log = logging.Named("videoroom")
+
+//span attributes
+const (
+	attrKeyRoom = attribute.Key("janus.room")
+	attrKeyFeed = attribute.Key("janus.feed")
+)
+
+//Track track
+type Track = rtcsession.Track
+
+//Stats statistics snapshot of a publisher or subscriber
+type Stats = rtcsession.Stats
+
+//StreamStats rtp statistics of one audio or video stream
+type StreamStats = rtcsession.StreamStats
+
+//SlowLink janus slowlink event
+type SlowLink = rtcsession.SlowLink
+
+//BaseSession base session
+type BaseSession struct {
+	rtc *rtcsession.Session
+}
+
+//Stats return statistics snapshot
+func (s *BaseSession) Stats() Stats {
+	return s.rtc.Stats()
+}
@@ -0,0 +1,106 @@
+package videoroom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi"
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/videoroom"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func findSpan(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+func TestPublishSpans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+
+	h := janustest.Attach(t, ctx, s, janustest.VideoRoomPlugin, jwsapi.WithConnectionTracerProvider(provider))
+	pub := videoroom.NewPublisher(ctx, janustest.NewAPI(), h, 1234)
+	if err := pub.Join(); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if err := pub.Publish(true, true); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	defer pub.Unpublish()
+
+	//trickles are sent by pion ICE goroutine after Publish returned
+	var trickles []tracetest.SpanStub
+	for i := 0; i < 200 && len(trickles) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "webrtc.Trickle" {
+				trickles = append(trickles, span)
+			}
+		}
+	}
+	spans := exporter.GetSpans()
+
+	publish, ok := findSpan(spans, "videoroom.Publisher.Publish")
+	if !ok {
+		t.Fatal("no publish span")
+	}
+	for _, name := range []string{"webrtc.NewPeerConnection", "webrtc.CreateOffer", "webrtc.SetLocalDescription", "webrtc.SetRemoteDescription", "janus.message.configure"} {
+		span, ok := findSpan(spans, name)
+		if !ok {
+			t.Fatalf("no %s span", name)
+		}
+		if span.Parent.SpanID() != publish.SpanContext.SpanID() {
+			t.Errorf("%s is not child of publish span", name)
+		}
+	}
+	configure, _ := findSpan(spans, "janus.message.configure")
+	hasTransaction := false
+	for _, attr := range configure.Attributes {
+		if attr.Key == jwsapi.AttrKeyTransaction && attr.Value.AsString() != "" {
+			hasTransaction = true
+		}
+	}
+	if !hasTransaction {
+		t.Error("no transaction attribute of configure span")
+	}
+
+	if len(trickles) == 0 {
+		t.Fatal("no trickle span")
+	}
+	for _, trickle := range trickles {
+		if trickle.Parent.IsValid() {
+			t.Errorf("trickle span has parent %s, want root", trickle.Parent.SpanID())
+		}
+		if len(trickle.Links) != 1 || trickle.Links[0].SpanContext.SpanID() != publish.SpanContext.SpanID() {
+			t.Errorf("trickle span is not linked to publish span: %v", trickle.Links)
+		}
+	}
+	//child ends before parent, janus.trickle of exported webrtc.Trickle is exported
+	spans = exporter.GetSpans()
+	for _, trickle := range trickles {
+		found := false
+		for _, span := range spans {
+			if span.Name == "janus.trickle" && span.Parent.SpanID() == trickle.SpanContext.SpanID() {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no janus.trickle child of webrtc.Trickle %s", trickle.SpanContext.SpanID())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package videoroom_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newzai/janus-go/jwsapi/janustest"
+	"github.com/newzai/janus-go/jwsapi/jplugin/jvideoroom"
+	"github.com/newzai/janus-go/videoroom"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v2"
+)
+
+type received struct {
+	kind    webrtc.RTPCodecType
+	payload []byte
+}
+
+//readTrack forward packets of track read by sub to packets
+func readTrack(sub *videoroom.Subscriber, packets chan<- received) func(context.Context, *webrtc.Track) {
+	return func(ctx context.Context, track *webrtc.Track) {
+		for {
+			packet, err := sub.ReadRTP(track)
+			if err != nil {
+				return
+			}
+			select {
+			case packets <- received{kind: track.Kind(), payload: packet.Payload}:
+			default:
+			}
+		}
+	}
+}
+
+//publish join and publish to room of fake videoroom
+func publish(t *testing.T, ctx context.Context, s *janustest.Server, room uint64, opts ...videoroom.PublisherOption) *videoroom.Publisher {
+	t.Helper()
+	pub := videoroom.NewPublisher(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideoroom.Plugin), room)
+	pub.SetOption(opts...)
+	if err := pub.Join(); err != nil {
+		t.Fatalf("join publisher: %v", err)
+	}
+	if err := pub.Publish(true, true); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	return pub
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := janustest.NewServer()
+	defer s.Close()
+	s.RegisterPlugin(janustest.VideoRoomPlugin, janustest.NewVideoRoom())
+
+	pub := publish(t, ctx, s, 1234)
+	defer pub.Unpublish()
+
+	packets := make(chan received, 64)
+	sub := videoroom.NewSubscriber(ctx, janustest.NewAPI(), janustest.Attach(t, ctx, s, jvideoroom.Plugin), 1234, pub.Object().ID())
+	sub.SetOption(
+		videoroom.WithSubscriberAudioTrack(readTrack(sub, packets)),
+		videoroom.WithSubscriberVideoTrack(readTrack(sub, packets)))
+	if err := sub.Start(); err != nil {
+		t.Fatalf("start subscriber: %v", err)
+	}
+	defer sub.Leave()
+
+	payloads := map[webrtc.RTPCodecType][]byte{
+		webrtc.RTPCodecTypeAudio: {0xf8, 0xff, 0xfe},
+		webrtc.RTPCodecTypeVideo: {0x90, 0x90, 0x90, 0x90},
+	}
+	got := map[webrtc.RTPCodecType]bool{}
+	deadline := time.After(10 * time.Second)
+	for seq := uint32(1); len(got) < len(payloads); seq++ {
+		for kind, payload := range payloads {
+			track := pub.GetTrack(kind)
+			track.WriteRTP(&rtp.Packet{
+				Header:  rtp.Header{Version: 2, PayloadType: track.PayloadType(), Timestamp: seq * 3000, SSRC: track.SSRC(), Marker: true},
+				Payload: payload,
+			})
+		}
+		select {
+		case r := <-packets:
+			if string(r.payload) != string(payloads[r.kind]) {
+				t.Fatalf("%s payload = %x, want %x", r.kind, r.payload, payloads[r.kind])
+			}
+			got[r.kind] = true
+		case <-deadline:
+			t.Fatalf("media of publisher is not received, got %v", got)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	stats := sub.Stats()
+	if stats.Audio.Packets == 0 || stats.Video.Packets == 0 || stats.Video.Frames == 0 {
+		t.Errorf("stats of subscriber = audio %+v, video %+v", stats.Audio, stats.Video)
+	}
+	if stats := pub.Stats(); stats.Audio.Packets == 0 || stats.Video.Packets == 0 {
+		t.Errorf("stats of publisher = audio %+v, video %+v", stats.Audio, stats.Video)
+	}
+}